@@ -3,6 +3,8 @@ package client
 import (
 	"context"
 	"time"
+
+	youtubemodel "github.com/researchaccelerator-hub/telegram-scraper/model/youtube"
 )
 
 // Client represents a generic client for any platform
@@ -23,6 +25,44 @@ type Client interface {
 	GetChannelType() string
 }
 
+// VideoSearcher is an optional capability of clients that can search a platform
+// for videos independently of any channel
+type VideoSearcher interface {
+	// SearchVideos returns up to maxResults videos matching query that were
+	// published between fromTime and toTime
+	SearchVideos(ctx context.Context, query string, fromTime, toTime time.Time, maxResults int) ([]Message, error)
+}
+
+// CommentFetcher is an optional capability of clients that can read the
+// comments on a video
+type CommentFetcher interface {
+	// GetVideoComments returns up to limit top-level comments on videoID,
+	// most relevant first
+	GetVideoComments(ctx context.Context, videoID string, limit int) ([]youtubemodel.YouTubeComment, error)
+}
+
+// LikeCounter is an optional capability of messages whose platform reports a
+// like count separately from reactions
+type LikeCounter interface {
+	// GetLikeCount returns the like count and whether the platform reported one
+	GetLikeCount() (int64, bool)
+}
+
+// ViewCounter is an optional capability of messages whose platform may hide
+// the view count
+type ViewCounter interface {
+	// GetViewCount returns the view count and whether the platform reported one
+	GetViewCount() (int64, bool)
+}
+
+// CommentCounter is an optional capability of messages whose platform may
+// hide the comment count
+type CommentCounter interface {
+	// GetReportedCommentCount returns the comment count and whether the
+	// platform reported one
+	GetReportedCommentCount() (int64, bool)
+}
+
 // Channel represents a generic channel across platforms
 type Channel interface {
 	// GetID returns the channel ID
@@ -40,6 +80,15 @@ type Channel interface {
 	// GetCountry returns the country code of the channel (if available)
 	GetCountry() string
 	
+	// GetViewCount returns the total views across the channel's content (if available)
+	GetViewCount() int64
+	
+	// GetVideoCount returns the number of videos published by the channel (if available)
+	GetVideoCount() int64
+	
+	// GetPublishedAt returns when the channel was created (if available)
+	GetPublishedAt() time.Time
+	
 	// GetType returns the platform type ("telegram", "youtube")
 	GetType() string
 }
@@ -40,6 +40,21 @@ func (c *TelegramChannel) GetCountry() string {
 	return "" // Not typically available for Telegram
 }
 
+// GetViewCount implements Channel
+func (c *TelegramChannel) GetViewCount() int64 {
+	return 0 // Not available for Telegram channels
+}
+
+// GetVideoCount implements Channel
+func (c *TelegramChannel) GetVideoCount() int64 {
+	return 0 // Not available for Telegram channels
+}
+
+// GetPublishedAt implements Channel
+func (c *TelegramChannel) GetPublishedAt() time.Time {
+	return time.Time{} // Not available for Telegram channels
+}
+
 // GetType implements Channel
 func (c *TelegramChannel) GetType() string {
 	return "telegram"
@@ -51,7 +66,10 @@ type YouTubeChannel struct {
 	Name        string
 	Description string
 	MemberCount int64
-	Country     string // Country code of the channel
+	Country     string    // Country code of the channel
+	ViewCount   int64     // Total views across all videos
+	VideoCount  int64     // Number of public videos
+	PublishedAt time.Time // When the channel was created
 }
 
 // GetID implements Channel
@@ -79,6 +97,21 @@ func (c *YouTubeChannel) GetCountry() string {
 	return c.Country
 }
 
+// GetViewCount implements Channel
+func (c *YouTubeChannel) GetViewCount() int64 {
+	return c.ViewCount
+}
+
+// GetVideoCount implements Channel
+func (c *YouTubeChannel) GetVideoCount() int64 {
+	return c.VideoCount
+}
+
+// GetPublishedAt implements Channel
+func (c *YouTubeChannel) GetPublishedAt() time.Time {
+	return c.PublishedAt
+}
+
 // GetType implements Channel
 func (c *YouTubeChannel) GetType() string {
 	return "youtube"
@@ -189,6 +222,11 @@ type YouTubeMessage struct {
 	Thumbnails   map[string]string  // Video thumbnails
 	CommentCount int64              // Video comment count
 	Language     string             // Video language
+	LikeCount    int64              // Like count from the video statistics
+	HasLikeCount bool               // Whether the API reported LikeCount
+
+	HasViewCount    bool // Whether the API reported Views
+	HasCommentCount bool // Whether the API reported CommentCount
 }
 
 // GetID implements Message
@@ -226,6 +264,21 @@ func (m *YouTubeMessage) GetViews() int64 {
 	return m.Views
 }
 
+// GetLikeCount implements LikeCounter
+func (m *YouTubeMessage) GetLikeCount() (int64, bool) {
+	return m.LikeCount, m.HasLikeCount
+}
+
+// GetViewCount implements ViewCounter
+func (m *YouTubeMessage) GetViewCount() (int64, bool) {
+	return m.Views, m.HasViewCount
+}
+
+// GetReportedCommentCount implements CommentCounter
+func (m *YouTubeMessage) GetReportedCommentCount() (int64, bool) {
+	return m.CommentCount, m.HasCommentCount
+}
+
 // GetReactions implements Message
 func (m *YouTubeMessage) GetReactions() map[string]int64 {
 	return m.Reactions
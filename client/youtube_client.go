@@ -1,9 +1,12 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"net/http"
 	"regexp"
 	"strings"
 	"sync"
@@ -11,6 +14,8 @@ import (
 
 	youtubemodel "github.com/researchaccelerator-hub/telegram-scraper/model/youtube"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/googleapi/transport"
 	"google.golang.org/api/option"
 	ytapi "google.golang.org/api/youtube/v3"
 )
@@ -83,7 +88,8 @@ func (c *YouTubeDataClient) Connect(ctx context.Context) error {
 		log.Error().Msg("YouTube API key is empty! This will cause authentication errors")
 	}
 
-	service, err := ytapi.NewService(ctx, option.WithAPIKey(c.apiKey))
+	httpClient := &http.Client{Transport: rawBodyTransport{base: &transport.APIKey{Key: c.apiKey}}}
+	service, err := ytapi.NewService(ctx, option.WithHTTPClient(httpClient))
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to create YouTube service")
 		return fmt.Errorf("failed to create YouTube service: %w", err)
@@ -579,6 +585,9 @@ func (c *YouTubeDataClient) GetVideosFromChannel(ctx context.Context, channelID
 					video.ViewCount = cachedVideo.ViewCount
 					video.LikeCount = cachedVideo.LikeCount
 					video.CommentCount = cachedVideo.CommentCount
+					video.HasViewCount = cachedVideo.HasViewCount
+					video.HasLikeCount = cachedVideo.HasLikeCount
+					video.HasCommentCount = cachedVideo.HasCommentCount
 					video.Duration = cachedVideo.Duration
 					video.Language = cachedVideo.Language
 					videos = append(videos, video)
@@ -587,10 +596,12 @@ func (c *YouTubeDataClient) GetVideosFromChannel(ctx context.Context, channelID
 
 			// Only make API call for uncached videos
 			if len(uncachedVideoIDs) > 0 {
-				// Get statistics for uncached videos only
+				// Get statistics for uncached videos only, keeping the raw
+				// response to see which counts YouTube hides
+				var rawResponse bytes.Buffer
 				videosCall := c.service.Videos.List([]string{"snippet", "statistics", "contentDetails"}).
 					Id(uncachedVideoIDs...).
-					Context(ctx)
+					Context(withRawBody(ctx, &rawResponse))
 
 				videosResponse, err := videosCall.Do()
 				if err != nil {
@@ -615,14 +626,23 @@ func (c *YouTubeDataClient) GetVideosFromChannel(ctx context.Context, channelID
 
 				// Track stats retrieval success rate
 				statsFound := 0
+				reported := parseReportedCounts(rawResponse.Bytes())
 
 				// Update videos with statistics
 				for _, videoItem := range videosResponse.Items {
 					if video, ok := batchVideoMap[videoItem.Id]; ok && len(videos) < effectiveLimit {
-						// Parse statistics
-						viewCount := int64(videoItem.Statistics.ViewCount)
-						likeCount := int64(videoItem.Statistics.LikeCount)
-						commentCount := int64(videoItem.Statistics.CommentCount)
+						// Parse statistics; the API omits them for some videos
+						// and hides single counts of others
+						var viewCount, likeCount, commentCount int64
+						if videoItem.Statistics != nil {
+							viewCount = int64(videoItem.Statistics.ViewCount)
+							likeCount = int64(videoItem.Statistics.LikeCount)
+							commentCount = int64(videoItem.Statistics.CommentCount)
+						}
+						counts := reported[videoItem.Id]
+						video.HasViewCount = counts.views
+						video.HasLikeCount = counts.likes
+						video.HasCommentCount = counts.comments
 
 						video.ViewCount = viewCount
 						video.LikeCount = likeCount
@@ -652,8 +672,8 @@ func (c *YouTubeDataClient) GetVideosFromChannel(ctx context.Context, channelID
 							Int64("view_count", viewCount).
 							Int64("like_count", likeCount).
 							Int64("comment_count", commentCount).
-							Bool("comments_disabled", videoItem.Statistics.CommentCount == 0 && viewCount > 1000). // Heuristic for detecting disabled comments
-							Bool("api_reports_comment_count", videoItem.Statistics.CommentCount > 0).
+							Bool("comments_disabled", commentCount == 0 && viewCount > 1000). // Heuristic for detecting disabled comments
+							Bool("api_reports_comment_count", video.HasCommentCount).
 							Str("duration", video.Duration).
 							Str("language", video.Language).
 							Msg("Added video with statistics")
@@ -670,7 +690,7 @@ func (c *YouTubeDataClient) GetVideosFromChannel(ctx context.Context, channelID
 				// Count videos with zero comments for debugging purposes
 				videosWithZeroComments := 0
 				for _, videoItem := range videosResponse.Items {
-					if videoItem.Statistics.CommentCount == 0 {
+					if videoItem.Statistics != nil && videoItem.Statistics.CommentCount == 0 {
 						videosWithZeroComments++
 					}
 				}
@@ -811,6 +831,167 @@ func (c *YouTubeDataClient) generateRandomPrefix(length int) string {
 	return watchPrefix + string(b) + "-"
 }
 
+// SearchVideos runs a single search.list query for videos published between
+// fromTime and toTime. Results carry snippet data only; statistics are not fetched.
+func (c *YouTubeDataClient) SearchVideos(ctx context.Context, query string, fromTime, toTime time.Time, maxResults int) ([]*youtubemodel.YouTubeVideo, error) {
+	if c.service == nil {
+		return nil, fmt.Errorf("YouTube client not connected")
+	}
+
+	// The API returns at most 50 results per page
+	if maxResults <= 0 || maxResults > 50 {
+		maxResults = 50
+	}
+
+	searchCall := c.service.Search.List([]string{"id", "snippet"}).
+		Q(query).
+		MaxResults(int64(maxResults)).
+		Context(ctx).
+		Type("video")
+	if !fromTime.IsZero() {
+		searchCall = searchCall.PublishedAfter(fromTime.Format(time.RFC3339))
+	}
+	if !toTime.IsZero() {
+		searchCall = searchCall.PublishedBefore(toTime.Format(time.RFC3339))
+	}
+
+	response, err := searchCall.Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search videos: %w", err)
+	}
+
+	videos := make([]*youtubemodel.YouTubeVideo, 0, len(response.Items))
+	for _, item := range response.Items {
+		if item.Id == nil || item.Id.VideoId == "" || item.Snippet == nil {
+			continue
+		}
+
+		publishedAt, _ := time.Parse(time.RFC3339, item.Snippet.PublishedAt)
+		thumbnails := make(map[string]string)
+		if item.Snippet.Thumbnails != nil {
+			if item.Snippet.Thumbnails.Default != nil {
+				thumbnails["default"] = item.Snippet.Thumbnails.Default.Url
+			}
+			if item.Snippet.Thumbnails.Medium != nil {
+				thumbnails["medium"] = item.Snippet.Thumbnails.Medium.Url
+			}
+			if item.Snippet.Thumbnails.High != nil {
+				thumbnails["high"] = item.Snippet.Thumbnails.High.Url
+			}
+		}
+
+		videos = append(videos, &youtubemodel.YouTubeVideo{
+			ID:          item.Id.VideoId,
+			ChannelID:   item.Snippet.ChannelId,
+			Title:       item.Snippet.Title,
+			Description: item.Snippet.Description,
+			PublishedAt: publishedAt,
+			Thumbnails:  thumbnails,
+		})
+	}
+
+	return videos, nil
+}
+
+// GetVideoComments pages through commentThreads.list for up to limit top-level
+// comments on a video, in relevance order. Each page of up to 100 comments costs
+// one quota unit. Videos with comments disabled return no comments.
+func (c *YouTubeDataClient) GetVideoComments(ctx context.Context, videoID string, limit int) ([]youtubemodel.YouTubeComment, error) {
+	if c.service == nil {
+		return nil, fmt.Errorf("YouTube client not connected")
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	comments := make([]youtubemodel.YouTubeComment, 0, min(limit, 100))
+	pageToken := ""
+	for len(comments) < limit {
+		call := c.service.CommentThreads.List([]string{"snippet"}).
+			VideoId(videoID).
+			MaxResults(int64(min(limit-len(comments), 100))).
+			Order("relevance").
+			TextFormat("plainText").
+			Context(ctx)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+
+		response, err := call.Do()
+		if err != nil {
+			if isCommentsDisabled(err) {
+				log.Debug().Str("video_id", videoID).Msg("Comments are disabled for video")
+				return comments, nil
+			}
+			return comments, fmt.Errorf("failed to fetch comments for video %s: %w", videoID, err)
+		}
+
+		for _, thread := range response.Items {
+			if thread.Snippet == nil || thread.Snippet.TopLevelComment == nil || thread.Snippet.TopLevelComment.Snippet == nil {
+				continue
+			}
+			snippet := thread.Snippet.TopLevelComment.Snippet
+			publishedAt, _ := time.Parse(time.RFC3339, snippet.PublishedAt)
+			comment := youtubemodel.YouTubeComment{
+				ID:          thread.Id,
+				AuthorName:  snippet.AuthorDisplayName,
+				Text:        snippet.TextOriginal,
+				LikeCount:   snippet.LikeCount,
+				ReplyCount:  thread.Snippet.TotalReplyCount,
+				PublishedAt: publishedAt,
+			}
+			if comment.Text == "" {
+				comment.Text = snippet.TextDisplay
+			}
+			if snippet.AuthorChannelId != nil {
+				comment.AuthorChannelID = snippet.AuthorChannelId.Value
+			}
+			comments = append(comments, comment)
+			if len(comments) >= limit {
+				break
+			}
+		}
+
+		pageToken = response.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+
+	return comments, nil
+}
+
+// isCommentsDisabled reports whether err is the API's refusal to list the
+// comments of a video whose comments are turned off
+func isCommentsDisabled(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "commentsDisabled" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsQuotaExceeded reports whether err is the API's refusal of a request
+// because the project's daily quota is used up. Further requests fail the
+// same way until the quota resets.
+func IsQuotaExceeded(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, item := range apiErr.Errors {
+		if item.Reason == "quotaExceeded" || item.Reason == "dailyLimitExceeded" {
+			return true
+		}
+	}
+	return false
+}
+
 // YouTubeClientAdapter adapts YouTubeDataClient to the Client interface
 type YouTubeClientAdapter struct {
 	client *YouTubeDataClient
@@ -852,6 +1033,9 @@ func (a *YouTubeClientAdapter) GetChannelInfo(ctx context.Context, channelID str
 		Description: channelInfo.Description,
 		MemberCount: channelInfo.SubscriberCount,
 		Country:     channelInfo.Country,
+		ViewCount:   channelInfo.ViewCount,
+		VideoCount:  channelInfo.VideoCount,
+		PublishedAt: channelInfo.PublishedAt,
 	}, nil
 }
 
@@ -862,7 +1046,26 @@ func (a *YouTubeClientAdapter) GetMessages(ctx context.Context, channelID string
 		return nil, err
 	}
 
-	// Convert YouTube videos to the common Message interface
+	return videosToMessages(videos), nil
+}
+
+// SearchVideos searches YouTube for videos (adapting to the Message interface)
+func (a *YouTubeClientAdapter) SearchVideos(ctx context.Context, query string, fromTime, toTime time.Time, maxResults int) ([]Message, error) {
+	videos, err := a.client.SearchVideos(ctx, query, fromTime, toTime, maxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	return videosToMessages(videos), nil
+}
+
+// GetVideoComments retrieves the top-level comments on a YouTube video
+func (a *YouTubeClientAdapter) GetVideoComments(ctx context.Context, videoID string, limit int) ([]youtubemodel.YouTubeComment, error) {
+	return a.client.GetVideoComments(ctx, videoID, limit)
+}
+
+// videosToMessages converts YouTube videos to the common Message interface
+func videosToMessages(videos []*youtubemodel.YouTubeVideo) []Message {
 	messages := make([]Message, 0, len(videos))
 	for _, video := range videos {
 		// Convert reactions (likes) to expected format
@@ -871,25 +1074,29 @@ func (a *YouTubeClientAdapter) GetMessages(ctx context.Context, channelID string
 		}
 
 		message := &YouTubeMessage{
-			ID:           video.ID,
-			ChannelID:    video.ChannelID,
-			SenderID:     video.ChannelID,
-			SenderName:   "YouTube Channel", // This would ideally be populated with the actual channel name
-			Text:         video.Title + "\n\n" + video.Description, // Keep for backward compatibility
-			Title:        video.Title,
-			Description:  video.Description,
-			Timestamp:    video.PublishedAt,
-			Views:        video.ViewCount,
-			Reactions:    reactions,
-			Thumbnails:   video.Thumbnails,
-			CommentCount: video.CommentCount,
-			Language:     video.Language,
+			ID:              video.ID,
+			ChannelID:       video.ChannelID,
+			SenderID:        video.ChannelID,
+			SenderName:      "YouTube Channel", // This would ideally be populated with the actual channel name
+			Text:            video.Title + "\n\n" + video.Description, // Keep for backward compatibility
+			Title:           video.Title,
+			Description:     video.Description,
+			Timestamp:       video.PublishedAt,
+			Views:           video.ViewCount,
+			Reactions:       reactions,
+			Thumbnails:      video.Thumbnails,
+			CommentCount:    video.CommentCount,
+			Language:        video.Language,
+			LikeCount:       video.LikeCount,
+			HasLikeCount:    video.HasLikeCount,
+			HasViewCount:    video.HasViewCount,
+			HasCommentCount: video.HasCommentCount,
 		}
 
 		messages = append(messages, message)
 	}
 
-	return messages, nil
+	return messages
 }
 
 // GetRandomVideos retrieves videos using random sampling with the prefix generator
@@ -1416,7 +1623,7 @@ func (c *YouTubeDataClient) GetSnowballVideos(ctx context.Context, seedChannelID
 					var newChannels []string
 					for _, video := range channelVideos {
 						// Extract mentioned channel IDs from the description
-						mentionedChannels := extractChannelIDsFromText(video.Description)
+						mentionedChannels := ExtractChannelIDsFromText(video.Description)
 
 						mu.Lock()
 						for _, mentionedChannelID := range mentionedChannels {
@@ -1562,11 +1769,11 @@ Wait:
 	return videos, nil
 }
 
-// extractChannelIDsFromText extracts potential YouTube channel IDs from text
+// ExtractChannelIDsFromText extracts potential YouTube channel IDs from text
 // This is a simplified implementation that looks for patterns like:
 // - "youtube.com/channel/UC..."
 // - "youtube.com/@..."
-func extractChannelIDsFromText(text string) []string {
+func ExtractChannelIDsFromText(text string) []string {
 	channelIDs := make([]string, 0)
 	
 	// Look for standard channel IDs (UCxxxx)
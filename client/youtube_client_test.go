@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	ytapi "google.golang.org/api/youtube/v3"
+)
+
+// newTestYouTubeClient returns a YouTubeClientAdapter whose API calls are served
+// by the given handler
+func newTestYouTubeClient(t *testing.T, handler http.Handler) *YouTubeClientAdapter {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	dataClient, err := NewYouTubeDataClient("test-key")
+	require.NoError(t, err)
+	dataClient.service, err = ytapi.NewService(context.Background(),
+		option.WithEndpoint(server.URL+"/"),
+		option.WithHTTPClient(&http.Client{Transport: rawBodyTransport{base: server.Client().Transport}}))
+	require.NoError(t, err)
+
+	return &YouTubeClientAdapter{client: dataClient}
+}
+
+func TestYouTubeClientAdapter_GetChannelInfoStatistics(t *testing.T) {
+	client := newTestYouTubeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/youtube/v3/channels", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items": [{
+			"id": "UCtest",
+			"snippet": {"title": "Test Channel", "publishedAt": "2012-03-04T05:06:07Z", "country": "DE"},
+			"statistics": {"subscriberCount": "1500", "viewCount": "987654", "videoCount": "321"}
+		}]}`))
+	}))
+
+	channel, err := client.GetChannelInfo(context.Background(), "UCtest")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Test Channel", channel.GetName())
+	assert.Equal(t, int64(1500), channel.GetMemberCount())
+	assert.Equal(t, int64(987654), channel.GetViewCount())
+	assert.Equal(t, int64(321), channel.GetVideoCount())
+	assert.Equal(t, time.Date(2012, 3, 4, 5, 6, 7, 0, time.UTC), channel.GetPublishedAt())
+	assert.Equal(t, "DE", channel.GetCountry())
+}
+
+func TestYouTubeClientAdapter_GetMessagesLikeCount(t *testing.T) {
+	client := newTestYouTubeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/youtube/v3/channels":
+			_, _ = w.Write([]byte(`{"items": [{"id": "UCtest", "contentDetails": {"relatedPlaylists": {"uploads": "UUtest"}}}]}`))
+		case "/youtube/v3/playlistItems":
+			_, _ = w.Write([]byte(`{"items": [
+				{"snippet": {"title": "Liked", "publishedAt": "2024-05-01T00:00:00Z"}, "contentDetails": {"videoId": "liked"}},
+				{"snippet": {"title": "No stats", "publishedAt": "2024-05-02T00:00:00Z"}, "contentDetails": {"videoId": "nostats"}}
+			]}`))
+		case "/youtube/v3/videos":
+			_, _ = w.Write([]byte(`{"items": [
+				{"id": "liked", "statistics": {"viewCount": "1000", "likeCount": "42", "commentCount": "7"}, "contentDetails": {"duration": "PT1M"}},
+				{"id": "nostats", "contentDetails": {"duration": "PT2M"}}
+			]}`))
+		default:
+			t.Errorf("unexpected request path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	messages, err := client.GetMessages(context.Background(), "UCtest", from, to, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+
+	likes := make(map[string]int64)
+	reported := make(map[string]bool)
+	for _, msg := range messages {
+		counter, ok := msg.(LikeCounter)
+		require.True(t, ok)
+		likes[msg.GetID()], reported[msg.GetID()] = counter.GetLikeCount()
+	}
+	assert.Equal(t, int64(42), likes["liked"])
+	assert.True(t, reported["liked"])
+	assert.False(t, reported["nostats"])
+}
+
+func TestYouTubeClientAdapter_GetMessagesHiddenCounts(t *testing.T) {
+	client := newTestYouTubeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/youtube/v3/channels":
+			_, _ = w.Write([]byte(`{"items": [{"id": "UCtest", "contentDetails": {"relatedPlaylists": {"uploads": "UUtest"}}}]}`))
+		case "/youtube/v3/playlistItems":
+			_, _ = w.Write([]byte(`{"items": [
+				{"snippet": {"title": "Hidden", "publishedAt": "2024-05-01T00:00:00Z"}, "contentDetails": {"videoId": "hidden"}}
+			]}`))
+		case "/youtube/v3/videos":
+			_, _ = w.Write([]byte(`{"items": [
+				{"id": "hidden", "statistics": {"viewCount": "1000", "likeCount": "0"}, "contentDetails": {"duration": "PT1M"}}
+			]}`))
+		default:
+			t.Errorf("unexpected request path %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	messages, err := client.GetMessages(context.Background(), "UCtest", from, to, 10)
+	require.NoError(t, err)
+	require.Len(t, messages, 1)
+
+	_, viewsReported := messages[0].(ViewCounter).GetViewCount()
+	_, likesReported := messages[0].(LikeCounter).GetLikeCount()
+	_, commentsReported := messages[0].(CommentCounter).GetReportedCommentCount()
+	assert.True(t, viewsReported)
+	assert.True(t, likesReported, "A like count of 0 is still reported")
+	assert.False(t, commentsReported, "A hidden comment count should not read as 0 comments")
+}
+
+func TestYouTubeClientAdapter_GetVideoComments(t *testing.T) {
+	var pages []string
+	client := newTestYouTubeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/youtube/v3/commentThreads", r.URL.Path)
+		assert.Equal(t, "vid1", r.URL.Query().Get("videoId"))
+		pages = append(pages, r.URL.Query().Get("pageToken"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageToken") == "" {
+			_, _ = w.Write([]byte(`{"nextPageToken": "page2", "items": [{
+				"id": "c1",
+				"snippet": {"totalReplyCount": 2, "topLevelComment": {"snippet": {
+					"authorDisplayName": "@alice", "authorChannelId": {"value": "UCalice"},
+					"textOriginal": "First!", "likeCount": 12, "publishedAt": "2024-05-01T10:00:00Z"
+				}}}
+			}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"items": [
+			{"id": "c2", "snippet": {"topLevelComment": {"snippet": {"authorDisplayName": "@bob", "textDisplay": "Nice video", "publishedAt": "2024-05-02T10:00:00Z"}}}},
+			{"id": "c3", "snippet": {"topLevelComment": {"snippet": {"authorDisplayName": "@carol", "textOriginal": "Over the limit"}}}}
+		]}`))
+	}))
+
+	comments, err := client.GetVideoComments(context.Background(), "vid1", 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"", "page2"}, pages)
+	require.Len(t, comments, 2)
+
+	assert.Equal(t, "c1", comments[0].ID)
+	assert.Equal(t, "@alice", comments[0].AuthorName)
+	assert.Equal(t, "UCalice", comments[0].AuthorChannelID)
+	assert.Equal(t, "First!", comments[0].Text)
+	assert.Equal(t, int64(12), comments[0].LikeCount)
+	assert.Equal(t, int64(2), comments[0].ReplyCount)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), comments[0].PublishedAt)
+	assert.Equal(t, "Nice video", comments[1].Text)
+}
+
+func TestYouTubeClientAdapter_GetVideoCommentsDisabled(t *testing.T) {
+	client := newTestYouTubeClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"error": {"code": 403, "message": "comments disabled", "errors": [{"reason": "commentsDisabled"}]}}`))
+	}))
+
+	comments, err := client.GetVideoComments(context.Background(), "vid1", 20)
+	require.NoError(t, err)
+	assert.Empty(t, comments)
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// rawBodyKey is the context key of the buffer rawBodyTransport copies API
+// responses to
+type rawBodyKey struct{}
+
+// withRawBody returns a context whose API responses are also copied to buf
+func withRawBody(ctx context.Context, buf *bytes.Buffer) context.Context {
+	return context.WithValue(ctx, rawBodyKey{}, buf)
+}
+
+// rawBodyTransport copies response bodies to the buffer in the request's
+// context, if any. The API client decodes a count YouTube hides and a count of
+// 0 alike, so the raw response is needed to tell them apart.
+type rawBodyTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t rawBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	res, err := base.RoundTrip(req)
+	if err != nil {
+		return res, err
+	}
+	if buf, ok := req.Context().Value(rawBodyKey{}).(*bytes.Buffer); ok {
+		res.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(res.Body, buf), res.Body}
+	}
+	return res, nil
+}
+
+// reportedCounts records which statistics of a video the API reported
+type reportedCounts struct {
+	views, likes, comments bool
+}
+
+// parseReportedCounts returns the statistics each video of a raw videos.list
+// response reports, keyed by video ID
+func parseReportedCounts(raw []byte) map[string]reportedCounts {
+	var response struct {
+		Items []struct {
+			ID         string                     `json:"id"`
+			Statistics map[string]json.RawMessage `json:"statistics"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return nil
+	}
+
+	counts := make(map[string]reportedCounts, len(response.Items))
+	for _, item := range response.Items {
+		_, views := item.Statistics["viewCount"]
+		_, likes := item.Statistics["likeCount"]
+		_, comments := item.Statistics["commentCount"]
+		counts[item.ID] = reportedCounts{views: views, likes: likes, comments: comments}
+	}
+	return counts
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// ChannelFilter decides which channels are crawled, using regular expressions
+// matched against a channel's username and, once it is known, its title.
+// A nil *ChannelFilter allows every channel.
+type ChannelFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// NewChannelFilter compiles the include and exclude patterns. It returns nil
+// if both lists are empty.
+func NewChannelFilter(include, exclude []string) (*ChannelFilter, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+
+	f := &ChannelFilter{}
+	var err error
+	if f.include, err = compilePatterns(include); err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %w", err)
+	}
+	if f.exclude, err = compilePatterns(exclude); err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	return f, nil
+}
+
+// Allows reports whether a channel known by the given names (username, title)
+// should be crawled. A channel is dropped if any name matches an exclude
+// pattern; otherwise, if include patterns are set, at least one name must match
+// one of them. Empty names are ignored.
+func (f *ChannelFilter) Allows(names ...string) bool {
+	if f == nil {
+		return true
+	}
+	for _, name := range names {
+		if name != "" && matchesAny(f.exclude, name) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, name := range names {
+		if name != "" && matchesAny(f.include, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func matchesAny(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
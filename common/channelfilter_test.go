@@ -0,0 +1,46 @@
+package common
+
+import "testing"
+
+func TestChannelFilter_Allows(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		names   []string
+		want    bool
+	}{
+		{"no patterns", nil, nil, []string{"anything"}, true},
+		{"include-only match", []string{"^news_"}, nil, []string{"news_daily"}, true},
+		{"include-only no match", []string{"^news_"}, nil, []string{"sports_daily"}, false},
+		{"include matches title", []string{"(?i)news"}, nil, []string{"dn123", "Daily News"}, true},
+		{"exclude-only match", nil, []string{"spam"}, []string{"cheap_spam_deals"}, false},
+		{"exclude-only no match", nil, []string{"spam"}, []string{"news_daily"}, true},
+		{"exclude matches title", nil, []string{"(?i)casino"}, []string{"lucky7", "Best Casino Bonuses"}, false},
+		{"combined included", []string{"^news_"}, []string{"_ads$"}, []string{"news_daily"}, true},
+		{"combined excluded wins", []string{"^news_"}, []string{"_ads$"}, []string{"news_ads"}, false},
+		{"combined not included", []string{"^news_"}, []string{"_ads$"}, []string{"sports"}, false},
+		{"empty names ignored", []string{".*"}, nil, []string{""}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewChannelFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("NewChannelFilter failed: %v", err)
+			}
+			if got := f.Allows(tt.names...); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.names, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewChannelFilter_InvalidPattern(t *testing.T) {
+	if _, err := NewChannelFilter([]string{"("}, nil); err == nil {
+		t.Error("Expected an error for an invalid include pattern")
+	}
+	if _, err := NewChannelFilter(nil, []string{"[a-"}); err == nil {
+		t.Error("Expected an error for an invalid exclude pattern")
+	}
+}
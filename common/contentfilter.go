@@ -0,0 +1,45 @@
+package common
+
+import "strings"
+
+// ContentTypeFilter decides which message content types are kept in the crawl
+// output. Types are matched against TDLib content type names, with or without
+// the "message" prefix and ignoring case and underscores, so "messageSticker",
+// "sticker" and "Sticker" are equivalent.
+type ContentTypeFilter struct {
+	Include []string // If non-empty, only these content types are kept
+	Exclude []string // Content types that are always dropped, even if included
+}
+
+// Enabled reports whether any include or exclude rule is configured.
+func (f ContentTypeFilter) Enabled() bool {
+	return len(f.Include) > 0 || len(f.Exclude) > 0
+}
+
+// Allows reports whether posts of the given content type should be kept.
+func (f ContentTypeFilter) Allows(contentType string) bool {
+	name := normalizeContentType(contentType)
+	for _, t := range f.Exclude {
+		if normalizeContentType(t) == name {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, t := range f.Include {
+		if normalizeContentType(t) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeContentType reduces a content type name to a canonical form for comparison.
+func normalizeContentType(contentType string) string {
+	name := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(contentType), "_", ""))
+	if name != "message" {
+		name = strings.TrimPrefix(name, "message")
+	}
+	return name
+}
@@ -0,0 +1,29 @@
+package common
+
+import "testing"
+
+func TestContentTypeFilter_Allows(t *testing.T) {
+	tests := []struct {
+		name        string
+		filter      ContentTypeFilter
+		contentType string
+		want        bool
+	}{
+		{"no rules", ContentTypeFilter{}, "messageSticker", true},
+		{"excluded short name", ContentTypeFilter{Exclude: []string{"sticker"}}, "messageSticker", false},
+		{"excluded full name", ContentTypeFilter{Exclude: []string{"messageAnimatedEmoji"}}, "messageAnimatedEmoji", false},
+		{"excluded case and underscores", ContentTypeFilter{Exclude: []string{"Animated_Emoji"}}, "messageAnimatedEmoji", false},
+		{"not excluded", ContentTypeFilter{Exclude: []string{"sticker"}}, "messageText", true},
+		{"included", ContentTypeFilter{Include: []string{"text", "photo"}}, "messagePhoto", true},
+		{"not included", ContentTypeFilter{Include: []string{"text", "photo"}}, "messageVideo", false},
+		{"exclude wins over include", ContentTypeFilter{Include: []string{"photo"}, Exclude: []string{"photo"}}, "messagePhoto", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Allows(tt.contentType); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// APICredential is a Telegram app's API ID and hash, as issued on
+// my.telegram.org. Sessions of a large crawl can log in with different apps to
+// spread the load across them.
+type APICredential struct {
+	APIID   int    `json:"api_id"`
+	APIHash string `json:"api_hash"`
+}
+
+// ParseAPICredentials parses "<api-id>:<api-hash>" entries, as given on the
+// command line.
+func ParseAPICredentials(entries []string) ([]APICredential, error) {
+	creds := make([]APICredential, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, hash, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid API credential %q, must be <api-id>:<api-hash>", entry)
+		}
+		apiID, err := strconv.Atoi(strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("invalid API ID in credential %q: %w", entry, err)
+		}
+		hash = strings.TrimSpace(hash)
+		if hash == "" {
+			return nil, fmt.Errorf("missing API hash in credential %q", entry)
+		}
+		creds = append(creds, APICredential{APIID: apiID, APIHash: hash})
+	}
+	return creds, nil
+}
+
+// LoadAPICredentialsFile reads a JSON array of credentials, e.g.
+// [{"api_id": 12345, "api_hash": "0123abcd"}].
+func LoadAPICredentialsFile(path string) ([]APICredential, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API credentials file: %w", err)
+	}
+	var creds []APICredential
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("failed to parse API credentials file %s: %w", path, err)
+	}
+	for i, cred := range creds {
+		if cred.APIID == 0 || cred.APIHash == "" {
+			return nil, fmt.Errorf("API credential %d in %s needs both api_id and api_hash", i, path)
+		}
+	}
+	return creds, nil
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseAPICredentials(t *testing.T) {
+	creds, err := ParseAPICredentials([]string{"12345:0123abcd", " 67890 : 4567ef01 ", ""})
+	if err != nil {
+		t.Fatalf("ParseAPICredentials failed: %v", err)
+	}
+	want := []APICredential{{APIID: 12345, APIHash: "0123abcd"}, {APIID: 67890, APIHash: "4567ef01"}}
+	if !reflect.DeepEqual(creds, want) {
+		t.Errorf("Expected %v, got %v", want, creds)
+	}
+
+	for _, entry := range []string{"12345", "abc:0123abcd", "12345:"} {
+		if _, err := ParseAPICredentials([]string{entry}); err == nil {
+			t.Errorf("Expected an error for %q", entry)
+		}
+	}
+}
+
+func TestLoadAPICredentialsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apps.json")
+	if err := os.WriteFile(path, []byte(`[{"api_id": 12345, "api_hash": "0123abcd"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	creds, err := LoadAPICredentialsFile(path)
+	if err != nil {
+		t.Fatalf("LoadAPICredentialsFile failed: %v", err)
+	}
+	if len(creds) != 1 || creds[0].APIID != 12345 || creds[0].APIHash != "0123abcd" {
+		t.Errorf("Unexpected credentials %v", creds)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"api_id": 12345}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAPICredentialsFile(path); err == nil {
+		t.Error("Expected an error for a credential without a hash")
+	}
+}
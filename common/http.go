@@ -0,0 +1,52 @@
+package common
+
+import (
+	"net/http"
+)
+
+// DefaultUserAgent is sent with outbound HTTP requests when no User-Agent is
+// configured.
+const DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"
+
+// HTTPConfig controls the identity the crawler presents to the servers it
+// downloads from, e.g. database archives and remote seed lists.
+type HTTPConfig struct {
+	UserAgent string            // User-Agent of every request (default: DefaultUserAgent)
+	Headers   map[string]string // Extra headers set on every request; they override per-request defaults
+}
+
+// userAgent returns the configured User-Agent or DefaultUserAgent.
+func (c HTTPConfig) userAgent() string {
+	if c.UserAgent != "" {
+		return c.UserAgent
+	}
+	return DefaultUserAgent
+}
+
+// Transport wraps base so every request carries the configured User-Agent and
+// headers. A nil base uses http.DefaultTransport.
+func (c HTTPConfig) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &headerTransport{base: base, userAgent: c.userAgent(), headers: c.Headers}
+}
+
+// headerTransport is an http.RoundTripper that sets fixed headers on each
+// request before handing it to the base transport.
+type headerTransport struct {
+	base      http.RoundTripper
+	userAgent string
+	headers   map[string]string
+}
+
+// RoundTrip sets the headers on a copy of req, leaving the caller's request
+// untouched as the RoundTripper contract requires.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestDownloadURLFile_SendsConfiguredHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Write([]byte("channel_one\n"))
+	}))
+	defer server.Close()
+
+	httpCfg := HTTPConfig{
+		UserAgent: "research-crawler/2.0 (+https://example.org/about)",
+		Headers:   map[string]string{"From": "researcher@example.org"},
+	}
+	filePath, err := DownloadURLFile(server.URL, httpCfg)
+	if err != nil {
+		t.Fatalf("DownloadURLFile failed with error: %v", err)
+	}
+	defer os.Remove(filePath)
+
+	if ua := got.Get("User-Agent"); ua != httpCfg.UserAgent {
+		t.Errorf("User-Agent doesn't match. Got: %s, Want: %s", ua, httpCfg.UserAgent)
+	}
+	if from := got.Get("From"); from != "researcher@example.org" {
+		t.Errorf("From header doesn't match. Got: %s, Want: researcher@example.org", from)
+	}
+
+	// Without configuration the default User-Agent is sent
+	filePath, err = DownloadURLFile(server.URL, HTTPConfig{})
+	if err != nil {
+		t.Fatalf("DownloadURLFile failed with error: %v", err)
+	}
+	defer os.Remove(filePath)
+
+	if ua := got.Get("User-Agent"); ua != DefaultUserAgent {
+		t.Errorf("User-Agent doesn't match. Got: %s, Want: %s", ua, DefaultUserAgent)
+	}
+}
+
+func TestHTTPConfig_HeadersOverrideRequestDefaults(t *testing.T) {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: HTTPConfig{Headers: map[string]string{"Accept": "application/gzip"}}.Transport(nil)}
+	req, err := http.NewRequest("GET", server.URL, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "*/*")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if accept != "application/gzip" {
+		t.Errorf("Accept header doesn't match. Got: %s, Want: application/gzip", accept)
+	}
+	if req.Header.Get("Accept") != "*/*" {
+		t.Error("The caller's request should not be modified")
+	}
+}
@@ -0,0 +1,153 @@
+package common
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minLanguageLetters is the minimum number of letters needed before a guess is made.
+const minLanguageLetters = 10
+
+// scriptLanguages maps scripts that are (nearly) unique to one language to its ISO-639-1 code.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	code  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Armenian, "hy"},
+	{unicode.Georgian, "ka"},
+}
+
+// latinStopwords holds very frequent function words for Latin-script languages.
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "is", "of", "to", "in", "that", "it", "for", "with", "this", "are", "was", "on"},
+	"es": {"el", "la", "de", "que", "y", "en", "los", "las", "por", "con", "para", "una", "es", "del"},
+	"fr": {"le", "la", "les", "de", "et", "est", "des", "une", "pour", "que", "dans", "qui", "pas", "du"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "zu", "mit", "den", "von", "sich", "auf"},
+	"pt": {"o", "os", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "é"},
+	"it": {"il", "di", "che", "e", "la", "per", "un", "non", "sono", "con", "del", "della", "gli", "è"},
+	"tr": {"ve", "bir", "bu", "için", "ile", "da", "de", "çok", "ne", "gibi", "daha", "olarak", "ama", "değil"},
+}
+
+// DetectLanguage returns a best-effort ISO-639-1 code for text, or an empty
+// string when the text is too short or the result is inconclusive. It uses the
+// dominant writing system, a handful of distinguishing letters within shared
+// scripts, and stopword frequencies for Latin-script languages.
+func DetectLanguage(text string) string {
+	var letters, cyrillic, arabic, latin int
+	var ukrainian, persian int
+	scripts := make(map[string]int)
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				ukrainian++
+			}
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+			if strings.ContainsRune("پچژگی", r) {
+				persian++
+			}
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		default:
+			for _, s := range scriptLanguages {
+				if unicode.Is(s.table, r) {
+					scripts[s.code]++
+					break
+				}
+			}
+		}
+	}
+
+	if letters < minLanguageLetters {
+		return ""
+	}
+
+	// Japanese text mixes kana and Han, so any kana makes it Japanese
+	if scripts["ja"] > 0 && scripts["ja"]+scripts["zh"] > letters/2 {
+		return "ja"
+	}
+
+	best, bestCount := "", 0
+	for code, count := range scripts {
+		if count > bestCount {
+			best, bestCount = code, count
+		}
+	}
+
+	switch {
+	case cyrillic > letters/2:
+		if ukrainian > 0 {
+			return "uk"
+		}
+		return "ru"
+	case arabic > letters/2:
+		if persian > 0 {
+			return "fa"
+		}
+		return "ar"
+	case bestCount > letters/2:
+		return best
+	case latin > letters/2:
+		return detectLatinLanguage(text)
+	}
+	return ""
+}
+
+// detectLatinLanguage scores text against the stopword lists and returns the
+// best match, or an empty string if no language clearly wins.
+func detectLatinLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	scores := make(map[string]int)
+	for _, word := range words {
+		for code, stopwords := range latinStopwords {
+			for _, sw := range stopwords {
+				if word == sw {
+					scores[code]++
+					break
+				}
+			}
+		}
+	}
+
+	best, bestScore, runnerUp := "", 0, 0
+	for code, score := range scores {
+		if score > bestScore {
+			best, bestScore, runnerUp = code, score, bestScore
+		} else if score > runnerUp {
+			runnerUp = score
+		}
+	}
+
+	if bestScore < 2 || bestScore == runnerUp {
+		return ""
+	}
+	return best
+}
+
+// ResolveLanguage detects the language of text, falling back to defaultLanguage
+// when detection is inconclusive.
+func ResolveLanguage(text, defaultLanguage string) string {
+	if code := DetectLanguage(text); code != "" {
+		return code
+	}
+	return defaultLanguage
+}
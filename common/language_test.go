@@ -0,0 +1,36 @@
+package common
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "The government announced that the new policy is going to take effect in the spring.", "en"},
+		{"russian", "Правительство объявило, что новая политика вступит в силу весной.", "ru"},
+		{"ukrainian", "Уряд оголосив, що нова політика набуде чинності навесні.", "uk"},
+		{"arabic", "أعلنت الحكومة أن السياسة الجديدة ستدخل حيز التنفيذ في الربيع.", "ar"},
+		{"spanish", "El gobierno anunció que la nueva política entrará en vigor en la primavera.", "es"},
+		{"too short", "ok", ""},
+		{"no letters", "12345 !!! 67890 ???", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveLanguage(t *testing.T) {
+	if got := ResolveLanguage("ok", "en"); got != "en" {
+		t.Errorf("Expected fallback to default language, got %q", got)
+	}
+	if got := ResolveLanguage("Правительство объявило о новой политике", "en"); got != "ru" {
+		t.Errorf("Expected detected language to win over default, got %q", got)
+	}
+}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Supported values for CrawlerConfig.LogFormat.
+const (
+	LogFormatConsole = "console" // Human-readable, colorized lines (the default)
+	LogFormatJSON    = "json"    // One JSON object per line with Unix timestamps
+)
+
+// NewLogger returns a logger that writes to out in format, which must be
+// LogFormatConsole, LogFormatJSON or empty (console).
+func NewLogger(out io.Writer, format string) (zerolog.Logger, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", LogFormatConsole:
+		return zerolog.New(zerolog.ConsoleWriter{Out: out, TimeFormat: time.RFC3339}).With().Timestamp().Logger(), nil
+	case LogFormatJSON:
+		return zerolog.New(out).With().Timestamp().Logger(), nil
+	default:
+		return zerolog.Logger{}, fmt.Errorf("unsupported log format %q, must be %q or %q", format, LogFormatConsole, LogFormatJSON)
+	}
+}
+
+// ConfigureLogging replaces the global logger with one writing to out in
+// format and sets the global level. An empty level selects info.
+func ConfigureLogging(level string, format string, out io.Writer) error {
+	logger, err := NewLogger(out, format)
+	if err != nil {
+		return err
+	}
+	parsed := zerolog.InfoLevel
+	if level != "" {
+		if parsed, err = zerolog.ParseLevel(strings.ToLower(level)); err != nil {
+			return fmt.Errorf("invalid log level %q: %w", level, err)
+		}
+	}
+
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	log.Logger = logger
+	zerolog.SetGlobalLevel(parsed)
+	return nil
+}
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// restoreLogging resets the global logger and level changed by ConfigureLogging.
+func restoreLogging(t *testing.T) {
+	logger, level := log.Logger, zerolog.GlobalLevel()
+	t.Cleanup(func() {
+		log.Logger = logger
+		zerolog.SetGlobalLevel(level)
+	})
+}
+
+func TestConfigureLogging_LevelSuppressesLowerMessages(t *testing.T) {
+	restoreLogging(t)
+	var out bytes.Buffer
+	if err := ConfigureLogging("error", LogFormatJSON, &out); err != nil {
+		t.Fatalf("ConfigureLogging failed: %v", err)
+	}
+
+	log.Info().Msg("routine progress")
+	log.Error().Msg("something broke")
+
+	if strings.Contains(out.String(), "routine progress") {
+		t.Errorf("Expected the info message to be suppressed, got %q", out.String())
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a single JSON log line, got %q: %v", out.String(), err)
+	}
+	if entry["message"] != "something broke" || entry["level"] != "error" {
+		t.Errorf("Unexpected log entry %v", entry)
+	}
+}
+
+func TestConfigureLogging_Console(t *testing.T) {
+	restoreLogging(t)
+	var out bytes.Buffer
+	if err := ConfigureLogging("", "", &out); err != nil {
+		t.Fatalf("ConfigureLogging failed: %v", err)
+	}
+
+	log.Debug().Msg("hidden at the default level")
+	log.Info().Str("channel", "news").Msg("crawling")
+
+	got := out.String()
+	if strings.Contains(got, "hidden") || !strings.Contains(got, "crawling") || !strings.Contains(got, "channel=") {
+		t.Errorf("Expected a single console line at info level, got %q", got)
+	}
+	if strings.HasPrefix(strings.TrimSpace(got), "{") {
+		t.Errorf("Expected console output rather than JSON, got %q", got)
+	}
+}
+
+func TestConfigureLogging_Invalid(t *testing.T) {
+	restoreLogging(t)
+	if err := ConfigureLogging("info", "xml", &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if err := ConfigureLogging("loud", LogFormatJSON, &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
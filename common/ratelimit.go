@@ -0,0 +1,26 @@
+package common
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter paces outbound requests. Wait blocks until the next request may
+// be made or ctx is done. Implementations must be safe for concurrent use.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// NewRateLimiter returns a token-bucket limiter allowing requestsPerSecond
+// requests on average and bursts of up to burst requests, or nil (no limit)
+// when requestsPerSecond is not positive.
+func NewRateLimiter(requestsPerSecond float64, burst int) RateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(requestsPerSecond), burst)
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Field names that can be listed in RedactionConfig.Fields.
+const (
+	RedactFieldSenderID = "sender_id" // Numeric ID of the post or comment author
+	RedactFieldHandle   = "handle"    // Display name / username of the post or comment author
+	RedactFieldPhone    = "phone"     // Phone number of a shared contact card
+	RedactFieldContact  = "contact"   // Name and user ID of a shared contact card
+)
+
+// RedactionConfig controls pseudonymization of personally identifiable
+// information before a post is handed to any storage sink.
+type RedactionConfig struct {
+	Fields []string // Names of the fields to pseudonymize (see RedactField* constants)
+	Salt   string   // Secret salt mixed into every hash; keep it stable within a study
+}
+
+// Enabled reports whether any field has been configured for redaction.
+func (r RedactionConfig) Enabled() bool {
+	return len(r.Fields) > 0
+}
+
+// redactFields lists the field names RedactionConfig.Fields accepts.
+var redactFields = []string{RedactFieldSenderID, RedactFieldHandle, RedactFieldPhone, RedactFieldContact}
+
+// Validate rejects unknown field names, which would silently leave a field in
+// the clear, and a missing salt when any field is redacted. Without a secret
+// salt, pseudonyms of numeric IDs and phone numbers can be brute-forced.
+func (r RedactionConfig) Validate() error {
+	for _, field := range r.Fields {
+		known := false
+		for _, f := range redactFields {
+			if field == f {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown redaction field %q, must be one of %v", field, redactFields)
+		}
+	}
+	if r.Enabled() && r.Salt == "" {
+		return fmt.Errorf("redaction requires a salt")
+	}
+	return nil
+}
+
+// ShouldRedact reports whether the named field is configured for redaction.
+func (r RedactionConfig) ShouldRedact(field string) bool {
+	for _, f := range r.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Pseudonymize returns a stable, non-reversible pseudonym for value using an
+// HMAC-SHA256 keyed with the configured salt. Empty values are returned unchanged
+// so that missing data stays distinguishable from redacted data.
+func (r RedactionConfig) Pseudonymize(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(r.Salt))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
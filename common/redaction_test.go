@@ -0,0 +1,25 @@
+package common
+
+import "testing"
+
+func TestRedactionConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     RedactionConfig
+		wantErr bool
+	}{
+		{"disabled", RedactionConfig{}, false},
+		{"known fields with salt", RedactionConfig{Fields: []string{RedactFieldSenderID, RedactFieldPhone}, Salt: "secret"}, false},
+		{"unknown field", RedactionConfig{Fields: []string{"senderid"}, Salt: "secret"}, true},
+		{"missing salt", RedactionConfig{Fields: []string{RedactFieldHandle}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
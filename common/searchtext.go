@@ -0,0 +1,32 @@
+package common
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeSearchText folds text for full-text search: it is lowercased,
+// diacritics are stripped ("Café" becomes "cafe") and runs of whitespace are
+// collapsed to single spaces.
+func NormalizeSearchText(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	space := false
+	for _, r := range norm.NFD.String(text) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Combining marks left over from the decomposition
+		case unicode.IsSpace(r):
+			space = b.Len() > 0
+		default:
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteString(strings.ToLower(string(r)))
+		}
+	}
+	return norm.NFC.String(b.String())
+}
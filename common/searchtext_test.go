@@ -0,0 +1,17 @@
+package common
+
+import "testing"
+
+func TestNormalizeSearchText(t *testing.T) {
+	tests := map[string]string{
+		"Café  CRÈME\nbrûlée ": "cafe creme brulee",
+		"  Привет, Мир!":       "привет, мир!",
+		"Ελληνικά":             "ελληνικα",
+		"":                     "",
+	}
+	for input, want := range tests {
+		if got := NormalizeSearchText(input); got != want {
+			t.Errorf("NormalizeSearchText(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
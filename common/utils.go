@@ -1,67 +1,213 @@
 package common
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/researchaccelerator-hub/telegram-scraper/enrich"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
 	"github.com/rs/zerolog/log"
 )
 
+// Supported values for CrawlerConfig.OutputFormat.
+const (
+	OutputFormatJSON   = "json"   // Native model.Post JSON only
+	OutputFormatCommon = "common" // Native JSON plus records in the common social-media schema
+)
+
+// Supported values for CrawlerConfig.TextFormat.
+const (
+	TextFormatMarkdown = "markdown" // Markdown with Telegram's __underline__ and ||spoiler|| extensions
+	TextFormatHTML     = "html"     // HTML with Telegram's <tg-spoiler> tag
+)
+
+// Supported values for CrawlerConfig.DiscoveryStrategy.
+const (
+	DiscoveryLinks     = "links"     // t.me links and @mentions in the text of text messages
+	DiscoveryMentions  = "mentions"  // @mentions in the text or caption only
+	DiscoveryForwarded = "forwarded" // Channels the posts were forwarded from only
+	DiscoveryPinned    = "pinned"    // t.me links and @mentions in pinned messages only
+)
+
 // Configuration structure
 type CrawlerConfig struct {
-	DaprMode          bool
-	DaprPort          int
-	Concurrency       int
-	Timeout           int
-	UserAgent         string
-	OutputFormat      string
-	StorageRoot       string
-	TDLibDatabaseURL  string   // Single database URL (for backward compatibility)
-	TDLibDatabaseURLs []string // Multiple database URLs for connection pooling
-	MinPostDate       time.Time
-	PostRecency       time.Time
-	DateBetweenMin    time.Time // Start date for date-between range
-	DateBetweenMax    time.Time // End date for date-between range
-	SampleSize        int       // Number of posts to randomly sample when using date-between
-	DaprJobMode       bool
-	MinUsers          int
-	CrawlID           string
-	CrawlLabel        string // User-defined label for the crawl (e.g., "youtube-snowball")
-	MaxComments       int
-	MaxPosts          int
-	MaxDepth          int
-	MaxPages          int    // Maximum number of pages to crawl (default: 108000)
-	TDLibVerbosity    int    // TDLib verbosity level for logging (default: 1)
-	SkipMediaDownload bool   // Skip downloading media files (only process metadata)
-	Platform          string // Platform to crawl: "telegram", "youtube", etc.
-	YouTubeAPIKey     string // API key for YouTube Data API
+	DaprMode            bool
+	DaprPort            int
+	Concurrency         int
+	Timeout             int
+	OutputFormat        string
+	StorageRoot         string
+	TDLibDatabaseURL    string   // Single database URL (for backward compatibility)
+	TDLibDatabaseURLs   []string // Multiple database URLs for connection pooling
+	VerifyTDLibDatabase bool     // Verify database archives against the SHA-256 in a "<url>.sha256" sidecar file
+	MinPostDate         time.Time
+	MaxPostDate         time.Time // Posts published after this time are skipped (zero = no upper bound)
+	PostRecency         time.Time
+	DateBetweenMin      time.Time // Start date for date-between range
+	DateBetweenMax      time.Time // End date for date-between range
+	SampleSize          int       // Number of posts to randomly sample when using date-between
+	DaprJobMode         bool
+	MinUsers            int
+	CrawlID             string
+	CrawlLabel          string // User-defined label for the crawl (e.g., "youtube-snowball")
+	ResumeCrawlID       string // Continue the unfinished pages of this crawl's saved layers instead of seeding (Dapr standalone mode)
+	QuarantineThreshold int    // Consecutive failures after which a page is quarantined and skipped (0 = never)
+	RetryQuarantined    bool   // Process quarantined pages again instead of skipping them
+	MaxComments         int    // Maximum comments fetched per post (-1 = all, 0 = none)
+	SkipComments        bool   // Do not fetch comments at all, saving the thread history calls on busy posts
+	CommentReplyDepth   int    // Levels of each comment's reply chain resolved, fetching missing parents (0 = none)
+	MaxPosts            int
+	MaxPostsPerChannel  int  // Stop processing a channel after this many parsed posts (0 = unlimited)
+	Incremental         bool // Only fetch messages newer than the last seen message ID recorded for each channel
+	MaxDepth            int
+	MaxPages            int                      // Maximum number of pages to crawl (default: 108000)
+	TDLibVerbosity      int                      // TDLib verbosity level for logging (default: 1)
+	SkipMediaDownload   bool                     // Skip downloading media files (only process metadata)
+	MediaDownloadDir    string                   // Directory completed media downloads are moved to before upload, safe to purge (empty = TDLib's files directory)
+	MediaTypes          []string                 // Content types whose media is downloaded, e.g. "photo", "video" (empty = all); other posts keep their metadata only
+	DryRun              bool                     // Parse posts and log a summary without downloading media or storing anything
+	Platform            string                   // Platform to crawl: "telegram", "youtube", etc.
+	YouTubeAPIKey       string                   // API key for YouTube Data API
+	YouTubeComments     int                      // Top-level comments fetched per YouTube video (0 = none, the default, to save quota)
+	Redaction           RedactionConfig          // Pseudonymization of PII before posts are stored
+	CaptureSenderFlags  bool                     // Resolve premium/verified/scam flags of senders (extra API call per sender)
+	GroupAlbums         bool                     // Store the messages of a media album in the same fetch batch as one post with AlbumItems
+	CommonSchemaMapping sink.CommonSchemaMapping // Output key overrides for the common schema output
+	CommonSchemaOutput  *sink.CommonSchemaWriter // Writer for common schema records, opened by the launcher when OutputFormat is "common"
+	OutputSinks         []string                 // Post outputs opened by the launcher: "state", "jsonl" (stdout), "jsonl=<path>", "csv" (stdout), "csv=<path>" or "parquet=<dir>" (default: state)
+	ParquetOptions      sink.ParquetOptions      // Partitioning and row-group size of "parquet=<dir>" post outputs
+	PostSinks           []sink.PostSink          // Outputs every Telegram post is written to (empty = the state manager only)
+	PostProcessors      enrich.Chain             // Enrichment run on every Telegram post before it is stored (empty = none)
+	EnableOCR           bool                     // Run OCR on downloaded photos, stickers and video thumbnails to fill in ImageText
+	OCREngine           enrich.OCREngine         // OCR implementation used when EnableOCR is set (nil = Tesseract)
+	OCRLanguages        string                   // Tesseract language codes for the default engine, e.g. "eng+rus" (empty = Tesseract's default)
+	TextFormat          string                   // Also render each post's text with its formatting into FormattedText: "markdown" or "html" (empty = off)
+	Transcribe          bool                     // Transcribe downloaded voice notes, audio and videos to fill in TranscriptText (videos are downloaded for it)
+	Transcriber         enrich.Transcriber       // Transcription implementation used when Transcribe is set (nil = the local Whisper CLI)
+	WhisperModel        string                   // Whisper model of the default transcriber, e.g. "base" or "small" (empty = Whisper's default)
+	MaxOutputFileBytes  int64                    // Roll file-based outputs to a new numbered file past this size (0 = unlimited)
+	MaxRecordsPerFile   int                      // Roll file-based outputs to a new numbered file after this many records (0 = unlimited)
+	ContentTypeFilter   ContentTypeFilter        // Message content types to keep or drop from the output
+	DumpUnknownTo       string                   // Directory where messages of content types the parser doesn't handle are saved as JSON, unredacted (empty = off)
+	DiscoveryStrategy   string                   // How channels to crawl next are found in posts, one of the Discovery* values (empty = links)
+	IncludePatterns     []string                 // Regexes a channel's username or title must match to be crawled (empty = all)
+	ExcludePatterns     []string                 // Regexes that drop a channel when its username or title matches
+	ChannelFilter       *ChannelFilter           // Compiled from IncludePatterns and ExcludePatterns (nil = crawl every channel)
+	DefaultLanguage     string                   // ISO-639-1 code used when a post's language cannot be detected
+	PlatformName        string                   // Platform name recorded on every Telegram post (default: Telegram)
+	DownloadMaxAttempts int                      // Attempts per media download before giving up (default: 3)
+	DownloadRetryDelay  time.Duration            // Delay before the first download retry, doubled on each attempt (default: 1s)
+	InitTimeout         time.Duration            // Time allowed for TDLib client initialization (default: 30s)
+	ChannelCacheTTL     time.Duration            // How long a channel's supergroup info is reused before it is looked up again (0 = no caching)
+	ChannelCacheSize    int                      // Maximum number of channels whose supergroup info is cached (default: 1000)
+	TDLibRateLimit      float64                  // Average TDLib requests per second across all clients (0 = unlimited)
+	TDLibRateBurst      int                      // Requests allowed in a burst above TDLibRateLimit (default: 1)
+	TDLibLimiter        RateLimiter              // Shared limiter every TDLib client waits on; built from TDLibRateLimit by the launcher (nil = unlimited)
+	AutoJoin            bool                     // Join private chats whose invite link (t.me/+hash, t.me/joinchat/hash) is a seed, if not yet a member
+	BotToken            string                   // Log in as a bot with this token instead of the phone login (bots cannot read most channel history)
+	APICredentials      []APICredential          // Telegram apps the session pool spreads sessions across (empty = TG_API_ID/TG_API_HASH)
+	APICredential       *APICredential           // App this client logs in with, set per session by the session pool (nil = credentials file or environment)
+	ProxyURL            string                   // HTTP(S) or SOCKS5 proxy for downloads and TDLib (empty = HTTP_PROXY/HTTPS_PROXY from the environment)
+	HTTP                HTTPConfig               // User-Agent and extra headers of outbound HTTP requests
+	StorageBackend      string                   // Where standalone crawls store state and output: "dapr" (default), "local", "s3" or "gcs"
+	BlobBucket          string                   // Bucket posts and media are written to with the "s3" and "gcs" backends
+	BlobPrefix          string                   // Key prefix of every object written to BlobBucket
+	BlobPathTemplate    string                   // Layout of post object keys below BlobPrefix, e.g. "{platform}/{date}/{channel}/{msgid}.jsonl" (empty = "{crawl}/{channel}/posts/{post_uid}.jsonl")
+	BlobMediaTemplate   string                   // Layout of media object keys below BlobPrefix, e.g. "{platform}/{date}/{channel}/media/{file}" (empty = "{crawl}/media/{channel}/{file}")
+	S3Region            string                   // S3 region (empty = from the AWS environment)
+	S3Endpoint          string                   // URL of an S3-compatible service such as MinIO (empty = AWS)
+	StatusPort          int                      // Port for the /status progress and /healthz endpoints in standalone mode (0 = disabled)
+	MetricsPort         int                      // Port for the Prometheus /metrics endpoint in standalone mode (0 = disabled)
+	SummaryFile         string                   // Path of the JSON crawl summary (default: <storage-root>/<crawl-id>/crawl_<crawl-id>_summary.json)
+	LogLevel            string                   // Minimum level logged: trace, debug, info, warn, error, fatal or panic (empty = info)
+	LogFormat           string                   // Log output: "console" (human-readable, the default) or "json"
+	CrawlIDFormat       CrawlIDOptions           // Prefix and random suffix of generated crawl and execution IDs
+	MaxRuntime          time.Duration            // Wall-clock budget of a crawl; when it runs out no new pages are started (0 = unlimited)
+	StateSavePages      int                      // Save the crawl state once this many pages have finished (0 or 1 = after every page)
+	StateSaveInterval   time.Duration            // Also save the crawl state this often while finished pages are unsaved (0 = by page count only)
+}
+
+// CrawlIDOptions customizes the IDs returned by GenerateCrawlIDWithOptions.
+// The zero value keeps the plain "YYYYMMDDHHMMSS" format.
+type CrawlIDOptions struct {
+	Prefix       string // Operator label prepended as "<prefix>-"
+	RandomSuffix bool   // Append "-" and 6 random hex characters so crawls started in the same second differ
+}
+
+// crawlIDPrefixPattern restricts prefixes to characters that are safe in
+// storage paths and state keys
+var crawlIDPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Validate reports whether the prefix can be used in a crawl ID.
+func (o CrawlIDOptions) Validate() error {
+	if o.Prefix != "" && !crawlIDPrefixPattern.MatchString(o.Prefix) {
+		return fmt.Errorf("invalid crawl ID prefix %q: only letters, digits, '-' and '_' are allowed", o.Prefix)
+	}
+	return nil
 }
 
 // GenerateCrawlID generates a unique identifier based on the current timestamp.
 // The identifier is formatted as a string in the "YYYYMMDDHHMMSS" format.
 func GenerateCrawlID() string {
-	// Get the current timestamp
-	currentTime := time.Now()
+	return GenerateCrawlIDWithOptions(CrawlIDOptions{})
+}
 
+// GenerateCrawlIDWithOptions generates a timestamp-based identifier like
+// GenerateCrawlID, formatted as "[<prefix>-]YYYYMMDDHHMMSS[-<6 hex chars>]".
+func GenerateCrawlIDWithOptions(opts CrawlIDOptions) string {
 	// Format the timestamp to a string (e.g., "20060102150405" for YYYYMMDDHHMMSS)
-	crawlID := currentTime.Format("20060102150405")
+	crawlID := time.Now().Format("20060102150405")
+
+	if opts.Prefix != "" {
+		crawlID = opts.Prefix + "-" + crawlID
+	}
+	if opts.RandomSuffix {
+		crawlID += fmt.Sprintf("-%06x", rand.IntN(1<<24))
+	}
 
 	return crawlID
 }
 
-// DownloadURLFile downloads a file from a URL and saves it to a temporary location.
+// EndOfDay returns the last instant of the day starting at day. An inclusive
+// max post date given as a date is compared against this time.
+func EndOfDay(day time.Time) time.Time {
+	return day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
+
+// WithMaxRuntime returns a context that is cancelled when parent is or, if
+// maxRuntime is positive, once maxRuntime has elapsed.
+func WithMaxRuntime(parent context.Context, maxRuntime time.Duration) (context.Context, context.CancelFunc) {
+	if maxRuntime <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, maxRuntime)
+}
+
+// MaxRuntimeExceeded reports whether ctx, created by WithMaxRuntime, ended
+// because the crawl ran out of time rather than being cancelled.
+func MaxRuntimeExceeded(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.DeadlineExceeded)
+}
+
+// DownloadURLFile downloads a file from a URL and saves it to a temporary location,
+// sending the User-Agent and headers of httpCfg.
 // Returns the path to the downloaded file and any error encountered.
-func DownloadURLFile(url string) (string, error) {
+func DownloadURLFile(url string, httpCfg HTTPConfig) (string, error) {
 	log.Info().Str("url", url).Msg("Downloading URL file")
 
 	// Create HTTP client with timeout
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Transport: httpCfg.Transport(nil),
+		Timeout:   30 * time.Second,
 	}
 
 	// Create request
@@ -70,9 +216,6 @@ func DownloadURLFile(url string) (string, error) {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
-	// Set a user agent
-	req.Header.Set("User-Agent", "Mozilla/5.0 Telegram-Scraper/1.0")
-
 	// Make the request
 	resp, err := client.Do(req)
 	if err != nil {
@@ -130,6 +273,22 @@ func ReadURLsFromFile(filename string) ([]string, error) {
 	return urls, nil
 }
 
+// ReadURLsFromURL downloads a remote seed list with DownloadURLFile and parses
+// it with ReadURLsFromFile. The downloaded copy is removed afterwards.
+func ReadURLsFromURL(url string, httpCfg HTTPConfig) ([]string, error) {
+	filename, err := DownloadURLFile(url, httpCfg)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.Remove(filename); err != nil {
+			log.Warn().Err(err).Str("file", filename).Msg("Failed to clean up downloaded URL file")
+		}
+	}()
+
+	return ReadURLsFromFile(filename)
+}
+
 // PlatformType defines the supported platform types for crawling
 type PlatformType string
 
@@ -59,6 +59,45 @@ func TestGenerateCrawlID(t *testing.T) {
 	}
 }
 
+func TestGenerateCrawlIDWithOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    CrawlIDOptions
+		pattern string
+	}{
+		{"default", CrawlIDOptions{}, `^\d{14}$`},
+		{"prefix", CrawlIDOptions{Prefix: "ops"}, `^ops-\d{14}$`},
+		{"random suffix", CrawlIDOptions{RandomSuffix: true}, `^\d{14}-[0-9a-f]{6}$`},
+		{"prefix and random suffix", CrawlIDOptions{Prefix: "team_a", RandomSuffix: true}, `^team_a-\d{14}-[0-9a-f]{6}$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crawlID := GenerateCrawlIDWithOptions(tt.opts)
+			if !regexp.MustCompile(tt.pattern).MatchString(crawlID) {
+				t.Errorf("CrawlID %s does not match %s", crawlID, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestGenerateCrawlIDWithOptions_RandomSuffixDistinct(t *testing.T) {
+	opts := CrawlIDOptions{RandomSuffix: true}
+	first := GenerateCrawlIDWithOptions(opts)
+	second := GenerateCrawlIDWithOptions(opts)
+	if first == second {
+		t.Errorf("Expected two rapid calls to produce distinct IDs, got %s twice", first)
+	}
+}
+
+func TestCrawlIDOptions_Validate(t *testing.T) {
+	if err := (CrawlIDOptions{Prefix: "ops-2024_a"}).Validate(); err != nil {
+		t.Errorf("Expected a valid prefix, got %v", err)
+	}
+	if err := (CrawlIDOptions{Prefix: "../ops"}).Validate(); err == nil {
+		t.Error("Expected an error for a prefix with path characters")
+	}
+}
+
 func ExampleGenerateCrawlID() {
 	// Mock the current time for consistent output in the example
 	// In a real application, you wouldn't do this
@@ -104,7 +143,7 @@ https://example.com/page3`
 	defer server.Close()
 
 	// Call the function with the test server URL
-	filePath, err := DownloadURLFile(server.URL)
+	filePath, err := DownloadURLFile(server.URL, HTTPConfig{})
 	if err != nil {
 		t.Fatalf("DownloadURLFile failed with error: %v", err)
 	}
@@ -157,14 +196,47 @@ func TestDownloadURLFile_ErrorHandling(t *testing.T) {
 	}))
 	defer notFoundServer.Close()
 
-	_, err := DownloadURLFile(notFoundServer.URL)
+	_, err := DownloadURLFile(notFoundServer.URL, HTTPConfig{})
 	if err == nil {
 		t.Error("Expected error for 404 response, got nil")
 	}
 
 	// Test with an invalid URL
-	_, err = DownloadURLFile("invalid-url")
+	_, err = DownloadURLFile("invalid-url", HTTPConfig{})
 	if err == nil {
 		t.Error("Expected error for invalid URL, got nil")
 	}
-}
\ No newline at end of file
+}
+
+func TestReadURLsFromURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("# Remote seeds\nchannel_one\n\nchannel_two\n"))
+	}))
+	defer server.Close()
+
+	urls, err := ReadURLsFromURL(server.URL, HTTPConfig{})
+	if err != nil {
+		t.Fatalf("ReadURLsFromURL failed with error: %v", err)
+	}
+
+	expectedURLs := []string{"channel_one", "channel_two"}
+	if len(urls) != len(expectedURLs) {
+		t.Fatalf("Incorrect number of URLs. Got: %v, Want: %v", urls, expectedURLs)
+	}
+	for i, url := range urls {
+		if url != expectedURLs[i] {
+			t.Errorf("URL at index %d doesn't match. Got: %s, Want: %s", i, url, expectedURLs[i])
+		}
+	}
+
+	// A failed download is reported rather than treated as an empty list
+	notFoundServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer notFoundServer.Close()
+
+	if _, err := ReadURLsFromURL(notFoundServer.URL, HTTPConfig{}); err == nil {
+		t.Error("Expected error for 404 response, got nil")
+	}
+}
@@ -221,6 +221,7 @@ func TestGetChannelInfo(t *testing.T) {
 				tc.getTotalViewsFn,
 				tc.getMessageCountFn,
 				tc.getMemberCountFn,
+				0,
 				testConfig,
 			)
 
@@ -272,3 +273,102 @@ func TestGetChannelInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestGetChannelInfo_CachesSupergroupLookups(t *testing.T) {
+	chatID := int64(424242)
+	supergroupID := int64(242424)
+	page := &state.Page{ID: uuid.New().String(), URL: "cachedchannel", Status: "unfetched"}
+	cfg := common.CrawlerConfig{MaxPosts: 10, ChannelCacheTTL: time.Hour, ChannelCacheSize: 10}
+
+	mockClient := new(MockTDLibClient)
+	mockClient.On("SearchPublicChat", &client.SearchPublicChatRequest{Username: "cachedchannel"}).Return(&client.Chat{
+		Id:   chatID,
+		Type: &client.ChatTypeSupergroup{SupergroupId: supergroupID, IsChannel: true},
+	}, nil)
+	mockClient.On("GetChat", &client.GetChatRequest{ChatId: chatID}).Return(&client.Chat{Id: chatID}, nil)
+	mockClient.On("GetChatHistory", mock.Anything).Return(&client.Messages{}, nil)
+	mockClient.On("GetSupergroup", &client.GetSupergroupRequest{SupergroupId: supergroupID}).
+		Return(&client.Supergroup{Id: supergroupID}, nil).Once()
+	mockClient.On("GetSupergroupFullInfo", &client.GetSupergroupFullInfoRequest{SupergroupId: supergroupID}).
+		Return(&client.SupergroupFullInfo{Description: "cached description"}, nil).Once()
+
+	for i := 0; i < 2; i++ {
+		info, _, err := getChannelInfoWithDeps(mockClient, page, nil, nil, nil, 0, cfg)
+		assert.NoError(t, err)
+		if assert.NotNil(t, info.supergroupInfo) {
+			assert.Equal(t, "cached description", info.supergroupInfo.Description)
+		}
+		assert.Equal(t, supergroupID, info.supergroup.Id)
+	}
+
+	// The second lookup for the same chat is served from the cache
+	mockClient.AssertNumberOfCalls(t, "GetSupergroup", 1)
+	mockClient.AssertNumberOfCalls(t, "GetSupergroupFullInfo", 1)
+}
+
+func TestGetChannelInfo_InviteLink(t *testing.T) {
+	chatID := int64(-100777)
+	inviteLink := "https://t.me/+AbCdEf123"
+	checkReq := &client.CheckChatInviteLinkRequest{InviteLink: inviteLink}
+	joinReq := &client.JoinChatByInviteLinkRequest{InviteLink: inviteLink}
+
+	t.Run("joins the chat when auto-join is enabled", func(t *testing.T) {
+		page := &state.Page{ID: uuid.New().String(), URL: "t.me/joinchat/AbCdEf123", Status: "unfetched"}
+		mockClient := new(MockTDLibClient)
+		mockClient.On("CheckChatInviteLink", checkReq).Return(&client.ChatInviteLinkInfo{Title: "Private"}, nil)
+		mockClient.On("JoinChatByInviteLink", joinReq).Return(&client.Chat{Id: chatID, Title: "Private"}, nil)
+		mockClient.On("GetChat", &client.GetChatRequest{ChatId: chatID}).Return(&client.Chat{Id: chatID, Title: "Private"}, nil)
+		mockClient.On("GetChatHistory", mock.Anything).Return(&client.Messages{}, nil)
+
+		info, _, err := getChannelInfoWithDeps(mockClient, page, nil, nil, nil, 0, common.CrawlerConfig{MaxPosts: 10, AutoJoin: true})
+		assert.NoError(t, err)
+		assert.Equal(t, chatID, info.chat.Id)
+		mockClient.AssertExpectations(t)
+		mockClient.AssertNotCalled(t, "SearchPublicChat", mock.Anything)
+	})
+
+	t.Run("uses a chat the account can already access", func(t *testing.T) {
+		page := &state.Page{ID: uuid.New().String(), URL: "https://t.me/+AbCdEf123", Status: "unfetched"}
+		mockClient := new(MockTDLibClient)
+		mockClient.On("CheckChatInviteLink", checkReq).Return(&client.ChatInviteLinkInfo{ChatId: chatID}, nil)
+		mockClient.On("GetChat", &client.GetChatRequest{ChatId: chatID}).Return(&client.Chat{Id: chatID}, nil)
+		mockClient.On("GetChatHistory", mock.Anything).Return(&client.Messages{}, nil)
+
+		info, _, err := getChannelInfoWithDeps(mockClient, page, nil, nil, nil, 0, common.CrawlerConfig{MaxPosts: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, chatID, info.chat.Id)
+		mockClient.AssertNotCalled(t, "JoinChatByInviteLink", mock.Anything)
+	})
+
+	t.Run("fails without auto-join", func(t *testing.T) {
+		page := &state.Page{ID: uuid.New().String(), URL: "t.me/+AbCdEf123", Status: "unfetched"}
+		mockClient := new(MockTDLibClient)
+		mockClient.On("CheckChatInviteLink", checkReq).Return(&client.ChatInviteLinkInfo{Title: "Private"}, nil)
+
+		_, _, err := getChannelInfoWithDeps(mockClient, page, nil, nil, nil, 0, common.CrawlerConfig{MaxPosts: 10})
+		assert.Error(t, err)
+		mockClient.AssertNotCalled(t, "JoinChatByInviteLink", mock.Anything)
+	})
+}
+
+func TestSupergroupCache_ExpiryAndEviction(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newSupergroupCache(time.Minute, 2)
+	cache.now = func() time.Time { return now }
+
+	cache.put(1, &client.Supergroup{Id: 1}, &client.SupergroupFullInfo{})
+	cache.put(2, &client.Supergroup{Id: 2}, &client.SupergroupFullInfo{})
+	_, _, ok := cache.get(1) // Chat 1 is now the most recently used
+	assert.True(t, ok)
+	cache.put(3, &client.Supergroup{Id: 3}, &client.SupergroupFullInfo{})
+
+	_, _, ok = cache.get(2)
+	assert.False(t, ok, "The least recently used chat should be evicted")
+	supergroup, _, ok := cache.get(1)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), supergroup.Id)
+
+	now = now.Add(2 * time.Minute)
+	_, _, ok = cache.get(3)
+	assert.False(t, ok, "Entries should expire after the TTL")
+}
@@ -0,0 +1,113 @@
+package crawl
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// DefaultChannelCacheSize is the number of chats whose supergroup info is
+// cached when no size is configured.
+const DefaultChannelCacheSize = 1000
+
+// supergroupCacheEntry is the cached result of the supergroup lookups of one chat.
+type supergroupCacheEntry struct {
+	chatID     int64
+	supergroup *client.Supergroup
+	fullInfo   *client.SupergroupFullInfo
+	expires    time.Time
+}
+
+// supergroupCache is an in-memory LRU cache of supergroup and supergroup full
+// info keyed by chat ID, so channels revisited within a crawl don't repeat the
+// API calls. Entries expire after ttl. It is safe for concurrent use.
+type supergroupCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	order   *list.List // Most recently used first
+	entries map[int64]*list.Element
+	now     func() time.Time
+}
+
+func newSupergroupCache(ttl time.Duration, size int) *supergroupCache {
+	if size <= 0 {
+		size = DefaultChannelCacheSize
+	}
+	return &supergroupCache{
+		ttl:     ttl,
+		size:    size,
+		order:   list.New(),
+		entries: make(map[int64]*list.Element),
+		now:     time.Now,
+	}
+}
+
+// get returns the cached lookups of a chat, if present and not expired.
+func (c *supergroupCache) get(chatID int64) (*client.Supergroup, *client.SupergroupFullInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[chatID]
+	if !ok {
+		return nil, nil, false
+	}
+	entry := elem.Value.(*supergroupCacheEntry)
+	if c.now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, chatID)
+		return nil, nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.supergroup, entry.fullInfo, true
+}
+
+// put caches the lookups of a chat, evicting the least recently used chat if
+// the cache is full.
+func (c *supergroupCache) put(chatID int64, supergroup *client.Supergroup, fullInfo *client.SupergroupFullInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &supergroupCacheEntry{chatID: chatID, supergroup: supergroup, fullInfo: fullInfo, expires: c.now().Add(c.ttl)}
+	if elem, ok := c.entries[chatID]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[chatID] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*supergroupCacheEntry).chatID)
+	}
+}
+
+// sharedSupergroupCache is the cache used by every crawl in the process. It is
+// recreated when a crawl asks for a different TTL or size.
+var sharedSupergroupCache struct {
+	sync.Mutex
+	cache *supergroupCache
+}
+
+// supergroupCacheFor returns the shared cache configured by cfg, or nil if
+// caching is disabled (ChannelCacheTTL <= 0).
+func supergroupCacheFor(cfg common.CrawlerConfig) *supergroupCache {
+	if cfg.ChannelCacheTTL <= 0 {
+		return nil
+	}
+	size := cfg.ChannelCacheSize
+	if size <= 0 {
+		size = DefaultChannelCacheSize
+	}
+
+	sharedSupergroupCache.Lock()
+	defer sharedSupergroupCache.Unlock()
+	if c := sharedSupergroupCache.cache; c != nil && c.ttl == cfg.ChannelCacheTTL && c.size == size {
+		return c
+	}
+	sharedSupergroupCache.cache = newSupergroupCache(cfg.ChannelCacheTTL, size)
+	return sharedSupergroupCache.cache
+}
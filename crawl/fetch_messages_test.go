@@ -350,4 +350,100 @@ func TestProcessAllMessagesWithFetcher(t *testing.T) {
 		// We don't check mockFetcher expectations as it's not actually used in processAllMessagesWithProcessor
 		processor.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+
+func TestProcessAllMessagesStopsAtMaxPostsPerChannel(t *testing.T) {
+	fixtures := NewTestFixtures(t)
+	defer fixtures.Cleanup()
+
+	mockClient := new(MockTDLibClient)
+	mockStateManager := new(MockStateManager)
+	mockStateManager.On("UpdatePage", mock.AnythingOfType("state.Page")).Return(nil)
+	mockStateManager.On("UpdateMessage", mock.AnythingOfType("string"), mock.AnythingOfType("int64"), mock.AnythingOfType("int64"), mock.AnythingOfType("string")).Return(nil)
+
+	messages := make([]*client.Message, 10)
+	for i := range messages {
+		messages[i] = &client.Message{
+			Id:      int64(i + 1),
+			ChatId:  fixtures.ChatID,
+			Date:    int32(time.Now().Unix()),
+			Content: &client.MessageText{Text: &client.FormattedText{Text: "post"}},
+		}
+	}
+	chatInfo := &channelInfo{
+		chat:        &client.Chat{Id: fixtures.ChatID, Title: "Test Channel"},
+		chatDetails: &client.Chat{Id: fixtures.ChatID},
+	}
+	page := &state.Page{ID: "test-page-id", URL: "test-channel", Status: "unfetched"}
+
+	processor := &MockMessageProcessor{}
+	processor.On("ProcessMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]string{}, nil)
+
+	cfg := common.CrawlerConfig{MaxPostsPerChannel: 3}
+	_, err := processAllMessagesWithProcessor(mockClient, chatInfo, messages, fixtures.CrawlID, "test-channel", mockStateManager, processor, page, cfg)
+
+	assert.NoError(t, err)
+	processor.AssertNumberOfCalls(t, "ProcessMessage", 3)
+	assert.Equal(t, "fetched", page.Status)
+}
+
+func TestProcessAllMessagesGroupsAlbums(t *testing.T) {
+	fixtures := NewTestFixtures(t)
+	defer fixtures.Cleanup()
+
+	mockClient := new(MockTDLibClient)
+	mockStateManager := new(MockStateManager)
+	mockStateManager.On("UpdatePage", mock.AnythingOfType("state.Page")).Return(nil)
+	mockStateManager.On("UpdateMessage", mock.AnythingOfType("string"), mock.AnythingOfType("int64"), mock.AnythingOfType("int64"), mock.AnythingOfType("string")).Return(nil)
+
+	// Three photos of one album, out of order, and a text post
+	messages := []*client.Message{
+		{Id: 3, ChatId: fixtures.ChatID, MediaAlbumId: 42, Content: &client.MessagePhoto{}},
+		{Id: 1, ChatId: fixtures.ChatID, MediaAlbumId: 42, Content: &client.MessagePhoto{}},
+		{Id: 2, ChatId: fixtures.ChatID, MediaAlbumId: 42, Content: &client.MessagePhoto{}},
+		{Id: 4, ChatId: fixtures.ChatID, Content: &client.MessageText{Text: &client.FormattedText{Text: "post"}}},
+	}
+	chatInfo := &channelInfo{
+		chat:        &client.Chat{Id: fixtures.ChatID, Title: "Test Channel"},
+		chatDetails: &client.Chat{Id: fixtures.ChatID},
+	}
+	inOrder := mock.MatchedBy(func(album []*client.Message) bool {
+		return len(album) == 3 && album[0].Id == 1 && album[1].Id == 2 && album[2].Id == 3
+	})
+
+	t.Run("grouped", func(t *testing.T) {
+		page := &state.Page{ID: "test-page-id", URL: "test-channel", Status: "unfetched"}
+		processor := &MockAlbumProcessor{}
+		processor.On("ProcessAlbum", mock.Anything, inOrder, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything).Return([]string{"albumchannel"}, nil).Once()
+		processor.On("ProcessMessage", mock.Anything, messages[3], mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]string{}, nil).Once()
+
+		cfg := common.CrawlerConfig{GroupAlbums: true}
+		discovered, err := processAllMessagesWithProcessor(mockClient, chatInfo, messages, fixtures.CrawlID, "test-channel", mockStateManager, processor, page, cfg)
+
+		assert.NoError(t, err)
+		processor.AssertExpectations(t)
+		if assert.Len(t, discovered, 1, "The album's outlinks should be reported once") {
+			assert.Equal(t, "albumchannel", discovered[0].URL)
+		}
+		for _, id := range []int64{1, 2, 3} {
+			mockStateManager.AssertCalled(t, "UpdateMessage", page.ID, id, fixtures.ChatID, "fetched")
+		}
+	})
+
+	t.Run("not grouped", func(t *testing.T) {
+		page := &state.Page{ID: "test-page-id", URL: "test-channel", Status: "unfetched"}
+		processor := &MockAlbumProcessor{}
+		processor.On("ProcessMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return([]string{}, nil)
+
+		_, err := processAllMessagesWithProcessor(mockClient, chatInfo, messages, fixtures.CrawlID, "test-channel", mockStateManager, processor, page, common.CrawlerConfig{})
+
+		assert.NoError(t, err)
+		processor.AssertNumberOfCalls(t, "ProcessMessage", 4)
+		processor.AssertNotCalled(t, "ProcessAlbum", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything)
+	})
+}
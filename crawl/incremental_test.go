@@ -0,0 +1,178 @@
+package crawl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/researchaccelerator-hub/telegram-scraper/telegramhelper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// historyClient serves a channel's history from an in-memory list of
+// messages, newest first, the way TDLib pages through GetChatHistory
+type historyClient struct {
+	MockTDLibClient
+	chat     *client.Chat
+	messages []*client.Message
+}
+
+func (h *historyClient) SearchPublicChat(req *client.SearchPublicChatRequest) (*client.Chat, error) {
+	return h.chat, nil
+}
+
+func (h *historyClient) GetChat(req *client.GetChatRequest) (*client.Chat, error) {
+	return h.chat, nil
+}
+
+func (h *historyClient) GetChatHistory(req *client.GetChatHistoryRequest) (*client.Messages, error) {
+	var batch []*client.Message
+	for _, m := range h.messages {
+		if req.FromMessageId != 0 && m.Id >= req.FromMessageId {
+			continue
+		}
+		if len(batch) == int(req.Limit) {
+			break
+		}
+		batch = append(batch, m)
+	}
+	return &client.Messages{TotalCount: int32(len(batch)), Messages: batch}, nil
+}
+
+func (h *historyClient) GetMessageLink(req *client.GetMessageLinkRequest) (*client.MessageLink, error) {
+	return &client.MessageLink{Link: "https://t.me/testchannel/1"}, nil
+}
+
+// post prepends a new message to the channel
+func (h *historyClient) post(id int64) {
+	m := &client.Message{
+		Id:      id,
+		ChatId:  h.chat.Id,
+		Date:    int32(time.Now().Unix()),
+		Content: &client.MessageText{Text: &client.FormattedText{Text: "post"}},
+	}
+	h.messages = append([]*client.Message{m}, h.messages...)
+}
+
+func TestRunForChannelIncrementalOnlyParsesNewMessages(t *testing.T) {
+	sm, err := state.NewLocalStateManager(state.Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &state.LocalConfig{BasePath: t.TempDir()},
+	})
+	require.NoError(t, err)
+
+	var parsed []int64
+	origParseMessage := telegramhelper.ParseMessage
+	telegramhelper.ParseMessage = func(
+		crawlid string,
+		message *client.Message,
+		mlr *client.MessageLink,
+		chat *client.Chat,
+		supergroup *client.Supergroup,
+		supergroupInfo *client.SupergroupFullInfo,
+		postcount int,
+		viewcount int,
+		channelName string,
+		tdlibClient crawler.TDLibClient,
+		sm state.StateManagementInterface,
+		cfg common.CrawlerConfig,
+	) (post model.Post, err error) {
+		parsed = append(parsed, message.Id)
+		return model.Post{}, nil
+	}
+	defer func() { telegramhelper.ParseMessage = origParseMessage }()
+
+	tdlibClient := &historyClient{chat: &client.Chat{Id: 12345, Title: "Test Channel"}}
+	for id := int64(1); id <= 3; id++ {
+		tdlibClient.post(id)
+	}
+	cfg := common.CrawlerConfig{CrawlID: "test-crawl", Incremental: true, MaxPosts: -1}
+
+	// First run parses the whole channel
+	_, err = RunForChannel(tdlibClient, &state.Page{ID: "page-1", URL: "testchannel"}, "", sm, cfg)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []int64{1, 2, 3}, parsed)
+
+	lastID, err := sm.GetLastMessageID("testchannel")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), lastID)
+
+	// Second run only sees the message posted in between
+	tdlibClient.post(4)
+	parsed = nil
+	_, err = RunForChannel(tdlibClient, &state.Page{ID: "page-2", URL: "testchannel"}, "", sm, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{4}, parsed)
+
+	lastID, err = sm.GetLastMessageID("testchannel")
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), lastID)
+
+	// A run with nothing new parses nothing and still succeeds
+	parsed = nil
+	page := &state.Page{ID: "page-3", URL: "testchannel"}
+	_, err = RunForChannel(tdlibClient, page, "", sm, cfg)
+	require.NoError(t, err)
+	assert.Empty(t, parsed)
+	assert.Equal(t, "fetched", page.Status)
+}
+
+func TestRunForChannelIncrementalOnlyAdvancesPastStoredMessages(t *testing.T) {
+	sm, err := state.NewLocalStateManager(state.Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &state.LocalConfig{BasePath: t.TempDir()},
+	})
+	require.NoError(t, err)
+
+	origParseMessage := telegramhelper.ParseMessage
+	telegramhelper.ParseMessage = func(
+		crawlid string,
+		message *client.Message,
+		mlr *client.MessageLink,
+		chat *client.Chat,
+		supergroup *client.Supergroup,
+		supergroupInfo *client.SupergroupFullInfo,
+		postcount int,
+		viewcount int,
+		channelName string,
+		tdlibClient crawler.TDLibClient,
+		sm state.StateManagementInterface,
+		cfg common.CrawlerConfig,
+	) (post model.Post, err error) {
+		if message.Id == 3 {
+			return model.Post{}, assert.AnError
+		}
+		return model.Post{}, nil
+	}
+	defer func() { telegramhelper.ParseMessage = origParseMessage }()
+
+	tdlibClient := &historyClient{chat: &client.Chat{Id: 12345, Title: "Test Channel"}}
+	for id := int64(1); id <= 3; id++ {
+		tdlibClient.post(id)
+	}
+
+	// A dry run stores nothing, so it leaves the channel's last message and
+	// history alone
+	cfg := common.CrawlerConfig{CrawlID: "test-crawl", Incremental: true, MaxPosts: -1, DryRun: true}
+	_, err = RunForChannel(tdlibClient, &state.Page{ID: "page-1", URL: "testchannel"}, "", sm, cfg)
+	require.NoError(t, err)
+	lastID, err := sm.GetLastMessageID("testchannel")
+	require.NoError(t, err)
+	assert.Zero(t, lastID)
+	history, err := sm.GetChannelHistory("testchannel")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// The newest message failed to parse, so only the ones before it count
+	cfg.DryRun = false
+	_, err = RunForChannel(tdlibClient, &state.Page{ID: "page-2", URL: "testchannel"}, "", sm, cfg)
+	require.NoError(t, err)
+	lastID, err = sm.GetLastMessageID("testchannel")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), lastID)
+}
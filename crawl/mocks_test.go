@@ -39,6 +39,22 @@ func (m *MockTDLibClient) SearchPublicChat(req *client.SearchPublicChatRequest)
 	return args.Get(0).(*client.Chat), args.Error(1)
 }
 
+func (m *MockTDLibClient) CheckChatInviteLink(req *client.CheckChatInviteLinkRequest) (*client.ChatInviteLinkInfo, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*client.ChatInviteLinkInfo), args.Error(1)
+}
+
+func (m *MockTDLibClient) JoinChatByInviteLink(req *client.JoinChatByInviteLinkRequest) (*client.Chat, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*client.Chat), args.Error(1)
+}
+
 func (m *MockTDLibClient) GetChat(req *client.GetChatRequest) (*client.Chat, error) {
 	args := m.Called(req)
 	if args.Get(0) == nil {
@@ -198,6 +214,15 @@ func (m *MockTDLibClient) DeleteFile(req *client.DeleteFileRequest) (*client.Ok,
 	return args.Get(0).(*client.Ok), args.Error(1)
 }
 
+// GetForumTopic implements the GetForumTopic method required by TDLibClient interface
+func (m *MockTDLibClient) GetForumTopic(req *client.GetForumTopicRequest) (*client.ForumTopic, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*client.ForumTopic), args.Error(1)
+}
+
 // MockMessageProcessor implements the MessageProcessor interface for testing.
 type MockMessageProcessor struct {
 	mock.Mock
@@ -218,6 +243,25 @@ func (m *MockMessageProcessor) ProcessMessage(
 	return args.Get(0).([]string), args.Error(1)
 }
 
+// MockAlbumProcessor is a MockMessageProcessor that also implements the
+// AlbumProcessor interface.
+type MockAlbumProcessor struct {
+	MockMessageProcessor
+}
+
+func (m *MockAlbumProcessor) ProcessAlbum(
+	tdlibClient crawler.TDLibClient,
+	messages []*client.Message,
+	info *channelInfo,
+	crawlID string,
+	channelUsername string,
+	sm *state.StateManagementInterface,
+	cfg common.CrawlerConfig) ([]string, error) {
+
+	args := m.Called(tdlibClient, messages, info, crawlID, channelUsername, sm, cfg)
+	return args.Get(0).([]string), args.Error(1)
+}
+
 // MockMessageFetcher implements the MessageFetcher interface for testing.
 type MockMessageFetcher struct {
 	mock.Mock
@@ -275,6 +319,12 @@ func (m *MockStateManager) GetMaxDepth() (int, error) {
 	return args.Int(0), args.Error(1)
 }
 
+// GetQuarantinedPages returns the pages skipped after repeated failures
+func (m *MockStateManager) GetQuarantinedPages() ([]state.Page, error) {
+	args := m.Called()
+	return args.Get(0).([]state.Page), args.Error(1)
+}
+
 // GetState returns a copy of the current state
 func (m *MockStateManager) GetState() state.State {
 	args := m.Called()
@@ -340,6 +390,71 @@ func (m *MockStateManager) MarkMediaAsProcessed(mediaID string) error {
 	return args.Error(0)
 }
 
+// MarkMediaAsStored marks media as processed and records its storage key
+func (m *MockStateManager) MarkMediaAsStored(mediaID string, storageKey string) error {
+	args := m.Called(mediaID, storageKey)
+	return args.Error(0)
+}
+
+// GetMediaStorageKey returns the storage key of processed media
+func (m *MockStateManager) GetMediaStorageKey(mediaID string) (string, error) {
+	args := m.Called(mediaID)
+	return args.String(0), args.Error(1)
+}
+
+// SaveSeenURLs records the seen-URL set
+func (m *MockStateManager) SaveSeenURLs(urls []string) error {
+	args := m.Called(urls)
+	return args.Error(0)
+}
+
+// HasPost reports whether the post has already been stored
+func (m *MockStateManager) HasPost(crawlID string, postUID string) bool {
+	args := m.Called(crawlID, postUID)
+	return args.Bool(0)
+}
+
+func (m *MockStateManager) QueryPosts(crawlID string, filter state.PostFilter) ([]model.Post, error) {
+	args := m.Called(crawlID, filter)
+	return args.Get(0).([]model.Post), args.Error(1)
+}
+
+// GetLastMessageID returns the channel's last seen message ID
+func (m *MockStateManager) GetLastMessageID(channelID string) (int64, error) {
+	args := m.Called(channelID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// SaveLastMessageID records the channel's last seen message ID
+func (m *MockStateManager) SaveLastMessageID(channelID string, messageID int64) error {
+	args := m.Called(channelID, messageID)
+	return args.Error(0)
+}
+
+// StoreChannelSnapshot records a channel snapshot
+func (m *MockStateManager) StoreChannelSnapshot(snapshot state.ChannelSnapshot) error {
+	args := m.Called(snapshot)
+	return args.Error(0)
+}
+
+// GetChannelHistory returns the channel's stored snapshots
+func (m *MockStateManager) GetChannelHistory(channelID string) ([]state.ChannelSnapshot, error) {
+	args := m.Called(channelID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]state.ChannelSnapshot), args.Error(1)
+}
+
+// LoadSeenURLs returns the saved seen-URL set
+func (m *MockStateManager) LoadSeenURLs() ([]string, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
 // Close closes the state manager
 func (m *MockStateManager) Close() error {
 	args := m.Called()
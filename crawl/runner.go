@@ -9,10 +9,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/researchaccelerator-hub/telegram-scraper/common"
 	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
 	"github.com/researchaccelerator-hub/telegram-scraper/state"
 	"github.com/researchaccelerator-hub/telegram-scraper/telegramhelper"
 	"github.com/rs/zerolog/log"
 	"github.com/zelenin/go-tdlib/client"
+	"sort"
 	"sync"
 	"time"
 )
@@ -185,6 +187,21 @@ func GetConnectionPoolStats() map[string]int {
 	return stats
 }
 
+// PingConnectionPool checks that every connection of the global connection pool
+// is connected and authorized. It returns an error if the pool is not
+// initialized, has no connections yet or any connection fails to answer before
+// ctx is done.
+func PingConnectionPool(ctx context.Context) error {
+	poolMu.Lock()
+	pool := connectionPool
+	poolMu.Unlock()
+
+	if pool == nil {
+		return fmt.Errorf("connection pool not initialized")
+	}
+	return pool.Ping(ctx)
+}
+
 // RunForChannelWithPool connects to a Telegram channel and crawls its messages.
 // This version uses the connection pool for more efficient client management,
 // obtaining a client from the pool, processing the channel, and then returning
@@ -226,6 +243,20 @@ func RunForChannelWithPool(ctx context.Context, p *state.Page, storagePrefix str
 	return RunForChannel(tdlibClient, p, storagePrefix, sm, cfg)
 }
 
+// RunForChannelWithSession crawls a Telegram channel with a session from the
+// given pool, waiting for one to become free if all are in use. The session is
+// returned to the pool when the channel is done, even if processing panics.
+func RunForChannelWithSession(ctx context.Context, sessions *telegramhelper.SessionPool, p *state.Page, storagePrefix string, sm state.StateManagementInterface, cfg common.CrawlerConfig) ([]*state.Page, error) {
+	session, err := sessions.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire TDLib session: %w", err)
+	}
+	defer sessions.Release(session)
+
+	log.Debug().Int("session", session.ID).Str("url", p.URL).Msg("Processing channel with TDLib session")
+	return RunForChannel(session.Client, p, storagePrefix, sm, cfg)
+}
+
 // RunForChannel processes a single Telegram channel using the provided TDLib client.
 // It retrieves channel information, verifies activity requirements, and processes all
 // messages in the channel, storing data and discovering linked channels.
@@ -245,11 +276,42 @@ func RunForChannelWithPool(ctx context.Context, p *state.Page, storagePrefix str
 // and member count to determine whether the channel should be fully processed.
 func RunForChannel(tdlibClient crawler.TDLibClient, p *state.Page, storagePrefix string, sm state.StateManagementInterface, cfg common.CrawlerConfig) ([]*state.Page, error) {
 
+	// In incremental mode only messages newer than the last crawl are fetched
+	var lastMessageID int64
+	if cfg.Incremental {
+		var err error
+		lastMessageID, err = sm.GetLastMessageID(p.URL)
+		if err != nil {
+			log.Warn().Err(err).Str("channel", p.URL).Msg("Failed to load last seen message ID, fetching all messages")
+			lastMessageID = 0
+		}
+	}
+
 	// Get channel information
-	channelInfo, messages, err := getChannelInfo(tdlibClient, p, cfg)
+	channelInfo, messages, err := getChannelInfo(tdlibClient, p, lastMessageID, cfg)
 	if err != nil {
 		return nil, err
 	}
+
+	// The title is only known once the chat is fetched, so check the filter again with it
+	if channelInfo.chat != nil && !cfg.ChannelFilter.Allows(p.URL, channelInfo.chat.Title) {
+		log.Info().Str("channel", p.URL).Str("title", channelInfo.chat.Title).Msg("Channel title rejected by channel filter, skipping.")
+		p.Status = "deadend"
+		if err := sm.SaveState(); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	if lastMessageID > 0 && len(messages) == 0 {
+		log.Info().Str("channel", p.URL).Int64("last_message_id", lastMessageID).Msg("No new messages since the last crawl.")
+		p.Status = "fetched"
+		if err := sm.SaveState(); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
 	active, err := isChannelActiveWithinPeriod(tdlibClient, channelInfo.chatDetails.Id, cfg.PostRecency)
 	if err != nil {
 		return nil, err
@@ -264,15 +326,61 @@ func RunForChannel(tdlibClient crawler.TDLibClient, p *state.Page, storagePrefix
 		return nil, nil
 	}
 
+	if !cfg.DryRun {
+		recordChannelSnapshot(sm, p.URL, channelInfo)
+	}
+
 	// Process all messages in the channel
 	discoveredChannels, err := processAllMessages(tdlibClient, channelInfo, messages, cfg.CrawlID, p.URL, sm, p, cfg)
+	// Let per-channel outputs such as Parquet partitions finish the channel's files
+	if finishErr := sink.FinishChannel(cfg.PostSinks, p.URL); finishErr != nil {
+		log.Warn().Err(finishErr).Str("channel", p.URL).Msg("Failed to finish the channel's post outputs")
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if cfg.Incremental && !cfg.DryRun {
+		recordLastMessageID(sm, p.URL, lastMessageID, p.Messages)
+	}
+
 	return discoveredChannels, nil
 }
 
+// recordLastMessageID saves the highest ID of the stored ("fetched") messages
+// as the channel's last seen message, if it is newer than lastMessageID, so
+// the next incremental crawl starts after it. Messages that failed or were
+// left out by the per-channel post limit do not advance it.
+func recordLastMessageID(sm state.StateManagementInterface, channel string, lastMessageID int64, messages []state.Message) {
+	highest := lastMessageID
+	for _, m := range messages {
+		if m.Status == "fetched" && m.MessageID > highest {
+			highest = m.MessageID
+		}
+	}
+	if highest == lastMessageID {
+		return
+	}
+	if err := sm.SaveLastMessageID(channel, highest); err != nil {
+		log.Error().Err(err).Str("channel", channel).Int64("message_id", highest).Msg("Failed to save last seen message ID")
+	}
+}
+
+// recordChannelSnapshot appends the channel's current view, member and
+// message counts to its history. A failure is logged rather than returned so
+// it does not stop the channel from being crawled.
+func recordChannelSnapshot(sm state.StateManagementInterface, channel string, info *channelInfo) {
+	snapshot := state.ChannelSnapshot{
+		ChannelID:     channel,
+		ViewsCount:    int(info.totalViews),
+		FollowerCount: int(info.memberCount),
+		PostCount:     int(info.messageCount),
+	}
+	if err := sm.StoreChannelSnapshot(snapshot); err != nil {
+		log.Error().Err(err).Str("channel", channel).Msg("Failed to store channel snapshot")
+	}
+}
+
 // getLatestMessageTime retrieves the timestamp of the most recent message in a chat.
 // This is used to determine if a channel is active within a specified time period.
 //
@@ -417,6 +525,7 @@ type MemberCountGetter func(client crawler.TDLibClient, channelUsername string)
 // Parameters:
 //   - tdlibClient: An initialized TDLib client connection
 //   - page: State representation of the channel to fetch
+//   - afterMessageID: Only fetch messages with a greater ID (0 = fetch all)
 //   - cfg: Configuration settings for the crawler
 //
 // Returns:
@@ -427,13 +536,14 @@ type MemberCountGetter func(client crawler.TDLibClient, channelUsername string)
 // This function uses the standard implementations for retrieving views, message count,
 // and member count from the telegramhelper package. For testing or custom implementations,
 // use getChannelInfoWithDeps directly.
-func getChannelInfo(tdlibClient crawler.TDLibClient, page *state.Page, cfg common.CrawlerConfig) (*channelInfo, []*client.Message, error) {
+func getChannelInfo(tdlibClient crawler.TDLibClient, page *state.Page, afterMessageID int64, cfg common.CrawlerConfig) (*channelInfo, []*client.Message, error) {
 	return getChannelInfoWithDeps(
 		tdlibClient,
 		page,
 		telegramhelper.GetTotalChannelViews,
 		telegramhelper.GetMessageCount,
 		telegramhelper.GetChannelMemberCount,
+		afterMessageID,
 		cfg,
 	)
 }
@@ -448,6 +558,7 @@ func getChannelInfo(tdlibClient crawler.TDLibClient, page *state.Page, cfg commo
 //   - getTotalViewsFn: Function to retrieve total view count for the channel
 //   - getMessageCountFn: Function to retrieve total message count for the channel
 //   - getMemberCountFn: Function to retrieve member count for the channel
+//   - afterMessageID: Only fetch messages with a greater ID (0 = fetch all)
 //   - cfg: Configuration settings for the crawler
 //
 // Returns:
@@ -468,12 +579,20 @@ func getChannelInfoWithDeps(
 	getTotalViewsFn TotalViewsGetter,
 	getMessageCountFn MessageCountGetter,
 	getMemberCountFn MemberCountGetter,
+	afterMessageID int64,
 	cfg common.CrawlerConfig,
 ) (*channelInfo, []*client.Message, error) {
-	// Search for the channel
-	chat, err := tdlibClient.SearchPublicChat(&client.SearchPublicChatRequest{
-		Username: page.URL,
-	})
+	// Search for the channel; invite links to private chats are resolved
+	// (and joined, if allowed) instead
+	var chat *client.Chat
+	var err error
+	if telegramhelper.IsInviteLink(page.URL) {
+		chat, err = telegramhelper.ResolveInviteLink(tdlibClient, page.URL, cfg.AutoJoin)
+	} else {
+		chat, err = tdlibClient.SearchPublicChat(&client.SearchPublicChatRequest{
+			Username: page.URL,
+		})
+	}
 	if err != nil {
 		log.Error().Err(err).Stack().Msgf("Failed to find channel: %v", page.URL)
 		return nil, nil, err
@@ -489,9 +608,9 @@ func getChannelInfoWithDeps(
 
 	var mess []*client.Message
 	if !cfg.DateBetweenMin.IsZero() && !cfg.DateBetweenMax.IsZero() {
-		mess, err = telegramhelper.FetchChannelMessagesWithSampling(tdlibClient, chat.Id, page, cfg.DateBetweenMin, cfg.DateBetweenMax, cfg.MaxPosts, cfg.SampleSize)
+		mess, err = telegramhelper.FetchChannelMessagesAfter(tdlibClient, chat.Id, page, cfg.DateBetweenMin, cfg.DateBetweenMax, cfg.MaxPosts, cfg.SampleSize, afterMessageID)
 	} else {
-		mess, err = telegramhelper.FetchChannelMessages(tdlibClient, chat.Id, page, cfg.MinPostDate, cfg.MaxPosts)
+		mess, err = telegramhelper.FetchChannelMessagesAfter(tdlibClient, chat.Id, page, cfg.MinPostDate, cfg.MaxPostDate, cfg.MaxPosts, 0, afterMessageID)
 	}
 
 	// Get channel stats
@@ -530,7 +649,13 @@ func getChannelInfoWithDeps(
 	var supergroup *client.Supergroup
 	var supergroupInfo *client.SupergroupFullInfo
 
-	if chat.Type != nil {
+	cache := supergroupCacheFor(cfg)
+	cached := false
+	if cache != nil {
+		supergroup, supergroupInfo, cached = cache.get(chat.Id)
+	}
+
+	if chat.Type != nil && !cached {
 		if supergroupType, ok := chat.Type.(*client.ChatTypeSupergroup); ok {
 			supergroup, err = tdlibClient.GetSupergroup(&client.GetSupergroupRequest{
 				SupergroupId: supergroupType.SupergroupId,
@@ -550,6 +675,11 @@ func getChannelInfoWithDeps(
 					// Continue anyway, this is not critical
 				}
 			}
+
+			// Only complete lookups are cached so failures are retried
+			if cache != nil && supergroup != nil && supergroupInfo != nil {
+				cache.put(chat.Id, supergroup, supergroupInfo)
+			}
 		}
 	}
 
@@ -618,6 +748,40 @@ func (p *DefaultMessageProcessor) ProcessMessage(tdlibClient crawler.TDLibClient
 	return processMessage(tdlibClient, message, messageId, chatId, info, crawlID, channelUsername, *sm, cfg)
 }
 
+// AlbumProcessor is implemented by message processors that can store the
+// messages of a media album as a single post. When cfg.GroupAlbums is set,
+// albums with more than one message in a fetch batch are passed to
+// ProcessAlbum instead of ProcessMessage.
+type AlbumProcessor interface {
+	// ProcessAlbum processes the messages of one media album, in message ID
+	// order, and returns the outlinks discovered in them.
+	ProcessAlbum(tdlibClient crawler.TDLibClient, messages []*client.Message, info *channelInfo, crawlID string, channelUsername string, sm *state.StateManagementInterface, cfg common.CrawlerConfig) ([]string, error)
+}
+
+// ProcessAlbum implements the AlbumProcessor interface
+func (p *DefaultMessageProcessor) ProcessAlbum(tdlibClient crawler.TDLibClient, messages []*client.Message, info *channelInfo, crawlID string, channelUsername string, sm *state.StateManagementInterface, cfg common.CrawlerConfig) ([]string, error) {
+	return processAlbum(tdlibClient, messages, info, crawlID, channelUsername, *sm, cfg)
+}
+
+// groupAlbums returns the messages of every media album with more than one
+// message in the batch, by album ID and in message ID order.
+func groupAlbums(messages []*client.Message) map[client.JsonInt64][]*client.Message {
+	albums := make(map[client.JsonInt64][]*client.Message)
+	for _, m := range messages {
+		if m != nil && m.MediaAlbumId != 0 {
+			albums[m.MediaAlbumId] = append(albums[m.MediaAlbumId], m)
+		}
+	}
+	for id, members := range albums {
+		if len(members) < 2 {
+			delete(albums, id)
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i].Id < members[j].Id })
+	}
+	return albums
+}
+
 // processAllMessages retrieves and processes all messages from a channel
 func processAllMessages(tdlibClient crawler.TDLibClient, info *channelInfo, messages []*client.Message, crawlID, channelUsername string, sm state.StateManagementInterface, owner *state.Page, cfg common.CrawlerConfig) ([]*state.Page, error) {
 	processor := &DefaultMessageProcessor{}
@@ -689,7 +853,16 @@ func processAllMessagesWithProcessor(
 	var processErrors []error
 	var fetched, deleted, processed, failed int
 
-	for _, message := range owner.Messages {
+	// Albums are processed once, when their first message comes up; the
+	// other messages of the album share that result
+	var albums map[client.JsonInt64][]*client.Message
+	albumProcessor, groupsAlbums := processor.(AlbumProcessor)
+	if cfg.GroupAlbums && groupsAlbums {
+		albums = groupAlbums(messages)
+	}
+	albumErrors := make(map[client.JsonInt64]error)
+
+	for i, message := range owner.Messages {
 		log.Debug().
 			Int64("chat_id", message.ChatID).
 			Int64("message_id", message.MessageID).
@@ -714,6 +887,7 @@ func processAllMessagesWithProcessor(
 					Str("page_id", message.PageID).
 					Msg("Message not found in latest fetch, marking as deleted")
 				sm.UpdateMessage(owner.ID, message.ChatID, message.MessageID, "deleted")
+				owner.Messages[i].Status = "deleted"
 				deleted++
 				continue
 			}
@@ -727,7 +901,18 @@ func processAllMessagesWithProcessor(
 				Msg("Processing message")
 
 			// Try to process the message, but continue even if it fails
-			outlinks, err := processor.ProcessMessage(tdlibClient, discMessage, message.MessageID, message.ChatID, info, crawlID, channelUsername, &sm, cfg)
+			var outlinks []string
+			var err error
+			if members, ok := albums[discMessage.MediaAlbumId]; ok {
+				if albumErr, done := albumErrors[discMessage.MediaAlbumId]; done {
+					err = albumErr
+				} else {
+					outlinks, err = albumProcessor.ProcessAlbum(tdlibClient, members, info, crawlID, channelUsername, &sm, cfg)
+					albumErrors[discMessage.MediaAlbumId] = err
+				}
+			} else {
+				outlinks, err = processor.ProcessMessage(tdlibClient, discMessage, message.MessageID, message.ChatID, info, crawlID, channelUsername, &sm, cfg)
+			}
 
 			if err != nil {
 				log.Error().Err(err).
@@ -737,9 +922,11 @@ func processAllMessagesWithProcessor(
 					Msg("Error processing message")
 				processErrors = append(processErrors, err)
 				sm.UpdateMessage(owner.ID, message.MessageID, message.ChatID, "failed")
+				owner.Messages[i].Status = "failed"
 				failed++
 			} else {
 				sm.UpdateMessage(owner.ID, message.MessageID, message.ChatID, "fetched")
+				owner.Messages[i].Status = "fetched"
 				fetched++
 
 				if outlinks != nil {
@@ -765,6 +952,14 @@ func processAllMessagesWithProcessor(
 				}
 			}
 		}
+
+		if cfg.MaxPostsPerChannel > 0 && fetched >= cfg.MaxPostsPerChannel {
+			log.Info().
+				Int("max_posts_per_channel", cfg.MaxPostsPerChannel).
+				Str("page_url", owner.URL).
+				Msg("Reached per-channel post limit, stopping message processing")
+			break
+		}
 	}
 
 	// Log processing summary
@@ -779,6 +974,7 @@ func processAllMessagesWithProcessor(
 		Msg("Message processing summary")
 
 	owner.Status = "fetched"
+	owner.ErrorCount = 0
 	err = sm.UpdatePage(*owner)
 	if err != nil {
 		return nil, err
@@ -949,7 +1145,7 @@ func addNewMessages(discoveredMessages []state.Message, owner *state.Page) []sta
 //   - cfg: Configuration settings for the crawler
 //
 // Returns:
-//   - A slice of outlink URLs discovered in the message
+//   - The channels the discovery strategy picked from the message
 //   - An error if message processing fails
 //
 // The function handles message parsing, media download (if applicable),
@@ -1002,8 +1198,50 @@ func processMessage(tdlibClient crawler.TDLibClient, message *client.Message, me
 			return []string{}, parseErr
 		}
 
-		return post.Outlinks, nil
+		return post.DiscoveredChannels, nil
 	}
 
 	return []string{}, fmt.Errorf("could not process message %d: no message link available", messageId)
 }
+
+// processAlbum stores the messages of a media album as a single post and
+// returns the channels the discovery strategy picked from them. It fails if the link of any of the
+// messages can't be retrieved.
+func processAlbum(tdlibClient crawler.TDLibClient, messages []*client.Message, info *channelInfo, crawlID, channelUsername string, sm state.StateManagementInterface, cfg common.CrawlerConfig) ([]string, error) {
+	links := make([]*client.MessageLink, 0, len(messages))
+	for _, message := range messages {
+		link, err := tdlibClient.GetMessageLink(&client.GetMessageLinkRequest{
+			ChatId:    message.ChatId,
+			MessageId: message.Id,
+		})
+		if err != nil {
+			log.Warn().Err(err).Msgf("Failed to get link for message %d", message.Id)
+			return []string{}, fmt.Errorf("failed to get message link: %w", err)
+		}
+		if link == nil {
+			return []string{}, fmt.Errorf("could not process message %d: no message link available", message.Id)
+		}
+		links = append(links, link)
+	}
+
+	post, err := telegramhelper.ParseAlbum(
+		crawlID,
+		messages,
+		links,
+		info.chatDetails,
+		info.supergroup,
+		info.supergroupInfo,
+		int(info.messageCount),
+		int(info.totalViews),
+		channelUsername,
+		tdlibClient,
+		sm,
+		cfg,
+	)
+	if err != nil {
+		log.Error().Stack().Err(err).Msgf("Failed to parse album %d", messages[0].MediaAlbumId)
+		return []string{}, err
+	}
+
+	return post.DiscoveredChannels, nil
+}
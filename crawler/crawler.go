@@ -112,6 +112,8 @@ type TDLibClient interface {
 	DownloadFile(req *tdlibclient.DownloadFileRequest) (*tdlibclient.File, error)
 	GetChatHistory(req *tdlibclient.GetChatHistoryRequest) (*tdlibclient.Messages, error)
 	SearchPublicChat(req *tdlibclient.SearchPublicChatRequest) (*tdlibclient.Chat, error)
+	CheckChatInviteLink(req *tdlibclient.CheckChatInviteLinkRequest) (*tdlibclient.ChatInviteLinkInfo, error)
+	JoinChatByInviteLink(req *tdlibclient.JoinChatByInviteLinkRequest) (*tdlibclient.Chat, error)
 	GetChat(req *tdlibclient.GetChatRequest) (*tdlibclient.Chat, error)
 	GetSupergroup(req *tdlibclient.GetSupergroupRequest) (*tdlibclient.Supergroup, error)
 	GetSupergroupFullInfo(req *tdlibclient.GetSupergroupFullInfoRequest) (*tdlibclient.SupergroupFullInfo, error)
@@ -120,6 +122,7 @@ type TDLibClient interface {
 	GetBasicGroupFullInfo(req *tdlibclient.GetBasicGroupFullInfoRequest) (*tdlibclient.BasicGroupFullInfo, error)
 	GetUser(*tdlibclient.GetUserRequest) (*tdlibclient.User, error)
 	DeleteFile(req *tdlibclient.DeleteFileRequest) (*tdlibclient.Ok, error)
+	GetForumTopic(req *tdlibclient.GetForumTopicRequest) (*tdlibclient.ForumTopic, error)
 }
 
 // CrawlerOptions holds configuration options for a crawler
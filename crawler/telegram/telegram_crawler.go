@@ -167,7 +167,7 @@ func (c *TelegramCrawler) convertMessageToPost(message client.Message) model.Pos
 		PublishedAt:   message.GetTimestamp(),
 		CreatedAt:     time.Now(),
 		Description:   message.GetText(),
-		ViewsCount:    int(message.GetViews()),
+		ViewCount:     int(message.GetViews()),
 		PlatformName:  "telegram",
 		SearchableText: message.GetText(),
 		AllText:       message.GetText(),
@@ -4,15 +4,50 @@ package youtube
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 	
 	clientpkg "github.com/researchaccelerator-hub/telegram-scraper/client"
 	youtubemodel "github.com/researchaccelerator-hub/telegram-scraper/model/youtube"
+	"github.com/rs/zerolog/log"
 )
 
+// Random sampling parameters
+const (
+	randomSamplePartitions  = 12   // Date partitions the sampling window is split into
+	randomSamplePerQuery    = 50   // Results requested per search call (API maximum)
+	randomSampleSeedLength  = 4    // Random characters in each query seed
+	randomSampleDefaultSize = 1000 // Sample size used when no limit is given
+)
+
+// DefaultSnowballMaxDepth is the number of expansion hops from the seed channels
+// used by snowball sampling unless overridden with SetSnowballMaxDepth
+const DefaultSnowballMaxDepth = 2
+
+// DefaultRandomSampleMaxQueries is the number of search calls a random sample
+// may spend unless overridden with SetRandomSampleMaxQueries: one per date
+// partition. Each call costs 100 quota units, so the default uses 1,200 of the
+// standard daily quota of 10,000.
+const DefaultRandomSampleMaxQueries = randomSamplePartitions
+
+// youtubeLaunchDate is the earliest possible publish date, used as the lower
+// bound of the sampling window when no fromTime is given
+var youtubeLaunchDate = time.Date(2005, time.April, 23, 0, 0, 0, 0, time.UTC)
+
 // ClientAdapter adapts a client.Client to the YouTubeClient interface
 type ClientAdapter struct {
 	client clientpkg.Client
+
+	// RNG for query seeds and partition order
+	rng   *rand.Rand
+	rngMu sync.Mutex
+
+	// Expansion hops from the seed channels in snowball sampling
+	snowballMaxDepth int
+
+	// Upper bound on search calls for a single random sample
+	randomSampleMaxQueries int
 }
 
 // NewClientAdapter creates a new adapter for the provided client
@@ -26,7 +61,10 @@ func NewClientAdapter(client clientpkg.Client) (*ClientAdapter, error) {
 	}
 	
 	adapter := &ClientAdapter{
-		client: client,
+		client:                 client,
+		rng:                    rand.New(rand.NewSource(time.Now().UnixNano())),
+		snowballMaxDepth:       DefaultSnowballMaxDepth,
+		randomSampleMaxQueries: DefaultRandomSampleMaxQueries,
 	}
 	
 	// Verify adapter implements YouTubeClient interface
@@ -59,10 +97,11 @@ func (a *ClientAdapter) GetChannelInfo(ctx context.Context, channelID string) (*
 		Title:           channel.GetName(),
 		Description:     channel.GetDescription(),
 		SubscriberCount: int64(channel.GetMemberCount()),
-		ViewCount:       0, // Not directly available
-		VideoCount:      0, // Not directly available
-		PublishedAt:     time.Time{}, // Not directly available
+		ViewCount:       channel.GetViewCount(),
+		VideoCount:      channel.GetVideoCount(),
+		PublishedAt:     channel.GetPublishedAt(),
 		Thumbnails:      make(map[string]string),
+		Country:         channel.GetCountry(),
 	}
 	
 	return ytChannel, nil
@@ -76,52 +115,341 @@ func (a *ClientAdapter) GetVideos(ctx context.Context, channelID string, fromTim
 		return nil, err
 	}
 	
-	// Convert messages to YouTube videos
+	// Convert messages to YouTube videos, dropping any the client returned
+	// outside the requested window
 	videos := make([]*youtubemodel.YouTubeVideo, 0, len(messages))
 	for _, msg := range messages {
-		// Use the new getter methods directly
-		video := &youtubemodel.YouTubeVideo{
-			ID:           msg.GetID(),
-			ChannelID:    channelID,
-			Title:        msg.GetTitle(),
-			Description:  msg.GetDescription(),
-			PublishedAt:  msg.GetTimestamp(),
-			ViewCount:    msg.GetViews(),
-			LikeCount:    0,
-			CommentCount: msg.GetCommentCount(),
-			Thumbnails:   msg.GetThumbnails(),
-			Language:     msg.GetLanguage(),
-		}
-		
-		// Extract like count from reactions if available
-		if reactions := msg.GetReactions(); reactions != nil {
-			if likeCount, ok := reactions["like"]; ok {
-				video.LikeCount = likeCount
-			}
+		video := messageToVideo(msg, channelID)
+		if !inTimeWindow(video.PublishedAt, fromTime, toTime) {
+			log.Debug().
+				Str("video_id", video.ID).
+				Time("published_at", video.PublishedAt).
+				Msg("Dropping video published outside the requested time window")
+			continue
 		}
-		
 		videos = append(videos, video)
 	}
 	
 	return videos, nil
 }
 
+// inTimeWindow reports whether t falls within [fromTime, toTime]. A zero bound
+// leaves that side of the window open.
+func inTimeWindow(t, fromTime, toTime time.Time) bool {
+	if !fromTime.IsZero() && t.Before(fromTime) {
+		return false
+	}
+	if !toTime.IsZero() && t.After(toTime) {
+		return false
+	}
+	return true
+}
+
+// messageToVideo converts a client message to a YouTube video
+func messageToVideo(msg clientpkg.Message, channelID string) *youtubemodel.YouTubeVideo {
+	// Use the new getter methods directly
+	video := &youtubemodel.YouTubeVideo{
+		ID:           msg.GetID(),
+		ChannelID:    channelID,
+		Title:        msg.GetTitle(),
+		Description:  msg.GetDescription(),
+		PublishedAt:  msg.GetTimestamp(),
+		ViewCount:    msg.GetViews(),
+		LikeCount:    0,
+		CommentCount: msg.GetCommentCount(),
+		Thumbnails:   msg.GetThumbnails(),
+		Language:     msg.GetLanguage(),
+	}
+	
+	// Keep track of the counts the API hides, which read 0
+	if counter, ok := msg.(clientpkg.ViewCounter); ok {
+		_, video.HasViewCount = counter.GetViewCount()
+	}
+	if counter, ok := msg.(clientpkg.CommentCounter); ok {
+		_, video.HasCommentCount = counter.GetReportedCommentCount()
+	}
+
+	// Prefer the like count from the video statistics
+	if counter, ok := msg.(clientpkg.LikeCounter); ok {
+		if likeCount, reported := counter.GetLikeCount(); reported {
+			video.LikeCount = likeCount
+			video.HasLikeCount = true
+			return video
+		}
+	}
+	
+	// Fall back to a like reaction when the API omitted statistics
+	if reactions := msg.GetReactions(); reactions != nil {
+		if likeCount, ok := reactions["like"]; ok {
+			video.LikeCount = likeCount
+		}
+	}
+	
+	return video
+}
+
 // GetVideosFromChannel retrieves videos from a specific YouTube channel
 func (a *ClientAdapter) GetVideosFromChannel(ctx context.Context, channelID string, fromTime, toTime time.Time, limit int) ([]*youtubemodel.YouTubeVideo, error) {
 	// Reuse the GetVideos implementation since they do the same thing
 	return a.GetVideos(ctx, channelID, fromTime, toTime, limit)
 }
 
-// GetRandomVideos retrieves videos using random sampling
+// GetVideoComments retrieves up to limit top-level comments on a video. Each
+// call spends API quota, so callers should only use it when comment retrieval
+// was requested.
+func (a *ClientAdapter) GetVideoComments(ctx context.Context, videoID string, limit int) ([]youtubemodel.YouTubeComment, error) {
+	fetcher, ok := a.client.(clientpkg.CommentFetcher)
+	if !ok {
+		return nil, fmt.Errorf("comment retrieval requires a client that supports video comments")
+	}
+	return fetcher.GetVideoComments(ctx, videoID, limit)
+}
+
+// GetRandomVideos draws a random sample of videos published between fromTime and
+// toTime without enumerating any channel.
+//
+// The window is split into equal date partitions which are visited in shuffled,
+// round-robin order. Each visit issues one search restricted to the partition
+// with a random query seed of the form "watch?v=<chars>-", matching videos whose
+// text happens to contain that fragment. Results are deduplicated by video ID
+// until limit videos are collected, the query budget is spent or the API
+// reports that the quota is exceeded.
+//
+// Sampling bias: the search API ranks by relevance and returns at most 50 results
+// per query, so popular and well-described videos are over-represented relative
+// to a uniform draw. Partitions receive equal query budgets, so quiet periods
+// contribute as many videos as busy ones and videos from quiet periods are
+// over-represented per capita. Seeds only match videos with searchable text,
+// and the returned videos carry search snippet data without statistics.
 func (a *ClientAdapter) GetRandomVideos(ctx context.Context, fromTime, toTime time.Time, limit int) ([]*youtubemodel.YouTubeVideo, error) {
-	// This is a simplified implementation since the underlying client doesn't support random sampling
-	// In a real implementation, this would use a more sophisticated method for random sampling
-	return []*youtubemodel.YouTubeVideo{}, fmt.Errorf("random sampling not implemented in adapter")
+	searcher, ok := a.client.(clientpkg.VideoSearcher)
+	if !ok {
+		return nil, fmt.Errorf("random sampling requires a client that supports video search")
+	}
+
+	if toTime.IsZero() {
+		toTime = time.Now()
+	}
+	if fromTime.IsZero() {
+		fromTime = youtubeLaunchDate
+	}
+	if !fromTime.Before(toTime) {
+		return nil, fmt.Errorf("invalid sampling window: from %s is not before to %s",
+			fromTime.Format(time.RFC3339), toTime.Format(time.RFC3339))
+	}
+	if limit <= 0 {
+		limit = randomSampleDefaultSize
+	}
+
+	partitions := a.shuffledPartitions(fromTime, toTime, randomSamplePartitions)
+	seen := make(map[string]bool)
+	videos := make([]*youtubemodel.YouTubeVideo, 0, limit)
+
+	var lastErr error
+	failures := 0
+	queries := 0
+	for ; queries < a.randomSampleMaxQueries && len(videos) < limit; queries++ {
+		if err := ctx.Err(); err != nil {
+			return videos, err
+		}
+
+		partition := partitions[queries%len(partitions)]
+		seed := a.randomSeed()
+
+		messages, err := searcher.SearchVideos(ctx, seed, partition[0], partition[1], randomSamplePerQuery)
+		if clientpkg.IsQuotaExceeded(err) {
+			// Every further search would fail the same way
+			log.Warn().Err(err).Int("videos", len(videos)).Msg("YouTube quota exceeded, ending random sampling")
+			if len(videos) == 0 {
+				return nil, fmt.Errorf("random sampling failed: %w", err)
+			}
+			queries++
+			break
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("seed", seed).Msg("Random sampling search failed")
+			lastErr = err
+			failures++
+			continue
+		}
+
+		for _, msg := range messages {
+			if seen[msg.GetID()] {
+				continue
+			}
+			// Guard against results the API returns outside the requested window
+			published := msg.GetTimestamp()
+			if published.Before(fromTime) || published.After(toTime) {
+				continue
+			}
+			seen[msg.GetID()] = true
+			videos = append(videos, messageToVideo(msg, msg.GetChannelID()))
+			if len(videos) >= limit {
+				break
+			}
+		}
+	}
+
+	if len(videos) == 0 && failures == queries && lastErr != nil {
+		return nil, fmt.Errorf("random sampling failed: all %d searches returned errors: %w", failures, lastErr)
+	}
+
+	log.Info().
+		Int("videos", len(videos)).
+		Int("queries", queries).
+		Int("failed_queries", failures).
+		Msg("Completed random YouTube video sampling")
+
+	return videos, nil
+}
+
+// shuffledPartitions splits [fromTime, toTime] into n equal ranges in random order
+func (a *ClientAdapter) shuffledPartitions(fromTime, toTime time.Time, n int) [][2]time.Time {
+	step := toTime.Sub(fromTime) / time.Duration(n)
+	if step <= 0 {
+		return [][2]time.Time{{fromTime, toTime}}
+	}
+
+	partitions := make([][2]time.Time, n)
+	for i := range partitions {
+		start := fromTime.Add(time.Duration(i) * step)
+		end := start.Add(step)
+		if i == n-1 {
+			end = toTime
+		}
+		partitions[i] = [2]time.Time{start, end}
+	}
+
+	a.rngMu.Lock()
+	a.rng.Shuffle(len(partitions), func(i, j int) {
+		partitions[i], partitions[j] = partitions[j], partitions[i]
+	})
+	a.rngMu.Unlock()
+
+	return partitions
 }
 
-// GetSnowballVideos retrieves videos using snowball sampling
+// randomSeed generates a random search query of the form "watch?v=<chars>-"
+func (a *ClientAdapter) randomSeed() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	a.rngMu.Lock()
+	defer a.rngMu.Unlock()
+
+	b := make([]byte, randomSampleSeedLength)
+	for i := range b {
+		b[i] = charset[a.rng.Intn(len(charset))]
+	}
+	return "watch?v=" + string(b) + "-"
+}
+
+// SetSnowballMaxDepth sets how many hops snowball sampling expands beyond the
+// seed channels. A depth of 0 only samples the seeds themselves.
+func (a *ClientAdapter) SetSnowballMaxDepth(depth int) {
+	if depth < 0 {
+		depth = 0
+	}
+	a.snowballMaxDepth = depth
+}
+
+// SetRandomSampleMaxQueries sets how many search calls a random sample may
+// spend. Values below 1 restore DefaultRandomSampleMaxQueries.
+func (a *ClientAdapter) SetRandomSampleMaxQueries(queries int) {
+	if queries < 1 {
+		queries = DefaultRandomSampleMaxQueries
+	}
+	a.randomSampleMaxQueries = queries
+}
+
+// GetSnowballVideos samples videos by breadth-first expansion from the seed
+// channels. Each channel's videos in the time window are collected, channels
+// linked from their descriptions form the next layer, and expansion continues
+// up to the configured depth or until limit videos are collected. Channels are
+// visited at most once, so reference loops cannot recur, and videos are
+// deduplicated by ID.
 func (a *ClientAdapter) GetSnowballVideos(ctx context.Context, seedChannelIDs []string, fromTime, toTime time.Time, limit int) ([]*youtubemodel.YouTubeVideo, error) {
-	// This is a simplified implementation since the underlying client doesn't support snowball sampling
-	// In a real implementation, this would implement snowball sampling
-	return []*youtubemodel.YouTubeVideo{}, fmt.Errorf("snowball sampling not implemented in adapter")
-}
\ No newline at end of file
+	if len(seedChannelIDs) == 0 {
+		return nil, fmt.Errorf("snowball sampling requires at least one seed channel")
+	}
+	if limit <= 0 {
+		limit = randomSampleDefaultSize
+	}
+
+	visited := make(map[string]bool)
+	frontier := make([]string, 0, len(seedChannelIDs))
+	for _, channelID := range seedChannelIDs {
+		if !visited[channelID] {
+			visited[channelID] = true
+			frontier = append(frontier, channelID)
+		}
+	}
+
+	seenVideos := make(map[string]bool)
+	videos := make([]*youtubemodel.YouTubeVideo, 0)
+
+	var lastErr error
+	fetched := 0
+	for depth := 0; depth <= a.snowballMaxDepth && len(frontier) > 0 && len(videos) < limit; depth++ {
+		next := make([]string, 0)
+
+		for _, channelID := range frontier {
+			if err := ctx.Err(); err != nil {
+				return videos, err
+			}
+			if len(videos) >= limit {
+				break
+			}
+
+			messages, err := a.client.GetMessages(ctx, channelID, fromTime, toTime, limit-len(videos))
+			if err != nil {
+				log.Warn().Err(err).Str("channel_id", channelID).Int("depth", depth).Msg("Snowball sampling failed to fetch channel videos")
+				lastErr = err
+				continue
+			}
+			fetched++
+
+			for _, msg := range messages {
+				// Collect referenced channels even from videos we already have
+				if depth < a.snowballMaxDepth {
+					for _, referenced := range clientpkg.ExtractChannelIDsFromText(msg.GetDescription()) {
+						if !visited[referenced] {
+							visited[referenced] = true
+							next = append(next, referenced)
+						}
+					}
+				}
+
+				if seenVideos[msg.GetID()] || len(videos) >= limit {
+					continue
+				}
+				seenVideos[msg.GetID()] = true
+
+				videoChannelID := msg.GetChannelID()
+				if videoChannelID == "" {
+					videoChannelID = channelID
+				}
+				videos = append(videos, messageToVideo(msg, videoChannelID))
+			}
+		}
+
+		log.Debug().
+			Int("depth", depth).
+			Int("channels", len(frontier)).
+			Int("next_channels", len(next)).
+			Int("videos", len(videos)).
+			Msg("Completed snowball sampling layer")
+
+		frontier = next
+	}
+
+	if fetched == 0 && lastErr != nil {
+		return nil, fmt.Errorf("snowball sampling failed: no channel could be fetched: %w", lastErr)
+	}
+
+	log.Info().
+		Int("videos", len(videos)).
+		Int("channels_visited", len(visited)).
+		Msg("Completed snowball YouTube video sampling")
+
+	return videos, nil
+}
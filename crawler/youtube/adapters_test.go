@@ -1,13 +1,344 @@
 package youtube
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 	
+	clientpkg "github.com/researchaccelerator-hub/telegram-scraper/client"
 	youtubemodel "github.com/researchaccelerator-hub/telegram-scraper/model/youtube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
 )
 
 // TestClientAdapterImplementsInterface tests that ClientAdapter implements the YouTubeClient interface
 func TestClientAdapterImplementsInterface(t *testing.T) {
 	// Type assertion check - this will fail at compile time if ClientAdapter doesn't implement YouTubeClient
 	var _ youtubemodel.YouTubeClient = (*ClientAdapter)(nil)
-}
\ No newline at end of file
+}
+// mockSearchClient is a mock client.Client that also supports video search
+type mockSearchClient struct {
+	mu      sync.Mutex
+	calls   []searchCall
+	results func(call searchCall) ([]clientpkg.Message, error)
+}
+
+type searchCall struct {
+	query    string
+	fromTime time.Time
+	toTime   time.Time
+}
+
+func (m *mockSearchClient) Connect(ctx context.Context) error    { return nil }
+func (m *mockSearchClient) Disconnect(ctx context.Context) error { return nil }
+func (m *mockSearchClient) GetChannelType() string               { return "youtube" }
+
+func (m *mockSearchClient) GetChannelInfo(ctx context.Context, channelID string) (clientpkg.Channel, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockSearchClient) GetMessages(ctx context.Context, channelID string, fromTime, toTime time.Time, limit int) ([]clientpkg.Message, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockSearchClient) SearchVideos(ctx context.Context, query string, fromTime, toTime time.Time, maxResults int) ([]clientpkg.Message, error) {
+	call := searchCall{query: query, fromTime: fromTime, toTime: toTime}
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	m.mu.Unlock()
+	return m.results(call)
+}
+
+// TestGetRandomVideos_DeduplicatesAndHonorsLimit tests that repeated search
+// results are collapsed by video ID and the sample stops at the limit
+func TestGetRandomVideos_DeduplicatesAndHonorsLimit(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	client := &mockSearchClient{}
+	client.results = func(call searchCall) ([]clientpkg.Message, error) {
+		// Every search returns the same video plus one unique to the call
+		client.mu.Lock()
+		n := len(client.calls)
+		client.mu.Unlock()
+		return []clientpkg.Message{
+			&clientpkg.YouTubeMessage{ID: "shared", ChannelID: "UC1", Timestamp: call.fromTime},
+			&clientpkg.YouTubeMessage{ID: fmt.Sprintf("video-%d", n), ChannelID: "UC2", Timestamp: call.fromTime},
+		}, nil
+	}
+
+	adapter, err := NewClientAdapter(client)
+	require.NoError(t, err)
+
+	videos, err := adapter.GetRandomVideos(context.Background(), from, to, 5)
+	require.NoError(t, err)
+	require.Len(t, videos, 5)
+
+	ids := make(map[string]bool)
+	for _, v := range videos {
+		assert.False(t, ids[v.ID], "duplicate video %s", v.ID)
+		ids[v.ID] = true
+	}
+	assert.True(t, ids["shared"])
+	assert.Len(t, client.calls, 4)
+}
+
+// TestGetRandomVideos_PartitionsWindow tests that searches use randomized seeds
+// and cover the window with date partitions inside fromTime and toTime
+func TestGetRandomVideos_PartitionsWindow(t *testing.T) {
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	client := &mockSearchClient{}
+	client.results = func(call searchCall) ([]clientpkg.Message, error) {
+		return []clientpkg.Message{
+			// Outside the window even though the search returned it
+			&clientpkg.YouTubeMessage{ID: "too-old", Timestamp: from.Add(-time.Hour)},
+		}, nil
+	}
+
+	adapter, err := NewClientAdapter(client)
+	require.NoError(t, err)
+
+	videos, err := adapter.GetRandomVideos(context.Background(), from, to, 10)
+	require.NoError(t, err)
+	assert.Empty(t, videos)
+	require.Len(t, client.calls, DefaultRandomSampleMaxQueries)
+
+	partitions := make(map[time.Time]bool)
+	queries := make(map[string]bool)
+	for _, call := range client.calls {
+		assert.False(t, call.fromTime.Before(from))
+		assert.False(t, call.toTime.After(to))
+		assert.True(t, call.fromTime.Before(call.toTime))
+		assert.True(t, strings.HasPrefix(call.query, "watch?v="))
+		partitions[call.fromTime] = true
+		queries[call.query] = true
+	}
+	assert.Len(t, partitions, randomSamplePartitions)
+	assert.Greater(t, len(queries), 1)
+
+	client.calls = nil
+	adapter.SetRandomSampleMaxQueries(3)
+	_, err = adapter.GetRandomVideos(context.Background(), from, to, 10)
+	require.NoError(t, err)
+	assert.Len(t, client.calls, 3, "The configured search budget should be respected")
+}
+
+// TestGetRandomVideos_Errors tests the error paths of random sampling
+func TestGetRandomVideos_Errors(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("all searches fail", func(t *testing.T) {
+		client := &mockSearchClient{results: func(searchCall) ([]clientpkg.Message, error) {
+			return nil, errors.New("quota exceeded")
+		}}
+		adapter, err := NewClientAdapter(client)
+		require.NoError(t, err)
+
+		_, err = adapter.GetRandomVideos(context.Background(), from, to, 10)
+		assert.ErrorContains(t, err, "quota exceeded")
+	})
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		client := &mockSearchClient{results: func(searchCall) ([]clientpkg.Message, error) {
+			return nil, fmt.Errorf("failed to search videos: %w", &googleapi.Error{
+				Code:   403,
+				Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded"}},
+			})
+		}}
+		adapter, err := NewClientAdapter(client)
+		require.NoError(t, err)
+
+		_, err = adapter.GetRandomVideos(context.Background(), from, to, 10)
+		assert.True(t, clientpkg.IsQuotaExceeded(err))
+		assert.Len(t, client.calls, 1, "Sampling should stop at the first quota error")
+	})
+
+	t.Run("inverted window", func(t *testing.T) {
+		adapter, err := NewClientAdapter(&mockSearchClient{})
+		require.NoError(t, err)
+
+		_, err = adapter.GetRandomVideos(context.Background(), to, from, 10)
+		assert.Error(t, err)
+	})
+}
+
+// mockChannelGraphClient is a mock client.Client serving fixed videos per channel
+type mockChannelGraphClient struct {
+	mockSearchClient
+	videos map[string][]clientpkg.Message
+	calls  map[string]int
+}
+
+func (m *mockChannelGraphClient) GetMessages(ctx context.Context, channelID string, fromTime, toTime time.Time, limit int) ([]clientpkg.Message, error) {
+	m.calls[channelID]++
+	videos, ok := m.videos[channelID]
+	if !ok {
+		return nil, fmt.Errorf("channel not found: %s", channelID)
+	}
+	return videos, nil
+}
+
+// newChannelGraphClient builds a chain of channels A -> B -> C -> A where each
+// channel's video description links the next one
+func newChannelGraphClient() *mockChannelGraphClient {
+	link := func(id, next string) []clientpkg.Message {
+		return []clientpkg.Message{&clientpkg.YouTubeMessage{
+			ID:          "video-" + id,
+			ChannelID:   id,
+			Description: "Also watch https://www.youtube.com/channel/" + next,
+		}}
+	}
+	return &mockChannelGraphClient{
+		videos: map[string][]clientpkg.Message{
+			"UCA": link("UCA", "UCB"),
+			"UCB": link("UCB", "UCC"),
+			"UCC": link("UCC", "UCA"),
+		},
+		calls: make(map[string]int),
+	}
+}
+
+// TestGetSnowballVideos_StopsAtDepthLimit tests that expansion does not go
+// beyond the configured number of hops from the seeds
+func TestGetSnowballVideos_StopsAtDepthLimit(t *testing.T) {
+	client := newChannelGraphClient()
+	adapter, err := NewClientAdapter(client)
+	require.NoError(t, err)
+	adapter.SetSnowballMaxDepth(1)
+
+	videos, err := adapter.GetSnowballVideos(context.Background(), []string{"UCA"}, time.Time{}, time.Time{}, 100)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(videos))
+	for _, v := range videos {
+		ids = append(ids, v.ID)
+	}
+	assert.Equal(t, []string{"video-UCA", "video-UCB"}, ids)
+	assert.Zero(t, client.calls["UCC"])
+}
+
+// TestGetSnowballVideos_DoesNotRevisitChannels tests that reference loops and
+// duplicate seeds fetch each channel only once
+func TestGetSnowballVideos_DoesNotRevisitChannels(t *testing.T) {
+	client := newChannelGraphClient()
+	adapter, err := NewClientAdapter(client)
+	require.NoError(t, err)
+	adapter.SetSnowballMaxDepth(10)
+
+	videos, err := adapter.GetSnowballVideos(context.Background(), []string{"UCA", "UCA"}, time.Time{}, time.Time{}, 100)
+	require.NoError(t, err)
+
+	assert.Len(t, videos, 3)
+	assert.Equal(t, map[string]int{"UCA": 1, "UCB": 1, "UCC": 1}, client.calls)
+}
+
+// TestGetSnowballVideos_Errors tests the error paths of snowball sampling
+func TestGetSnowballVideos_Errors(t *testing.T) {
+	adapter, err := NewClientAdapter(newChannelGraphClient())
+	require.NoError(t, err)
+
+	_, err = adapter.GetSnowballVideos(context.Background(), nil, time.Time{}, time.Time{}, 10)
+	assert.Error(t, err)
+
+	_, err = adapter.GetSnowballVideos(context.Background(), []string{"UCmissing"}, time.Time{}, time.Time{}, 10)
+	assert.ErrorContains(t, err, "channel not found")
+}
+
+// mockChannelStatsClient is a mock client.Client returning a fixed channel
+type mockChannelStatsClient struct {
+	mockSearchClient
+	channel clientpkg.Channel
+}
+
+func (m *mockChannelStatsClient) GetChannelInfo(ctx context.Context, channelID string) (clientpkg.Channel, error) {
+	return m.channel, nil
+}
+
+// TestGetChannelInfo_PopulatesStatistics tests that channel statistics and the
+// creation date are carried over from the client
+func TestGetChannelInfo_PopulatesStatistics(t *testing.T) {
+	publishedAt := time.Date(2012, 3, 4, 5, 6, 7, 0, time.UTC)
+	adapter, err := NewClientAdapter(&mockChannelStatsClient{channel: &clientpkg.YouTubeChannel{
+		ID:          "UCtest",
+		Name:        "Test Channel",
+		MemberCount: 1500,
+		ViewCount:   987654,
+		VideoCount:  321,
+		PublishedAt: publishedAt,
+	}})
+	require.NoError(t, err)
+
+	channel, err := adapter.GetChannelInfo(context.Background(), "UCtest")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(1500), channel.SubscriberCount)
+	assert.Equal(t, int64(987654), channel.ViewCount)
+	assert.Equal(t, int64(321), channel.VideoCount)
+	assert.Equal(t, publishedAt, channel.PublishedAt)
+}
+
+// mockVideosClient is a mock client.Client returning fixed videos for any channel
+type mockVideosClient struct {
+	mockSearchClient
+	messages []clientpkg.Message
+}
+
+func (m *mockVideosClient) GetMessages(ctx context.Context, channelID string, fromTime, toTime time.Time, limit int) ([]clientpkg.Message, error) {
+	return m.messages, nil
+}
+
+// TestGetVideos_LikeCount tests that the like count from the video statistics
+// is used, with the like reaction only as a fallback when statistics are missing
+func TestGetVideos_LikeCount(t *testing.T) {
+	adapter, err := NewClientAdapter(&mockVideosClient{messages: []clientpkg.Message{
+		&clientpkg.YouTubeMessage{ID: "stats", LikeCount: 42, HasLikeCount: true, Reactions: map[string]int64{"like": 1}},
+		&clientpkg.YouTubeMessage{ID: "reaction-only", Reactions: map[string]int64{"like": 7}},
+	}})
+	require.NoError(t, err)
+
+	videos, err := adapter.GetVideos(context.Background(), "UCtest", time.Time{}, time.Time{}, 10)
+	require.NoError(t, err)
+	require.Len(t, videos, 2)
+
+	assert.Equal(t, int64(42), videos[0].LikeCount)
+	assert.True(t, videos[0].HasLikeCount)
+	assert.Equal(t, int64(7), videos[1].LikeCount)
+	assert.False(t, videos[1].HasLikeCount)
+}
+
+// TestGetVideos_FiltersByTimeWindow tests that videos the client returns
+// outside [fromTime, toTime] are dropped, with the bounds themselves included
+func TestGetVideos_FiltersByTimeWindow(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	adapter, err := NewClientAdapter(&mockVideosClient{messages: []clientpkg.Message{
+		&clientpkg.YouTubeMessage{ID: "before", Timestamp: from.Add(-time.Second)},
+		&clientpkg.YouTubeMessage{ID: "first", Timestamp: from},
+		&clientpkg.YouTubeMessage{ID: "middle", Timestamp: from.AddDate(0, 0, 10)},
+		&clientpkg.YouTubeMessage{ID: "last", Timestamp: to},
+		&clientpkg.YouTubeMessage{ID: "after", Timestamp: to.Add(time.Hour)},
+	}})
+	require.NoError(t, err)
+
+	videos, err := adapter.GetVideos(context.Background(), "UCtest", from, to, 10)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(videos))
+	for _, video := range videos {
+		ids = append(ids, video.ID)
+	}
+	assert.Equal(t, []string{"first", "middle", "last"}, ids)
+
+	// An open upper bound keeps everything from fromTime on
+	videos, err = adapter.GetVideos(context.Background(), "UCtest", from, time.Time{}, 10)
+	require.NoError(t, err)
+	assert.Len(t, videos, 4)
+}
@@ -0,0 +1,164 @@
+package youtube
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	youtubemodel "github.com/researchaccelerator-hub/telegram-scraper/model/youtube"
+	"github.com/rs/zerolog/log"
+)
+
+// YouTubePlatformName is the PlatformName recorded on posts converted from YouTube videos
+const YouTubePlatformName = "youtube"
+
+// YouTubeVideoToPost maps a YouTube video into the shared post schema used by
+// the Telegram crawler, so both platforms can be consumed the same way.
+// PlatformName is set to "youtube". The channel data is derived from the video
+// alone; use YouTubeChannelToChannelData to replace it when the channel itself
+// has been fetched.
+func YouTubeVideoToPost(video *youtubemodel.YouTubeVideo) model.Post {
+	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", video.ID)
+	title := video.Title
+	now := time.Now()
+
+	// Parse duration string to seconds
+	var videoLengthSeconds int
+	if video.Duration != "" {
+		duration, err := parseISO8601Duration(video.Duration)
+		if err == nil {
+			videoLengthSeconds = duration
+		} else {
+			log.Warn().Err(err).Str("duration", video.Duration).Msg("Failed to parse video duration")
+		}
+	}
+
+	hasEmbedMedia := true
+	likesCount := int(video.LikeCount)
+	commentsCount := int(video.CommentCount)
+
+	// Note: YouTube API may report zero comments even when comments exist, e.g.
+	// when comments are hidden by the channel owner or the count lags behind
+
+	// YouTube doesn't provide OCR text, but we can include thumbnails as image metadata
+	var ocrData []model.OCRData
+	for quality, url := range video.Thumbnails {
+		if url != "" {
+			ocrData = append(ocrData, model.OCRData{
+				ThumbURL: url,
+				OCRText:  fmt.Sprintf("YouTube thumbnail: %s quality", quality),
+			})
+		}
+	}
+
+	channelURL := youtubeChannelURL(video.ChannelID)
+
+	return model.Post{
+		PostUID:        video.ID,
+		ChannelName:    video.ChannelID,
+		ChannelID:      video.ChannelID,
+		URL:            videoURL,
+		PublishedAt:    video.PublishedAt,
+		CreatedAt:      now,
+		Engagement:     int(video.LikeCount + video.CommentCount + (video.ViewCount / 100)),
+		PostTitle:      &title,
+		Description:    video.Description,
+		ViewCount:      int(video.ViewCount),
+		LikeCount:      likesCount,
+		CommentCount:   commentsCount,
+		PlatformName:   YouTubePlatformName,
+		SearchableText: video.Title + " " + video.Description,
+		AllText:        video.Title + " " + video.Description,
+		PostType:       []string{"video"},
+		CaptureTime:    now,
+		ThumbURL:       bestThumbnail(video.Thumbnails),
+		MediaURL:       videoURL,
+		Handle:         video.ChannelID,
+		PostLink:       videoURL,
+		VideoLength:    &videoLengthSeconds,
+		HasEmbedMedia:  &hasEmbedMedia,
+		PerformanceScores: model.PerformanceScores{
+			Likes:    &likesCount,
+			Comments: &commentsCount,
+			Views:    float64(video.ViewCount),
+		},
+		Outlinks: extractURLs(video.Description),
+		OCRData:  ocrData,
+		MediaData: model.MediaData{
+			DocumentName: fmt.Sprintf("%s-%s.mp4", video.ID, sanitizeFilename(video.Title)),
+		},
+		Reactions: map[string]int{"like": likesCount},
+		Comments:  videoCommentsToComments(video.Comments),
+		// Without the channel itself, describe it with the video's own figures
+		ChannelData: model.ChannelData{
+			ChannelID:          video.ChannelID,
+			ChannelName:        video.ChannelID,
+			ChannelURL:         channelURL,
+			ChannelURLExternal: channelURL,
+			PublishedAt:        video.PublishedAt, // Use video's publish date as fallback
+			ChannelEngagementData: model.EngagementData{
+				ViewsCount:   int(video.ViewCount),
+				LikeCount:    likesCount,
+				CommentCount: commentsCount,
+			},
+		},
+	}
+}
+
+// YouTubeChannelToChannelData maps a YouTube channel into the shared channel
+// schema used by the Telegram crawler
+func YouTubeChannelToChannelData(channel *youtubemodel.YouTubeChannel) model.ChannelData {
+	channelURL := youtubeChannelURL(channel.ID)
+	return model.ChannelData{
+		ChannelID:           channel.ID,
+		ChannelName:         channel.Title,
+		ChannelDescription:  channel.Description,
+		ChannelURL:          channelURL,
+		ChannelURLExternal:  channelURL,
+		ChannelProfileImage: channel.Thumbnails["default"],
+		CountryCode:         channel.Country,
+		PublishedAt:         channel.PublishedAt,
+		ChannelEngagementData: model.EngagementData{
+			FollowerCount: int(channel.SubscriberCount),
+			ViewsCount:    int(channel.ViewCount),
+			PostCount:     int(channel.VideoCount),
+		},
+	}
+}
+
+// youtubeChannelURL builds the channel URL for a channel ID or @handle
+func youtubeChannelURL(channelID string) string {
+	if len(channelID) > 0 && channelID[0] == '@' {
+		return fmt.Sprintf("https://www.youtube.com/%s", channelID)
+	}
+	return fmt.Sprintf("https://www.youtube.com/channel/%s", channelID)
+}
+
+// bestThumbnail returns the highest quality thumbnail URL available
+func bestThumbnail(thumbnails map[string]string) string {
+	for _, quality := range []string{"maxres", "high", "medium", "default"} {
+		if url := thumbnails[quality]; url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+// videoCommentsToComments converts fetched YouTube comments to post comments
+func videoCommentsToComments(videoComments []youtubemodel.YouTubeComment) []model.Comment {
+	if len(videoComments) == 0 {
+		return nil
+	}
+
+	comments := make([]model.Comment, 0, len(videoComments))
+	for _, vc := range videoComments {
+		comments = append(comments, model.Comment{
+			Text:       vc.Text,
+			Reactions:  map[string]int{"like": int(vc.LikeCount)},
+			ReplyCount: int(vc.ReplyCount),
+			Handle:     vc.AuthorName,
+			SenderID:   vc.AuthorChannelID,
+		})
+	}
+	return comments
+}
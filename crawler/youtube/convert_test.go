@@ -0,0 +1,82 @@
+package youtube
+
+import (
+	"testing"
+	"time"
+
+	youtubemodel "github.com/researchaccelerator-hub/telegram-scraper/model/youtube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYouTubeVideoToPost(t *testing.T) {
+	published := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+	video := &youtubemodel.YouTubeVideo{
+		ID:           "abc123",
+		ChannelID:    "UCtest",
+		Title:        "Launch day",
+		Description:  "Slides at https://example.com/slides",
+		PublishedAt:  published,
+		ViewCount:    12000,
+		LikeCount:    340,
+		CommentCount: 56,
+		Duration:     "PT1M30S",
+		Thumbnails:   map[string]string{"default": "https://i.ytimg.com/d.jpg", "high": "https://i.ytimg.com/h.jpg"},
+	}
+
+	post := YouTubeVideoToPost(video)
+
+	assert.Equal(t, "youtube", post.PlatformName)
+	assert.Equal(t, "abc123", post.PostUID)
+	assert.Equal(t, "https://www.youtube.com/watch?v=abc123", post.URL)
+	assert.Equal(t, published, post.PublishedAt)
+	assert.False(t, post.CaptureTime.IsZero())
+
+	assert.Equal(t, 12000, post.ViewCount)
+	assert.Equal(t, 12000, post.ViewsCount())
+	assert.Equal(t, 340, post.LikeCount)
+	assert.Equal(t, 340, post.LikesCount())
+	assert.Equal(t, 56, post.CommentCount)
+	assert.Equal(t, 56, post.CommentsCount())
+	assert.Equal(t, 340+56+120, post.Engagement)
+	assert.Equal(t, map[string]int{"like": 340}, post.Reactions)
+	require.NotNil(t, post.PerformanceScores.Likes)
+	assert.Equal(t, 340, *post.PerformanceScores.Likes)
+	assert.Equal(t, float64(12000), post.PerformanceScores.Views)
+
+	require.NotNil(t, post.PostTitle)
+	assert.Equal(t, "Launch day", *post.PostTitle)
+	require.NotNil(t, post.VideoLength)
+	assert.Equal(t, 90, *post.VideoLength)
+	assert.Equal(t, "https://i.ytimg.com/h.jpg", post.ThumbURL)
+	assert.Equal(t, []string{"https://example.com/slides"}, post.Outlinks)
+
+	assert.Equal(t, "UCtest", post.ChannelData.ChannelID)
+	assert.Equal(t, "https://www.youtube.com/channel/UCtest", post.ChannelData.ChannelURL)
+	assert.Equal(t, 12000, post.ChannelData.ChannelEngagementData.ViewsCount)
+}
+
+func TestYouTubeChannelToChannelData(t *testing.T) {
+	created := time.Date(2012, 3, 4, 5, 6, 7, 0, time.UTC)
+	data := YouTubeChannelToChannelData(&youtubemodel.YouTubeChannel{
+		ID:              "@newsdesk",
+		Title:           "News Desk",
+		Description:     "Daily news",
+		SubscriberCount: 1500,
+		ViewCount:       987654,
+		VideoCount:      321,
+		PublishedAt:     created,
+		Thumbnails:      map[string]string{"default": "https://yt3.ggpht.com/p.jpg"},
+		Country:         "DE",
+	})
+
+	assert.Equal(t, "@newsdesk", data.ChannelID)
+	assert.Equal(t, "News Desk", data.ChannelName)
+	assert.Equal(t, "https://www.youtube.com/@newsdesk", data.ChannelURL)
+	assert.Equal(t, "https://yt3.ggpht.com/p.jpg", data.ChannelProfileImage)
+	assert.Equal(t, "DE", data.CountryCode)
+	assert.Equal(t, created, data.PublishedAt)
+	assert.Equal(t, 1500, data.ChannelEngagementData.FollowerCount)
+	assert.Equal(t, 987654, data.ChannelEngagementData.ViewsCount)
+	assert.Equal(t, 321, data.ChannelEngagementData.PostCount)
+}
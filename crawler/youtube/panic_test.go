@@ -41,6 +41,10 @@ func (m *MockPanicClient) GetSnowballVideos(ctx context.Context, seedChannels []
 	panic("test panic in GetSnowballVideos")
 }
 
+func (m *MockPanicClient) GetVideoComments(ctx context.Context, videoID string, limit int) ([]youtubemodel.YouTubeComment, error) {
+	panic("test panic in GetVideoComments")
+}
+
 func (m *MockPanicClient) Disconnect(ctx context.Context) error {
 	return nil
 }
@@ -55,15 +59,26 @@ func (m *MockStateManager) UpdateMessage(pageID string, chatID int64, messageID
 func (m *MockStateManager) AddLayer(pages []state.Page) error                                                  { return nil }
 func (m *MockStateManager) GetLayerByDepth(depth int) ([]state.Page, error)                                   { return nil, nil }
 func (m *MockStateManager) GetMaxDepth() (int, error)                                                          { return 0, nil }
+func (m *MockStateManager) GetQuarantinedPages() ([]state.Page, error)                                         { return nil, nil }
 func (m *MockStateManager) SaveState() error                                                                   { return nil }
 func (m *MockStateManager) ExportPagesToBinding(crawlID string) error                                          { return nil }
 func (m *MockStateManager) StorePost(channelID string, post model.Post) error                                  { return nil }
+func (m *MockStateManager) HasPost(crawlID string, postUID string) bool                                      { return false }
+func (m *MockStateManager) QueryPosts(crawlID string, filter state.PostFilter) ([]model.Post, error)           { return nil, nil }
 func (m *MockStateManager) StoreFile(channelID string, sourceFilePath string, fileName string) (string, string, error) { return "", "", nil }
 func (m *MockStateManager) GetPreviousCrawls() ([]string, error)                                               { return nil, nil }
 func (m *MockStateManager) UpdateCrawlMetadata(crawlID string, metadata map[string]interface{}) error         { return nil }
 func (m *MockStateManager) FindIncompleteCrawl(crawlID string) (string, bool, error)                          { return "", false, nil }
 func (m *MockStateManager) HasProcessedMedia(mediaID string) (bool, error)                                     { return false, nil }
 func (m *MockStateManager) MarkMediaAsProcessed(mediaID string) error                                          { return nil }
+func (m *MockStateManager) MarkMediaAsStored(mediaID string, storageKey string) error                        { return nil }
+func (m *MockStateManager) GetMediaStorageKey(mediaID string) (string, error)                                 { return "", nil }
+func (m *MockStateManager) SaveSeenURLs(urls []string) error                                                   { return nil }
+func (m *MockStateManager) GetLastMessageID(channelID string) (int64, error)                                 { return 0, nil }
+func (m *MockStateManager) SaveLastMessageID(channelID string, messageID int64) error                        { return nil }
+func (m *MockStateManager) LoadSeenURLs() ([]string, error)                                                    { return nil, nil }
+func (m *MockStateManager) StoreChannelSnapshot(snapshot state.ChannelSnapshot) error                         { return nil }
+func (m *MockStateManager) GetChannelHistory(channelID string) ([]state.ChannelSnapshot, error)              { return nil, nil }
 func (m *MockStateManager) Close() error                                                                       { return nil }
 
 func TestPanicRecovery(t *testing.T) {
@@ -42,6 +42,17 @@ type YouTubeCrawlerConfig struct {
 
 	// MinChannelVideos specifies the minimum number of videos a channel must have
 	MinChannelVideos int64 `json:"min_channel_videos"`
+
+	// SnowballMaxDepth limits how many hops snowball sampling expands beyond the seed channels
+	SnowballMaxDepth int `json:"snowball_max_depth"`
+
+	// RandomSampleMaxQueries limits the search calls of random sampling, which
+	// cost 100 quota units each
+	RandomSampleMaxQueries int `json:"random_sample_max_queries"`
+
+	// CommentLimit is the number of top-level comments fetched per video; 0 disables
+	// comment retrieval, which costs one quota unit per page of 100 comments
+	CommentLimit int `json:"comment_limit"`
 }
 
 // YouTubeCrawler implements the crawler.Crawler interface for YouTube
@@ -58,8 +69,10 @@ type YouTubeCrawler struct {
 func NewYouTubeCrawler() crawler.Crawler {
 	// Set default configuration
 	defaultConfig := YouTubeCrawlerConfig{
-		SamplingMethod:   SamplingMethodChannel, // Default to channel-based sampling
-		MinChannelVideos: 10,                    // Default to 10 minimum videos
+		SamplingMethod:         SamplingMethodChannel, // Default to channel-based sampling
+		MinChannelVideos:       10,                    // Default to 10 minimum videos
+		SnowballMaxDepth:       DefaultSnowballMaxDepth,
+		RandomSampleMaxQueries: DefaultRandomSampleMaxQueries,
 	}
 
 	return &YouTubeCrawler{
@@ -136,6 +149,45 @@ func (c *YouTubeCrawler) Initialize(ctx context.Context, config map[string]inter
 				}
 				log.Info().Int64("min_channel_videos", crawlerConfig.MinChannelVideos).Msg("Using configured minimum channel videos")
 			}
+
+			// Extract snowball expansion depth
+			if depthObj, ok := crawlerConfigMap["snowball_max_depth"]; ok {
+				switch v := depthObj.(type) {
+				case int:
+					crawlerConfig.SnowballMaxDepth = v
+				case int64:
+					crawlerConfig.SnowballMaxDepth = int(v)
+				case float64:
+					crawlerConfig.SnowballMaxDepth = int(v)
+				}
+				log.Info().Int("snowball_max_depth", crawlerConfig.SnowballMaxDepth).Msg("Using configured snowball sampling depth")
+			}
+
+			// Extract the search budget of random sampling
+			if queriesObj, ok := crawlerConfigMap["random_sample_max_queries"]; ok {
+				switch v := queriesObj.(type) {
+				case int:
+					crawlerConfig.RandomSampleMaxQueries = v
+				case int64:
+					crawlerConfig.RandomSampleMaxQueries = int(v)
+				case float64:
+					crawlerConfig.RandomSampleMaxQueries = int(v)
+				}
+				log.Info().Int("random_sample_max_queries", crawlerConfig.RandomSampleMaxQueries).Msg("Using configured random sampling search budget")
+			}
+
+			// Extract the per-video comment limit
+			if limitObj, ok := crawlerConfigMap["comment_limit"]; ok {
+				switch v := limitObj.(type) {
+				case int:
+					crawlerConfig.CommentLimit = v
+				case int64:
+					crawlerConfig.CommentLimit = int(v)
+				case float64:
+					crawlerConfig.CommentLimit = int(v)
+				}
+				log.Info().Int("comment_limit", crawlerConfig.CommentLimit).Msg("Using configured comment limit")
+			}
 		}
 	}
 
@@ -153,6 +205,16 @@ func (c *YouTubeCrawler) Initialize(ctx context.Context, config map[string]inter
 		}
 	}
 
+	// Pass the snowball depth to clients that implement the expansion themselves
+	if depthSetter, ok := youtubeClient.(interface{ SetSnowballMaxDepth(int) }); ok {
+		depthSetter.SetSnowballMaxDepth(crawlerConfig.SnowballMaxDepth)
+	}
+
+	// Pass the search budget to clients that implement random sampling themselves
+	if queriesSetter, ok := youtubeClient.(interface{ SetRandomSampleMaxQueries(int) }); ok {
+		queriesSetter.SetRandomSampleMaxQueries(crawlerConfig.RandomSampleMaxQueries)
+	}
+
 	// Set the client, state manager, and configuration
 	c.client = youtubeClient
 	c.stateManager = stateManager
@@ -380,6 +442,8 @@ func (c *YouTubeCrawler) FetchMessages(ctx context.Context, job crawler.CrawlJob
 			defer wg.Done()
 
 			for video := range videoCh {
+				c.attachComments(ctxWithTimeout, video)
+
 				// Convert the video to a post
 				post := c.convertVideoToPost(video)
 
@@ -433,6 +497,20 @@ func (c *YouTubeCrawler) FetchMessages(ctx context.Context, job crawler.CrawlJob
 	}, nil
 }
 
+// attachComments fetches the video's comments when comment retrieval is
+// enabled. Videos whose statistics report no comments are skipped to save quota.
+func (c *YouTubeCrawler) attachComments(ctx context.Context, video *youtubemodel.YouTubeVideo) {
+	if c.config.CommentLimit <= 0 || (video.HasCommentCount && video.CommentCount == 0) {
+		return
+	}
+
+	comments, err := c.client.GetVideoComments(ctx, video.ID, c.config.CommentLimit)
+	if err != nil {
+		log.Warn().Err(err).Str("video_id", video.ID).Msg("Failed to fetch video comments")
+	}
+	video.Comments = comments
+}
+
 // GetPlatformType returns the type of platform this crawler supports
 func (c *YouTubeCrawler) GetPlatformType() crawler.PlatformType {
 	return crawler.PlatformYouTube
@@ -449,7 +527,6 @@ func (c *YouTubeCrawler) Close() error {
 	return nil
 }
 
-
 // parseISO8601Duration parses YouTube's ISO 8601 duration format to seconds
 // Example: PT1H2M3S = 1 hour, 2 minutes, 3 seconds = 3723 seconds
 func parseISO8601Duration(duration string) (int, error) {
@@ -543,271 +620,39 @@ func sanitizeFilename(filename string) string {
 	return sanitized
 }
 
-// convertVideoToPost converts a YouTubeVideo to model.Post
+// convertVideoToPost converts a YouTubeVideo to model.Post, filling in the
+// channel data from the client when the channel can be looked up
 func (c *YouTubeCrawler) convertVideoToPost(video *youtubemodel.YouTubeVideo) model.Post {
-	// Get channel info for consistent naming and engagement data - check cache first
-	var channelName string
-	var ok bool
-
-	// For channel data
-	var channel *youtubemodel.YouTubeChannel
-
-	// Check if we already have this channel's full data in the client's cache
-	channel, err := c.client.GetChannelInfo(context.Background(), video.ChannelID)
-	if err == nil {
-		ok = true
-	} else {
-		ok = false
-	}
-
-	if ok {
-		channelName = channel.Title
-		log.Debug().
-			Str("channel_id", video.ChannelID).
-			Str("channel_name", channelName).
-			Int64("subscriber_count", channel.SubscriberCount).
-			Int64("video_count", channel.VideoCount).
-			Msg("Using cached channel data for video conversion")
-	} else {
-		log.Warn().Err(err).Str("channel_id", video.ChannelID).Msg("Failed to get channel info for video conversion")
-		channelName = video.ChannelID
-		channel = nil // Set to nil so we use fallback channel data
-	}
-
-	// Calculate video engagement (likes + comments + views)
-	engagement := int(video.LikeCount + video.CommentCount + (video.ViewCount / 100))
-
-	// Make sure title is appropriate length for a title
-	// If it's too long, it might be a mismapped description
-	title := video.Title
-	//if len(title) > 300 {
-	//	// Try to find a shorter version by taking the first sentence or line
-	//	shorterTitle := title
-	//
-	//	// First try to find the first sentence
-	//	if idx := strings.Index(shorterTitle, "."); idx > 0 && idx < 100 {
-	//		shorterTitle = shorterTitle[:idx+1]
-	//	} else if idx := strings.Index(shorterTitle, "\n"); idx > 0 && idx < 100 {
-	//		// If no sentence, try to find the first line
-	//		shorterTitle = shorterTitle[:idx]
-	//	} else if len(shorterTitle) > 100 {
-	//		// If all else fails, truncate to a reasonable length
-	//		shorterTitle = shorterTitle[:97] + "..."
-	//	}
-	//
-	//	log.Info().
-	//		Str("video_id", video.ID).
-	//		Str("original_title", title).
-	//		Str("shortened_title", shorterTitle).
-	//		Int("original_length", len(title)).
-	//		Int("shortened_length", len(shorterTitle)).
-	//		Msg("Shortened unusually long title")
-	//
-	//	title = shorterTitle
-	//}
-
 	// Log title and description for debugging
 	log.Debug().
 		Str("video_id", video.ID).
 		Str("title", video.Title).
-		Str("first_50_chars_of_title", func() string {
-			if len(video.Title) > 50 {
-				return video.Title[:50] + "..."
-			}
-			return video.Title
-		}()).
-		Str("first_50_chars_of_description", func() string {
-			if len(video.Description) > 50 {
-				return video.Description[:50] + "..."
-			}
-			return video.Description
-		}()).
 		Bool("title_contains_description", strings.Contains(video.Title, video.Description) && len(video.Description) > 50).
 		Bool("description_contains_title", strings.Contains(video.Description, video.Title) && len(video.Title) > 0).
 		Int("title_length", len(video.Title)).
 		Int("description_length", len(video.Description)).
 		Msg("Video title and description analysis")
 
-	// Convert thumbnail URL map to best available thumbnail
-	thumbURL := ""
-	if url, ok := video.Thumbnails["maxres"]; ok && url != "" {
-		thumbURL = url
-	} else if url, ok := video.Thumbnails["high"]; ok && url != "" {
-		thumbURL = url
-	} else if url, ok := video.Thumbnails["medium"]; ok && url != "" {
-		thumbURL = url
-	} else if url, ok := video.Thumbnails["default"]; ok && url != "" {
-		thumbURL = url
-	}
-
-	// Create video URL
-	videoURL := fmt.Sprintf("https://www.youtube.com/watch?v=%s", video.ID)
-
-	// Parse duration string to seconds
-	var videoLengthSeconds int
-	if durationStr := video.Duration; durationStr != "" {
-		duration, err := parseISO8601Duration(durationStr)
-		if err == nil {
-			videoLengthSeconds = duration
-			log.Debug().Int("video_length_seconds", videoLengthSeconds).Msg("Parsed video duration")
-		} else {
-			log.Warn().Err(err).Str("duration", durationStr).Msg("Failed to parse video duration")
-		}
-	}
-
-	// Set HasEmbedMedia flag
-	hasEmbedMedia := true
-
-	// Setup performance scores
-	likesCount := int(video.LikeCount)
-	commentsCount := int(video.CommentCount)
-	viewsCount := float64(video.ViewCount)
-
-	// Note: YouTube API may report zero comments even when comments exist
-	// This can happen for several reasons:
-	// 1. Comments are disabled for the video
-	// 2. Comments are hidden by the channel owner
-	// 3. API delay in updating comment counts
-	// 4. API quota limits preventing full comment data retrieval
-
-	// Extract URLs from description for outlinks
-	outlinks := extractURLs(video.Description)
-
-	// Create OCR data with thumbnail information
-	// YouTube doesn't provide OCR text, but we can include thumbnails as image metadata
-	var ocrData []model.OCRData
-	// Add all available thumbnails to OCR data for completeness
-	for quality, url := range video.Thumbnails {
-		if url != "" {
-			ocrData = append(ocrData, model.OCRData{
-				ThumbURL: url,
-				OCRText:  fmt.Sprintf("YouTube thumbnail: %s quality", quality),
-			})
-		}
-	}
+	post := YouTubeVideoToPost(video)
+	post.CrawlLabel = c.crawlLabel // Add the crawl label to identify crawl source
 
-	// Extra debug log specifically for post title
-	log.Info().
-		Str("video_id", video.ID).
-		Str("title_from_api", video.Title).
-		Str("first_50_chars_title", func() string {
-			if len(video.Title) > 50 {
-				return video.Title[:50] + "..."
-			}
-			return video.Title
-		}()).
-		Str("first_50_chars_description", func() string {
-			if len(video.Description) > 50 {
-				return video.Description[:50] + "..."
-			}
-			return video.Description
-		}()).
-		Msg("Title right before post creation")
-
-	// Create the post object
-	post := model.Post{
-		PostUID:        video.ID,
-		ChannelName:    channelName,
-		ChannelID:      video.ChannelID, // Set the proper YouTube channel ID
-		URL:            videoURL,
-		PublishedAt:    video.PublishedAt,
-		CreatedAt:      time.Now(),
-		Engagement:     engagement,
-		PostTitle:      &title,
-		Description:    video.Description,
-		ViewsCount:     int(video.ViewCount),
-		LikesCount:     int(video.LikeCount),
-		CommentsCount:  int(video.CommentCount),
-		ViewCount:      int(video.ViewCount),
-		LikeCount:      int(video.LikeCount),
-		CommentCount:   int(video.CommentCount),
-		PlatformName:   "youtube",
-		SearchableText: video.Title + " " + video.Description,
-		AllText:        video.Title + " " + video.Description,
-		PostType:       []string{"video"},
-		CrawlLabel:     c.crawlLabel, // Add the crawl label to identify crawl source
-		CaptureTime:    time.Now(),
-		ThumbURL:       thumbURL,
-		MediaURL:       videoURL,
-		Handle:         video.ChannelID,
-		PostLink:       videoURL,
-		// Additional fields
-		VideoLength:   &videoLengthSeconds,
-		HasEmbedMedia: &hasEmbedMedia,
-		PerformanceScores: model.PerformanceScores{
-			Likes:    &likesCount,
-			Comments: &commentsCount,
-			Views:    viewsCount,
-		},
-		// Add the outlinks
-		Outlinks: outlinks,
-		// Add the OCR data with thumbnail information
-		OCRData: ocrData,
-		// Create media data
-		MediaData: model.MediaData{
-			DocumentName: fmt.Sprintf("%s-%s.mp4", video.ID, sanitizeFilename(video.Title)),
-		},
-		// Add reactions as a map
-		Reactions: map[string]int{"like": int(video.LikeCount)},
+	// The client caches channels, so this is usually not an API call
+	channel, err := c.client.GetChannelInfo(context.Background(), video.ChannelID)
+	if err != nil {
+		log.Warn().Err(err).Str("channel_id", video.ChannelID).Msg("Failed to get channel info, using limited channel data without engagement metrics")
+		return post
 	}
 
-	// Construct channel URL based on ID format
-	channelURL := fmt.Sprintf("https://www.youtube.com/channel/%s", video.ChannelID)
-	if len(video.ChannelID) > 0 && video.ChannelID[0] == '@' {
-		// Handle username format (@username)
-		channelURL = fmt.Sprintf("https://www.youtube.com/%s", video.ChannelID)
-	}
-
-	// With our improved cache, we no longer need to fetch channel data separately
-	// as we now cache the complete channel object including engagement metrics
-
-	// Use full channel data if available
-	if channel != nil {
-		// We have channel data, so use all the details
-		post.ChannelData = model.ChannelData{
-			ChannelID:           video.ChannelID, // Set the proper YouTube channel ID
-			ChannelName:         channel.Title,
-			ChannelDescription:  channel.Description, // Add channel description
-			ChannelURL:          channelURL,
-			ChannelURLExternal:  channelURL,
-			ChannelProfileImage: channel.Thumbnails["default"],
-			CountryCode:         channel.Country,     // Add country code from YouTube data
-			PublishedAt:         channel.PublishedAt, // Add channel creation date
-			ChannelEngagementData: model.EngagementData{
-				FollowerCount: int(channel.SubscriberCount),
-				ViewsCount:    int(channel.ViewCount),
-				PostCount:     int(channel.VideoCount),
-			},
-		}
-	} else {
-		// Fallback to basic data if channel info isn't available
-		// But still include any engagement data we have from the video itself
-		post.ChannelData = model.ChannelData{
-			ChannelID:          video.ChannelID, // Set the proper YouTube channel ID
-			ChannelName:        channelName,
-			ChannelDescription: "", // Empty description when not available
-			ChannelURL:         channelURL,
-			ChannelURLExternal: channelURL,
-			CountryCode:        "",                // No country data available when channel info is missing
-			PublishedAt:        video.PublishedAt, // Use video's publish date as fallback
-			ChannelEngagementData: model.EngagementData{
-				// Use the video's data to provide some engagement metrics
-				ViewsCount:   int(video.ViewCount),
-				LikeCount:    int(video.LikeCount),
-				CommentCount: int(video.CommentCount),
-				// These will remain zero as we don't have this information
-				FollowerCount:  0,
-				FollowingCount: 0,
-				PostCount:      0,
-				ShareCount:     0,
-			},
-		}
+	log.Debug().
+		Str("channel_id", video.ChannelID).
+		Str("channel_name", channel.Title).
+		Int64("subscriber_count", channel.SubscriberCount).
+		Int64("video_count", channel.VideoCount).
+		Msg("Using cached channel data for video conversion")
 
-		log.Warn().
-			Str("channel_id", video.ChannelID).
-			Str("channel_name", channelName).
-			Msg("Using limited channel data without engagement metrics")
-	}
+	post.ChannelName = channel.Title
+	post.ChannelData = YouTubeChannelToChannelData(channel)
+	post.ChannelData.ChannelID = video.ChannelID // Keep the ID the video was fetched under
 
 	return post
 }
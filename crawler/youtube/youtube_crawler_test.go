@@ -0,0 +1,96 @@
+package youtube
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	youtubemodel "github.com/researchaccelerator-hub/telegram-scraper/model/youtube"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCommentsClient returns the same comments for every video and records
+// which videos were asked for
+type mockCommentsClient struct {
+	MockConcurrentClient
+	mu     sync.Mutex
+	calls  map[string]int
+	limits []int
+}
+
+func (m *mockCommentsClient) GetVideoComments(ctx context.Context, videoID string, limit int) ([]youtubemodel.YouTubeComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[videoID]++
+	m.limits = append(m.limits, limit)
+	return []youtubemodel.YouTubeComment{
+		{ID: "c1", AuthorName: "@alice", AuthorChannelID: "UCalice", Text: "Great video", LikeCount: 5, ReplyCount: 1},
+	}, nil
+}
+
+func fetchChannel(t *testing.T, c *YouTubeCrawler) crawler.CrawlResult {
+	t.Helper()
+	result, err := c.FetchMessages(context.Background(), crawler.CrawlJob{
+		Target:   crawler.CrawlTarget{ID: "UCtest", Type: crawler.PlatformYouTube},
+		FromTime: time.Now().Add(-24 * time.Hour),
+		ToTime:   time.Now(),
+		Limit:    2,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Posts, 2)
+	return result
+}
+
+func TestFetchMessages_AttachesCommentsWhenEnabled(t *testing.T) {
+	client := &mockCommentsClient{calls: make(map[string]int)}
+	c := &YouTubeCrawler{
+		client:       client,
+		stateManager: &MockStateManager{},
+		initialized:  true,
+		config:       YouTubeCrawlerConfig{SamplingMethod: SamplingMethodChannel, CommentLimit: 25},
+	}
+
+	for _, post := range fetchChannel(t, c).Posts {
+		require.Len(t, post.Comments, 1, "post %s", post.PostUID)
+		comment := post.Comments[0]
+		assert.Equal(t, "Great video", comment.Text)
+		assert.Equal(t, "@alice", comment.Handle)
+		assert.Equal(t, "UCalice", comment.SenderID)
+		assert.Equal(t, 5, comment.Reactions["like"])
+		assert.Equal(t, 1, comment.ReplyCount)
+	}
+	assert.Len(t, client.calls, 2)
+	assert.Equal(t, []int{25, 25}, client.limits)
+}
+
+func TestFetchMessages_SkipsCommentsByDefault(t *testing.T) {
+	client := &mockCommentsClient{calls: make(map[string]int)}
+	c := &YouTubeCrawler{
+		client:       client,
+		stateManager: &MockStateManager{},
+		initialized:  true,
+		config:       YouTubeCrawlerConfig{SamplingMethod: SamplingMethodChannel},
+	}
+
+	for _, post := range fetchChannel(t, c).Posts {
+		assert.Empty(t, post.Comments)
+	}
+	assert.Empty(t, client.calls)
+}
+
+func TestAttachComments_FetchesWhenCommentCountIsHidden(t *testing.T) {
+	client := &mockCommentsClient{calls: make(map[string]int)}
+	c := &YouTubeCrawler{client: client, config: YouTubeCrawlerConfig{CommentLimit: 25}}
+
+	hidden := &youtubemodel.YouTubeVideo{ID: "hidden"}
+	c.attachComments(context.Background(), hidden)
+	none := &youtubemodel.YouTubeVideo{ID: "none", HasCommentCount: true}
+	c.attachComments(context.Background(), none)
+
+	assert.Len(t, hidden.Comments, 1)
+	assert.Empty(t, none.Comments)
+	assert.Equal(t, map[string]int{"hidden": 1}, client.calls, "Only videos reporting 0 comments should be skipped")
+}
@@ -0,0 +1,61 @@
+package dapr
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// healthCheckTimeout bounds how long a /healthz request waits for Telegram.
+const healthCheckTimeout = 5 * time.Second
+
+// HealthChecker reports whether the crawler's connection to its platform is
+// usable, for liveness and readiness probes.
+type HealthChecker interface {
+	// Ping returns an error if the connection is down or not authorized.
+	Ping(ctx context.Context) error
+}
+
+// HealthCheckFunc adapts a function to the HealthChecker interface.
+type HealthCheckFunc func(ctx context.Context) error
+
+// Ping calls f.
+func (f HealthCheckFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+// HealthStatus is the report served on the /healthz endpoint.
+type HealthStatus struct {
+	Status string `json:"status"`          // "ok" or "unhealthy"
+	Error  string `json:"error,omitempty"` // Why the connection is unhealthy
+}
+
+// healthHandler serves /healthz, answering 200 when checker's Ping succeeds
+// and 503 Service Unavailable otherwise. A nil checker is always healthy.
+type healthHandler struct {
+	checker HealthChecker
+	timeout time.Duration
+}
+
+// ServeHTTP pings the checker and writes the result as JSON.
+func (h healthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status := HealthStatus{Status: "ok"}
+	code := http.StatusOK
+	if h.checker != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+		defer cancel()
+		if err := h.checker.Ping(ctx); err != nil {
+			status = HealthStatus{Status: "unhealthy", Error: err.Error()}
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		log.Warn().Err(err).Msg("Failed to write health status")
+	}
+}
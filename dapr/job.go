@@ -10,8 +10,8 @@ import (
 	"github.com/dapr/go-sdk/service/common"
 	daprs "github.com/dapr/go-sdk/service/grpc"
 	common2 "github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/metrics"
 	"github.com/researchaccelerator-hub/telegram-scraper/state"
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/protobuf/types/known/anypb"
 	"os"
@@ -325,14 +325,7 @@ func handleJob(ctx context.Context, job *common.JobEvent) error {
 // launchCrawl initializes and runs the scraping process for a given list of strings using the specified crawler configuration.
 // Returns an error if any critical process fails.
 func launchCrawl(stringList []string, crawlCfg common2.CrawlerConfig) error {
-	seenURLs := make(map[string]bool)
-
-	// Initialize seenURLs with the seed URLs
-	for _, url := range stringList {
-		seenURLs[url] = true
-	}
-
-	crawlexecid := common2.GenerateCrawlID()
+	crawlexecid := common2.GenerateCrawlIDWithOptions(crawlCfg.CrawlIDFormat)
 	log.Info().Msgf("Starting scraper for crawl: %s", crawlCfg.CrawlID)
 
 	cfg := state.Config{
@@ -342,14 +335,24 @@ func launchCrawl(stringList []string, crawlCfg common2.CrawlerConfig) error {
 		Platform:         crawlCfg.Platform, // Pass the platform information
 	}
 
-	smfact := state.DefaultStateManagerFactory{}
+	smfact := state.NewStateManagerFactory()
 	sm, err := smfact.Create(cfg)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to initialize state manager")
 		return err
 	}
 
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	// Open the configured post outputs alongside the state manager
+	closeOutputs, err := openOutputs(&crawlCfg, sm, crawlexecid)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to open crawl outputs")
+		return err
+	}
+	defer closeOutputs()
+
+	// Track layer outcomes for the crawl summary
+	progress := newCrawlProgress(crawlCfg.CrawlID, crawlexecid)
+	startTotals := metrics.Snapshot()
 
 	// Get the existing layers or seed a new crawl
 	err = sm.Initialize(stringList)
@@ -358,6 +361,12 @@ func launchCrawl(stringList []string, crawlCfg common2.CrawlerConfig) error {
 		return err
 	}
 
+	// Track URLs seen across all layers, including those queued by earlier runs
+	seenURLs := loadSeenURLs(sm, stringList)
+	if err := seenURLs.save(); err != nil {
+		log.Warn().Err(err).Msg("Failed to save seen URLs")
+	}
+
 	// Process layers iteratively, with potential for new layers to be added during execution
 	depth := 0
 	for {
@@ -401,7 +410,8 @@ func launchCrawl(stringList []string, crawlCfg common2.CrawlerConfig) error {
 		}
 
 		// Process pages in current layer in parallel
-		processLayerInParallel(layer, crawlCfg.Concurrency, sm, crawlCfg)
+		progress.startLayer(layer)
+		processLayerInParallel(context.Background(), layer, crawlCfg.Concurrency, sm, crawlCfg, progress, seenURLs)
 
 		// Log progress after completing a layer
 		log.Info().Msgf("Completed layer at depth %d", depth)
@@ -410,6 +420,9 @@ func launchCrawl(stringList []string, crawlCfg common2.CrawlerConfig) error {
 		depth++
 	}
 
+	progress.complete()
+	progress.writeSummary(metrics.SummaryPath(crawlCfg.SummaryFile, crawlCfg.StorageRoot, crawlCfg.CrawlID), startTotals)
+
 	completionMetadata := map[string]interface{}{
 		"status":          "completed",
 		"endTime":         time.Now(),
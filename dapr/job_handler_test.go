@@ -1,9 +1,18 @@
 package dapr
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/stretchr/testify/assert"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/metrics"
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestJobHandling tests that job data is properly parsed and validated
@@ -63,4 +72,45 @@ func TestJobHandling(t *testing.T) {
 		// Verify URL file field is preserved
 		assert.Equal(t, jobData.URLFile, parsedJobData.URLFile)
 	})
-}
\ No newline at end of file
+}
+// TestLaunchCrawl_WritesSummary tests that a job crawl writes its summary file
+func TestLaunchCrawl_WritesSummary(t *testing.T) {
+	basePath := t.TempDir()
+
+	originalFactory := state.NewStateManagerFactory
+	state.NewStateManagerFactory = func() state.StateManagerFactory {
+		return localStateManagerFactory{basePath: basePath}
+	}
+	originalRun := runForChannel
+	defer func() {
+		state.NewStateManagerFactory = originalFactory
+		runForChannel = originalRun
+	}()
+
+	// The seed links to one channel, which fails
+	runForChannel = func(ctx context.Context, p *state.Page, storagePrefix string, sm state.StateManagementInterface, cfg common.CrawlerConfig) ([]*state.Page, error) {
+		if p.URL == "seed" {
+			return []*state.Page{{URL: "linked"}}, nil
+		}
+		return nil, errors.New("channel unavailable")
+	}
+
+	summaryPath := filepath.Join(t.TempDir(), "summary.json")
+	cfg := common.CrawlerConfig{CrawlID: "job-crawl", StorageRoot: basePath, Concurrency: 1, MaxDepth: 1, SummaryFile: summaryPath}
+	require.NoError(t, launchCrawl([]string{"seed"}, cfg))
+
+	data, err := os.ReadFile(summaryPath)
+	require.NoError(t, err)
+	var summary metrics.CrawlSummary
+	require.NoError(t, json.Unmarshal(data, &summary))
+
+	assert.Equal(t, "job-crawl", summary.CrawlID)
+	assert.NotEmpty(t, summary.ExecutionID)
+	assert.False(t, summary.EndedAt.Before(summary.StartedAt))
+	assert.Equal(t, 1, summary.ChannelsCrawled)
+	assert.Equal(t, 1, summary.ChannelErrors)
+	assert.Equal(t, []metrics.DepthSummary{
+		{Depth: 0, Pages: 1, Fetched: 1},
+		{Depth: 1, Pages: 1, Errors: 1},
+	}, summary.Depths)
+}
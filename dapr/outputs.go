@@ -0,0 +1,47 @@
+package dapr
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/rs/zerolog/log"
+)
+
+// openOutputs opens the common schema output (--output common) and the post
+// sinks (--post-sinks) configured in crawlCfg for the crawl execution and sets
+// them on crawlCfg, as the standalone mode does. The returned function closes
+// them and must be called once the crawl ends.
+func openOutputs(crawlCfg *common.CrawlerConfig, sm state.StateManagementInterface, crawlexecid string) (func(), error) {
+	limits := sink.RollingLimits{MaxBytes: crawlCfg.MaxOutputFileBytes, MaxRecords: crawlCfg.MaxRecordsPerFile}
+
+	closeCommon := func() {}
+	if crawlCfg.OutputFormat == common.OutputFormatCommon {
+		commonPath := filepath.Join(crawlCfg.StorageRoot, crawlCfg.CrawlID, "common", crawlexecid+".jsonl")
+		commonWriter, err := sink.NewCommonSchemaFileWriter(commonPath, crawlCfg.CommonSchemaMapping, limits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open common schema output %s: %w", commonPath, err)
+		}
+		closeCommon = func() {
+			if err := commonWriter.Close(); err != nil {
+				log.Warn().Err(err).Str("path", commonPath).Msg("Failed to close common schema output")
+			}
+		}
+		crawlCfg.CommonSchemaOutput = commonWriter
+		log.Info().Str("path", commonPath).Msg("Writing common schema output")
+	}
+
+	postSinks, closeSinks, err := state.OpenPostSinks(crawlCfg.OutputSinks, sm, limits, crawlCfg.ParquetOptions)
+	if err != nil {
+		closeCommon()
+		return nil, fmt.Errorf("failed to open post outputs %v: %w", crawlCfg.OutputSinks, err)
+	}
+	crawlCfg.PostSinks = postSinks
+
+	return func() {
+		closeSinks()
+		closeCommon()
+	}, nil
+}
@@ -0,0 +1,42 @@
+package dapr
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenOutputs_SetsConfiguredOutputs(t *testing.T) {
+	basePath := t.TempDir()
+	sm := newLocalStateManager(t, basePath)
+	postsPath := filepath.Join(basePath, "posts.jsonl")
+	cfg := common.CrawlerConfig{
+		CrawlID:      "test-crawl",
+		StorageRoot:  basePath,
+		OutputFormat: common.OutputFormatCommon,
+		OutputSinks:  []string{"jsonl=" + postsPath},
+	}
+
+	closeOutputs, err := openOutputs(&cfg, sm, "exec-1")
+	require.NoError(t, err)
+	require.Len(t, cfg.PostSinks, 1)
+	require.NotNil(t, cfg.CommonSchemaOutput)
+
+	require.NoError(t, sink.WriteAll(context.Background(), cfg.PostSinks, model.Post{PostUID: "1-news"}))
+	closeOutputs()
+
+	assert.FileExists(t, postsPath)
+	assert.FileExists(t, filepath.Join(basePath, "test-crawl", "common", "exec-1.jsonl"))
+}
+
+func TestOpenOutputs_RejectsUnknownSink(t *testing.T) {
+	cfg := common.CrawlerConfig{OutputSinks: []string{"bogus"}}
+	_, err := openOutputs(&cfg, newLocalStateManager(t, t.TempDir()), "exec-1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package dapr
+
+import (
+	"fmt"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+)
+
+// resumeLayers loads the saved layers of the crawl sm was created for instead
+// of seeding it. It fails if the crawl has no saved pages to resume.
+func resumeLayers(sm state.StateManagementInterface) error {
+	if err := sm.Initialize(nil); err != nil {
+		return fmt.Errorf("failed to load crawl state: %w", err)
+	}
+	pages, err := sm.GetLayerByDepth(0)
+	if err != nil {
+		return fmt.Errorf("failed to load the first layer: %w", err)
+	}
+	if len(pages) == 0 {
+		return fmt.Errorf("crawl has no saved layers to resume")
+	}
+	return nil
+}
+
+// pendingPages returns the pages of a layer that are still to be crawled.
+// Fetched pages are done and errored pages are skipped on resume, the same
+// as processLayerInParallel does; quarantined pages are pending only if
+// retryQuarantined is set.
+func pendingPages(pages []state.Page, retryQuarantined bool) []state.Page {
+	pending := make([]state.Page, 0, len(pages))
+	for _, page := range pages {
+		switch page.Status {
+		case "fetched", "error":
+		case state.PageStatusQuarantined:
+			if retryQuarantined {
+				pending = append(pending, page)
+			}
+		default:
+			pending = append(pending, page)
+		}
+	}
+	return pending
+}
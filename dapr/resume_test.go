@@ -0,0 +1,60 @@
+package dapr
+
+import (
+	"testing"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResume_PartiallyCompletedCrawl(t *testing.T) {
+	basePath := t.TempDir()
+
+	// First run: three seeds, one fetched and one errored before the crawl stopped
+	first := newLocalStateManager(t, basePath)
+	require.NoError(t, first.Initialize([]string{"done", "failed", "todo"}))
+	pages, err := first.GetLayerByDepth(0)
+	require.NoError(t, err)
+	for _, page := range pages {
+		switch page.URL {
+		case "done":
+			page.Status = "fetched"
+		case "failed":
+			page.Status = "error"
+		}
+		require.NoError(t, first.UpdatePage(page))
+	}
+	require.NoError(t, first.SaveState())
+
+	// Resumed run: the saved layer is loaded rather than re-seeded
+	resumed := newLocalStateManager(t, basePath)
+	require.NoError(t, resumeLayers(resumed))
+
+	layer, err := resumed.GetLayerByDepth(0)
+	require.NoError(t, err)
+	require.Len(t, layer, 3)
+
+	pending := pendingPages(layer, false)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "todo", pending[0].URL)
+	assert.Equal(t, "unfetched", pending[0].Status)
+}
+
+func TestResume_NothingSaved(t *testing.T) {
+	sm := newLocalStateManager(t, t.TempDir())
+	assert.Error(t, resumeLayers(sm))
+}
+
+func TestPendingPages_Quarantined(t *testing.T) {
+	layer := []state.Page{
+		{URL: "broken", Status: state.PageStatusQuarantined, ErrorCount: 3},
+		{URL: "todo", Status: "unfetched"},
+	}
+
+	pending := pendingPages(layer, false)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "todo", pending[0].URL)
+
+	assert.Len(t, pendingPages(layer, true), 2)
+}
@@ -0,0 +1,81 @@
+package dapr
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/rs/zerolog/log"
+)
+
+// seenURLSet tracks every URL queued in any layer of a crawl. It is persisted
+// through the state manager so a restarted crawl does not queue channels that
+// an earlier run already discovered. A nil *seenURLSet treats every URL as new.
+type seenURLSet struct {
+	mu   sync.Mutex
+	sm   state.StateManagementInterface
+	urls map[string]bool
+}
+
+// loadSeenURLs restores the seen-URL set saved for the crawl and adds the seed
+// URLs to it. A failed load is logged and the crawl continues with the seeds only.
+func loadSeenURLs(sm state.StateManagementInterface, seeds []string) *seenURLSet {
+	s := &seenURLSet{sm: sm, urls: make(map[string]bool)}
+
+	saved, err := sm.LoadSeenURLs()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to load seen URLs, starting with seed URLs only")
+	}
+	for _, url := range saved {
+		s.urls[url] = true
+	}
+	for _, url := range seeds {
+		s.urls[url] = true
+	}
+
+	log.Info().Int("restored", len(saved)).Int("total", len(s.urls)).Msg("Seen URL set initialized")
+	return s
+}
+
+// markNew records url and reports whether it had not been seen before.
+func (s *seenURLSet) markNew(url string) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.urls[url] {
+		return false
+	}
+	s.urls[url] = true
+	return true
+}
+
+// forget removes the URLs of pages, e.g. pages that could not be queued, so a
+// later discovery can queue them again.
+func (s *seenURLSet) forget(pages []state.Page) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, page := range pages {
+		delete(s.urls, page.URL)
+	}
+}
+
+// save persists the current set through the state manager.
+func (s *seenURLSet) save() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	urls := make([]string, 0, len(s.urls))
+	for url := range s.urls {
+		urls = append(urls, url)
+	}
+	s.mu.Unlock()
+
+	sort.Strings(urls)
+	return s.sm.SaveSeenURLs(urls)
+}
@@ -0,0 +1,155 @@
+package dapr
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLocalStateManager(t *testing.T, basePath string) state.StateManagementInterface {
+	t.Helper()
+	sm, err := state.NewLocalStateManager(state.Config{
+		CrawlID:     "crawl-1",
+		LocalConfig: &state.LocalConfig{BasePath: basePath},
+	})
+	require.NoError(t, err)
+	return sm
+}
+
+func TestSeenURLs_SecondRunSkipsPreviouslySeenURLs(t *testing.T) {
+	basePath := t.TempDir()
+
+	// First run: seeds plus a channel discovered at depth 1
+	first := loadSeenURLs(newLocalStateManager(t, basePath), []string{"seed"})
+	assert.False(t, first.markNew("seed"))
+	assert.True(t, first.markNew("discovered"))
+	require.NoError(t, first.save())
+
+	// Second run after a restart with a different seed list
+	second := loadSeenURLs(newLocalStateManager(t, basePath), []string{"other-seed"})
+	assert.False(t, second.markNew("seed"))
+	assert.False(t, second.markNew("discovered"))
+	assert.False(t, second.markNew("other-seed"))
+	assert.True(t, second.markNew("new-channel"))
+}
+
+func TestSeenURLs_NilSetTreatsEveryURLAsNew(t *testing.T) {
+	var seen *seenURLSet
+	assert.True(t, seen.markNew("a"))
+	assert.True(t, seen.markNew("a"))
+	assert.NoError(t, seen.save())
+}
+
+// localStateManagerFactory creates local state managers under basePath
+type localStateManagerFactory struct {
+	basePath string
+}
+
+func (f localStateManagerFactory) Create(cfg state.Config) (state.StateManagementInterface, error) {
+	cfg.LocalConfig = &state.LocalConfig{BasePath: f.basePath}
+	return state.NewLocalStateManager(cfg)
+}
+
+func TestLaunch_SecondRunSkipsPreviouslySeenURLs(t *testing.T) {
+	basePath := t.TempDir()
+
+	originalFactory := state.NewStateManagerFactory
+	state.NewStateManagerFactory = func() state.StateManagerFactory {
+		return localStateManagerFactory{basePath: basePath}
+	}
+	originalRun := runForChannel
+	defer func() {
+		state.NewStateManagerFactory = originalFactory
+		runForChannel = originalRun
+	}()
+
+	// Every channel links to the same two channels
+	var mu sync.Mutex
+	var crawled []string
+	runForChannel = func(ctx context.Context, p *state.Page, storagePrefix string, sm state.StateManagementInterface, cfg common.CrawlerConfig) ([]*state.Page, error) {
+		mu.Lock()
+		crawled = append(crawled, p.URL)
+		mu.Unlock()
+		return []*state.Page{{URL: "discovered"}, {URL: "seed"}}, nil
+	}
+
+	// The local state manager loads any saved state, including the empty state
+	// the temporary manager in launch saves on close, so seed it up front
+	seed := func(urls ...string) {
+		require.NoError(t, os.RemoveAll(filepath.Join(basePath, "crawl-1", "state.json")))
+		require.NoError(t, newLocalStateManager(t, basePath).Initialize(urls))
+	}
+
+	cfg := common.CrawlerConfig{CrawlID: "crawl-1", StorageRoot: basePath, Concurrency: 1, MaxDepth: 1}
+	seed("seed")
+	launch(context.Background(), []string{"seed"}, cfg)
+	assert.ElementsMatch(t, []string{"seed", "discovered"}, crawled)
+
+	// Restart from a new seed list; only the seen URLs carry over
+	seed("other-seed")
+	crawled = nil
+	launch(context.Background(), []string{"other-seed"}, cfg)
+	assert.Equal(t, []string{"other-seed"}, crawled, "Channels seen by the first run should not be crawled again")
+}
+
+func TestNextLayerPages_DropsOutlinksPastMaxDepth(t *testing.T) {
+	seen := loadSeenURLs(newLocalStateManager(t, t.TempDir()), []string{"seed"})
+	discovered := []*state.Page{{URL: "a", ParentID: "p1"}, {URL: "a"}, {URL: "seed"}, {URL: "b"}}
+	cfg := common.CrawlerConfig{MaxDepth: 1}
+
+	pages := nextLayerPages(discovered, 0, seen, cfg)
+	require.Len(t, pages, 2)
+	assert.Equal(t, "a", pages[0].URL)
+	assert.Equal(t, "p1", pages[0].ParentID)
+	assert.Equal(t, 1, pages[0].Depth)
+	assert.Equal(t, "b", pages[1].URL)
+
+	// Outlinks of the last layer are recorded but not queued
+	assert.Empty(t, nextLayerPages([]*state.Page{{URL: "c"}}, 1, seen, cfg))
+	assert.False(t, seen.markNew("c"), "Dropped outlinks should still be recorded as seen")
+}
+
+func TestNextLayerPages_SkipsChannelsRejectedByFilter(t *testing.T) {
+	filter, err := common.NewChannelFilter(nil, []string{"spam"})
+	require.NoError(t, err)
+	seen := loadSeenURLs(newLocalStateManager(t, t.TempDir()), nil)
+
+	pages := nextLayerPages([]*state.Page{{URL: "news"}, {URL: "spam_channel"}}, 0, seen, common.CrawlerConfig{MaxDepth: 2, ChannelFilter: filter})
+	require.Len(t, pages, 1)
+	assert.Equal(t, "news", pages[0].URL)
+}
+
+// failingAddLayer is a state manager whose AddLayer always fails
+type failingAddLayer struct {
+	state.StateManagementInterface
+}
+
+func (f failingAddLayer) AddLayer(pages []state.Page) error {
+	return errors.New("state store unavailable")
+}
+
+func TestProcessLayer_ForgetsURLsWhenAddLayerFails(t *testing.T) {
+	sm := failingAddLayer{newLocalStateManager(t, t.TempDir())}
+	require.NoError(t, sm.Initialize([]string{"seed"}))
+	seen := loadSeenURLs(sm, []string{"seed"})
+
+	originalRun := runForChannel
+	defer func() { runForChannel = originalRun }()
+	runForChannel = func(ctx context.Context, p *state.Page, storagePrefix string, sm state.StateManagementInterface, cfg common.CrawlerConfig) ([]*state.Page, error) {
+		return []*state.Page{{URL: "discovered"}}, nil
+	}
+
+	pages, err := sm.GetLayerByDepth(0)
+	require.NoError(t, err)
+	processLayerInParallel(context.Background(), &state.Layer{Depth: 0, Pages: pages}, 1, sm, common.CrawlerConfig{MaxDepth: 1}, nil, seen)
+
+	assert.True(t, seen.markNew("discovered"), "A channel that could not be queued should not be skipped later")
+}
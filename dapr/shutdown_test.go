@@ -0,0 +1,76 @@
+package dapr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown_CancelledContextPersistsLayers(t *testing.T) {
+	basePath := t.TempDir()
+	sm := newLocalStateManager(t, basePath)
+	require.NoError(t, sm.Initialize([]string{"a", "b"}))
+	seen := loadSeenURLs(sm, []string{"a", "b"})
+
+	pages, err := sm.GetLayerByDepth(0)
+	require.NoError(t, err)
+	require.Len(t, pages, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// With the context already cancelled no page is started, so no Telegram
+	// client is needed
+	processLayerInParallel(ctx, &state.Layer{Depth: 0, Pages: pages}, 2, sm, common.CrawlerConfig{}, nil, seen)
+	persistInterruptedCrawl(sm, seen)
+
+	restored := newLocalStateManager(t, basePath)
+	require.NoError(t, restored.Initialize(nil))
+
+	layer, err := restored.GetLayerByDepth(0)
+	require.NoError(t, err)
+	urls := make([]string, 0, len(layer))
+	for _, page := range layer {
+		urls = append(urls, page.URL)
+		assert.Equal(t, "unfetched", page.Status, "Pages not started before shutdown should stay unfetched")
+	}
+	assert.ElementsMatch(t, []string{"a", "b"}, urls)
+
+	savedURLs, err := restored.LoadSeenURLs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, savedURLs)
+}
+
+func TestShutdown_MaxRuntimeStopsBeforeNextPage(t *testing.T) {
+	basePath := t.TempDir()
+	sm := newLocalStateManager(t, basePath)
+	require.NoError(t, sm.Initialize([]string{"a", "b"}))
+	seen := loadSeenURLs(sm, []string{"a", "b"})
+
+	pages, err := sm.GetLayerByDepth(0)
+	require.NoError(t, err)
+
+	ctx, cancel := common.WithMaxRuntime(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	require.True(t, common.MaxRuntimeExceeded(ctx))
+
+	// The runtime is spent before the layer starts, so no page is processed
+	// and no Telegram client is needed
+	processLayerInParallel(ctx, &state.Layer{Depth: 0, Pages: pages}, 2, sm, common.CrawlerConfig{MaxRuntime: time.Millisecond}, nil, seen)
+	persistInterruptedCrawl(sm, seen)
+
+	restored := newLocalStateManager(t, basePath)
+	require.NoError(t, restored.Initialize(nil))
+	layer, err := restored.GetLayerByDepth(0)
+	require.NoError(t, err)
+	require.Len(t, layer, 2)
+	for _, page := range layer {
+		assert.Equal(t, "unfetched", page.Status, "Pages not started before the deadline should stay unfetched")
+	}
+}
@@ -10,14 +10,16 @@ import (
 	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
 	crawlercommon "github.com/researchaccelerator-hub/telegram-scraper/crawler/common"
 	"github.com/researchaccelerator-hub/telegram-scraper/crawler/youtube"
+	"github.com/researchaccelerator-hub/telegram-scraper/metrics"
 	"github.com/researchaccelerator-hub/telegram-scraper/state"
 	"github.com/researchaccelerator-hub/telegram-scraper/telegramhelper"
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sync"
+	"syscall"
 	"time"
 )
 
@@ -48,10 +50,21 @@ func handler(w http.ResponseWriter, r *http.Request) {
 //   - generateCode: A flag indicating whether to run code generation for Telegram API
 //
 // The function will log fatal errors if no URLs are provided or if essential
-// initialization steps fail. It will block indefinitely after starting the crawler.
+// initialization steps fail. It will block indefinitely after starting the crawler,
+// unless SIGINT or SIGTERM is received, in which case the in-flight pages are
+// finished, state is persisted and the TDLib clients are closed before returning.
 func StartDaprStandaloneMode(urlList []string, urlFile string, crawlerCfg common.CrawlerConfig, generateCode bool) {
 	log.Info().Msg("Starting crawler in standalone mode")
 
+	// Resuming continues the saved crawl, so its ID replaces any other
+	if crawlerCfg.ResumeCrawlID != "" {
+		crawlerCfg.CrawlID = crawlerCfg.ResumeCrawlID
+		log.Info().Str("crawl_id", crawlerCfg.CrawlID).Msg("Resuming crawl from its saved layers")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	http.HandleFunc("/", handler)
 	go func() {
 		if err := http.ListenAndServe(":6481", nil); err != nil {
@@ -83,7 +96,7 @@ func StartDaprStandaloneMode(urlList []string, urlFile string, crawlerCfg common
 		urls = append(urls, fileURLs...)
 	}
 
-	if len(urls) == 0 {
+	if len(urls) == 0 && crawlerCfg.ResumeCrawlID == "" {
 		log.Fatal().Msg("No URLs provided. Use --urls or --url-file to specify URLs to crawl")
 	}
 
@@ -143,10 +156,23 @@ func StartDaprStandaloneMode(urlList []string, urlFile string, crawlerCfg common
 		defer crawl.CloseConnectionPool()
 	}
 
-	launch(urls, crawlerCfg)
+	// The runtime budget stops the crawl like a shutdown request does
+	runCtx, cancelRun := common.WithMaxRuntime(ctx, crawlerCfg.MaxRuntime)
+	defer cancelRun()
+	launch(runCtx, urls, crawlerCfg)
+
+	if common.MaxRuntimeExceeded(runCtx) {
+		log.Warn().Dur("max_runtime", crawlerCfg.MaxRuntime).Msg("Crawl stopped after reaching its max runtime; it is partially complete and can be resumed")
+		return
+	}
+	if ctx.Err() != nil {
+		log.Info().Msg("Crawl interrupted, shutting down")
+		return
+	}
 
 	log.Info().Msg("Crawling completed")
-	select {}
+	<-ctx.Done()
+	log.Info().Msg("Shutting down")
 }
 
 // Note: readURLsFromFile function removed as we're now using the common implementation
@@ -158,21 +184,23 @@ func StartDaprStandaloneMode(urlList []string, urlFile string, crawlerCfg common
 // progress is saved after each item is processed. The function ensures that all items are processed successfully, and
 // handles any panics that occur during item processing.
 //
+// With crawlCfg.ResumeCrawlID set, the saved layers of that crawl's incomplete
+// execution are loaded instead of seeding stringList, and only the pages that
+// are not yet finished are processed.
+//
+// When ctx is cancelled or its deadline (the crawl's max runtime) passes no
+// further pages are started; pages already in flight are finished, the state is
+// persisted and launch returns without marking the crawl as completed, so it can
+// be resumed.
+//
 // Parameters:
+//   - ctx: Cancelled to request a graceful shutdown.
 //   - stringList: A slice of strings representing the items to be processed.
 //   - crawlCfg: A CrawlerConfig struct containing configuration settings for the crawler.
-func launch(stringList []string, crawlCfg common.CrawlerConfig) {
-	// Create a global map to track all URLs we've seen across all layers
-	seenURLs := make(map[string]bool)
-
-	// Initialize seenURLs with the seed URLs
-	for _, url := range stringList {
-		seenURLs[url] = true
-	}
-
+func launch(ctx context.Context, stringList []string, crawlCfg common.CrawlerConfig) {
 	// Initialize state manager factory
 	log.Info().Msgf("Starting scraper for crawl ID: %s", crawlCfg.CrawlID)
-	smfact := state.DefaultStateManagerFactory{}
+	smfact := state.NewStateManagerFactory()
 
 	// Create a temporary state manager to check for incomplete crawls
 	tempCfg := state.Config{
@@ -203,9 +231,15 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		_ = tempSM.Close()
 	}
 
+	// A resumed crawl must continue its own execution
+	if crawlCfg.ResumeCrawlID != "" && crawlexecid == "" {
+		log.Error().Str("crawl_id", crawlCfg.CrawlID).Msg("No incomplete crawl found to resume")
+		return
+	}
+
 	// If no existing crawl was found, generate a new execution ID
 	if crawlexecid == "" {
-		crawlexecid = common.GenerateCrawlID()
+		crawlexecid = common.GenerateCrawlIDWithOptions(crawlCfg.CrawlIDFormat)
 		log.Info().Msgf("Starting new crawl execution: %s", crawlexecid)
 	}
 
@@ -236,15 +270,49 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		return
 	}
 
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
+	// Open the configured post outputs alongside the state manager
+	closeOutputs, err := openOutputs(&crawlCfg, sm, crawlexecid)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to open crawl outputs")
+		return
+	}
+	defer closeOutputs()
+
+	// Optionally expose progress for headless monitoring; the server keeps running
+	// after the crawl so the final status stays observable
+	progress := newCrawlProgress(crawlCfg.CrawlID, crawlexecid)
+	startTotals := metrics.Snapshot()
+	if crawlCfg.StatusPort > 0 {
+		// YouTube crawls hold no TDLib connection to check
+		var health HealthChecker
+		if crawlCfg.Platform != "youtube" {
+			health = HealthCheckFunc(crawl.PingConnectionPool)
+		}
+		if _, _, err := startStatusServer(crawlCfg.StatusPort, progress, health); err != nil {
+			log.Error().Err(err).Msg("Failed to start status server, continuing without it")
+		}
+	}
+
+	// Clear media left behind by an earlier run before any page is crawled
+	telegramhelper.PurgeMediaDownloadDir(crawlCfg)
 
 	// Get the existing layers or seed a new crawl
-	err = sm.Initialize(stringList)
-	if err != nil {
+	if crawlCfg.ResumeCrawlID != "" {
+		if err := resumeLayers(sm); err != nil {
+			log.Error().Err(err).Str("crawl_id", crawlCfg.CrawlID).Msg("Failed to resume crawl")
+			return
+		}
+	} else if err := sm.Initialize(stringList); err != nil {
 		log.Error().Err(err).Msg("Failed to set up seed URLs")
 		return
 	}
 
+	// Track URLs seen across all layers, including those queued by earlier runs
+	seenURLs := loadSeenURLs(sm, stringList)
+	if err := seenURLs.save(); err != nil {
+		log.Warn().Err(err).Msg("Failed to save seen URLs")
+	}
+
 	// Process layers iteratively, with potential for new layers to be added during execution
 	depth := 0
 	for {
@@ -300,6 +368,16 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 			log.Info().Msgf("Processed all layers up to max depth %d", maxDepth)
 			break
 		}
+
+		// When resuming, go straight to the unfinished pages
+		if crawlCfg.ResumeCrawlID != "" {
+			pages = pendingPages(pages, crawlCfg.RetryQuarantined)
+			if len(pages) == 0 {
+				log.Info().Msgf("All pages at depth %d are finished, skipping", depth)
+				depth++
+				continue
+			}
+		}
 		log.Info().Msgf("Processing layer at depth %d with %d pages", depth, len(pages))
 
 		// Create a Layer object
@@ -309,7 +387,18 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		}
 
 		// Process pages in current layer in parallel
-		processLayerInParallel(layer, crawlCfg.Concurrency, sm, crawlCfg)
+		progress.startLayer(layer)
+		processLayerInParallel(ctx, layer, crawlCfg.Concurrency, sm, crawlCfg, progress, seenURLs)
+
+		if ctx.Err() != nil {
+			if common.MaxRuntimeExceeded(ctx) {
+				log.Warn().Int("depth", depth).Msg("Max runtime reached, stopping crawl after in-flight pages")
+			} else {
+				log.Warn().Int("depth", depth).Msg("Shutdown requested, stopping crawl after in-flight pages")
+			}
+			persistInterruptedCrawl(sm, seenURLs)
+			return
+		}
 
 		// Log progress after completing a layer
 		log.Info().Msgf("Completed layer at depth %d", depth)
@@ -318,6 +407,10 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		depth++
 	}
 
+	progress.complete()
+
+	progress.writeSummary(metrics.SummaryPath(crawlCfg.SummaryFile, crawlCfg.StorageRoot, crawlCfg.CrawlID), startTotals)
+
 	// Explicitly save any pending media cache data before completing the crawl
 	log.Info().Msg("Saving final state before marking crawl as completed")
 	if closeErr := sm.Close(); closeErr != nil {
@@ -341,10 +434,18 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 	log.Info().Msg("All items processed successfully.")
 }
 
+// runForChannel crawls a Telegram channel with a pooled connection. Tests
+// replace it to crawl without TDLib.
+var runForChannel = crawl.RunForChannelWithPool
+
 // processLayerInParallel processes all pages in a layer with a maximum of maxWorkers concurrent goroutines.
 // It uses a semaphore pattern to limit concurrency and ensures all pages are processed before returning.
 // This version uses the connection pool for efficient client management.
-func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateManagementInterface, crawlCfg common.CrawlerConfig) {
+// Page outcomes are reported to progress, which may be nil. Discovered channels
+// already in seen are not queued again; a nil seen only dedupes within the layer.
+// Once parent is cancelled no new pages are started, but pages in flight run to
+// completion and their discoveries are still added as the next layer.
+func processLayerInParallel(parent context.Context, layer *state.Layer, maxWorkers int, sm state.StateManagementInterface, crawlCfg common.CrawlerConfig, progress *crawlProgress, seen *seenURLSet) {
 	// In dapr mode it's harder to accurately detect this, so we'll simplify the approach
 	// to prevent reprocessing of fetched pages, always skip them
 	isResumingSameCrawlExecution := true
@@ -361,10 +462,14 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 	// Semaphore to limit concurrent processing
 	semaphore := make(chan struct{}, maxWorkers)
 
-	// Create a context that can be cancelled
-	ctx, cancel := context.WithCancel(context.Background())
+	// Create a context that can be cancelled; it is detached from parent so a
+	// shutdown request does not abort pages that are already in flight
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
 	defer cancel()
 
+	// Finished pages are saved in batches; Close persists the rest of the layer
+	saver := state.NewSaveBatcher(sm, crawlCfg.StateSavePages, crawlCfg.StateSaveInterval)
+
 	// Create a map to track unique pages by URL to avoid processing duplicates
 	uniquePages := make(map[string]bool)
 
@@ -394,6 +499,12 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 			Bool("resuming_execution", isResumingSameCrawlExecution).
 			Msg("Page discovered during crawl restart in Dapr mode")
 
+		// Skip quarantined pages unless they are explicitly retried
+		if pageToProcess.Status == state.PageStatusQuarantined && !crawlCfg.RetryQuarantined {
+			log.Debug().Int("error_count", pageToProcess.ErrorCount).Msgf("Skipping quarantined page: %s", pageToProcess.URL)
+			continue
+		}
+
 		// Skip already processed or errored pages
 		if pageToProcess.Status == "fetched" || pageToProcess.Status == "error" {
 			if isResumingSameCrawlExecution {
@@ -417,8 +528,15 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 			}
 		}
 
-		// Acquire semaphore slot (block if we're at max workers)
-		semaphore <- struct{}{}
+		// Acquire semaphore slot (block if we're at max workers), unless shutting down
+		select {
+		case semaphore <- struct{}{}:
+		case <-parent.Done():
+		}
+		if parent.Err() != nil {
+			log.Info().Str("url", pageToProcess.URL).Msg("Shutdown requested, not starting further pages")
+			break
+		}
 		wg.Add(1)
 
 		go func(page state.Page) {
@@ -431,9 +549,11 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 				if r := recover(); r != nil {
 					log.Error().Msgf("Recovered from panic while processing item: %s, error: %v", page.URL, r)
 
-					// Update the page status to error
-					page.Status = "error"
-					page.Error = fmt.Sprintf("Panic: %v", r)
+					// Update the page status to error, quarantining it after repeated failures
+					if page.RecordError(fmt.Errorf("Panic: %v", r), crawlCfg.QuarantineThreshold) {
+						log.Warn().Int("error_count", page.ErrorCount).Msgf("Quarantined page after repeated failures: %s", page.URL)
+					}
+					progress.pageFinished(layer.Depth, page.Status)
 
 					// Update the page in the state manager
 					if err := sm.UpdatePage(page); err != nil {
@@ -441,7 +561,7 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 					}
 
 					// Save state after recovery
-					if err := sm.SaveState(); err != nil {
+					if err := saver.PageDone(); err != nil {
 						log.Error().Err(err).Msg("Failed to save state after panic recovery")
 					}
 				}
@@ -535,9 +655,12 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 													Time("date_between_max", toTime).
 													Msg("Using date-between filter for YouTube crawl in DAPR mode")
 											} else {
-												// Use traditional min post date with current time as upper bound
+												// Use traditional min post date with max post date (or current time) as upper bound
 												fromTime = crawlCfg.MinPostDate
 												toTime = time.Now()
+												if !crawlCfg.MaxPostDate.IsZero() {
+													toTime = crawlCfg.MaxPostDate
+												}
 											}
 
 											job := crawler.CrawlJob{
@@ -589,15 +712,17 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 				// Telegram platform processing (default)
 				// Use the pooled channel processing
 				log.Info().Msgf("Starting run for Telegram channel: %s", page.URL)
-				discoveredChannels, err = crawl.RunForChannelWithPool(ctx, &page, crawlCfg.StorageRoot, sm, crawlCfg)
+				discoveredChannels, err = runForChannel(ctx, &page, crawlCfg.StorageRoot, sm, crawlCfg)
 			}
 
 			log.Info().Msgf("Page processed for %s", page.URL)
 
 			if err != nil {
 				log.Error().Stack().Err(err).Msgf("Error processing item %s", page.URL)
-				page.Status = "error"
-				page.Error = err.Error()
+				if page.RecordError(err, crawlCfg.QuarantineThreshold) {
+					log.Warn().Int("error_count", page.ErrorCount).Msgf("Quarantined page after repeated failures: %s", page.URL)
+				}
+				progress.pageFinished(layer.Depth, page.Status)
 
 				// Update the page in the state manager
 				if updateErr := sm.UpdatePage(page); updateErr != nil {
@@ -605,17 +730,19 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 				}
 
 				// Save the state to ensure error status is persisted
-				if err := sm.SaveState(); err != nil {
+				if err := saver.PageDone(); err != nil {
 					log.Error().Err(err).Msgf("Error saving state after marking channel %s as error", page.URL)
 				}
 			} else {
 				page.Status = "fetched"
+				page.ErrorCount = 0
+				progress.pageFinished(layer.Depth, page.Status)
 				if updateErr := sm.UpdatePage(page); updateErr != nil {
 					log.Error().Err(updateErr).Msg("Failed to update page status after successful processing")
 				}
 
-				// Save the entire state
-				if err := sm.SaveState(); err != nil {
+				// Save the entire state once a batch of pages has finished
+				if err := saver.PageDone(); err != nil {
 					log.Error().Err(err).Msgf("Error saving state after processing channel %s", page.URL)
 				}
 
@@ -631,6 +758,9 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 
 	// Wait for all pages to be processed
 	wg.Wait()
+	if err := saver.Close(); err != nil {
+		log.Error().Err(err).Int("depth", layer.Depth).Msg("Failed to save state after processing layer")
+	}
 
 	// Log summary of unique pages processed
 	uniqueCount := len(uniquePages)
@@ -644,53 +774,23 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 
 	// After all pages in the layer are processed, append the new layer with all discovered channels
 	if len(allDiscoveredChannels) > 0 {
-		currentDepth := layer.Depth
-		newPages := make([]state.Page, 0, len(allDiscoveredChannels))
-
-		// Track unique URLs in the new layer
-		newLayerUniqueURLs := make(map[string]bool)
-
-		// Count of total and unique pages for logging
-		totalDiscovered := len(allDiscoveredChannels)
-		uniqueDiscovered := 0
-
-		for _, channel := range allDiscoveredChannels {
-			// Skip if this URL has already been seen in the new layer
-			if newLayerUniqueURLs[channel.URL] {
-				log.Debug().Str("url", channel.URL).Msg("Skipping duplicate discovered URL for next layer")
-				continue
-			}
-			newLayerUniqueURLs[channel.URL] = true
-			uniqueDiscovered++
-
-			parentID := ""
-			if channel.ParentID != "" {
-				parentID = channel.ParentID
-			}
-			// Create a new Page for each discovered channel
-			page := state.Page{
-				URL:       channel.URL,      // Assuming channel has a URL field
-				Depth:     currentDepth + 1, // Set depth one level deeper than current
-				Status:    "unfetched",
-				Timestamp: time.Now(),
-				ParentID:  parentID, // Set parent ID to the current page being processed
-				// Any other fields you need to set
+		newPages := nextLayerPages(allDiscoveredChannels, layer.Depth, seen, crawlCfg)
+		if len(newPages) == 0 {
+			log.Info().Msg("No discovered channels to queue, no new layer added")
+			if err := seen.save(); err != nil {
+				log.Error().Err(err).Msg("Failed to save seen URLs")
 			}
-			newPages = append(newPages, page)
-		}
-
-		// Log the deduplication results for the new layer
-		log.Info().
-			Int("total_discovered", totalDiscovered).
-			Int("unique_discovered", uniqueDiscovered).
-			Int("duplicate_discovered", totalDiscovered-uniqueDiscovered).
-			Msgf("Deduplicated discovered channels for next layer at depth %d", currentDepth+1)
-
-		if err := sm.AddLayer(newPages); err != nil {
+		} else if err := sm.AddLayer(newPages); err != nil {
 			log.Error().Err(err).Msg("Failed to add discovered channels as new layer")
+			// Forget the URLs so a later discovery can queue them again
+			seen.forget(newPages)
 		} else {
 			log.Info().Int("count", len(newPages)).Msg("Added new channels to be processed")
 
+			if err := seen.save(); err != nil {
+				log.Error().Err(err).Msg("Failed to save seen URLs after adding new layer")
+			}
+
 			// Save state after adding new pages
 			if err := sm.SaveState(); err != nil {
 				log.Error().Err(err).Msg("Failed to save state after adding new layer")
@@ -698,3 +798,87 @@ func processLayerInParallel(layer *state.Layer, maxWorkers int, sm state.StateMa
 		}
 	}
 }
+
+// nextLayerPages returns the pages of the layer after depth for the channels
+// discovered in it, skipping duplicates, channels already in seen and channels
+// rejected by crawlCfg.ChannelFilter. When the next layer would be past
+// crawlCfg.MaxDepth, the new channels are recorded in seen but not queued, and
+// the number dropped is logged.
+func nextLayerPages(discovered []*state.Page, depth int, seen *seenURLSet, crawlCfg common.CrawlerConfig) []state.Page {
+	newPages := make([]state.Page, 0, len(discovered))
+
+	// Track unique URLs in the new layer
+	newLayerUniqueURLs := make(map[string]bool)
+
+	// Count of total and unique pages for logging
+	totalDiscovered := len(discovered)
+	uniqueDiscovered := 0
+	droppedByDepth := 0
+
+	for _, channel := range discovered {
+		// Skip if this URL has already been seen in the new layer
+		if newLayerUniqueURLs[channel.URL] {
+			log.Debug().Str("url", channel.URL).Msg("Skipping duplicate discovered URL for next layer")
+			continue
+		}
+		newLayerUniqueURLs[channel.URL] = true
+
+		// Skip channels queued in an earlier layer or an earlier run
+		if !seen.markNew(channel.URL) {
+			log.Debug().Str("url", channel.URL).Msg("Skipping previously seen URL for next layer")
+			continue
+		}
+		uniqueDiscovered++
+
+		if !crawlCfg.ChannelFilter.Allows(channel.URL) {
+			log.Debug().Str("url", channel.URL).Msg("Discovered channel rejected by channel filter")
+			continue
+		}
+		if depth+1 > crawlCfg.MaxDepth {
+			// Recorded as seen, but the crawl does not go this deep
+			droppedByDepth++
+			continue
+		}
+
+		// Create a new Page for each discovered channel
+		newPages = append(newPages, state.Page{
+			URL:       channel.URL,
+			Depth:     depth + 1, // One level deeper than the layer it was found in
+			Status:    "unfetched",
+			Timestamp: time.Now(),
+			ParentID:  channel.ParentID,
+		})
+	}
+
+	// Log the deduplication results for the new layer
+	log.Info().
+		Int("total_discovered", totalDiscovered).
+		Int("unique_discovered", uniqueDiscovered).
+		Int("duplicate_discovered", totalDiscovered-uniqueDiscovered).
+		Msgf("Deduplicated discovered channels for next layer at depth %d", depth+1)
+
+	if droppedByDepth > 0 {
+		log.Info().
+			Int("depth", depth).
+			Int("max_depth", crawlCfg.MaxDepth).
+			Int("dropped_outlinks", droppedByDepth).
+			Msg("Outlinks not queued because they are past the max depth")
+	}
+	return newPages
+}
+
+// persistInterruptedCrawl saves the seen URLs and the state, including all
+// layers, after a shutdown request. The crawl is left incomplete so the next
+// run resumes it.
+func persistInterruptedCrawl(sm state.StateManagementInterface, seen *seenURLSet) {
+	if err := seen.save(); err != nil {
+		log.Error().Err(err).Msg("Failed to save seen URLs during shutdown")
+	}
+	if err := sm.SaveState(); err != nil {
+		log.Error().Err(err).Msg("Failed to save state during shutdown")
+	}
+	if err := sm.Close(); err != nil {
+		log.Warn().Err(err).Msg("Error closing state manager during shutdown")
+	}
+	log.Info().Msg("Crawl state persisted, crawl can be resumed")
+}
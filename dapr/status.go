@@ -0,0 +1,214 @@
+package dapr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/metrics"
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/rs/zerolog/log"
+)
+
+// CrawlStatus is the progress report served on the /status endpoint.
+type CrawlStatus struct {
+	CrawlID      string    `json:"crawl_id"`
+	ExecutionID  string    `json:"execution_id"`
+	State        string    `json:"state"` // "running" or "completed"
+	StartedAt    time.Time `json:"started_at"`
+	CurrentDepth int       `json:"current_depth"`
+	Layers       int       `json:"layers"`
+	PagesTotal   int       `json:"pages_total"`
+	PagesFetched int       `json:"pages_fetched"`
+	PagesPending int       `json:"pages_pending"`
+	Errors       int       `json:"errors"`
+}
+
+// layerProgress counts page outcomes within a single layer.
+type layerProgress struct {
+	total   int
+	fetched int
+	errored int
+}
+
+// crawlProgress tracks the progress of the layers processed by launch. All
+// methods are safe for concurrent use and are no-ops on a nil receiver, so
+// callers that don't report progress can pass nil.
+type crawlProgress struct {
+	mu           sync.Mutex
+	crawlID      string
+	executionID  string
+	startedAt    time.Time
+	currentDepth int
+	completed    bool
+	layers       map[int]*layerProgress
+}
+
+func newCrawlProgress(crawlID, executionID string) *crawlProgress {
+	return &crawlProgress{
+		crawlID:     crawlID,
+		executionID: executionID,
+		startedAt:   time.Now(),
+		layers:      make(map[int]*layerProgress),
+	}
+}
+
+// startLayer registers the pages of a layer about to be processed. Duplicate
+// URLs are counted once, matching processLayerInParallel, and pages that were
+// already fetched or errored in an earlier run are counted immediately.
+func (p *crawlProgress) startLayer(layer *state.Layer) {
+	if p == nil {
+		return
+	}
+	lp := &layerProgress{}
+	seen := make(map[string]bool)
+	for _, page := range layer.Pages {
+		if seen[page.URL] {
+			continue
+		}
+		seen[page.URL] = true
+		lp.total++
+		switch page.Status {
+		case "fetched":
+			lp.fetched++
+		case "error":
+			lp.errored++
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.currentDepth = layer.Depth
+	p.layers[layer.Depth] = lp
+}
+
+// pageFinished records the final status of a page processed at depth.
+func (p *crawlProgress) pageFinished(depth int, status string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	lp, ok := p.layers[depth]
+	if !ok {
+		return
+	}
+	switch status {
+	case "fetched":
+		lp.fetched++
+	case "error", state.PageStatusQuarantined:
+		lp.errored++
+	}
+}
+
+// complete marks the crawl as finished.
+func (p *crawlProgress) complete() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.completed = true
+}
+
+// snapshot returns the current progress totals across all layers.
+func (p *crawlProgress) snapshot() CrawlStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := CrawlStatus{
+		CrawlID:      p.crawlID,
+		ExecutionID:  p.executionID,
+		State:        "running",
+		StartedAt:    p.startedAt,
+		CurrentDepth: p.currentDepth,
+		Layers:       len(p.layers),
+	}
+	if p.completed {
+		status.State = "completed"
+	}
+	for _, lp := range p.layers {
+		status.PagesTotal += lp.total
+		status.PagesFetched += lp.fetched
+		status.Errors += lp.errored
+		status.PagesPending += lp.total - lp.fetched - lp.errored
+	}
+	return status
+}
+
+// summary returns the crawl summary of the layers registered so far, ordered
+// by depth. Post and media counts are filled in by CrawlSummary.Finish.
+func (p *crawlProgress) summary() metrics.CrawlSummary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	summary := metrics.CrawlSummary{
+		CrawlID:     p.crawlID,
+		ExecutionID: p.executionID,
+		StartedAt:   p.startedAt,
+	}
+	depths := make([]int, 0, len(p.layers))
+	for depth := range p.layers {
+		depths = append(depths, depth)
+	}
+	sort.Ints(depths)
+	for _, depth := range depths {
+		lp := p.layers[depth]
+		summary.Depths = append(summary.Depths, metrics.DepthSummary{
+			Depth:   depth,
+			Pages:   lp.total,
+			Fetched: lp.fetched,
+			Errors:  lp.errored,
+		})
+	}
+	return summary
+}
+
+// writeSummary writes the crawl summary to path, with the post and media counts
+// since the start totals. A failed write is logged and does not fail the crawl.
+func (p *crawlProgress) writeSummary(path string, start metrics.Totals) {
+	summary := p.summary()
+	summary.Finish(start)
+	if err := metrics.WriteSummary(path, summary); err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to write crawl summary")
+	} else {
+		log.Info().Str("path", path).Msg("Wrote crawl summary")
+	}
+}
+
+// ServeHTTP writes the current progress as JSON.
+func (p *crawlProgress) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(p.snapshot()); err != nil {
+		log.Warn().Err(err).Msg("Failed to write crawl status")
+	}
+}
+
+// startStatusServer serves the crawl progress on /status and the state of the
+// connection reported by health on /healthz at the given port in the
+// background, and returns the server along with the address it is listening
+// on. health may be nil, in which case /healthz always reports healthy.
+func startStatusServer(port int, progress *crawlProgress, health HealthChecker) (*http.Server, net.Addr, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen on status port %d: %w", port, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/status", progress)
+	mux.Handle("/healthz", healthHandler{checker: health, timeout: healthCheckTimeout})
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("Status server stopped")
+		}
+	}()
+
+	log.Info().Str("addr", listener.Addr().String()).Msg("Serving crawl status on /status and health on /healthz")
+	return server, listener.Addr(), nil
+}
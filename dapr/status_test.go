@@ -0,0 +1,126 @@
+package dapr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/metrics"
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusServer_ReportsProgress(t *testing.T) {
+	progress := newCrawlProgress("crawl-1", "exec-1")
+	progress.startLayer(&state.Layer{Depth: 0, Pages: []state.Page{
+		{URL: "a", Status: "fetched"}, // Fetched in an earlier run
+		{URL: "b", Status: "unfetched"},
+		{URL: "c", Status: "unfetched"},
+	}})
+	progress.pageFinished(0, "fetched")
+	progress.startLayer(&state.Layer{Depth: 1, Pages: []state.Page{
+		{URL: "d", Status: "unfetched"},
+		{URL: "e", Status: "unfetched"},
+	}})
+	progress.pageFinished(1, "error")
+
+	server, addr, err := startStatusServer(0, progress, nil)
+	require.NoError(t, err)
+	defer server.Close()
+
+	port := addr.(*net.TCPAddr).Port
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/status", port))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var status CrawlStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	assert.Equal(t, "crawl-1", status.CrawlID)
+	assert.Equal(t, "exec-1", status.ExecutionID)
+	assert.Equal(t, "running", status.State)
+	assert.Equal(t, 2, status.Layers)
+	assert.Equal(t, 1, status.CurrentDepth)
+	assert.Equal(t, 5, status.PagesTotal)
+	assert.Equal(t, 2, status.PagesFetched)
+	assert.Equal(t, 1, status.Errors)
+	assert.Equal(t, 2, status.PagesPending)
+}
+
+func TestCrawlProgress_NilIsNoop(t *testing.T) {
+	var progress *crawlProgress
+	assert.NotPanics(t, func() {
+		progress.startLayer(&state.Layer{})
+		progress.pageFinished(0, "fetched")
+		progress.complete()
+	})
+}
+
+func TestCrawlProgress_Summary(t *testing.T) {
+	progress := newCrawlProgress("crawl-1", "exec-1")
+	progress.startLayer(&state.Layer{Depth: 1, Pages: []state.Page{{URL: "c", Status: "unfetched"}}})
+	progress.startLayer(&state.Layer{Depth: 0, Pages: []state.Page{
+		{URL: "a", Status: "fetched"},
+		{URL: "b", Status: "unfetched"},
+	}})
+	progress.pageFinished(0, "error")
+	progress.pageFinished(1, "fetched")
+
+	summary := progress.summary()
+	assert.Equal(t, "crawl-1", summary.CrawlID)
+	assert.Equal(t, "exec-1", summary.ExecutionID)
+	assert.Equal(t, []metrics.DepthSummary{
+		{Depth: 0, Pages: 2, Fetched: 1, Errors: 1},
+		{Depth: 1, Pages: 1, Fetched: 1},
+	}, summary.Depths)
+}
+
+// mockHealthChecker reports the connection as healthy unless err is set
+type mockHealthChecker struct {
+	err error
+}
+
+func (m *mockHealthChecker) Ping(ctx context.Context) error {
+	return m.err
+}
+
+func TestStatusServer_Healthz(t *testing.T) {
+	checker := &mockHealthChecker{}
+	server, addr, err := startStatusServer(0, newCrawlProgress("crawl-1", "exec-1"), checker)
+	require.NoError(t, err)
+	defer server.Close()
+
+	get := func() (int, HealthStatus) {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/healthz", addr.(*net.TCPAddr).Port))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		var status HealthStatus
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+		return resp.StatusCode, status
+	}
+
+	code, status := get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HealthStatus{Status: "ok"}, status)
+
+	checker.err = errors.New("TDLib client is not authorized")
+	code, status = get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthStatus{Status: "unhealthy", Error: "TDLib client is not authorized"}, status)
+}
+
+func TestHealthHandler_NilCheckerIsHealthy(t *testing.T) {
+	rec := httptest.NewRecorder()
+	healthHandler{timeout: time.Second}.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -87,6 +87,7 @@ type WorkItemConfig struct {
 	Concurrency       int       `json:"concurrency"`
 	Timeout           int       `json:"timeout"`
 	MinPostDate       time.Time `json:"min_post_date,omitempty"`
+	MaxPostDate       time.Time `json:"max_post_date,omitempty"`
 	PostRecency       time.Time `json:"post_recency,omitempty"`
 	DateBetweenMin    time.Time `json:"date_between_min,omitempty"`
 	DateBetweenMax    time.Time `json:"date_between_max,omitempty"`
@@ -0,0 +1,42 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// OCREngine extracts the text shown in an image file. Implementations must be
+// safe for concurrent use.
+type OCREngine interface {
+	ExtractText(ctx context.Context, imagePath string) (string, error)
+}
+
+// TesseractOCR is an OCREngine that runs the Tesseract command line tool,
+// which must be installed separately.
+type TesseractOCR struct {
+	Binary    string // Path of the tesseract executable (default: "tesseract" on the PATH)
+	Languages string // Tesseract language codes joined with "+", e.g. "eng+rus" (default: Tesseract's own)
+}
+
+// ExtractText runs Tesseract on the image and returns the recognized text.
+func (t TesseractOCR) ExtractText(ctx context.Context, imagePath string) (string, error) {
+	binary := t.Binary
+	if binary == "" {
+		binary = "tesseract"
+	}
+	args := []string{imagePath, "stdout"}
+	if t.Languages != "" {
+		args = append(args, "-l", t.Languages)
+	}
+
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("tesseract failed on %s: %w: %s", imagePath, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,24 @@
+package enrich
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTesseractOCR_ExtractText(t *testing.T) {
+	// A stand-in for tesseract that prints its arguments
+	binary := filepath.Join(t.TempDir(), "tesseract")
+	require.NoError(t, os.WriteFile(binary, []byte("#!/bin/sh\necho \"$@\"\n"), 0755))
+
+	text, err := TesseractOCR{Binary: binary, Languages: "eng+rus"}.ExtractText(context.Background(), "/tmp/image.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/image.jpg stdout -l eng+rus", text)
+
+	_, err = TesseractOCR{Binary: filepath.Join(t.TempDir(), "missing")}.ExtractText(context.Background(), "/tmp/image.jpg")
+	assert.Error(t, err)
+}
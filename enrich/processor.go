@@ -0,0 +1,52 @@
+// Package enrich runs post-processing steps, such as sentiment scoring, OCR or
+// entity extraction, on posts after they are parsed and before they are stored.
+package enrich
+
+import (
+	"context"
+	"errors"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+)
+
+// PostProcessor enriches or rewrites a post in place. Implementations must be
+// safe for concurrent use, since channels may be crawled in parallel.
+type PostProcessor interface {
+	Process(ctx context.Context, post *model.Post) error
+}
+
+// PostProcessorFunc adapts an ordinary function to the PostProcessor interface.
+type PostProcessorFunc func(ctx context.Context, post *model.Post) error
+
+// Process calls f(ctx, post).
+func (f PostProcessorFunc) Process(ctx context.Context, post *model.Post) error {
+	return f(ctx, post)
+}
+
+// Noop is a PostProcessor that leaves posts unchanged.
+type Noop struct{}
+
+// Process does nothing.
+func (Noop) Process(ctx context.Context, post *model.Post) error {
+	return nil
+}
+
+// Chain is a sequence of processors run in order, each seeing the changes of
+// the ones before it. An empty chain leaves posts unchanged.
+type Chain []PostProcessor
+
+// Process runs every processor on the post. A failing processor does not stop
+// the others; all errors are returned joined.
+func (c Chain) Process(ctx context.Context, post *model.Post) error {
+	var errs []error
+	for _, p := range c {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err := p.Process(ctx, post); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
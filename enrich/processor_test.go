@@ -0,0 +1,34 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain_RunsProcessorsInOrder(t *testing.T) {
+	failing := errors.New("sentiment service unavailable")
+	chain := Chain{
+		Noop{},
+		PostProcessorFunc(func(ctx context.Context, post *model.Post) error {
+			post.SearchableText = post.Description
+			return nil
+		}),
+		PostProcessorFunc(func(ctx context.Context, post *model.Post) error {
+			return failing
+		}),
+		PostProcessorFunc(func(ctx context.Context, post *model.Post) error {
+			post.SearchableText += " enriched"
+			return nil
+		}),
+	}
+
+	post := model.Post{Description: "hello"}
+	err := chain.Process(context.Background(), &post)
+
+	assert.ErrorIs(t, err, failing)
+	assert.Equal(t, "hello enriched", post.SearchableText, "A failing processor should not stop the ones after it")
+}
@@ -0,0 +1,76 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Transcript is the speech recognized in an audio or video file.
+type Transcript struct {
+	Text     string // The spoken text
+	Language string // ISO-639-1 code of the detected spoken language, if known
+}
+
+// Transcriber turns the speech in an audio or video file into text.
+// Implementations must be safe for concurrent use.
+type Transcriber interface {
+	Transcribe(ctx context.Context, mediaPath string) (Transcript, error)
+}
+
+// WhisperCLI is a Transcriber that runs the OpenAI Whisper command line tool
+// locally, which must be installed separately (pip install openai-whisper).
+type WhisperCLI struct {
+	Binary   string // Path of the whisper executable (default: "whisper" on the PATH)
+	Model    string // Whisper model name, e.g. "base" or "small" (default: Whisper's own)
+	Language string // Spoken language to assume instead of detecting it (default: detect)
+}
+
+// whisperOutput is the part of Whisper's JSON output that is used.
+type whisperOutput struct {
+	Text     string `json:"text"`
+	Language string `json:"language"`
+}
+
+// Transcribe runs Whisper on the file and reads back its JSON output.
+func (w WhisperCLI) Transcribe(ctx context.Context, mediaPath string) (Transcript, error) {
+	outDir, err := os.MkdirTemp("", "whisper-")
+	if err != nil {
+		return Transcript{}, fmt.Errorf("failed to create whisper output directory: %w", err)
+	}
+	defer os.RemoveAll(outDir)
+
+	binary := w.Binary
+	if binary == "" {
+		binary = "whisper"
+	}
+	args := []string{mediaPath, "--output_format", "json", "--output_dir", outDir}
+	if w.Model != "" {
+		args = append(args, "--model", w.Model)
+	}
+	if w.Language != "" {
+		args = append(args, "--language", w.Language)
+	}
+
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return Transcript{}, fmt.Errorf("whisper failed on %s: %w: %s", mediaPath, err, strings.TrimSpace(stderr.String()))
+	}
+
+	base := strings.TrimSuffix(filepath.Base(mediaPath), filepath.Ext(mediaPath))
+	data, err := os.ReadFile(filepath.Join(outDir, base+".json"))
+	if err != nil {
+		return Transcript{}, fmt.Errorf("failed to read whisper output: %w", err)
+	}
+	var out whisperOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return Transcript{}, fmt.Errorf("failed to parse whisper output: %w", err)
+	}
+	return Transcript{Text: strings.TrimSpace(out.Text), Language: out.Language}, nil
+}
@@ -0,0 +1,37 @@
+package enrich
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWhisperCLI_Transcribe(t *testing.T) {
+	// A stand-in for whisper that writes the JSON output Whisper would
+	script := `#!/bin/sh
+input="$1"
+shift
+while [ "$#" -gt 0 ]; do
+	case "$1" in
+		--output_dir) dir="$2"; shift ;;
+		--model) model="$2"; shift ;;
+	esac
+	shift
+done
+name=$(basename "$input")
+printf '{"text": " hello from %s ", "language": "en"}' "$model" > "$dir/${name%.*}.json"
+`
+	binary := filepath.Join(t.TempDir(), "whisper")
+	require.NoError(t, os.WriteFile(binary, []byte(script), 0755))
+
+	transcript, err := WhisperCLI{Binary: binary, Model: "tiny"}.Transcribe(context.Background(), "/tmp/voice.ogg")
+	require.NoError(t, err)
+	assert.Equal(t, Transcript{Text: "hello from tiny", Language: "en"}, transcript)
+
+	_, err = WhisperCLI{Binary: filepath.Join(t.TempDir(), "missing")}.Transcribe(context.Background(), "/tmp/voice.ogg")
+	assert.Error(t, err)
+}
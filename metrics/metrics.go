@@ -0,0 +1,83 @@
+// Package metrics exposes crawl counters in the Prometheus text format so that
+// throughput, download failures and queue depth can be graphed without
+// parsing logs.
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+const namespace = "telegram_scraper"
+
+// Registry holds every crawler metric. A dedicated registry keeps the Go
+// runtime collectors of the default registry out of the output.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// PostsParsed counts posts produced by ParseMessage.
+	PostsParsed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_parsed_total",
+		Help:      "Number of posts parsed from crawled messages.",
+	})
+
+	// PostsInvalid counts posts skipped because they failed validation.
+	PostsInvalid = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_invalid_total",
+		Help:      "Number of posts skipped because they failed validation.",
+	})
+
+	// MediaDownloaded counts media files downloaded from the platform.
+	MediaDownloaded = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "media_downloaded_total",
+		Help:      "Number of media files downloaded.",
+	})
+
+	// DownloadErrors counts media downloads that failed after all retries.
+	DownloadErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "download_errors_total",
+		Help:      "Number of media downloads that failed after all retries.",
+	})
+
+	// PendingPages is the number of pages of the current layer not yet crawled.
+	PendingPages = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_pages",
+		Help:      "Number of pages in the current layer waiting to be crawled.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(PostsParsed, PostsInvalid, MediaDownloaded, DownloadErrors, PendingPages)
+}
+
+// StartServer serves the registry on /metrics at the given port. Port 0 picks
+// a free port; the address actually bound is returned.
+func StartServer(port int) (*http.Server, net.Addr, error) {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to listen on metrics port %d: %w", port, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error().Err(err).Msg("Metrics server stopped")
+		}
+	}()
+
+	log.Info().Str("addr", listener.Addr().String()).Msg("Serving Prometheus metrics on /metrics")
+	return server, listener.Addr(), nil
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, addr net.Addr) string {
+	t.Helper()
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", addr.(*net.TCPAddr).Port))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestServer_ReportsCounterIncrements(t *testing.T) {
+	server, addr, err := StartServer(0)
+	require.NoError(t, err)
+	defer server.Close()
+
+	posts := testutil.ToFloat64(PostsParsed)
+	downloads := testutil.ToFloat64(MediaDownloaded)
+	failures := testutil.ToFloat64(DownloadErrors)
+
+	PostsParsed.Inc()
+	PostsParsed.Inc()
+	MediaDownloaded.Inc()
+	DownloadErrors.Inc()
+	PendingPages.Set(7)
+
+	body := scrape(t, addr)
+	assert.Contains(t, body, fmt.Sprintf("telegram_scraper_posts_parsed_total %g", posts+2))
+	assert.Contains(t, body, fmt.Sprintf("telegram_scraper_media_downloaded_total %g", downloads+1))
+	assert.Contains(t, body, fmt.Sprintf("telegram_scraper_download_errors_total %g", failures+1))
+	assert.Contains(t, body, "telegram_scraper_pending_pages 7")
+	assert.NotContains(t, body, "go_goroutines", "Runtime collectors should not be registered")
+
+	PendingPages.Dec()
+	assert.Contains(t, scrape(t, addr), "telegram_scraper_pending_pages 6")
+}
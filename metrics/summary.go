@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// CrawlSummary is the machine-readable report written when a crawl finishes.
+type CrawlSummary struct {
+	CrawlID         string         `json:"crawl_id"`
+	ExecutionID     string         `json:"execution_id"`
+	StartedAt       time.Time      `json:"started_at"`
+	EndedAt         time.Time      `json:"ended_at"`
+	ChannelsCrawled int            `json:"channels_crawled"` // Channels fetched successfully, across all depths
+	ChannelErrors   int            `json:"channel_errors"`   // Channels that failed, across all depths
+	PostsParsed     int            `json:"posts_parsed"`
+	MediaDownloaded int            `json:"media_downloaded"`
+	DownloadErrors  int            `json:"download_errors"` // Media downloads that failed after all retries
+	Depths          []DepthSummary `json:"depths"`
+}
+
+// DepthSummary counts the channels of one crawl layer by outcome.
+type DepthSummary struct {
+	Depth   int `json:"depth"`
+	Pages   int `json:"pages"`
+	Fetched int `json:"fetched"`
+	Skipped int `json:"skipped"` // Already fetched in an earlier run or rejected by the channel filter
+	Errors  int `json:"errors"`
+}
+
+// Totals is a reading of the process-wide crawl counters.
+type Totals struct {
+	PostsParsed     int
+	MediaDownloaded int
+	DownloadErrors  int
+}
+
+// Snapshot reads the current values of the crawl counters.
+func Snapshot() Totals {
+	return Totals{
+		PostsParsed:     counterValue(PostsParsed),
+		MediaDownloaded: counterValue(MediaDownloaded),
+		DownloadErrors:  counterValue(DownloadErrors),
+	}
+}
+
+func counterValue(c prometheus.Counter) int {
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		return 0
+	}
+	return int(m.GetCounter().GetValue())
+}
+
+// Finish stamps the end time, fills in the counters accumulated since start
+// was taken and totals the per-depth channel counts.
+func (s *CrawlSummary) Finish(start Totals) {
+	s.EndedAt = time.Now()
+
+	end := Snapshot()
+	s.PostsParsed = end.PostsParsed - start.PostsParsed
+	s.MediaDownloaded = end.MediaDownloaded - start.MediaDownloaded
+	s.DownloadErrors = end.DownloadErrors - start.DownloadErrors
+
+	s.ChannelsCrawled, s.ChannelErrors = 0, 0
+	for _, depth := range s.Depths {
+		s.ChannelsCrawled += depth.Fetched
+		s.ChannelErrors += depth.Errors
+	}
+}
+
+// SummaryPath returns where the summary of a crawl is written: path if set,
+// otherwise crawl_<crawlID>_summary.json in the crawl's storage directory.
+func SummaryPath(path, storageRoot, crawlID string) string {
+	if path != "" {
+		return path
+	}
+	return filepath.Join(storageRoot, crawlID, fmt.Sprintf("crawl_%s_summary.json", crawlID))
+}
+
+// WriteSummary writes summary to path as indented JSON, creating the parent
+// directory if needed. The file is replaced atomically so readers never see a
+// partial summary.
+func WriteSummary(path string, summary CrawlSummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal crawl summary: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create crawl summary directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write crawl summary: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write crawl summary: %w", err)
+	}
+	return nil
+}
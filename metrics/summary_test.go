@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSummary_CountsThisCrawlOnly(t *testing.T) {
+	// Counters are process-wide; activity before the crawl started must not be counted
+	PostsParsed.Inc()
+	start := Snapshot()
+
+	summary := CrawlSummary{CrawlID: "crawl-1", ExecutionID: "exec-1", StartedAt: time.Now()}
+	PostsParsed.Add(3)
+	MediaDownloaded.Add(2)
+	DownloadErrors.Inc()
+	summary.Depths = []DepthSummary{
+		{Depth: 0, Pages: 2, Fetched: 2},
+		{Depth: 1, Pages: 5, Fetched: 3, Skipped: 1, Errors: 1},
+	}
+	summary.Finish(start)
+
+	path := SummaryPath("", t.TempDir(), "crawl-1")
+	assert.Equal(t, "crawl_crawl-1_summary.json", filepath.Base(path))
+	require.NoError(t, WriteSummary(path, summary))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var parsed map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &parsed))
+
+	assert.Equal(t, "crawl-1", parsed["crawl_id"])
+	assert.Equal(t, "exec-1", parsed["execution_id"])
+	assert.Equal(t, float64(5), parsed["channels_crawled"])
+	assert.Equal(t, float64(1), parsed["channel_errors"])
+	assert.Equal(t, float64(3), parsed["posts_parsed"])
+	assert.Equal(t, float64(2), parsed["media_downloaded"])
+	assert.Equal(t, float64(1), parsed["download_errors"])
+	assert.NotEmpty(t, parsed["started_at"])
+	assert.NotEmpty(t, parsed["ended_at"])
+
+	depths, ok := parsed["depths"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, depths, 2)
+	assert.Equal(t, map[string]interface{}{
+		"depth": float64(1), "pages": float64(5), "fetched": float64(3), "skipped": float64(1), "errors": float64(1),
+	}, depths[1])
+}
+
+func TestSummaryPath_Override(t *testing.T) {
+	assert.Equal(t, "/tmp/summary.json", SummaryPath("/tmp/summary.json", "/data", "crawl-1"))
+}
@@ -2,7 +2,10 @@
 // in a standardized format for storage and processing.
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Post represents a complete Telegram or YouTube post with all associated metadata.
 // This struct is used for storing and exporting post data in a standardized format.
@@ -12,7 +15,9 @@ type Post struct {
 	PostUID                 string            `json:"post_uid"`
 	URL                     string            `json:"url"`
 	PublishedAt             time.Time         `json:"published_at"`
-	CreatedAt               time.Time         `json:"created_at"`
+	CreatedAt               time.Time         `json:"created_at"` // Last edit time, or PublishedAt for posts never edited
+	EditedAt                *time.Time        `json:"edited_at"`  // Time of the last edit; nil for posts never edited
+	WasEdited               bool              `json:"was_edited"`
 	LanguageCode            string            `json:"language_code"`
 	Engagement              int               `json:"engagement"`
 	ViewCount               int               `json:"view_count"`
@@ -56,10 +61,6 @@ type Post struct {
 	MediaData               MediaData         `json:"media_data"`
 	IsReply                 *bool             `json:"is_reply"`
 	AdFields                *string           `json:"ad_fields"`
-	LikesCount              int               `json:"likes_count"`
-	SharesCount             int               `json:"shares_count"`
-	CommentsCount           int               `json:"comments_count"`
-	ViewsCount              int               `json:"views_count"`
 	SearchableText          string            `json:"searchable_text"`
 	AllText                 string            `json:"all_text"`
 	ContrastAgentProjectIDs []interface{}     `json:"contrast_agent_project_ids"`
@@ -70,18 +71,255 @@ type Post struct {
 	Comments                []Comment         `json:"comments"`
 	Reactions               map[string]int    `json:"reactions"`
 	Outlinks                []string          `json:"outlinks"`
+	DiscoveredChannels      []string          `json:"-"` // Channels the discovery strategy picked to crawl next; not stored
 	CaptureTime             time.Time         `json:"capture_time"`
 	Handle                  string            `json:"handle"`
+	AlbumID                 string            `json:"album_id"`
+	AlbumItems              []MediaItem       `json:"album_items"` // Every message of the album, in order, when albums are grouped into one post
+	SenderID                string            `json:"sender_id"`
+	SenderFlags             *SenderFlags      `json:"sender_flags"`
+	MediaStorageKeys        []string          `json:"media_storage_keys"` // Canonical storage keys of the post's media, shared with duplicates
+	PaidMedia               *PaidMediaData    `json:"paid_media"`
+	LiveEvent               *LiveEventData    `json:"live_event"`
+	ServiceEvent            *ServiceEventData `json:"service_event"`  // Set for channel service messages such as pins and title changes
+	ForwardedFrom           *ForwardedFrom    `json:"forwarded_from"` // Origin of a forwarded post; nil for original posts
+	MediaErrors             []string          `json:"media_errors"`   // Download or upload failures for the post's media; empty if complete
+	Latitude                *float64          `json:"latitude"`       // Set for location and venue posts
+	Longitude               *float64          `json:"longitude"`      // Set for location and venue posts
+	LivePeriod              int               `json:"live_period"`    // Seconds a live location is shared for; 0 for static locations
+	VenueName               string            `json:"venue_name"`
+	VenueAddress            string            `json:"venue_address"`
+	Audio                   *AudioData        `json:"audio"` // Set for voice note and audio posts
+	Video                   *VideoData        `json:"video"` // Set for video posts
+	PollData                *PollData         `json:"poll_data"`
+	ReplyToMessageID        int64             `json:"reply_to_message_id"` // TDLib ID of the message this post replies to; 0 if not a reply
+	ReplyToChatID           int64             `json:"reply_to_chat_id"`    // Chat of the replied-to message when it is in another chat; 0 otherwise
+	TopicID                 int64             `json:"topic_id"`            // Message thread ID of the forum topic the post was made in; 0 outside forum topics
+	TopicName               string            `json:"topic_name"`          // Name of the forum topic; empty if it couldn't be looked up
+	URLs                    []string          `json:"urls"`                // Links marked up in the text, with hyperlinks resolved to their href
+	Mentions                []string          `json:"mentions"`            // @mentioned usernames, without the @
+	Hashtags                []string          `json:"hashtags"`            // Hashtags, without the #
+	Contact                 *ContactData      `json:"contact"`             // Set for shared contact cards
+	Dice                    *DiceData         `json:"dice"`                // Set for animated dice, darts and similar throws
+	Game                    *GameData         `json:"game"`                // Set for game posts
+	TranscriptLanguage      string            `json:"transcript_language"` // ISO-639-1 code of the language spoken in the transcribed media
+	FormattedText           string            `json:"formatted_text"`      // Description with its bold, link, spoiler etc. formatting as Markdown or HTML; empty unless enabled
 }
+
+// ViewsCount returns ViewCount. The plural count names are read-through
+// aliases of the canonical ViewCount, LikeCount, ShareCount and CommentCount
+// fields, so they can't disagree with them.
+func (p Post) ViewsCount() int { return p.ViewCount }
+
+// LikesCount returns LikeCount.
+func (p Post) LikesCount() int { return p.LikeCount }
+
+// SharesCount returns ShareCount.
+func (p Post) SharesCount() int { return p.ShareCount }
+
+// CommentsCount returns CommentCount.
+func (p Post) CommentsCount() int { return p.CommentCount }
+
+// MarshalJSON encodes the post with the views_count, likes_count, shares_count
+// and comments_count keys next to the canonical counts, as consumers of the
+// JSON output still read them.
+func (p Post) MarshalJSON() ([]byte, error) {
+	type post Post // Has no MarshalJSON, so encoding it doesn't recurse
+	return json.Marshal(struct {
+		post
+		LikesCount    int `json:"likes_count"`
+		SharesCount   int `json:"shares_count"`
+		CommentsCount int `json:"comments_count"`
+		ViewsCount    int `json:"views_count"`
+	}{
+		post:          post(p),
+		LikesCount:    p.LikeCount,
+		SharesCount:   p.ShareCount,
+		CommentsCount: p.CommentCount,
+		ViewsCount:    p.ViewCount,
+	})
+}
+
+// DiceData records an animated emoji throw. Value is 0 while the throw has no
+// final result yet.
+type DiceData struct {
+	Emoji string `json:"emoji"`
+	Value int    `json:"value"`
+}
+
+// GameData describes a game shared in a Telegram post.
+type GameData struct {
+	ShortName   string `json:"short_name"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Text        string `json:"text"` // Game text, usually a scoreboard
+}
+
+// ContactData describes a contact card shared in a Telegram post. UserID is
+// empty when the contact is not linked to a Telegram account.
+type ContactData struct {
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	PhoneNumber string `json:"phone_number"`
+	UserID      string `json:"user_id"`
+}
+
+// PollData holds the options and results of a Telegram poll or quiz.
+type PollData struct {
+	Question             string       `json:"question"`
+	Options              []PollOption `json:"options"`
+	TotalVoterCount      int          `json:"total_voter_count"`
+	IsAnonymous          bool         `json:"is_anonymous"`
+	IsClosed             bool         `json:"is_closed"`
+	IsQuiz               bool         `json:"is_quiz"`
+	AllowMultipleAnswers bool         `json:"allow_multiple_answers"` // Regular polls only
+	CorrectOptionIndex   *int         `json:"correct_option_index"`   // Quizzes only, when known to the crawling account
+}
+
+// PollOption is a single answer of a poll with its vote count.
+type PollOption struct {
+	Text           string `json:"text"`
+	VoterCount     int    `json:"voter_count"`
+	VotePercentage int    `json:"vote_percentage"`
+}
+
+// Audio kinds recorded in AudioData.Kind.
+const (
+	AudioKindVoiceNote = "voice_note" // Voice message recorded in the Telegram app
+	AudioKindAudio     = "audio"      // Uploaded audio file such as music or a podcast
+)
+
+// AudioData describes the audio attached to a voice note or audio post.
+// Performer, Title and FileName are only available for audio files.
+type AudioData struct {
+	Kind            string `json:"kind"`
+	DurationSeconds int    `json:"duration_seconds"`
+	MimeType        string `json:"mime_type"`
+	Performer       string `json:"performer"`
+	Title           string `json:"title"`
+	FileName        string `json:"file_name"`
+}
+
+// VideoData describes the video of a video post. FileSize is in bytes, or the
+// expected size while TDLib doesn't know the exact size yet.
+type VideoData struct {
+	DurationSeconds int    `json:"duration_seconds"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	FileName        string `json:"file_name"`
+	FileSize        int64  `json:"file_size"`
+	MimeType        string `json:"mime_type"`
+}
+
+// MediaItem is one message of a media album that was stored as a single post.
+type MediaItem struct {
+	MessageID        int64      `json:"message_id"`
+	PostLink         string     `json:"post_link"`
+	PostType         []string   `json:"post_type"`
+	Description      string     `json:"description"` // Caption of this item
+	ThumbURL         string     `json:"thumb_url"`
+	MediaURL         string     `json:"media_url"`
+	MediaStorageKeys []string   `json:"media_storage_keys"`
+	MediaErrors      []string   `json:"media_errors"`
+	Video            *VideoData `json:"video"`
+}
+
+// Forward origin types recorded in ForwardedFrom.OriginType.
+const (
+	ForwardOriginChannel    = "channel"     // Forwarded from a post in a channel
+	ForwardOriginUser       = "user"        // Forwarded from a message sent by a known user
+	ForwardOriginHiddenUser = "hidden_user" // Forwarded from a user who hides their account in forwards
+	ForwardOriginChat       = "chat"        // Forwarded from a message sent on behalf of a chat
+)
+
+// ForwardedFrom describes where a forwarded Telegram post was originally published.
+// Only the fields relevant to the origin type are populated.
+type ForwardedFrom struct {
+	OriginType      string    `json:"origin_type"`
+	ChannelID       string    `json:"channel_id"`  // Original channel or chat ID, for channel and chat origins
+	MessageID       int64     `json:"message_id"`  // Original message ID, for channel origins
+	SenderID        string    `json:"sender_id"`   // Original sender's user ID, for user origins
+	SenderName      string    `json:"sender_name"` // Display name, for hidden user origins
+	AuthorSignature string    `json:"author_signature"`
+	OriginalDate    time.Time `json:"original_date"`
+}
+
+// Live event types recorded in LiveEventData.Event.
+const (
+	LiveEventScheduled = "video_chat_scheduled" // A video chat or live stream was scheduled
+	LiveEventStarted   = "video_chat_started"   // A video chat or live stream started
+	LiveEventEnded     = "video_chat_ended"     // A video chat or live stream ended
+	LiveEventInvite    = "video_chat_invite"    // Users were invited to a running video chat
+)
+
+// LiveEventData describes a video chat / live stream lifecycle event taken from
+// a Telegram service message. OccurredAt is the time of the service message.
+type LiveEventData struct {
+	Event           string     `json:"event"`
+	GroupCallID     int32      `json:"group_call_id"`
+	OccurredAt      time.Time  `json:"occurred_at"`
+	ScheduledStart  *time.Time `json:"scheduled_start"`  // Announced start time, only for scheduled events
+	DurationSeconds int        `json:"duration_seconds"` // Length of the call, only for ended events
+	InvitedCount    int        `json:"invited_count"`    // Number of invited users, only for invite events
+}
+
+// Service event types recorded in ServiceEventData.Event.
+const (
+	ServiceEventPinMessage    = "pin_message"     // A message was pinned
+	ServiceEventAddMembers    = "add_members"     // Users were added to the chat
+	ServiceEventDeleteMember  = "delete_member"   // A user left or was removed from the chat
+	ServiceEventJoinByLink    = "join_by_link"    // A user joined through an invite link
+	ServiceEventJoinByRequest = "join_by_request" // A user's request to join was approved
+	ServiceEventChangeTitle   = "change_title"    // The chat title was changed
+	ServiceEventChangePhoto   = "change_photo"    // The chat photo was changed
+	ServiceEventDeletePhoto   = "delete_photo"    // The chat photo was removed
+)
+
+// ServiceEventData describes a channel governance event taken from a Telegram
+// service message. ActorID is the sender of the service message and OccurredAt
+// its time.
+type ServiceEventData struct {
+	Event      string    `json:"event"`
+	ActorID    string    `json:"actor_id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	MessageID  int64     `json:"message_id,omitempty"` // Pinned message, only for pin events
+	UserIDs    []string  `json:"user_ids,omitempty"`   // Added or removed users, only for member events
+	Title      string    `json:"title,omitempty"`      // New chat title, only for title changes
+}
+
+// PaidMediaData describes media that is sold for Telegram Stars. Locked items
+// are only visible as low-resolution previews unless they have been purchased.
+type PaidMediaData struct {
+	StarCount   int64    `json:"star_count"`   // Price in Telegram Stars to unlock the media
+	ItemCount   int      `json:"item_count"`   // Total number of media items in the message
+	LockedCount int      `json:"locked_count"` // Number of items only available as a preview
+	IsPaywalled bool     `json:"is_paywalled"` // True if any of the full media is behind the paywall
+	PreviewKeys []string `json:"preview_keys"` // Storage keys of the downloaded preview thumbnails
+}
+
 // Comment represents a single comment on a Telegram post, including
 // its text content, reaction counts, and metadata.
 type Comment struct {
-	Text       string         `json:"text"`
-	Reactions  map[string]int `json:"reactions"`
-	ViewCount  int            `json:"view_count"`
-	ReplyCount int            `json:"reply_count"`
-	Handle     string         `json:"handle"`
+	Text        string         `json:"text"`
+	Reactions   map[string]int `json:"reactions"`
+	ViewCount   int            `json:"view_count"`
+	ReplyCount  int            `json:"reply_count"`
+	Handle      string         `json:"handle"`
+	SenderID    string         `json:"sender_id"`
+	MessageID   int64          `json:"message_id"`
+	ParentID    int64          `json:"parent_id"` // MessageID of the comment this one replies to; 0 for top-level comments
+	SenderFlags *SenderFlags   `json:"sender_flags"`
+}
+
+// SenderFlags holds account badges of the user who sent a post or comment.
+// It is only populated when sender flag capture is enabled in the crawler config.
+type SenderFlags struct {
+	IsPremium  bool `json:"is_premium"`
+	IsVerified bool `json:"is_verified"`
+	IsScam     bool `json:"is_scam"`
+	IsFake     bool `json:"is_fake"`
 }
+
 // ChannelData contains information about a Telegram or YouTube channel, including
 // its identifying information, engagement metrics, and URLs.
 type ChannelData struct {
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPostMarshalJSON_WritesCountAliases(t *testing.T) {
+	post := validPost()
+	post.LikeCount = 3
+	post.ShareCount = 4
+	post.CommentCount = 5
+
+	data, err := json.Marshal(post)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	pairs := map[string]string{
+		"view_count":    "views_count",
+		"like_count":    "likes_count",
+		"share_count":   "shares_count",
+		"comment_count": "comments_count",
+	}
+	for canonical, alias := range pairs {
+		if record[canonical] != record[alias] {
+			t.Errorf("Expected %s to match %s, got %v and %v", alias, canonical, record[alias], record[canonical])
+		}
+	}
+	if record["view_count"] != float64(10) || record["comment_count"] != float64(5) {
+		t.Errorf("Expected the post's counts, got %v views and %v comments", record["view_count"], record["comment_count"])
+	}
+	if record["post_uid"] != "42-channel" {
+		t.Errorf("Expected the other fields to be kept, got post_uid %v", record["post_uid"])
+	}
+
+	var decoded Post
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal into Post failed: %v", err)
+	}
+	if decoded.ShareCount != 4 || decoded.SharesCount() != 4 {
+		t.Errorf("Expected the share count to round-trip, got %d", decoded.ShareCount)
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPost is wrapped by the error Post.Validate returns.
+var ErrInvalidPost = errors.New("invalid post")
+
+// maxClockSkew is how far in the future of its capture time a post may be
+// published before its timestamp is considered bogus.
+const maxClockSkew = 24 * time.Hour
+
+// Validate checks that the post has the fields downstream consumers rely on
+// and that its counts, timestamps and coordinates are in range. Every problem
+// found is listed in the returned error, which wraps ErrInvalidPost.
+func (p Post) Validate() error {
+	var problems []string
+	if strings.TrimSpace(p.PostUID) == "" {
+		problems = append(problems, "post_uid is empty")
+	}
+	if p.ChannelID == "" || p.ChannelID == "0" {
+		problems = append(problems, "channel_id is missing")
+	}
+	if p.PublishedAt.IsZero() || p.PublishedAt.Unix() <= 0 {
+		problems = append(problems, "published_at is missing")
+	} else if !p.CaptureTime.IsZero() && p.PublishedAt.After(p.CaptureTime.Add(maxClockSkew)) {
+		problems = append(problems, fmt.Sprintf("published_at %s is after capture_time %s", p.PublishedAt.Format(time.RFC3339), p.CaptureTime.Format(time.RFC3339)))
+	}
+	if p.CaptureTime.IsZero() {
+		problems = append(problems, "capture_time is missing")
+	}
+	if p.EditedAt != nil && !p.PublishedAt.IsZero() && p.EditedAt.Before(p.PublishedAt) {
+		problems = append(problems, "edited_at is before published_at")
+	}
+
+	counts := []struct {
+		name  string
+		value int
+	}{
+		{"view_count", p.ViewCount},
+		{"like_count", p.LikeCount},
+		{"share_count", p.ShareCount},
+		{"comment_count", p.CommentCount},
+	}
+	for _, count := range counts {
+		if count.value < 0 {
+			problems = append(problems, fmt.Sprintf("%s is negative (%d)", count.name, count.value))
+		}
+	}
+
+	if p.Latitude != nil && (*p.Latitude < -90 || *p.Latitude > 90) {
+		problems = append(problems, fmt.Sprintf("latitude %v is out of range", *p.Latitude))
+	}
+	if p.Longitude != nil && (*p.Longitude < -180 || *p.Longitude > 180) {
+		problems = append(problems, fmt.Sprintf("longitude %v is out of range", *p.Longitude))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w %q: %s", ErrInvalidPost, p.PostUID, strings.Join(problems, "; "))
+}
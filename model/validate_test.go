@@ -0,0 +1,73 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validPost() Post {
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return Post{
+		PostUID:     "42-channel",
+		ChannelID:   "-1001",
+		PublishedAt: published,
+		CreatedAt:   published,
+		CaptureTime: published.Add(time.Hour),
+		ViewCount:   10,
+	}
+}
+
+func TestPostValidate_Valid(t *testing.T) {
+	if err := validPost().Validate(); err != nil {
+		t.Errorf("Expected a valid post, got %v", err)
+	}
+}
+
+func TestPostValidate_Invalid(t *testing.T) {
+	before := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	north := 91.0
+
+	tests := []struct {
+		name   string
+		modify func(p *Post)
+		want   string
+	}{
+		{"empty uid", func(p *Post) { p.PostUID = " " }, "post_uid is empty"},
+		{"missing channel", func(p *Post) { p.ChannelID = "" }, "channel_id is missing"},
+		{"zero channel", func(p *Post) { p.ChannelID = "0" }, "channel_id is missing"},
+		{"zero published time", func(p *Post) { p.PublishedAt = time.Time{} }, "published_at is missing"},
+		{"epoch published time", func(p *Post) { p.PublishedAt = time.Unix(0, 0) }, "published_at is missing"},
+		{"published in the future", func(p *Post) { p.PublishedAt = p.CaptureTime.Add(48 * time.Hour) }, "is after capture_time"},
+		{"missing capture time", func(p *Post) { p.CaptureTime = time.Time{} }, "capture_time is missing"},
+		{"edited before published", func(p *Post) { p.EditedAt = &before }, "edited_at is before published_at"},
+		{"negative views", func(p *Post) { p.ViewCount = -1 }, "view_count is negative"},
+		{"latitude out of range", func(p *Post) { p.Latitude = &north }, "latitude 91 is out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := validPost()
+			tt.modify(&post)
+			err := post.Validate()
+			if !errors.Is(err, ErrInvalidPost) {
+				t.Fatalf("Expected ErrInvalidPost, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected %q in %q", tt.want, err.Error())
+			}
+		})
+	}
+}
+
+func TestPostValidate_ListsEveryProblem(t *testing.T) {
+	err := Post{LikeCount: -3}.Validate()
+	if err == nil {
+		t.Fatal("Expected an error for an empty post")
+	}
+	for _, want := range []string{"post_uid", "channel_id", "published_at", "capture_time", "like_count"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %q", want, err.Error())
+		}
+	}
+}
@@ -21,18 +21,37 @@ type YouTubeChannel struct {
 
 // YouTubeVideo represents a YouTube video
 type YouTubeVideo struct {
-	ID           string
-	ChannelID    string
-	Title        string
-	Description  string
-	PublishedAt  time.Time
-	ViewCount    int64
-	LikeCount    int64
-	CommentCount int64
-	Duration     string
-	Thumbnails   map[string]string
-	Tags         []string
-	Language     string           // Default language of the video
+	ID            string
+	ChannelID     string
+	Title         string
+	Description   string
+	PublishedAt   time.Time
+	ViewCount     int64
+	LikeCount     int64
+	CommentCount  int64
+	Duration      string
+	Thumbnails    map[string]string
+	Tags          []string
+	Language      string // Default language of the video
+
+	// Whether the API reported ViewCount, LikeCount and CommentCount. YouTube
+	// hides single counts of some videos, which then read 0.
+	HasViewCount    bool
+	HasLikeCount    bool
+	HasCommentCount bool
+
+	Comments []YouTubeComment // Top-level comments, only fetched when comment retrieval is enabled
+}
+
+// YouTubeComment represents a top-level comment on a YouTube video
+type YouTubeComment struct {
+	ID              string
+	AuthorName      string
+	AuthorChannelID string
+	Text            string
+	LikeCount       int64
+	ReplyCount      int64
+	PublishedAt     time.Time
 }
 
 // YouTubeClient defines the methods needed for YouTube API operations
@@ -57,4 +76,7 @@ type YouTubeClient interface {
 	
 	// GetSnowballVideos retrieves videos using snowball sampling from channels with > 10 videos
 	GetSnowballVideos(ctx context.Context, seedChannelIDs []string, fromTime, toTime time.Time, limit int) ([]*YouTubeVideo, error)
+	
+	// GetVideoComments retrieves up to limit top-level comments on a video
+	GetVideoComments(ctx context.Context, videoID string, limit int) ([]YouTubeComment, error)
 }
\ No newline at end of file
@@ -283,6 +283,7 @@ func (o *Orchestrator) createWorkItem(page *state.Page) distributed.WorkItem {
 		Concurrency:       o.config.Concurrency,
 		Timeout:           o.config.Timeout,
 		MinPostDate:       o.config.MinPostDate,
+		MaxPostDate:       o.config.MaxPostDate,
 		PostRecency:       o.config.PostRecency,
 		DateBetweenMin:    o.config.DateBetweenMin,
 		DateBetweenMax:    o.config.DateBetweenMax,
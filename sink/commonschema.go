@@ -0,0 +1,192 @@
+// Package sink provides output writers that emit crawled posts in formats other
+// than the native model.Post JSON written by the state managers.
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+)
+
+// Fields of the common social-media schema. Every record written by a
+// CommonSchemaWriter contains exactly these fields:
+//
+//	id         unique post identifier (model.Post.PostUID)
+//	author     display name or handle of the poster
+//	author_id  platform identifier of the poster
+//	text       post text or caption
+//	timestamp  publication time in RFC 3339 format (UTC)
+//	platform   source platform, e.g. "Telegram" or "YouTube"
+//	channel    name of the channel the post was published in
+//	url        public link to the post
+//	engagement engagement count reported for the post (model.Post.Engagement)
+//	parent     identifier of the message this one replies to
+//	           (model.Post.ReplyToMessageID, as "<chat id>:<message id>" when
+//	           the message is in another chat), empty if none
+const (
+	FieldID         = "id"
+	FieldAuthor     = "author"
+	FieldAuthorID   = "author_id"
+	FieldText       = "text"
+	FieldTimestamp  = "timestamp"
+	FieldPlatform   = "platform"
+	FieldChannel    = "channel"
+	FieldURL        = "url"
+	FieldEngagement = "engagement"
+	FieldParent     = "parent"
+)
+
+// commonSchemaFields is the ordered list of fields in the common schema.
+var commonSchemaFields = []string{
+	FieldID, FieldAuthor, FieldAuthorID, FieldText, FieldTimestamp,
+	FieldPlatform, FieldChannel, FieldURL, FieldEngagement, FieldParent,
+}
+
+// CommonSchemaMapping renames common-schema fields in the output. Keys are
+// common-schema field names and values are the output keys to use instead.
+// Fields that are not mapped keep their common-schema name.
+type CommonSchemaMapping map[string]string
+
+// Validate checks that the mapping only references known fields and that no
+// two fields end up with the same output key.
+func (m CommonSchemaMapping) Validate() error {
+	known := make(map[string]bool, len(commonSchemaFields))
+	for _, f := range commonSchemaFields {
+		known[f] = true
+	}
+
+	// Report problems in a deterministic order
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, field := range keys {
+		if !known[field] {
+			return fmt.Errorf("unknown common schema field %q", field)
+		}
+		if m[field] == "" {
+			return fmt.Errorf("empty output key for common schema field %q", field)
+		}
+	}
+
+	seen := make(map[string]string, len(commonSchemaFields))
+	for _, field := range commonSchemaFields {
+		key := m.outputKey(field)
+		if other, exists := seen[key]; exists {
+			return fmt.Errorf("common schema fields %q and %q both map to output key %q", other, field, key)
+		}
+		seen[key] = field
+	}
+	return nil
+}
+
+// outputKey returns the output key configured for a common-schema field.
+func (m CommonSchemaMapping) outputKey(field string) string {
+	if key, ok := m[field]; ok && key != "" {
+		return key
+	}
+	return field
+}
+
+// Transform maps a post into a common-schema record using the configured output keys.
+func (m CommonSchemaMapping) Transform(post model.Post) map[string]interface{} {
+	parent := ""
+	switch {
+	case post.RepliedID != nil:
+		parent = *post.RepliedID
+	case post.ReplyToMessageID != 0 && post.ReplyToChatID != 0:
+		parent = fmt.Sprintf("%d:%d", post.ReplyToChatID, post.ReplyToMessageID)
+	case post.ReplyToMessageID != 0:
+		parent = strconv.FormatInt(post.ReplyToMessageID, 10)
+	}
+
+	values := map[string]interface{}{
+		FieldID:         post.PostUID,
+		FieldAuthor:     post.Handle,
+		FieldAuthorID:   post.SenderID,
+		FieldText:       post.Description,
+		FieldTimestamp:  post.PublishedAt.UTC().Format(time.RFC3339),
+		FieldPlatform:   post.PlatformName,
+		FieldChannel:    post.ChannelName,
+		FieldURL:        post.URL,
+		FieldEngagement: post.Engagement,
+		FieldParent:     parent,
+	}
+
+	record := make(map[string]interface{}, len(values))
+	for field, value := range values {
+		record[m.outputKey(field)] = value
+	}
+	return record
+}
+
+// CommonSchemaWriter writes posts as newline-delimited JSON records in the
+// common schema. It is safe for concurrent use.
+type CommonSchemaWriter struct {
+	mu      sync.Mutex
+	out     RecordWriter
+	mapping CommonSchemaMapping
+}
+
+// NewCommonSchemaWriter creates a writer that emits common-schema records to out.
+func NewCommonSchemaWriter(out io.WriteCloser, mapping CommonSchemaMapping) (*CommonSchemaWriter, error) {
+	return newCommonSchemaWriter(streamWriter{out}, mapping)
+}
+
+// NewCommonSchemaFileWriter creates a writer that appends common-schema records
+// to the file at path, rolling over to numbered files according to limits.
+func NewCommonSchemaFileWriter(path string, mapping CommonSchemaMapping, limits RollingLimits) (*CommonSchemaWriter, error) {
+	file, err := NewRollingFile(path, limits, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open common schema output: %w", err)
+	}
+	writer, err := newCommonSchemaWriter(file, mapping)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+// newCommonSchemaWriter validates mapping and creates a writer that emits
+// common-schema records to out.
+func newCommonSchemaWriter(out RecordWriter, mapping CommonSchemaMapping) (*CommonSchemaWriter, error) {
+	if err := mapping.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid common schema mapping: %w", err)
+	}
+	return &CommonSchemaWriter{
+		out:     out,
+		mapping: mapping,
+	}, nil
+}
+
+// Write transforms the post and writes it as a single JSON line.
+func (c *CommonSchemaWriter) Write(post model.Post) error {
+	data, err := json.Marshal(c.mapping.Transform(post))
+	if err != nil {
+		return fmt.Errorf("failed to marshal common schema record: %w", err)
+	}
+	data = append(data, '\n')
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.out.WriteRecord(data); err != nil {
+		return fmt.Errorf("failed to write common schema record: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying output.
+func (c *CommonSchemaWriter) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.out.Close()
+}
@@ -0,0 +1,82 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonSchemaMapping_Validate(t *testing.T) {
+	assert.NoError(t, CommonSchemaMapping{}.Validate())
+	assert.NoError(t, CommonSchemaMapping{FieldAuthor: "user", FieldText: "body"}.Validate())
+
+	assert.Error(t, CommonSchemaMapping{"unknown": "x"}.Validate(), "Unknown fields should be rejected")
+	assert.Error(t, CommonSchemaMapping{FieldAuthor: ""}.Validate(), "Empty output keys should be rejected")
+	assert.Error(t, CommonSchemaMapping{FieldAuthor: FieldText}.Validate(), "Colliding output keys should be rejected")
+}
+
+func TestCommonSchemaWriter(t *testing.T) {
+	parent := "41-channel"
+	post := model.Post{
+		PostUID:      "42-channel",
+		Handle:       "alice",
+		SenderID:     "1001",
+		Description:  "hello, world",
+		PublishedAt:  time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		PlatformName: "Telegram",
+		ChannelName:  "Channel",
+		URL:          "https://t.me/channel/42",
+		Engagement:   150,
+		RepliedID:    &parent,
+	}
+
+	path := filepath.Join(t.TempDir(), "common", "out.jsonl")
+	w, err := NewCommonSchemaFileWriter(path, CommonSchemaMapping{FieldAuthor: "user", FieldText: "body"}, RollingLimits{})
+	require.NoError(t, err)
+	require.NoError(t, w.Write(post))
+	require.NoError(t, w.Write(post))
+	require.NoError(t, w.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+
+		assert.Equal(t, "alice", record["user"])
+		assert.Equal(t, "hello, world", record["body"])
+		assert.NotContains(t, record, FieldAuthor, "Renamed fields should not keep their common name")
+		assert.Equal(t, "42-channel", record[FieldID])
+		assert.Equal(t, "1001", record[FieldAuthorID])
+		assert.Equal(t, "2024-03-01T12:00:00Z", record[FieldTimestamp])
+		assert.Equal(t, "Telegram", record[FieldPlatform])
+		assert.Equal(t, float64(150), record[FieldEngagement])
+		assert.Equal(t, "41-channel", record[FieldParent])
+	}
+	assert.Equal(t, 2, lines)
+}
+
+func TestCommonSchemaMapping_TransformParent(t *testing.T) {
+	mapping := CommonSchemaMapping{}
+	assert.Equal(t, "", mapping.Transform(model.Post{})[FieldParent], "A post that is not a reply has no parent")
+	assert.Equal(t, "2097152", mapping.Transform(model.Post{ReplyToMessageID: 2097152})[FieldParent])
+	assert.Equal(t, "-1002:3145728", mapping.Transform(model.Post{ReplyToMessageID: 3145728, ReplyToChatID: -1002})[FieldParent],
+		"Replies to another chat should name the chat")
+}
+
+func TestNewCommonSchemaWriter_InvalidMapping(t *testing.T) {
+	_, err := NewCommonSchemaFileWriter(filepath.Join(t.TempDir(), "out.jsonl"), CommonSchemaMapping{"bogus": "x"}, RollingLimits{})
+	assert.Error(t, err)
+}
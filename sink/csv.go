@@ -0,0 +1,168 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+)
+
+// csvListSeparator joins the values of list fields within a single CSV cell.
+const csvListSeparator = "|"
+
+// CSVColumns is the header of every CSV written by a CSVSink, in column order.
+// The set of columns is stable: new columns are only ever appended.
+//
+// Nested fields are flattened. reactions holds the per-emoji counts as a JSON
+// object and reactions_total their sum; comments_collected is the number of
+// comments crawled for the post, as opposed to comment_count reported by the
+// platform. List fields join their values with "|".
+var CSVColumns = []string{
+	"post_uid", "post_link", "url", "platform_name", "channel_id", "channel_name",
+	"handle", "sender_id", "published_at", "capture_time", "post_type",
+	"description", "language_code", "view_count", "like_count", "share_count",
+	"comment_count", "engagement", "reactions_total", "reactions",
+	"comments_collected", "media_url", "thumb_url", "media_storage_keys",
+	"outlinks", "urls", "mentions", "hashtags", "album_id", "reply_to_message_id",
+}
+
+// csvRow flattens a post into the values of CSVColumns.
+func csvRow(post model.Post) ([]string, error) {
+	reactionsTotal := 0
+	for _, count := range post.Reactions {
+		reactionsTotal += count
+	}
+	reactions := ""
+	if len(post.Reactions) > 0 {
+		data, err := json.Marshal(post.Reactions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal reactions: %w", err)
+		}
+		reactions = string(data)
+	}
+
+	return []string{
+		post.PostUID,
+		post.PostLink,
+		post.URL,
+		post.PlatformName,
+		post.ChannelID,
+		post.ChannelName,
+		post.Handle,
+		post.SenderID,
+		csvTime(post.PublishedAt),
+		csvTime(post.CaptureTime),
+		strings.Join(post.PostType, csvListSeparator),
+		post.Description,
+		post.LanguageCode,
+		strconv.Itoa(post.ViewCount),
+		strconv.Itoa(post.LikeCount),
+		strconv.Itoa(post.ShareCount),
+		strconv.Itoa(post.CommentCount),
+		strconv.Itoa(post.Engagement),
+		strconv.Itoa(reactionsTotal),
+		reactions,
+		strconv.Itoa(len(post.Comments)),
+		post.MediaURL,
+		post.ThumbURL,
+		strings.Join(post.MediaStorageKeys, csvListSeparator),
+		strings.Join(post.Outlinks, csvListSeparator),
+		strings.Join(post.URLs, csvListSeparator),
+		strings.Join(post.Mentions, csvListSeparator),
+		strings.Join(post.Hashtags, csvListSeparator),
+		post.AlbumID,
+		strconv.FormatInt(post.ReplyToMessageID, 10),
+	}, nil
+}
+
+// csvTime formats t as RFC 3339 in UTC, or an empty string for the zero time.
+func csvTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// encodeCSVRecord encodes one CSV record, quoting values that contain commas,
+// quotes or newlines.
+func encodeCSVRecord(values []string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(values); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CSVSink writes posts as flat CSV rows with the columns in CSVColumns. It is
+// safe for concurrent use.
+type CSVSink struct {
+	mu  sync.Mutex
+	out RecordWriter
+}
+
+// NewCSVSink creates a sink that writes the header and then one row per post
+// to w, e.g. os.Stdout. Closing the sink does not close w.
+func NewCSVSink(w io.Writer) (*CSVSink, error) {
+	header, err := encodeCSVRecord(CSVColumns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CSV header: %w", err)
+	}
+	out := streamWriter{nopCloser{w}}
+	if err := out.WriteRecord(header); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	return &CSVSink{out: out}, nil
+}
+
+// NewCSVFileSink creates a sink that appends to the file at path, rolling over
+// to numbered files according to limits. Every file starts with the header.
+func NewCSVFileSink(path string, limits RollingLimits) (*CSVSink, error) {
+	header, err := encodeCSVRecord(CSVColumns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CSV header: %w", err)
+	}
+	file, err := NewRollingFile(path, limits, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV output: %w", err)
+	}
+	return &CSVSink{out: file}, nil
+}
+
+// Write flattens the post and writes it as a single CSV record.
+func (s *CSVSink) Write(ctx context.Context, post model.Post) error {
+	row, err := csvRow(post)
+	if err != nil {
+		return err
+	}
+	data, err := encodeCSVRecord(row)
+	if err != nil {
+		return fmt.Errorf("failed to encode post as CSV: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.out.WriteRecord(data); err != nil {
+		return fmt.Errorf("failed to write post: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying output.
+func (s *CSVSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.out.Close()
+}
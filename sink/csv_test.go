@@ -0,0 +1,99 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readCSV(t *testing.T, data []byte) []map[string]string {
+	t.Helper()
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	require.NotEmpty(t, records)
+	require.Equal(t, CSVColumns, records[0])
+
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(record))
+		for i, value := range record {
+			row[records[0][i]] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestCSVSink_RoundTrip(t *testing.T) {
+	published := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	posts := []model.Post{
+		{
+			PostUID:      "1-channel",
+			ChannelName:  "Channel",
+			PublishedAt:  published,
+			Description:  "first line, with a comma\nsecond \"quoted\" line",
+			ViewCount:    120,
+			CommentCount: 7,
+			Reactions:    map[string]int{"👍": 3, "❤": 2},
+			Comments:     []model.Comment{{Text: "a"}, {Text: "b"}},
+			MediaURL:     "https://example.com/media.jpg",
+			Outlinks:     []string{"a.example", "b.example"},
+		},
+		{PostUID: "2-channel", ChannelName: "Channel"},
+	}
+
+	var out bytes.Buffer
+	s, err := NewCSVSink(&out)
+	require.NoError(t, err)
+	for _, post := range posts {
+		require.NoError(t, s.Write(context.Background(), post))
+	}
+	require.NoError(t, s.Close())
+
+	rows := readCSV(t, out.Bytes())
+	require.Len(t, rows, 2)
+
+	first := rows[0]
+	assert.Equal(t, "1-channel", first["post_uid"])
+	assert.Equal(t, posts[0].Description, first["description"])
+	assert.Equal(t, "2024-03-01T12:30:00Z", first["published_at"])
+	assert.Equal(t, "120", first["view_count"])
+	assert.Equal(t, "7", first["comment_count"])
+	assert.Equal(t, "5", first["reactions_total"])
+	assert.JSONEq(t, `{"👍": 3, "❤": 2}`, first["reactions"])
+	assert.Equal(t, "2", first["comments_collected"])
+	assert.Equal(t, "https://example.com/media.jpg", first["media_url"])
+	assert.Equal(t, "a.example|b.example", first["outlinks"])
+
+	second := rows[1]
+	assert.Equal(t, "2-channel", second["post_uid"])
+	assert.Empty(t, second["published_at"])
+	assert.Empty(t, second["reactions"])
+	assert.Equal(t, "0", second["reactions_total"])
+}
+
+func TestCSVFileSink_HeaderInEveryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.csv")
+	s, err := NewCSVFileSink(path, RollingLimits{MaxRecords: 1})
+	require.NoError(t, err)
+	for _, uid := range []string{"1-channel", "2-channel"} {
+		require.NoError(t, s.Write(context.Background(), model.Post{PostUID: uid}))
+	}
+	require.NoError(t, s.Close())
+
+	for file, uid := range map[string]string{"posts-00001.csv": "1-channel", "posts-00002.csv": "2-channel"} {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(path), file))
+		require.NoError(t, err)
+		rows := readCSV(t, data)
+		require.Len(t, rows, 1)
+		assert.Equal(t, uid, rows[0]["post_uid"])
+	}
+}
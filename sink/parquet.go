@@ -0,0 +1,348 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+)
+
+// Parquet partitioning schemes. Partitions are Hive-style directories, e.g.
+// channel=<name>/ or date=2024-03-01/, so query engines can prune them.
+const (
+	ParquetPartitionByChannel = "channel" // One partition per channel name
+	ParquetPartitionByDate    = "date"    // One partition per crawl (capture) date in UTC
+)
+
+// DefaultParquetRowGroupSize is the number of posts buffered per row group
+// when no size is configured.
+const DefaultParquetRowGroupSize = 10000
+
+// ParquetOptions configures a ParquetSink.
+type ParquetOptions struct {
+	PartitionBy  string // ParquetPartitionByChannel (default) or ParquetPartitionByDate
+	RowGroupSize int    // Posts buffered before a row group is written (0 = DefaultParquetRowGroupSize)
+}
+
+// Validate checks the partitioning scheme and row-group size.
+func (o ParquetOptions) Validate() error {
+	switch o.PartitionBy {
+	case "", ParquetPartitionByChannel, ParquetPartitionByDate:
+	default:
+		return fmt.Errorf("unknown parquet partitioning %q, must be %q or %q", o.PartitionBy, ParquetPartitionByChannel, ParquetPartitionByDate)
+	}
+	if o.RowGroupSize < 0 {
+		return fmt.Errorf("parquet row group size must not be negative, got %d", o.RowGroupSize)
+	}
+	return nil
+}
+
+// ParquetPost is the columnar schema of the Parquet output. It mirrors
+// model.Post, leaving out the untyped label and project fields, which are
+// not populated by the crawler.
+type ParquetPost struct {
+	PostUID          string           `parquet:"post_uid"`
+	PostLink         string           `parquet:"post_link"`
+	URL              string           `parquet:"url"`
+	PlatformName     string           `parquet:"platform_name"`
+	ChannelID        string           `parquet:"channel_id"`
+	ChannelName      string           `parquet:"channel_name"`
+	Handle           string           `parquet:"handle"`
+	SenderID         string           `parquet:"sender_id"`
+	PublishedAt      time.Time        `parquet:"published_at,timestamp(millisecond)"`
+	CaptureTime      time.Time        `parquet:"capture_time,timestamp(millisecond)"`
+	PostType         []string         `parquet:"post_type,list"`
+	Description      string           `parquet:"description"`
+	LanguageCode     string           `parquet:"language_code"`
+	ViewCount        int64            `parquet:"view_count"`
+	LikeCount        int64            `parquet:"like_count"`
+	ShareCount       int64            `parquet:"share_count"`
+	CommentCount     int64            `parquet:"comment_count"`
+	Engagement       int64            `parquet:"engagement"`
+	Reactions        map[string]int64 `parquet:"reactions"`
+	Comments         []ParquetComment `parquet:"comments,list"`
+	MediaURL         string           `parquet:"media_url"`
+	ThumbURL         string           `parquet:"thumb_url"`
+	MediaStorageKeys []string         `parquet:"media_storage_keys,list"`
+	Outlinks         []string         `parquet:"outlinks,list"`
+	URLs             []string         `parquet:"urls,list"`
+	Mentions         []string         `parquet:"mentions,list"`
+	Hashtags         []string         `parquet:"hashtags,list"`
+	AlbumID          string           `parquet:"album_id"`
+	ReplyToMessageID int64            `parquet:"reply_to_message_id"`
+	ReplyToChatID    int64            `parquet:"reply_to_chat_id"`
+}
+
+// ParquetComment is the columnar schema of a comment nested in a ParquetPost.
+type ParquetComment struct {
+	MessageID  int64            `parquet:"message_id"`
+	ParentID   int64            `parquet:"parent_id"`
+	Text       string           `parquet:"text"`
+	Handle     string           `parquet:"handle"`
+	SenderID   string           `parquet:"sender_id"`
+	ViewCount  int64            `parquet:"view_count"`
+	ReplyCount int64            `parquet:"reply_count"`
+	Reactions  map[string]int64 `parquet:"reactions"`
+}
+
+// NewParquetPost converts a post to its Parquet row.
+func NewParquetPost(post model.Post) ParquetPost {
+	comments := make([]ParquetComment, 0, len(post.Comments))
+	for _, c := range post.Comments {
+		comments = append(comments, ParquetComment{
+			MessageID:  c.MessageID,
+			ParentID:   c.ParentID,
+			Text:       c.Text,
+			Handle:     c.Handle,
+			SenderID:   c.SenderID,
+			ViewCount:  int64(c.ViewCount),
+			ReplyCount: int64(c.ReplyCount),
+			Reactions:  parquetCounts(c.Reactions),
+		})
+	}
+
+	return ParquetPost{
+		PostUID:          post.PostUID,
+		PostLink:         post.PostLink,
+		URL:              post.URL,
+		PlatformName:     post.PlatformName,
+		ChannelID:        post.ChannelID,
+		ChannelName:      post.ChannelName,
+		Handle:           post.Handle,
+		SenderID:         post.SenderID,
+		PublishedAt:      post.PublishedAt.UTC(),
+		CaptureTime:      post.CaptureTime.UTC(),
+		PostType:         post.PostType,
+		Description:      post.Description,
+		LanguageCode:     post.LanguageCode,
+		ViewCount:        int64(post.ViewCount),
+		LikeCount:        int64(post.LikeCount),
+		ShareCount:       int64(post.ShareCount),
+		CommentCount:     int64(post.CommentCount),
+		Engagement:       int64(post.Engagement),
+		Reactions:        parquetCounts(post.Reactions),
+		Comments:         comments,
+		MediaURL:         post.MediaURL,
+		ThumbURL:         post.ThumbURL,
+		MediaStorageKeys: post.MediaStorageKeys,
+		Outlinks:         post.Outlinks,
+		URLs:             post.URLs,
+		Mentions:         post.Mentions,
+		Hashtags:         post.Hashtags,
+		AlbumID:          post.AlbumID,
+		ReplyToMessageID: post.ReplyToMessageID,
+		ReplyToChatID:    post.ReplyToChatID,
+	}
+}
+
+func parquetCounts(counts map[string]int) map[string]int64 {
+	if len(counts) == 0 {
+		return nil
+	}
+	out := make(map[string]int64, len(counts))
+	for k, v := range counts {
+		out[k] = int64(v)
+	}
+	return out
+}
+
+// parquetPartition is the open output file of one partition.
+type parquetPartition struct {
+	file   *os.File
+	writer *parquet.GenericWriter[ParquetPost]
+	buffer []ParquetPost
+}
+
+// flush writes the buffered posts as one row group.
+func (p *parquetPartition) flush() error {
+	if len(p.buffer) == 0 {
+		return nil
+	}
+	if _, err := p.writer.Write(p.buffer); err != nil {
+		return fmt.Errorf("failed to write parquet rows to %s: %w", p.file.Name(), err)
+	}
+	if err := p.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write parquet row group to %s: %w", p.file.Name(), err)
+	}
+	p.buffer = p.buffer[:0]
+	return nil
+}
+
+// close flushes the remaining posts, writes the footer and closes the file.
+func (p *parquetPartition) close() error {
+	err := p.flush()
+	if closeErr := p.writer.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to finish parquet file %s: %w", p.file.Name(), closeErr)
+	}
+	if closeErr := p.file.Close(); closeErr != nil && err == nil {
+		err = fmt.Errorf("failed to close parquet file %s: %w", p.file.Name(), closeErr)
+	}
+	return err
+}
+
+// ParquetSink writes posts as Parquet files partitioned by channel or crawl
+// date under a directory. Posts are buffered per partition and written a row
+// group at a time; Close writes the remaining posts and the file footers, so
+// the files are only readable once the sink is closed. When partitioning by
+// channel, FinishChannel does the same for the partitions of one crawled
+// channel. It is safe for concurrent use.
+//
+// Each partition gets a new file per sink, e.g.
+// <dir>/channel=news/part-00001.parquet; files from earlier runs are kept.
+type ParquetSink struct {
+	mu         sync.Mutex
+	dir        string
+	opts       ParquetOptions
+	partitions map[string]*parquetPartition
+	byChannel  map[string]map[string]bool // Partitions written per crawled channel (see WithChannel)
+	closed     bool
+}
+
+// NewParquetSink creates a sink that writes partitioned Parquet files under dir.
+func NewParquetSink(dir string, opts ParquetOptions) (*ParquetSink, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.PartitionBy == "" {
+		opts.PartitionBy = ParquetPartitionByChannel
+	}
+	if opts.RowGroupSize == 0 {
+		opts.RowGroupSize = DefaultParquetRowGroupSize
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create parquet output directory: %w", err)
+	}
+	return &ParquetSink{
+		dir:        dir,
+		opts:       opts,
+		partitions: make(map[string]*parquetPartition),
+		byChannel:  make(map[string]map[string]bool),
+	}, nil
+}
+
+// partitionDir returns the Hive-style partition directory of a post.
+func (s *ParquetSink) partitionDir(post model.Post) string {
+	if s.opts.PartitionBy == ParquetPartitionByDate {
+		day := post.CaptureTime
+		if day.IsZero() {
+			day = post.PublishedAt
+		}
+		return "date=" + day.UTC().Format("2006-01-02")
+	}
+
+	channel := post.ChannelName
+	if channel == "" {
+		channel = post.ChannelID
+	}
+	if channel == "" {
+		channel = "unknown"
+	}
+	return "channel=" + url.PathEscape(channel)
+}
+
+// openPartition opens a new file in the partition directory, skipping part
+// numbers already taken by earlier runs.
+func (s *ParquetSink) openPartition(name string) (*parquetPartition, error) {
+	dir := filepath.Join(s.dir, name)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create parquet partition directory: %w", err)
+	}
+
+	for i := 1; ; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("part-%05d.parquet", i))
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open parquet file: %w", err)
+		}
+		return &parquetPartition{
+			file:   file,
+			writer: parquet.NewGenericWriter[ParquetPost](file),
+			buffer: make([]ParquetPost, 0, s.opts.RowGroupSize),
+		}, nil
+	}
+}
+
+// Write buffers the post in its partition, writing a row group once the
+// partition holds RowGroupSize posts.
+func (s *ParquetSink) Write(ctx context.Context, post model.Post) error {
+	row := NewParquetPost(post)
+	name := s.partitionDir(post)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("parquet sink is closed")
+	}
+
+	partition, ok := s.partitions[name]
+	if !ok {
+		var err error
+		partition, err = s.openPartition(name)
+		if err != nil {
+			return err
+		}
+		s.partitions[name] = partition
+	}
+	if channel := ChannelFromContext(ctx); channel != "" && s.opts.PartitionBy == ParquetPartitionByChannel {
+		if s.byChannel[channel] == nil {
+			s.byChannel[channel] = make(map[string]bool)
+		}
+		s.byChannel[channel][name] = true
+	}
+
+	partition.buffer = append(partition.buffer, row)
+	if len(partition.buffer) >= s.opts.RowGroupSize {
+		return partition.flush()
+	}
+	return nil
+}
+
+// FinishChannel writes the buffered posts and finishes the files of the
+// partitions the channel's posts were written to. It does nothing when
+// partitioning by date, since those partitions are shared by all channels.
+// Posts written for the channel afterwards go to a new part file.
+func (s *ParquetSink) FinishChannel(channel string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for name := range s.byChannel[channel] {
+		if partition, ok := s.partitions[name]; ok {
+			if err := partition.close(); err != nil {
+				errs = append(errs, err)
+			}
+			delete(s.partitions, name)
+		}
+	}
+	delete(s.byChannel, channel)
+	return errors.Join(errs...)
+}
+
+// Close writes the buffered posts and finishes every partition file. Posts
+// written after Close are rejected.
+func (s *ParquetSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.byChannel = make(map[string]map[string]bool)
+	var errs []error
+	for name, partition := range s.partitions {
+		if err := partition.close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(s.partitions, name)
+	}
+	return errors.Join(errs...)
+}
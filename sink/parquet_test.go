@@ -0,0 +1,131 @@
+package sink
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParquetSink_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewParquetSink(dir, ParquetOptions{RowGroupSize: 2})
+	require.NoError(t, err)
+
+	published := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	posts := []model.Post{
+		{
+			PostUID:     "1-news",
+			ChannelName: "news",
+			PublishedAt: published,
+			Description: "first",
+			ViewCount:   120,
+			Reactions:   map[string]int{"👍": 3},
+			Comments:    []model.Comment{{MessageID: 10, Text: "reply", Reactions: map[string]int{"❤": 1}}},
+			Outlinks:    []string{"a.example"},
+		},
+		{PostUID: "2-news", ChannelName: "news", PublishedAt: published},
+		{PostUID: "3-news", ChannelName: "news", PublishedAt: published},
+		{PostUID: "1-sport", ChannelName: "sport", PublishedAt: published},
+	}
+	for _, post := range posts {
+		require.NoError(t, s.Write(context.Background(), post))
+	}
+	require.NoError(t, s.Close())
+
+	newsPath := filepath.Join(dir, "channel=news", "part-00001.parquet")
+	rows, err := parquet.ReadFile[ParquetPost](newsPath)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "1-news", rows[0].PostUID)
+	assert.Equal(t, "first", rows[0].Description)
+	assert.True(t, published.Equal(rows[0].PublishedAt))
+	assert.Equal(t, int64(120), rows[0].ViewCount)
+	assert.Equal(t, map[string]int64{"👍": 3}, rows[0].Reactions)
+	require.Len(t, rows[0].Comments, 1)
+	assert.Equal(t, "reply", rows[0].Comments[0].Text)
+	assert.Equal(t, map[string]int64{"❤": 1}, rows[0].Comments[0].Reactions)
+	assert.Equal(t, []string{"a.example"}, rows[0].Outlinks)
+	assert.Equal(t, "3-news", rows[2].PostUID)
+
+	// Three posts with a row group size of two make two row groups
+	f, err := os.Open(newsPath)
+	require.NoError(t, err)
+	defer f.Close()
+	info, err := f.Stat()
+	require.NoError(t, err)
+	file, err := parquet.OpenFile(f, info.Size())
+	require.NoError(t, err)
+	assert.Len(t, file.RowGroups(), 2)
+	var columns []string
+	for _, field := range file.Schema().Fields() {
+		columns = append(columns, field.Name())
+	}
+	assert.Subset(t, columns, []string{"post_uid", "channel_name", "published_at", "reactions", "comments"})
+
+	sportRows, err := parquet.ReadFile[ParquetPost](filepath.Join(dir, "channel=sport", "part-00001.parquet"))
+	require.NoError(t, err)
+	require.Len(t, sportRows, 1)
+	assert.Equal(t, "1-sport", sportRows[0].PostUID)
+}
+
+func TestParquetSink_PartitionByDate(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewParquetSink(dir, ParquetOptions{PartitionBy: ParquetPartitionByDate})
+	require.NoError(t, err)
+
+	captured := time.Date(2024, 3, 2, 23, 0, 0, 0, time.UTC)
+	require.NoError(t, s.Write(context.Background(), model.Post{PostUID: "1-news", ChannelName: "news", CaptureTime: captured}))
+	require.NoError(t, s.Close())
+
+	rows, err := parquet.ReadFile[ParquetPost](filepath.Join(dir, "date=2024-03-02", "part-00001.parquet"))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "1-news", rows[0].PostUID)
+
+	// A second sink on the same directory starts a new file rather than overwriting
+	s, err = NewParquetSink(dir, ParquetOptions{PartitionBy: ParquetPartitionByDate})
+	require.NoError(t, err)
+	require.NoError(t, s.Write(context.Background(), model.Post{PostUID: "2-news", ChannelName: "news", CaptureTime: captured}))
+	require.NoError(t, s.Close())
+	assert.FileExists(t, filepath.Join(dir, "date=2024-03-02", "part-00002.parquet"))
+}
+
+func TestParquetSink_FinishChannel(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewParquetSink(dir, ParquetOptions{})
+	require.NoError(t, err)
+
+	newsCtx := WithChannel(context.Background(), "news")
+	sportCtx := WithChannel(context.Background(), "sport")
+	require.NoError(t, s.Write(newsCtx, model.Post{PostUID: "1-news", ChannelName: "News Daily"}))
+	require.NoError(t, s.Write(sportCtx, model.Post{PostUID: "1-sport", ChannelName: "Sport"}))
+
+	// The finished channel's file is readable before the sink is closed
+	require.NoError(t, FinishChannel([]PostSink{s}, "news"))
+	rows, err := parquet.ReadFile[ParquetPost](filepath.Join(dir, "channel=News%20Daily", "part-00001.parquet"))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "1-news", rows[0].PostUID)
+
+	// The other channel's partition is still open
+	require.NoError(t, s.Write(sportCtx, model.Post{PostUID: "2-sport", ChannelName: "Sport"}))
+	require.NoError(t, s.Close())
+	rows, err = parquet.ReadFile[ParquetPost](filepath.Join(dir, "channel=Sport", "part-00001.parquet"))
+	require.NoError(t, err)
+	assert.Len(t, rows, 2)
+
+	assert.Error(t, s.Write(sportCtx, model.Post{PostUID: "3-sport", ChannelName: "Sport"}), "A closed sink should reject posts")
+}
+
+func TestParquetOptions_Validate(t *testing.T) {
+	assert.NoError(t, ParquetOptions{}.Validate())
+	assert.Error(t, ParquetOptions{PartitionBy: "hour"}.Validate())
+	assert.Error(t, ParquetOptions{RowGroupSize: -1}.Validate())
+}
@@ -0,0 +1,128 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+)
+
+// PostSink receives every post a crawler produces. Implementations must be
+// safe for concurrent use, since channels may be crawled in parallel.
+type PostSink interface {
+	Write(ctx context.Context, post model.Post) error
+}
+
+// PostSinkFunc adapts an ordinary function to the PostSink interface.
+type PostSinkFunc func(ctx context.Context, post model.Post) error
+
+// Write calls f(ctx, post).
+func (f PostSinkFunc) Write(ctx context.Context, post model.Post) error {
+	return f(ctx, post)
+}
+
+// WriteAll writes the post to every sink. A failing sink does not stop the
+// others; all errors are returned joined.
+func WriteAll(ctx context.Context, sinks []PostSink, post model.Post) error {
+	var errs []error
+	for _, s := range sinks {
+		if err := s.Write(ctx, post); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ChannelFinisher is implemented by sinks that keep output open per channel,
+// such as the Parquet sink, so they can finish it once the channel has been
+// crawled instead of holding it until the sink is closed.
+type ChannelFinisher interface {
+	FinishChannel(channel string) error
+}
+
+// FinishChannel tells every sink implementing ChannelFinisher that no more
+// posts of the channel will be written. All errors are returned joined.
+func FinishChannel(sinks []PostSink, channel string) error {
+	var errs []error
+	for _, s := range sinks {
+		if f, ok := s.(ChannelFinisher); ok {
+			if err := f.FinishChannel(channel); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type channelKey struct{}
+
+// WithChannel returns a context carrying the name of the channel the post
+// being written was crawled from. Sinks that key their output by channel,
+// such as the state manager, read it back with ChannelFromContext.
+func WithChannel(ctx context.Context, channel string) context.Context {
+	return context.WithValue(ctx, channelKey{}, channel)
+}
+
+// ChannelFromContext returns the channel name stored by WithChannel, or an
+// empty string if there is none.
+func ChannelFromContext(ctx context.Context) string {
+	channel, _ := ctx.Value(channelKey{}).(string)
+	return channel
+}
+
+// JSONLinesSink writes posts as newline-delimited JSON (one model.Post per
+// line). It is safe for concurrent use.
+type JSONLinesSink struct {
+	mu  sync.Mutex
+	out RecordWriter
+}
+
+// NewJSONLinesSink creates a sink that writes to w, e.g. os.Stdout. Closing
+// the sink does not close w.
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{out: streamWriter{nopCloser{w}}}
+}
+
+// NewJSONLinesFileSink creates a sink that appends to the file at path,
+// rolling over to numbered files according to limits.
+func NewJSONLinesFileSink(path string, limits RollingLimits) (*JSONLinesSink, error) {
+	file, err := NewRollingFile(path, limits, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open JSON lines output: %w", err)
+	}
+	return &JSONLinesSink{out: file}, nil
+}
+
+// Write marshals the post and writes it as a single JSON line.
+func (s *JSONLinesSink) Write(ctx context.Context, post model.Post) error {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return fmt.Errorf("failed to marshal post: %w", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.out.WriteRecord(data); err != nil {
+		return fmt.Errorf("failed to write post: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying output.
+func (s *JSONLinesSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.out.Close()
+}
+
+// nopCloser adds a no-op Close to an io.Writer the sink does not own.
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
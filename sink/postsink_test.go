@@ -0,0 +1,82 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readPosts(t *testing.T, data []byte) []model.Post {
+	t.Helper()
+	var posts []model.Post
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var post model.Post
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &post))
+		posts = append(posts, post)
+	}
+	return posts
+}
+
+func TestWriteAll_TwoSinks(t *testing.T) {
+	var stdout bytes.Buffer
+	streamSink := NewJSONLinesSink(&stdout)
+
+	path := filepath.Join(t.TempDir(), "posts", "out.jsonl")
+	fileSink, err := NewJSONLinesFileSink(path, RollingLimits{})
+	require.NoError(t, err)
+
+	sinks := []PostSink{streamSink, fileSink}
+	var wg sync.WaitGroup
+	for _, uid := range []string{"1-channel", "2-channel", "3-channel"} {
+		wg.Add(1)
+		go func(uid string) {
+			defer wg.Done()
+			assert.NoError(t, WriteAll(context.Background(), sinks, model.Post{PostUID: uid, ChannelName: "Channel"}))
+		}(uid)
+	}
+	wg.Wait()
+	require.NoError(t, streamSink.Close())
+	require.NoError(t, fileSink.Close())
+
+	fileData, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	for _, posts := range [][]model.Post{readPosts(t, stdout.Bytes()), readPosts(t, fileData)} {
+		uids := make([]string, 0, len(posts))
+		for _, post := range posts {
+			uids = append(uids, post.PostUID)
+			assert.Equal(t, "Channel", post.ChannelName)
+		}
+		assert.ElementsMatch(t, []string{"1-channel", "2-channel", "3-channel"}, uids)
+	}
+}
+
+func TestWriteAll_FailingSinkDoesNotStopOthers(t *testing.T) {
+	var buf bytes.Buffer
+	failure := errors.New("queue unavailable")
+	var gotChannel string
+	sinks := []PostSink{
+		PostSinkFunc(func(ctx context.Context, post model.Post) error {
+			gotChannel = ChannelFromContext(ctx)
+			return failure
+		}),
+		NewJSONLinesSink(&buf),
+	}
+
+	err := WriteAll(WithChannel(context.Background(), "channel"), sinks, model.Post{PostUID: "1-channel"})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, "channel", gotChannel)
+	assert.Len(t, readPosts(t, buf.Bytes()), 1, "The second sink should still receive the post")
+	assert.Empty(t, ChannelFromContext(context.Background()))
+}
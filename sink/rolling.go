@@ -0,0 +1,158 @@
+package sink
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RollingLimits controls when a RollingFile starts a new numbered file.
+// A zero value for either limit disables it; with both disabled all records
+// go to a single file.
+type RollingLimits struct {
+	MaxBytes   int64 // Roll before a record would push the current file past this size
+	MaxRecords int   // Roll after this many records have been written to the current file
+}
+
+// Enabled reports whether any rollover limit is configured.
+func (l RollingLimits) Enabled() bool {
+	return l.MaxBytes > 0 || l.MaxRecords > 0
+}
+
+// RecordWriter writes complete, self-contained records to an output.
+type RecordWriter interface {
+	WriteRecord(record []byte) error
+	Close() error
+}
+
+// RollingFile is a RecordWriter that splits its output over numbered files.
+// Records are never split across files and the optional header is written at
+// the start of every file, so each rolled file is independently valid.
+//
+// Without limits the output goes to basePath itself. With limits, files are
+// named after basePath with a five digit sequence number before the extension,
+// e.g. posts-00001.jsonl, posts-00002.jsonl.
+type RollingFile struct {
+	basePath string
+	limits   RollingLimits
+	header   []byte
+
+	file    *os.File
+	index   int
+	bytes   int64
+	records int
+}
+
+// NewRollingFile opens the first output file for basePath, creating parent
+// directories as needed.
+func NewRollingFile(basePath string, limits RollingLimits, header []byte) (*RollingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(basePath), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	r := &RollingFile{
+		basePath: basePath,
+		limits:   limits,
+		header:   header,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns the path of the file currently being written.
+func (r *RollingFile) Path() string {
+	if !r.limits.Enabled() {
+		return r.basePath
+	}
+	ext := filepath.Ext(r.basePath)
+	return fmt.Sprintf("%s-%05d%s", strings.TrimSuffix(r.basePath, ext), r.index, ext)
+}
+
+// open opens the next output file. When rolling is enabled, existing numbered
+// files (e.g. from an earlier run of the same crawl) are skipped.
+func (r *RollingFile) open() error {
+	if r.limits.Enabled() {
+		for {
+			r.index++
+			if _, err := os.Stat(r.Path()); os.IsNotExist(err) {
+				break
+			}
+		}
+	}
+
+	file, err := os.OpenFile(r.Path(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat output file: %w", err)
+	}
+
+	r.file = file
+	r.bytes = info.Size()
+	r.records = 0
+
+	if r.bytes == 0 && len(r.header) > 0 {
+		n, err := file.Write(r.header)
+		r.bytes += int64(n)
+		if err != nil {
+			return fmt.Errorf("failed to write header: %w", err)
+		}
+	}
+	return nil
+}
+
+// shouldRoll reports whether the next record of the given size belongs in a new file.
+func (r *RollingFile) shouldRoll(size int) bool {
+	if !r.limits.Enabled() || r.records == 0 {
+		return false
+	}
+	if r.limits.MaxRecords > 0 && r.records >= r.limits.MaxRecords {
+		return true
+	}
+	return r.limits.MaxBytes > 0 && r.bytes+int64(size) > r.limits.MaxBytes
+}
+
+// WriteRecord writes a complete record, rolling to a new file first if the
+// record would exceed the configured limits.
+func (r *RollingFile) WriteRecord(record []byte) error {
+	if r.shouldRoll(len(record)) {
+		if err := r.file.Close(); err != nil {
+			return fmt.Errorf("failed to close output file: %w", err)
+		}
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+
+	n, err := r.file.Write(record)
+	r.bytes += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	r.records++
+	return nil
+}
+
+// Close closes the current output file.
+func (r *RollingFile) Close() error {
+	return r.file.Close()
+}
+
+// streamWriter adapts an io.WriteCloser to the RecordWriter interface.
+type streamWriter struct {
+	io.WriteCloser
+}
+
+// WriteRecord writes the record to the underlying stream.
+func (s streamWriter) WriteRecord(record []byte) error {
+	_, err := s.Write(record)
+	return err
+}
@@ -0,0 +1,76 @@
+package sink
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollingFile_NoLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "posts.jsonl")
+	r, err := NewRollingFile(path, RollingLimits{}, nil)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, r.WriteRecord([]byte("{}\n")))
+	}
+	require.NoError(t, r.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 5, strings.Count(string(data), "\n"))
+}
+
+func TestRollingFile_MaxRecords(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRollingFile(filepath.Join(dir, "posts.csv"), RollingLimits{MaxRecords: 2}, []byte("id,text\n"))
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, r.WriteRecord([]byte("1,hello\n")))
+	}
+	require.NoError(t, r.Close())
+
+	for i, want := range []int{2, 2, 1} {
+		data, err := os.ReadFile(filepath.Join(dir, []string{"posts-00001.csv", "posts-00002.csv", "posts-00003.csv"}[i]))
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		assert.Equal(t, "id,text", lines[0], "Every rolled file should start with the header")
+		assert.Len(t, lines[1:], want)
+	}
+}
+
+func TestRollingFile_MaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRollingFile(filepath.Join(dir, "posts.jsonl"), RollingLimits{MaxBytes: 10}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, r.WriteRecord([]byte("aaaaaa\n")))
+	require.NoError(t, r.WriteRecord([]byte("bbbbbb\n"))) // Would exceed 10 bytes, so rolls
+	require.NoError(t, r.WriteRecord([]byte("a record longer than the limit\n")))
+	require.NoError(t, r.Close())
+
+	first, err := os.ReadFile(filepath.Join(dir, "posts-00001.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "aaaaaa\n", string(first))
+
+	second, err := os.ReadFile(filepath.Join(dir, "posts-00002.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "bbbbbb\n", string(second))
+
+	third, err := os.ReadFile(filepath.Join(dir, "posts-00003.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "a record longer than the limit\n", string(third), "Oversized records are written whole to their own file")
+}
+
+func TestRollingFile_SkipsExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "posts-00001.jsonl"), []byte("old\n"), 0644))
+
+	r, err := NewRollingFile(filepath.Join(dir, "posts.jsonl"), RollingLimits{MaxRecords: 1}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "posts-00002.jsonl"), r.Path())
+	require.NoError(t, r.Close())
+}
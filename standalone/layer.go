@@ -0,0 +1,136 @@
+package standalone
+
+import (
+	"context"
+	"sync"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/rs/zerolog/log"
+)
+
+// processLayer runs process for every page on a pool of concurrency workers fed
+// from a bounded channel. It returns only after all pages are done, so callers
+// can keep the crawl layer-by-layer. Once ctx is done no further pages are
+// started, but pages already being processed are finished.
+func processLayer(ctx context.Context, pages []state.Page, concurrency int, process func(page state.Page)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	queue := make(chan state.Page, concurrency)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range queue {
+				if ctx.Err() != nil {
+					continue
+				}
+				process(page)
+			}
+		}()
+	}
+
+enqueue:
+	for _, page := range pages {
+		select {
+		case queue <- page:
+		case <-ctx.Done():
+			break enqueue
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// discoveredPages deduplicates channels discovered by concurrent workers and
+// appends the new ones to the next layer, dropping channels rejected by the
+// channel filter or deeper than the depth cap. The seen set and the AddLayer
+// call share one mutex so two workers cannot queue the same URL.
+type discoveredPages struct {
+	mu             sync.Mutex
+	sm             state.StateManagementInterface
+	filter         *common.ChannelFilter
+	maxDepth       int // Pages deeper than this are not queued (<= 0 = no cap)
+	droppedByDepth int
+	seen           map[string]bool
+}
+
+// newDiscoveredPages creates a tracker that treats seeds as already seen.
+func newDiscoveredPages(sm state.StateManagementInterface, seeds []string, filter *common.ChannelFilter, maxDepth int) *discoveredPages {
+	d := &discoveredPages{sm: sm, filter: filter, maxDepth: maxDepth, seen: make(map[string]bool)}
+	d.markSeen(seeds)
+	return d
+}
+
+// markSeen records urls without queueing them, e.g. the pages of a layer
+// restored from state.
+func (d *discoveredPages) markSeen(urls []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, url := range urls {
+		d.seen[url] = true
+	}
+}
+
+// markQueued records the pages already queued at depth, e.g. by an earlier run
+// of the same crawl that was interrupted while building that layer, so
+// rediscovering them does not count them as added again.
+func (d *discoveredPages) markQueued(depth int) error {
+	pages, err := d.sm.GetLayerByDepth(depth)
+	if err != nil {
+		return err
+	}
+	urls := make([]string, 0, len(pages))
+	for _, page := range pages {
+		urls = append(urls, page.URL)
+	}
+	d.markSeen(urls)
+	return nil
+}
+
+// add queues the pages whose URL has not been seen yet and returns how many
+// were added.
+func (d *discoveredPages) add(pages []*state.Page) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	newPages := make([]state.Page, 0, len(pages))
+	for _, page := range pages {
+		if d.seen[page.URL] {
+			continue
+		}
+		d.seen[page.URL] = true
+		if !d.filter.Allows(page.URL) {
+			log.Debug().Str("url", page.URL).Msg("Discovered channel rejected by channel filter")
+			continue
+		}
+		if d.maxDepth > 0 && page.Depth > d.maxDepth {
+			// Recorded as seen, but the crawl does not go this deep
+			d.droppedByDepth++
+			continue
+		}
+		newPages = append(newPages, *page)
+	}
+	if len(newPages) == 0 {
+		return 0, nil
+	}
+	if err := d.sm.AddLayer(newPages); err != nil {
+		// Forget the URLs so a later discovery can queue them again
+		for _, page := range newPages {
+			delete(d.seen, page.URL)
+		}
+		return 0, err
+	}
+	return len(newPages), nil
+}
+
+// dropped returns how many discovered outlinks were not queued because they
+// were past the depth cap.
+func (d *discoveredPages) dropped() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.droppedByDepth
+}
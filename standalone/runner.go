@@ -10,16 +10,18 @@ import (
 	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
 	crawlercommon "github.com/researchaccelerator-hub/telegram-scraper/crawler/common"
 	"github.com/researchaccelerator-hub/telegram-scraper/crawler/youtube"
+	"github.com/researchaccelerator-hub/telegram-scraper/metrics"
 	youtubemodel "github.com/researchaccelerator-hub/telegram-scraper/model/youtube"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
 	"github.com/researchaccelerator-hub/telegram-scraper/state"
 	"github.com/researchaccelerator-hub/telegram-scraper/telegramhelper"
-	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/zelenin/go-tdlib/client"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -195,22 +197,41 @@ func generatePCode() {
 // progress is saved after each item is processed. The function ensures that all items are processed successfully, and
 // handles any panics that occur during item processing.
 //
+// Once crawlCfg.MaxRuntime has elapsed no further pages are started; pages
+// already in flight are finished and the crawl is left incomplete so it can be
+// resumed.
+//
 // Parameters:
 //   - stringList: A slice of strings representing the items to be processed.
 //   - crawlCfg: A CrawlerConfig struct containing configuration settings for the crawler.
 func launch(stringList []string, crawlCfg common.CrawlerConfig) {
+	runCtx, cancelRun := common.WithMaxRuntime(context.Background(), crawlCfg.MaxRuntime)
+	defer cancelRun()
+	
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	
 	// Store the state manager in a package-level variable so signal handler can access it
 	var shutdownSM state.StateManagementInterface
+	// The post outputs are closed on shutdown as well, since Parquet files are
+	// unreadable until their footer is written
+	var shutdownMu sync.Mutex
+	var shutdownSinks func()
 	
 	// Start a goroutine to handle shutdown signals
 	go func() {
 		sig := <-sigChan
 		log.Warn().Str("signal", sig.String()).Msg("Received shutdown signal, performing graceful shutdown")
 		
+		shutdownMu.Lock()
+		closeSinks := shutdownSinks
+		shutdownMu.Unlock()
+		if closeSinks != nil {
+			log.Info().Msg("Closing post outputs during signal-triggered shutdown")
+			closeSinks()
+		}
+		
 		// If we have a state manager, close it to save any pending data
 		if shutdownSM != nil {
 			log.Info().Msg("Saving media cache and state during signal-triggered shutdown")
@@ -243,6 +264,8 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		},
 	}
 
+	applyStorageBackend(&tempCfg, crawlCfg)
+
 	// Create a temporary state manager to look for incomplete crawls
 	log.Info().Msgf("Checking for incomplete crawls with ID: %s", crawlCfg.CrawlID)
 	tempSM, err := smfact.Create(tempCfg)
@@ -274,7 +297,7 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 
 	// If no existing crawl was found or there was an error, generate a new execution ID
 	if crawlexecid == "" {
-		crawlexecid = common.GenerateCrawlID()
+		crawlexecid = common.GenerateCrawlIDWithOptions(crawlCfg.CrawlIDFormat)
 		log.Info().Msgf("Starting new crawl execution: %s", crawlexecid)
 	}
 	
@@ -286,6 +309,10 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 	}
 	log.Info().Bool("is_resuming_same_execution", isResumingSameCrawlExecution).Msg("Crawl execution mode")
 
+	// Counters in the summary only cover this execution
+	summary := metrics.CrawlSummary{CrawlID: crawlCfg.CrawlID, ExecutionID: crawlexecid, StartedAt: time.Now()}
+	startTotals := metrics.Snapshot()
+
 	// Create the actual state manager with the determined execution ID
 	cfg := state.Config{
 		StorageRoot:      crawlCfg.StorageRoot,
@@ -303,8 +330,13 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		MaxPagesConfig: &state.MaxPagesConfig{
 			MaxPages: crawlCfg.MaxPages,
 		},
+
+		// Roll the native posts output like the file post sinks
+		OutputLimits: sink.RollingLimits{MaxBytes: crawlCfg.MaxOutputFileBytes, MaxRecords: crawlCfg.MaxRecordsPerFile},
 	}
 
+	applyStorageBackend(&cfg, crawlCfg)
+
 	sm, err := smfact.Create(cfg)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to load progress")
@@ -313,7 +345,6 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 	
 	// Store reference to state manager for signal handler
 	shutdownSM = sm
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 
 	// Initialize with seed URLs if this is a new crawl
 	// If resuming an existing crawl, this is a no-op 
@@ -324,6 +355,44 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		return
 	}
 
+	// Open the common schema output alongside the native post storage if requested
+	if crawlCfg.OutputFormat == common.OutputFormatCommon {
+		commonPath := filepath.Join(crawlCfg.StorageRoot, crawlCfg.CrawlID, "common", crawlexecid+".jsonl")
+		limits := sink.RollingLimits{MaxBytes: crawlCfg.MaxOutputFileBytes, MaxRecords: crawlCfg.MaxRecordsPerFile}
+		commonWriter, err := sink.NewCommonSchemaFileWriter(commonPath, crawlCfg.CommonSchemaMapping, limits)
+		if err != nil {
+			log.Error().Err(err).Str("path", commonPath).Msg("Failed to open common schema output")
+			return
+		}
+		defer commonWriter.Close()
+		crawlCfg.CommonSchemaOutput = commonWriter
+		log.Info().Str("path", commonPath).Msg("Writing common schema output")
+	}
+
+	// Open the configured post outputs; ParseMessage falls back to the state manager without them
+	postSinks, closeSinks, err := state.OpenPostSinks(crawlCfg.OutputSinks, sm, sink.RollingLimits{MaxBytes: crawlCfg.MaxOutputFileBytes, MaxRecords: crawlCfg.MaxRecordsPerFile}, crawlCfg.ParquetOptions)
+	if err != nil {
+		log.Error().Err(err).Strs("sinks", crawlCfg.OutputSinks).Msg("Failed to open post outputs")
+		return
+	}
+	defer closeSinks()
+	shutdownMu.Lock()
+	shutdownSinks = closeSinks
+	shutdownMu.Unlock()
+	crawlCfg.PostSinks = postSinks
+
+	if crawlCfg.MetricsPort > 0 {
+		metricsServer, _, err := metrics.StartServer(crawlCfg.MetricsPort)
+		if err != nil {
+			log.Error().Err(err).Int("port", crawlCfg.MetricsPort).Msg("Failed to start metrics server")
+		} else {
+			defer metricsServer.Close()
+		}
+	}
+
+	// Clear media left behind by an earlier run before any page is crawled
+	telegramhelper.PurgeMediaDownloadDir(crawlCfg)
+
 	// Initialize connection pool with an appropriate size
 	poolSize := crawlCfg.Concurrency
 	if poolSize < 1 {
@@ -341,7 +410,7 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 	}
 
 	// Platform-specific initialization
-	var connect crawler.TDLibClient
+	var sessions *telegramhelper.SessionPool
 	var ytClient clientpkg.Client
 	var ytCrawler crawler.Crawler
 	
@@ -425,6 +494,9 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 			"client": ytModelClient,
 			"state_manager": sm,
 			"crawl_label": crawlCfg.CrawlLabel, // Pass the crawl label to be added to posts
+			"crawler_config": map[string]interface{}{
+				"comment_limit": crawlCfg.YouTubeComments,
+			},
 		}
 		
 		err = ytCrawler.Initialize(clientCtx, crawlerConfig)
@@ -436,17 +508,17 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		log.Info().Msg("YouTube crawler components initialized successfully")
 	} else {
 		// Telegram platform initialization (default)
-		// Initialize the connection pool
-		crawl.InitConnectionPool(poolSize, crawlCfg.StorageRoot, crawlCfg)
-		defer crawl.CloseConnectionPool()
-	
-		// Create a single non-pooled connection for backward compatibility
-		var connectErr error
-		connect, connectErr = crawl.Connect(crawlCfg.StorageRoot, crawlCfg)
-		if connectErr != nil {
-			log.Error().Err(connectErr).Msg("Failed to create Telegram connection")
+		// Give each worker its own TDLib session so pages can be crawled concurrently
+		var sessionErr error
+		sessions, sessionErr = telegramhelper.NewSessionPool(&telegramhelper.RealTelegramService{}, poolSize, crawlCfg.StorageRoot, crawlCfg)
+		if sessionErr != nil {
+			log.Error().Err(sessionErr).Msg("Failed to create Telegram sessions")
 			return
 		}
+		defer sessions.Close()
+
+		// Only as many pages as there are sessions can be crawled at once
+		poolSize = sessions.Size()
 	}
 	
 	// Process layers sequentially starting from depth 0
@@ -464,6 +536,15 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 	// Track overall statistics
 	var totalPagesProcessed, totalPagesSkipped, totalPagesSuccess, totalPagesError int
 	
+	// Discovered channels are deduplicated across all layers and workers
+	discovered := newDiscoveredPages(sm, stringList, crawlCfg.ChannelFilter, crawlCfg.MaxDepth)
+	droppedByDepth := 0
+	
+	// Finished pages are saved in batches rather than after every page
+	saver := state.NewSaveBatcher(sm, crawlCfg.StateSavePages, crawlCfg.StateSaveInterval)
+	defer saver.Close()
+	
+	stoppedEarly := false
 	for currentDepth <= maxDepthConfig {
 		// Fetch the current layer of pages
 		currentLayer, err := sm.GetLayerByDepth(currentDepth)
@@ -480,6 +561,15 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		
 		log.Info().Msgf("Processing layer at depth %d with %d pages", currentDepth, len(currentLayer))
 		
+		layerURLs := make([]string, 0, len(currentLayer))
+		for _, page := range currentLayer {
+			layerURLs = append(layerURLs, page.URL)
+		}
+		discovered.markSeen(layerURLs)
+		if err := discovered.markQueued(currentDepth + 1); err != nil {
+			log.Warn().Err(err).Int("depth", currentDepth+1).Msg("Failed to read pages already queued for the next layer")
+		}
+		
 		// Print all page statuses before processing
 		log.Info().Int("page_count", len(currentLayer)).Int("depth", currentDepth).Msg("Page status summary before processing")
 		pageStatusCount := make(map[string]int)
@@ -540,7 +630,12 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		}
 		
 		// Process from the first page that needs processing
-		log.Info().Int("starting_index", startIndex).Int("total_pages", len(currentLayer)).Msg("Starting processing from index")
+		log.Info().Int("starting_index", startIndex).Int("total_pages", len(currentLayer)).Int("workers", poolSize).Msg("Starting processing from index")
+		
+		// Pages in a layer are crawled by poolSize workers; the next layer starts
+		// only after every page in this one is done
+		var statsMu sync.Mutex
+		pending := make([]state.Page, 0, len(currentLayer)-startIndex)
 		
 		for i := startIndex; i < len(currentLayer); i++ {
 			la := currentLayer[i]
@@ -561,166 +656,190 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 				}
 			}
 			
+			// Seeds and restored pages are filtered here; discovered ones before they are queued
+			if !crawlCfg.ChannelFilter.Allows(la.URL) {
+				log.Info().Str("url", la.URL).Msg("Skipping channel rejected by channel filter")
+				layerSkipped++
+				totalPagesSkipped++
+				continue
+			}
+			
+			if la.Status == state.PageStatusQuarantined && !crawlCfg.RetryQuarantined {
+				log.Info().Str("url", la.URL).Int("error_count", la.ErrorCount).Msg("Skipping quarantined page")
+				layerSkipped++
+				totalPagesSkipped++
+				continue
+			}
+			
 			if la.Status == "processing" {
 				log.Info().Str("url", la.URL).Msg("Found page in 'processing' state - will retry")
 				// Continue to process it
 			}
 			
-			// Process this page in a self-contained function to handle panics
-			func() {
-				defer func() {
-					if r := recover(); r != nil {
-						log.Error().Msgf("Recovered from panic while processing item: %s, error: %v", la.URL, r)
-						la.Status = "error" // Mark as error so we can retry later
-						layerError++
-						totalPagesError++
-						
-						// Make sure we save the state even after a panic
-						saveErr := sm.SaveState()
-						if saveErr != nil {
-							log.Error().Err(saveErr).Msg("Failed to save state after panic")
-						}
+			pending = append(pending, la)
+		}
+		metrics.PendingPages.Set(float64(len(pending)))
+		
+		processLayer(runCtx, pending, poolSize, func(la state.Page) {
+			defer metrics.PendingPages.Dec()
+			// Recover per page so one panic does not stop the worker
+			defer func() {
+				if r := recover(); r != nil {
+					log.Error().Msgf("Recovered from panic while processing item: %s, error: %v", la.URL, r)
+					recordPageError(sm, &la, fmt.Errorf("Panic: %v", r), crawlCfg.QuarantineThreshold)
+					statsMu.Lock()
+					layerError++
+					totalPagesError++
+					statsMu.Unlock()
+					
+					// Make sure the state is saved even after a panic
+					if saveErr := saver.PageDone(); saveErr != nil {
+						log.Error().Err(saveErr).Msg("Failed to save state after panic")
 					}
-				}()
-
-				// Update page status and timestamp before processing
-				la.Timestamp = time.Now()
-				la.Status = "processing" // Mark as in-progress
-				
-				// Save state before processing to record that we're working on this page
-				saveErr := sm.SaveState()
-				if saveErr != nil {
-					log.Warn().Err(saveErr).Str("url", la.URL).Msg("Failed to save state before processing page")
 				}
-				
-				// Try to use the connection pool
-				var discoveredChannels []*state.Page
-				var runErr error
+			}()
 
-				log.Info().Msgf("Processing page: %s", la.URL)
+			// Update page status and timestamp before processing
+			la.Timestamp = time.Now()
+			la.Status = "processing" // Mark as in-progress
+			
+			// Try to use the connection pool
+			var discoveredChannels []*state.Page
+			var runErr error
+
+			log.Info().Msgf("Processing page: %s", la.URL)
 
-				// Create context for operations
-				ctx := context.Background()
+			// Create context for operations
+			ctx := context.Background()
+			
+			// Process based on selected platform
+			if crawlCfg.Platform == "youtube" {
+				log.Info().Str("url", la.URL).Msg("Processing YouTube channel")
 				
-				// Process based on selected platform
-				if crawlCfg.Platform == "youtube" {
-					log.Info().Str("url", la.URL).Msg("Processing YouTube channel")
+				// Create a crawl target for the YouTube channel
+				target := crawler.CrawlTarget{
+					Type: crawler.PlatformYouTube,
+					ID:   la.URL, // YouTube channel ID/handle
+				}
+				
+				// Fetch channel information first
+				channelInfo, err := ytCrawler.GetChannelInfo(ctx, target)
+				if err != nil {
+					log.Error().Err(err).Str("channel", la.URL).Msg("Failed to get YouTube channel info")
+					runErr = err
+				} else {
+					log.Info().
+						Str("channel_name", channelInfo.ChannelName).
+						Int("subscribers", channelInfo.ChannelEngagementData.FollowerCount).
+						Msg("Retrieved YouTube channel info")
+					if err := sm.StoreChannelSnapshot(state.ChannelSnapshot{
+						ChannelID:     la.URL,
+						ViewsCount:    channelInfo.ChannelEngagementData.ViewsCount,
+						FollowerCount: channelInfo.ChannelEngagementData.FollowerCount,
+						PostCount:     channelInfo.ChannelEngagementData.PostCount,
+					}); err != nil {
+						log.Error().Err(err).Str("channel", la.URL).Msg("Failed to store channel snapshot")
+					}
+						
+					// Construct crawl job with appropriate time filters
+					var fromTime, toTime time.Time
+					if !crawlCfg.DateBetweenMin.IsZero() && !crawlCfg.DateBetweenMax.IsZero() {
+						// Use date-between range
+						fromTime = crawlCfg.DateBetweenMin
+						toTime = crawlCfg.DateBetweenMax
+						log.Info().
+							Time("date_between_min", fromTime).
+							Time("date_between_max", toTime).
+							Msg("Using date-between filter for YouTube crawl")
+					} else {
+						// Use traditional min post date with max post date (or current time) as upper bound
+						fromTime = crawlCfg.MinPostDate
+						toTime = time.Now()
+						if !crawlCfg.MaxPostDate.IsZero() {
+							toTime = crawlCfg.MaxPostDate
+						}
+					}
 					
-					// Create a crawl target for the YouTube channel
-					target := crawler.CrawlTarget{
-						Type: crawler.PlatformYouTube,
-						ID:   la.URL, // YouTube channel ID/handle
+					job := crawler.CrawlJob{
+						Target:     target,
+						FromTime:   fromTime,
+						ToTime:     toTime,
+						Limit:      crawlCfg.MaxPosts,
+						SampleSize: crawlCfg.SampleSize,
 					}
 					
-					// Fetch channel information first
-					channelInfo, err := ytCrawler.GetChannelInfo(ctx, target)
+					log.Debug().
+						Time("from_time", fromTime).
+						Time("to_time", toTime).
+						Int("limit", job.Limit).
+						Msg("YouTube crawl job configured")
+					
+					// Execute the crawl
+					result, err := ytCrawler.FetchMessages(ctx, job)
 					if err != nil {
-						log.Error().Err(err).Str("channel", la.URL).Msg("Failed to get YouTube channel info")
+						log.Error().Err(err).Str("channel", la.URL).Msg("Failed to fetch YouTube videos")
 						runErr = err
 					} else {
 						log.Info().
-							Str("channel_name", channelInfo.ChannelName).
-							Int("subscribers", channelInfo.ChannelEngagementData.FollowerCount).
-							Msg("Retrieved YouTube channel info")
+							Int("video_count", len(result.Posts)).
+							Str("channel", la.URL).
+							Msg("Successfully crawled YouTube channel")
 							
-						// Construct crawl job with appropriate time filters
-						var fromTime, toTime time.Time
-						if !crawlCfg.DateBetweenMin.IsZero() && !crawlCfg.DateBetweenMax.IsZero() {
-							// Use date-between range
-							fromTime = crawlCfg.DateBetweenMin
-							toTime = crawlCfg.DateBetweenMax
-							log.Info().
-								Time("date_between_min", fromTime).
-								Time("date_between_max", toTime).
-								Msg("Using date-between filter for YouTube crawl")
-						} else {
-							// Use traditional min post date with current time as upper bound
-							fromTime = crawlCfg.MinPostDate
-							toTime = time.Now()
-						}
-						
-						job := crawler.CrawlJob{
-							Target:     target,
-							FromTime:   fromTime,
-							ToTime:     toTime,
-							Limit:      crawlCfg.MaxPosts,
-							SampleSize: crawlCfg.SampleSize,
-						}
-						
-						log.Debug().
-							Time("from_time", fromTime).
-							Time("to_time", toTime).
-							Int("limit", job.Limit).
-							Msg("YouTube crawl job configured")
-						
-						// Execute the crawl
-						result, err := ytCrawler.FetchMessages(ctx, job)
-						if err != nil {
-							log.Error().Err(err).Str("channel", la.URL).Msg("Failed to fetch YouTube videos")
-							runErr = err
-						} else {
-							log.Info().
-								Int("video_count", len(result.Posts)).
-								Str("channel", la.URL).
-								Msg("Successfully crawled YouTube channel")
-								
-							// For now, we don't handle outlinks from YouTube channels
-							discoveredChannels = []*state.Page{}
+						if crawlCfg.CommonSchemaOutput != nil {
+							for _, post := range result.Posts {
+								if err := crawlCfg.CommonSchemaOutput.Write(post); err != nil {
+									log.Error().Err(err).Str("post_uid", post.PostUID).Msg("Failed to write common schema record")
+								}
+							}
 						}
-					}
-				} else {
-					// Telegram platform processing (default)
-					// Try to get the connection pool stats
-					poolStats := crawl.GetConnectionPoolStats()
-					log.Info().Interface("poolStats", poolStats).Msg("Connection pool status")
-					
-					// Use the connection pool if it's initialized
-					if crawl.IsConnectionPoolInitialized() {
-						log.Info().Msg("Using connection pool for channel processing")
-						discoveredChannels, runErr = crawl.RunForChannelWithPool(ctx, &la, crawlCfg.StorageRoot, sm, crawlCfg)
-					} else {
-						log.Info().Msg("No connection pool available, using single connection")
-						discoveredChannels, runErr = crawl.RunForChannel(connect, &la, crawlCfg.StorageRoot, sm, crawlCfg)
+
+						// For now, we don't handle outlinks from YouTube channels
+						discoveredChannels = []*state.Page{}
 					}
 				}
+			} else {
+				// Telegram platform processing (default)
+				discoveredChannels, runErr = crawl.RunForChannelWithSession(ctx, sessions, &la, crawlCfg.StorageRoot, sm, crawlCfg)
+			}
 
-				if runErr != nil {
-					log.Error().Stack().Err(runErr).Msgf("Error processing item %s", la.URL)
-					la.Status = "error"
-					layerError++
-					totalPagesError++
-				} else {
-					la.Status = "fetched"
-					log.Info().Msgf("Successfully processed page: %s", la.URL)
-					layerSuccess++
-					totalPagesSuccess++
-
-					// Handle any discovered channels from this page
-					if len(discoveredChannels) > 0 {
-						log.Info().Msgf("Discovered %d new channels from %s", len(discoveredChannels), la.URL)
-						
-						// Convert to Page structs needed for AddLayer
-						newPages := make([]state.Page, 0, len(discoveredChannels))
-						for _, channel := range discoveredChannels {
-							// Use the existing Page struct directly
-							newPages = append(newPages, *channel)
-						}
-						
-						// Add the new channels as a layer
-						if err := sm.AddLayer(newPages); err != nil {
-							log.Error().Err(err).Msg("Failed to add discovered channels as new layer")
-						} else {
-							log.Info().Int("count", len(newPages)).Msg("Added new channels to be processed in next layer")
-						}
+			if runErr != nil {
+				log.Error().Stack().Err(runErr).Msgf("Error processing item %s", la.URL)
+				recordPageError(sm, &la, runErr, crawlCfg.QuarantineThreshold)
+				statsMu.Lock()
+				layerError++
+				totalPagesError++
+				statsMu.Unlock()
+			} else {
+				la.Status = "fetched"
+				log.Info().Msgf("Successfully processed page: %s", la.URL)
+				statsMu.Lock()
+				layerSuccess++
+				totalPagesSuccess++
+				statsMu.Unlock()
+
+				// Handle any discovered channels from this page
+				if len(discoveredChannels) > 0 {
+					log.Info().Msgf("Discovered %d new channels from %s", len(discoveredChannels), la.URL)
+					
+					// Add the channels not seen before to the next layer
+					added, err := discovered.add(discoveredChannels)
+					if err != nil {
+						log.Error().Err(err).Msg("Failed to add discovered channels as new layer")
+					} else {
+						log.Info().Int("count", added).Msg("Added new channels to be processed in next layer")
 					}
 				}
+			}
 
-				// Save state after processing
-				saveErr = sm.SaveState()
-				if saveErr != nil {
-					log.Error().Stack().Err(saveErr).Msg("Failed to save state after processing page")
-				}
-			}()
+			// Save state once a batch of pages has finished
+			if saveErr := saver.PageDone(); saveErr != nil {
+				log.Error().Stack().Err(saveErr).Msg("Failed to save state after processing page")
+			}
+		})
+		
+		// Persist the rest of the layer before moving on
+		if saveErr := saver.Flush(); saveErr != nil {
+			log.Error().Err(saveErr).Int("depth", currentDepth).Msg("Failed to save state after processing layer")
 		}
 		
 		// Log statistics about the layer processing
@@ -731,6 +850,28 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 			Int("successPages", layerSuccess).
 			Int("errorPages", layerError).
 			Msg("Layer processing statistics")
+		summary.Depths = append(summary.Depths, metrics.DepthSummary{
+			Depth:   currentDepth,
+			Pages:   layerPages,
+			Fetched: layerSuccess,
+			Skipped: layerSkipped,
+			Errors:  layerError,
+		})
+		
+		if droppedNow := discovered.dropped(); droppedNow > droppedByDepth {
+			log.Info().
+				Int("depth", currentDepth).
+				Int("max_depth", crawlCfg.MaxDepth).
+				Int("dropped_outlinks", droppedNow-droppedByDepth).
+				Msg("Outlinks not queued because they are past the max depth")
+			droppedByDepth = droppedNow
+		}
+		
+		if common.MaxRuntimeExceeded(runCtx) {
+			log.Warn().Int("depth", currentDepth).Dur("max_runtime", crawlCfg.MaxRuntime).Msg("Max runtime reached, stopping crawl after in-flight pages")
+			stoppedEarly = true
+			break
+		}
 		
 		// Move to the next depth
 		currentDepth++
@@ -743,10 +884,26 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 		Int("totalPagesSuccess", totalPagesSuccess).
 		Int("totalPagesError", totalPagesError).
 		Int("maxDepthReached", currentDepth-1).
+		Int("outlinksDroppedByDepth", discovered.dropped()).
+		Interface("contentTypeSkips", telegramhelper.ContentTypeSkipCounts()).
 		Msg("Overall crawl statistics")
+
+	summary.Finish(startTotals)
+	summaryPath := metrics.SummaryPath(crawlCfg.SummaryFile, crawlCfg.StorageRoot, crawlCfg.CrawlID)
+	if err := metrics.WriteSummary(summaryPath, summary); err != nil {
+		log.Error().Err(err).Str("path", summaryPath).Msg("Failed to write crawl summary")
+	} else {
+		log.Info().Str("path", summaryPath).Msg("Wrote crawl summary")
+	}
 			
 	// Update crawl metadata to mark as completed if all pages were processed successfully
-	if totalPagesError == 0 {
+	if stoppedEarly {
+		log.Warn().Msg("Crawl is partially complete - can be resumed later")
+		if closeErr := sm.Close(); closeErr != nil {
+			log.Warn().Err(closeErr).Msg("Error during final state save")
+		}
+		return
+	} else if totalPagesError == 0 {
 		// Explicitly call Close() to save any unsaved cache data
 		// This ensures media cache is fully persisted before marking the crawl as completed
 		log.Info().Msg("Saving final state before marking crawl as completed")
@@ -773,4 +930,35 @@ func launch(stringList []string, crawlCfg common.CrawlerConfig) {
 	}
 
 	log.Info().Msg("All layers processed successfully.")
-}
\ No newline at end of file
+}
+
+// applyStorageBackend selects the storage backend configured with
+// --storage-backend. The default (dapr) leaves the config unchanged.
+func applyStorageBackend(cfg *state.Config, crawlCfg common.CrawlerConfig) {
+	backend, err := state.ParseStorageBackend(crawlCfg.StorageBackend)
+	if err != nil || backend == state.StorageBackendDapr {
+		return
+	}
+	cfg.StorageBackend = backend
+	if backend.IsBlob() {
+		cfg.BlobConfig = &state.BlobConfig{
+			Bucket:            crawlCfg.BlobBucket,
+			Prefix:            crawlCfg.BlobPrefix,
+			Region:            crawlCfg.S3Region,
+			Endpoint:          crawlCfg.S3Endpoint,
+			PostPathTemplate:  crawlCfg.BlobPathTemplate,
+			MediaPathTemplate: crawlCfg.BlobMediaTemplate,
+		}
+	}
+}
+
+// recordPageError counts a failed attempt on page and stores it, so a page
+// that keeps failing is quarantined after threshold consecutive failures.
+func recordPageError(sm state.StateManagementInterface, page *state.Page, err error, threshold int) {
+	if page.RecordError(err, threshold) {
+		log.Warn().Str("url", page.URL).Int("error_count", page.ErrorCount).Msg("Quarantining page after repeated failures")
+	}
+	if updateErr := sm.UpdatePage(*page); updateErr != nil {
+		log.Error().Err(updateErr).Str("url", page.URL).Msg("Failed to record page error")
+	}
+}
@@ -1,16 +1,21 @@
 package standalone
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 	
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
 	"github.com/researchaccelerator-hub/telegram-scraper/model"
 	"github.com/researchaccelerator-hub/telegram-scraper/state"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockDaprClient mocks the Dapr client 
@@ -119,6 +124,58 @@ func (m *MockDaprStateManager) MarkMediaAsProcessed(mediaID string) error {
 	return nil
 }
 
+func (m *MockDaprStateManager) MarkMediaAsStored(mediaID string, storageKey string) error {
+	// Call SaveState to simulate updating media cache
+	m.client.SaveState(mock.Anything, m.stateStoreName, mock.Anything, []byte("{}"), nil)
+	return nil
+}
+
+func (m *MockDaprStateManager) GetMediaStorageKey(mediaID string) (string, error) {
+	// Call GetState to simulate checking media cache
+	m.client.GetState(mock.Anything, m.stateStoreName, mock.Anything, nil)
+	return "", nil
+}
+
+func (m *MockDaprStateManager) HasPost(crawlID string, postUID string) bool {
+	return false
+}
+
+func (m *MockDaprStateManager) QueryPosts(crawlID string, filter state.PostFilter) ([]model.Post, error) {
+	return nil, nil
+}
+
+func (m *MockDaprStateManager) GetLastMessageID(channelID string) (int64, error) {
+	// Call GetState to simulate loading the last message ID
+	m.client.GetState(mock.Anything, m.stateStoreName, mock.Anything, nil)
+	return 0, nil
+}
+
+func (m *MockDaprStateManager) SaveLastMessageID(channelID string, messageID int64) error {
+	// Call SaveState to simulate persisting the last message ID
+	m.client.SaveState(mock.Anything, m.stateStoreName, mock.Anything, []byte("0"), nil)
+	return nil
+}
+
+func (m *MockDaprStateManager) StoreChannelSnapshot(snapshot state.ChannelSnapshot) error {
+	return nil
+}
+
+func (m *MockDaprStateManager) GetChannelHistory(channelID string) ([]state.ChannelSnapshot, error) {
+	return nil, nil
+}
+
+func (m *MockDaprStateManager) SaveSeenURLs(urls []string) error {
+	// Call SaveState to simulate persisting the seen URLs
+	m.client.SaveState(mock.Anything, m.stateStoreName, mock.Anything, []byte("[]"), nil)
+	return nil
+}
+
+func (m *MockDaprStateManager) LoadSeenURLs() ([]string, error) {
+	// Call GetState to simulate loading the seen URLs
+	m.client.GetState(mock.Anything, m.stateStoreName, mock.Anything, nil)
+	return []string{}, nil
+}
+
 func (m *MockDaprStateManager) ExportPagesToBinding(crawlID string) error {
 	// Call InvokeBinding to simulate exporting pages
 	m.client.InvokeBinding(mock.Anything, mock.Anything)
@@ -141,6 +198,10 @@ func (m *MockDaprStateManager) GetMaxDepth() (int, error) {
 	return m.BaseStateManager.GetMaxDepth()
 }
 
+func (m *MockDaprStateManager) GetQuarantinedPages() ([]state.Page, error) {
+	return m.BaseStateManager.GetQuarantinedPages()
+}
+
 func (m *MockDaprStateManager) GetLayerByDepth(depth int) ([]state.Page, error) {
 	return m.BaseStateManager.GetLayerByDepth(depth)
 }
@@ -275,6 +336,62 @@ func (m *MockStateManager) MarkMediaAsProcessed(mediaID string) error {
 	return args.Error(0)
 }
 
+func (m *MockStateManager) MarkMediaAsStored(mediaID string, storageKey string) error {
+	args := m.Called(mediaID, storageKey)
+	return args.Error(0)
+}
+
+func (m *MockStateManager) GetMediaStorageKey(mediaID string) (string, error) {
+	args := m.Called(mediaID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockStateManager) SaveSeenURLs(urls []string) error {
+	args := m.Called(urls)
+	return args.Error(0)
+}
+
+func (m *MockStateManager) HasPost(crawlID string, postUID string) bool {
+	args := m.Called(crawlID, postUID)
+	return args.Bool(0)
+}
+
+func (m *MockStateManager) QueryPosts(crawlID string, filter state.PostFilter) ([]model.Post, error) {
+	args := m.Called(crawlID, filter)
+	return args.Get(0).([]model.Post), args.Error(1)
+}
+
+func (m *MockStateManager) GetLastMessageID(channelID string) (int64, error) {
+	args := m.Called(channelID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStateManager) SaveLastMessageID(channelID string, messageID int64) error {
+	args := m.Called(channelID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockStateManager) StoreChannelSnapshot(snapshot state.ChannelSnapshot) error {
+	args := m.Called(snapshot)
+	return args.Error(0)
+}
+
+func (m *MockStateManager) GetChannelHistory(channelID string) ([]state.ChannelSnapshot, error) {
+	args := m.Called(channelID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]state.ChannelSnapshot), args.Error(1)
+}
+
+func (m *MockStateManager) LoadSeenURLs() ([]string, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (m *MockStateManager) ExportPagesToBinding(crawlID string) error {
 	args := m.Called(crawlID)
 	return args.Error(0)
@@ -285,6 +402,11 @@ func (m *MockStateManager) GetMaxDepth() (int, error) {
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockStateManager) GetQuarantinedPages() ([]state.Page, error) {
+	args := m.Called()
+	return args.Get(0).([]state.Page), args.Error(1)
+}
+
 
 func (m *MockStateManager) GetPage(id string) (state.Page, error) {
 	args := m.Called(id)
@@ -1069,4 +1191,188 @@ func TestLayerResumption(t *testing.T) {
 	assert.False(t, processed["page1"], "Page1 should NOT have been processed")
 	assert.False(t, processed["page2"], "Page2 should NOT have been processed")
 	assert.False(t, processed["page3"], "Page3 should NOT have been processed")
-}
\ No newline at end of file
+}
+
+// TestProcessLayerConcurrentDedup runs a layer on four workers where every page
+// discovers overlapping channels, and checks that each page is processed once
+// and each discovered URL is queued once.
+func TestProcessLayerConcurrentDedup(t *testing.T) {
+	sm := new(MockStateManager)
+	var addMu sync.Mutex
+	var added []string
+	sm.On("AddLayer", mock.Anything).Run(func(args mock.Arguments) {
+		addMu.Lock()
+		defer addMu.Unlock()
+		for _, page := range args.Get(0).([]state.Page) {
+			added = append(added, page.URL)
+		}
+	}).Return(nil)
+
+	pages := make([]state.Page, 20)
+	seeds := make([]string, len(pages))
+	for i := range pages {
+		pages[i] = state.Page{ID: fmt.Sprintf("page-%d", i), URL: fmt.Sprintf("seed%d", i)}
+		seeds[i] = pages[i].URL
+	}
+	discovered := newDiscoveredPages(sm, seeds, nil, 0)
+
+	var processedMu sync.Mutex
+	processed := make(map[string]int)
+	var active, maxActive int32
+	processLayer(context.Background(), pages, 4, func(page state.Page) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		processedMu.Lock()
+		processed[page.URL]++
+		processedMu.Unlock()
+
+		// Every page links to a seed and to two of five shared channels
+		var idx int
+		fmt.Sscanf(page.URL, "seed%d", &idx)
+		_, err := discovered.add([]*state.Page{
+			{URL: "seed0", Depth: 1},
+			{URL: fmt.Sprintf("channel%d", idx%5), Depth: 1},
+			{URL: fmt.Sprintf("channel%d", (idx+1)%5), Depth: 1},
+		})
+		assert.NoError(t, err)
+	})
+
+	assert.Len(t, processed, len(pages))
+	for url, count := range processed {
+		assert.Equal(t, 1, count, "page %s processed more than once", url)
+	}
+	assert.LessOrEqual(t, maxActive, int32(4))
+	assert.ElementsMatch(t, []string{"channel0", "channel1", "channel2", "channel3", "channel4"}, added)
+}
+
+// TestProcessLayerStopsAtMaxRuntime checks that no page is started once the
+// crawl's max runtime has passed, while pages in flight are finished
+func TestProcessLayerStopsAtMaxRuntime(t *testing.T) {
+	pages := make([]state.Page, 50)
+	for i := range pages {
+		pages[i] = state.Page{ID: fmt.Sprintf("page-%d", i), URL: fmt.Sprintf("seed%d", i)}
+	}
+
+	ctx, cancel := common.WithMaxRuntime(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	var processed, finishedLate int32
+	processLayer(ctx, pages, 2, func(page state.Page) {
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&processed, 1)
+		if ctx.Err() != nil {
+			atomic.AddInt32(&finishedLate, 1)
+		}
+	})
+
+	assert.True(t, common.MaxRuntimeExceeded(ctx))
+	assert.Less(t, processed, int32(len(pages)), "pages should stop being started once the runtime is spent")
+	assert.Greater(t, processed, int32(0))
+	assert.LessOrEqual(t, finishedLate, int32(2), "only pages in flight at the deadline should finish after it")
+}
+
+// TestDiscoveredPagesChannelFilter checks that discovered channels rejected by
+// the channel filter are never added to the next layer
+func TestDiscoveredPagesChannelFilter(t *testing.T) {
+	sm := new(MockStateManager)
+	var added []string
+	sm.On("AddLayer", mock.Anything).Run(func(args mock.Arguments) {
+		for _, page := range args.Get(0).([]state.Page) {
+			added = append(added, page.URL)
+		}
+	}).Return(nil)
+
+	filter, err := common.NewChannelFilter([]string{"^news_"}, []string{"_ads$"})
+	require.NoError(t, err)
+	discovered := newDiscoveredPages(sm, []string{"news_seed"}, filter, 0)
+
+	count, err := discovered.add([]*state.Page{
+		{URL: "news_daily", Depth: 1},
+		{URL: "news_ads", Depth: 1},
+		{URL: "sports_daily", Depth: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"news_daily"}, added)
+}
+
+// TestDiscoveredPagesMaxDepth crawls a seed whose every page links to new
+// channels and checks that nothing past the max depth is queued
+func TestDiscoveredPagesMaxDepth(t *testing.T) {
+	sm := new(MockStateManager)
+	layers := map[int][]state.Page{}
+	sm.On("AddLayer", mock.Anything).Run(func(args mock.Arguments) {
+		for _, page := range args.Get(0).([]state.Page) {
+			layers[page.Depth] = append(layers[page.Depth], page)
+		}
+	}).Return(nil)
+
+	const maxDepth = 2
+	discovered := newDiscoveredPages(sm, []string{"seed"}, nil, maxDepth)
+	layers[0] = []state.Page{{URL: "seed", Depth: 0}}
+
+	// Without a cap every page would keep expanding the crawl
+	for depth := 0; depth <= maxDepth; depth++ {
+		for _, page := range layers[depth] {
+			_, err := discovered.add([]*state.Page{
+				{URL: page.URL + "/a", Depth: depth + 1},
+				{URL: page.URL + "/b", Depth: depth + 1},
+			})
+			require.NoError(t, err)
+		}
+	}
+
+	assert.Len(t, layers[1], 2)
+	assert.Len(t, layers[2], 4)
+	assert.Empty(t, layers[3], "Outlinks past the max depth should not be queued")
+	assert.Equal(t, 8, discovered.dropped())
+}
+
+// TestDiscoveredPagesSharedOutlink checks that a channel discovered from two
+// different parents is queued in the next layer exactly once, including when
+// a resumed run rediscovers it
+func TestDiscoveredPagesSharedOutlink(t *testing.T) {
+	sm, err := state.NewLocalStateManager(state.Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &state.LocalConfig{BasePath: t.TempDir()},
+	})
+	require.NoError(t, err)
+	require.NoError(t, sm.Initialize([]string{"parent_a", "parent_b"}))
+
+	discovered := newDiscoveredPages(sm, []string{"parent_a", "parent_b"}, nil, 0)
+	require.NoError(t, discovered.markQueued(1))
+	for _, parent := range []string{"parent_a", "parent_b"} {
+		_, err := discovered.add([]*state.Page{
+			{URL: "shared", Depth: 1, ParentID: parent},
+			{URL: parent + "_only", Depth: 1, ParentID: parent},
+		})
+		require.NoError(t, err)
+	}
+
+	layerURLs := func() []string {
+		layer, err := sm.GetLayerByDepth(1)
+		require.NoError(t, err)
+		urls := make([]string, 0, len(layer))
+		for _, page := range layer {
+			urls = append(urls, page.URL)
+		}
+		return urls
+	}
+	assert.ElementsMatch(t, []string{"shared", "parent_a_only", "parent_b_only"}, layerURLs())
+
+	// A resumed run starts with a fresh tracker and reprocesses the parents
+	resumed := newDiscoveredPages(sm, []string{"parent_a", "parent_b"}, nil, 0)
+	require.NoError(t, resumed.markQueued(1))
+	added, err := resumed.add([]*state.Page{{URL: "shared", Depth: 1, ParentID: "parent_b"}})
+	require.NoError(t, err)
+	assert.Equal(t, 0, added)
+	assert.ElementsMatch(t, []string{"shared", "parent_a_only", "parent_b_only"}, layerURLs())
+}
@@ -3,6 +3,8 @@ package state
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -24,6 +26,11 @@ type BaseStateManager struct {
 
 	// Map of page ID -> Page (to store all pages)
 	pageMap map[string]Page
+
+	// Set of "crawlID/postUID" keys for stored posts. Implementations also
+	// persist them, so the set survives restarts.
+	storedPosts      map[string]bool
+	storedPostsMutex sync.RWMutex
 }
 
 // NewBaseStateManager creates a new BaseStateManager
@@ -39,6 +46,7 @@ func NewBaseStateManager(config Config) *BaseStateManager {
 		lastUpdated: time.Now(),
 		layerMap:    make(map[int][]string),
 		pageMap:     make(map[string]Page),
+		storedPosts: make(map[string]bool),
 	}
 }
 
@@ -480,6 +488,71 @@ func (bsm *BaseStateManager) FindIncompleteCrawl(crawlID string) (string, bool,
 	return "", false, nil
 }
 
+// GetQuarantinedPages returns every page that is quarantined after failing
+// repeatedly, ordered by depth and URL.
+func (bsm *BaseStateManager) GetQuarantinedPages() ([]Page, error) {
+	bsm.mutex.RLock()
+	defer bsm.mutex.RUnlock()
+
+	pages := make([]Page, 0)
+	for _, page := range bsm.pageMap {
+		if page.Status == PageStatusQuarantined {
+			pages = append(pages, page)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].Depth != pages[j].Depth {
+			return pages[i].Depth < pages[j].Depth
+		}
+		return pages[i].URL < pages[j].URL
+	})
+	return pages, nil
+}
+
+// HasPost reports whether a post with the given UID was stored for the crawl
+func (bsm *BaseStateManager) HasPost(crawlID string, postUID string) bool {
+	bsm.storedPostsMutex.RLock()
+	defer bsm.storedPostsMutex.RUnlock()
+	return bsm.storedPosts[storedPostKey(crawlID, postUID)]
+}
+
+// markPostStored records a successfully stored post so HasPost reports it.
+// Implementations call it from StorePost.
+func (bsm *BaseStateManager) markPostStored(postUID string) {
+	bsm.storedPostsMutex.Lock()
+	defer bsm.storedPostsMutex.Unlock()
+	bsm.storedPosts[storedPostKey(bsm.config.CrawlID, postUID)] = true
+}
+
+// stampSnapshot fills in the crawl execution ID and capture time of a channel
+// snapshot when the caller left them empty. Implementations call it from
+// StoreChannelSnapshot.
+func (bsm *BaseStateManager) stampSnapshot(snapshot *ChannelSnapshot) {
+	if snapshot.CrawlID == "" {
+		snapshot.CrawlID = bsm.config.CrawlExecutionID
+		if snapshot.CrawlID == "" {
+			snapshot.CrawlID = bsm.config.CrawlID
+		}
+	}
+	if snapshot.CapturedAt.IsZero() {
+		snapshot.CapturedAt = time.Now()
+	}
+}
+
+// historyPlatform returns the platform channel histories are kept under. The
+// history is keyed by platform and channel rather than by crawl ID, so it
+// builds up across crawls.
+func (bsm *BaseStateManager) historyPlatform() string {
+	if bsm.config.Platform == "" {
+		return "telegram"
+	}
+	return strings.ToLower(bsm.config.Platform)
+}
+
+func storedPostKey(crawlID string, postUID string) string {
+	return crawlID + "/" + postUID
+}
+
 // StorePost and StoreFile are left to specific implementations
 // HasProcessedMedia and MarkMediaAsProcessed are left to specific implementations
 // SaveState is left to specific implementations
@@ -0,0 +1,128 @@
+package state
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+)
+
+// DefaultPostPathTemplate is the object key layout of posts in a bucket,
+// relative to BlobConfig.Prefix.
+const DefaultPostPathTemplate = "{crawl}/{channel}/posts/{post_uid}.jsonl"
+
+// DefaultMediaPathTemplate is the object key layout of media files in a
+// bucket, relative to BlobConfig.Prefix.
+const DefaultMediaPathTemplate = "{crawl}/media/{channel}/{file}"
+
+// pathPlaceholder matches a placeholder such as {channel} in a path template.
+var pathPlaceholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// PostPathVars are the values a post path template is rendered with.
+type PostPathVars struct {
+	CrawlID     string // Logical crawl ID
+	ExecutionID string // Crawl execution ID
+	Platform    string // Platform the post was crawled from, e.g. "telegram"
+	Channel     string // Channel the post is stored for
+	Post        model.Post
+}
+
+// RenderPostPath fills in the placeholders of template. Supported placeholders:
+//
+//	{crawl}      crawl ID
+//	{execution}  crawl execution ID
+//	{platform}   platform, e.g. "telegram" or "youtube"
+//	{channel}    channel the post is stored for
+//	{post_uid}   post UID
+//	{msgid}      message or video ID, i.e. the post UID without its channel suffix
+//	{date}       publication date as YYYY-MM-DD
+//	{year}, {month}, {day}  parts of the publication date
+//
+// Unknown placeholders are an error, so a typo does not silently end up in
+// every object key.
+func RenderPostPath(template string, vars PostPathVars) (string, error) {
+	values := pathValues(vars.CrawlID, vars.ExecutionID, vars.Platform, vars.Channel, vars.Post.PublishedAt)
+	values["post_uid"] = vars.Post.PostUID
+	values["msgid"] = strings.TrimSuffix(vars.Post.PostUID, "-"+vars.Channel)
+	return renderPath(template, values)
+}
+
+// ValidatePostPathTemplate checks that template only uses known placeholders
+// and names a post by {post_uid} or {msgid}. Blob stores cannot append, so
+// without one every post of a channel would overwrite the previous one.
+func ValidatePostPathTemplate(template string) error {
+	if _, err := RenderPostPath(template, PostPathVars{}); err != nil {
+		return err
+	}
+	if !strings.Contains(template, "{post_uid}") && !strings.Contains(template, "{msgid}") {
+		return fmt.Errorf("path template %q must contain {post_uid} or {msgid}, or every post would overwrite the previous one", template)
+	}
+	return nil
+}
+
+// MediaPathVars are the values a media path template is rendered with.
+type MediaPathVars struct {
+	CrawlID     string    // Logical crawl ID
+	ExecutionID string    // Crawl execution ID
+	Platform    string    // Platform the file was crawled from, e.g. "telegram"
+	Channel     string    // Channel the file is stored for
+	File        string    // File name, unique within the channel
+	StoredAt    time.Time // Time of the upload
+}
+
+// RenderMediaPath fills in the placeholders of a media path template. It
+// supports {crawl}, {execution}, {platform}, {channel} and the date
+// placeholders of RenderPostPath, which hold the upload date since media is
+// stored before its post, and {file} for the file name.
+func RenderMediaPath(template string, vars MediaPathVars) (string, error) {
+	values := pathValues(vars.CrawlID, vars.ExecutionID, vars.Platform, vars.Channel, vars.StoredAt)
+	values["file"] = vars.File
+	return renderPath(template, values)
+}
+
+// ValidateMediaPathTemplate checks that template only uses known placeholders
+// and names a file by {file}, so files do not overwrite each other.
+func ValidateMediaPathTemplate(template string) error {
+	if _, err := RenderMediaPath(template, MediaPathVars{}); err != nil {
+		return err
+	}
+	if !strings.Contains(template, "{file}") {
+		return fmt.Errorf("media path template %q must contain {file}, or every file would overwrite the previous one", template)
+	}
+	return nil
+}
+
+// pathValues returns the placeholder values shared by post and media paths.
+func pathValues(crawlID, executionID, platform, channel string, date time.Time) map[string]string {
+	date = date.UTC()
+	return map[string]string{
+		"crawl":     crawlID,
+		"execution": executionID,
+		"platform":  strings.ToLower(platform),
+		"channel":   channel,
+		"date":      date.Format("2006-01-02"),
+		"year":      date.Format("2006"),
+		"month":     date.Format("01"),
+		"day":       date.Format("02"),
+	}
+}
+
+// renderPath replaces the placeholders of template with values.
+func renderPath(template string, values map[string]string) (string, error) {
+	var unknown []string
+	rendered := pathPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		value, ok := values[name]
+		if !ok {
+			unknown = append(unknown, placeholder)
+			return placeholder
+		}
+		return value
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown placeholder %s in path template %q", strings.Join(unknown, ", "), template)
+	}
+	return rendered, nil
+}
@@ -0,0 +1,298 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/rs/zerolog/log"
+	gcs "google.golang.org/api/storage/v1"
+)
+
+// StorageBackend selects where a state manager keeps crawl output.
+type StorageBackend string
+
+const (
+	StorageBackendDapr  StorageBackend = "dapr"  // Dapr state store and bindings (the default)
+	StorageBackendLocal StorageBackend = "local" // Files under the local storage root
+	StorageBackendS3    StorageBackend = "s3"    // Posts and media in an S3 or S3-compatible bucket
+	StorageBackendGCS   StorageBackend = "gcs"   // Posts and media in a Google Cloud Storage bucket
+)
+
+// ParseStorageBackend validates a storage backend name. An empty name selects
+// StorageBackendDapr.
+func ParseStorageBackend(name string) (StorageBackend, error) {
+	switch backend := StorageBackend(strings.ToLower(strings.TrimSpace(name))); backend {
+	case "":
+		return StorageBackendDapr, nil
+	case StorageBackendDapr, StorageBackendLocal, StorageBackendS3, StorageBackendGCS:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("unknown storage backend %q, must be %q, %q, %q or %q", name, StorageBackendDapr, StorageBackendLocal, StorageBackendS3, StorageBackendGCS)
+	}
+}
+
+// IsBlob reports whether the backend writes posts and media to object storage.
+func (b StorageBackend) IsBlob() bool {
+	return b == StorageBackendS3 || b == StorageBackendGCS
+}
+
+// BlobStore writes objects to blob storage. Implementations must be safe for
+// concurrent use.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader) error
+}
+
+// UploadBlobFileAndDelete uploads the file at filePath to key and removes the
+// local copy once the upload has succeeded.
+func UploadBlobFileAndDelete(ctx context.Context, store BlobStore, key string, filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file for upload: %w", err)
+	}
+	err = store.Put(ctx, key, file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		log.Warn().Err(err).Str("path", filePath).Msg("Failed to delete uploaded file")
+	}
+	return nil
+}
+
+// LocalBlobStore is a BlobStore that writes objects as files under BasePath.
+type LocalBlobStore struct {
+	BasePath string
+}
+
+// Put writes body to the file for key, creating parent directories as needed.
+func (s *LocalBlobStore) Put(ctx context.Context, key string, body io.Reader) error {
+	dest := filepath.Join(s.BasePath, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// S3PutObjectAPI is the part of the S3 client used by S3BlobStore.
+type S3PutObjectAPI interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// S3BlobStore is a BlobStore backed by an S3 bucket.
+type S3BlobStore struct {
+	client S3PutObjectAPI
+	bucket string
+}
+
+// NewS3BlobStore creates a store that writes to bucket through client.
+func NewS3BlobStore(client S3PutObjectAPI, bucket string) *S3BlobStore {
+	return &S3BlobStore{client: client, bucket: bucket}
+}
+
+// Put uploads body as the object key.
+func (s *S3BlobStore) Put(ctx context.Context, key string, body io.Reader) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   body,
+	})
+	return err
+}
+
+// newS3Client creates an S3 client from the AWS environment (credentials,
+// profile, region), overridden by the region and endpoint in cfg. A custom
+// endpoint, e.g. MinIO, is addressed path-style.
+func newS3Client(ctx context.Context, cfg BlobConfig) (*s3.Client, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
+}
+
+// GCSBlobStore is a BlobStore backed by a Google Cloud Storage bucket.
+type GCSBlobStore struct {
+	service *gcs.Service
+	bucket  string
+}
+
+// NewGCSBlobStore creates a store that writes to bucket using Application
+// Default Credentials.
+func NewGCSBlobStore(ctx context.Context, bucket string) (*GCSBlobStore, error) {
+	service, err := gcs.NewService(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Google Cloud Storage client: %w", err)
+	}
+	return &GCSBlobStore{service: service, bucket: bucket}, nil
+}
+
+// Put uploads body as the object key.
+func (s *GCSBlobStore) Put(ctx context.Context, key string, body io.Reader) error {
+	_, err := s.service.Objects.Insert(s.bucket, &gcs.Object{Name: key}).Media(body).Context(ctx).Do()
+	return err
+}
+
+// newBlobStore creates the blob store for a blob storage backend.
+var newBlobStore = func(ctx context.Context, backend StorageBackend, cfg BlobConfig) (BlobStore, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("storage backend %q requires a bucket", backend)
+	}
+	switch backend {
+	case StorageBackendS3:
+		client, err := newS3Client(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewS3BlobStore(client, cfg.Bucket), nil
+	case StorageBackendGCS:
+		return NewGCSBlobStore(ctx, cfg.Bucket)
+	default:
+		return nil, fmt.Errorf("storage backend %q is not a blob storage backend", backend)
+	}
+}
+
+// BlobStateManager keeps the crawl state (layers, caches, metadata) on the
+// local filesystem like LocalStateManager, but writes posts and media to a
+// BlobStore. Each post is stored as its own object, the way the Dapr state
+// manager stores them, since blob stores cannot append.
+type BlobStateManager struct {
+	*LocalStateManager
+	store         BlobStore
+	prefix        string
+	postTemplate  string // Layout of post object keys below prefix
+	mediaTemplate string // Layout of media object keys below prefix
+}
+
+// NewBlobStateManager creates a state manager that stores posts and media in
+// store. Object keys start with config.BlobConfig.Prefix, if set, and posts are
+// laid out by config.BlobConfig.PostPathTemplate (default:
+// DefaultPostPathTemplate) and media by config.BlobConfig.MediaPathTemplate
+// (default: DefaultMediaPathTemplate).
+func NewBlobStateManager(config Config, store BlobStore) (*BlobStateManager, error) {
+	prefix, postTemplate, mediaTemplate := "", DefaultPostPathTemplate, DefaultMediaPathTemplate
+	if config.BlobConfig != nil {
+		prefix = strings.Trim(config.BlobConfig.Prefix, "/")
+		if config.BlobConfig.PostPathTemplate != "" {
+			postTemplate = config.BlobConfig.PostPathTemplate
+		}
+		if config.BlobConfig.MediaPathTemplate != "" {
+			mediaTemplate = config.BlobConfig.MediaPathTemplate
+		}
+	}
+	if err := ValidatePostPathTemplate(postTemplate); err != nil {
+		return nil, err
+	}
+	if err := ValidateMediaPathTemplate(mediaTemplate); err != nil {
+		return nil, err
+	}
+
+	lsm, err := NewLocalStateManager(config)
+	if err != nil {
+		return nil, err
+	}
+	return &BlobStateManager{LocalStateManager: lsm, store: store, prefix: prefix, postTemplate: postTemplate, mediaTemplate: mediaTemplate}, nil
+}
+
+// StorePost uploads the post as a single JSON line object.
+func (bsm *BlobStateManager) StorePost(channelID string, post model.Post) error {
+	postData, err := json.Marshal(post)
+	if err != nil {
+		return fmt.Errorf("failed to marshal post: %w", err)
+	}
+	postData = append(postData, '\n')
+
+	rendered, err := RenderPostPath(bsm.postTemplate, PostPathVars{
+		CrawlID:     bsm.config.CrawlID,
+		ExecutionID: bsm.config.CrawlExecutionID,
+		Platform:    bsm.platform(post),
+		Channel:     channelID,
+		Post:        post,
+	})
+	if err != nil {
+		return err
+	}
+	key := path.Join(bsm.prefix, rendered)
+	if err := bsm.store.Put(context.Background(), key, bytes.NewReader(postData)); err != nil {
+		return fmt.Errorf("failed to store post %s: %w", key, err)
+	}
+	bsm.markPostStored(post.PostUID)
+
+	log.Debug().Str("channel", channelID).Str("postID", post.PostUID).Str("key", key).Msg("Post stored")
+	return nil
+}
+
+// platform returns the configured platform, falling back to the post's own
+// platform name and then to Telegram.
+func (bsm *BlobStateManager) platform(post model.Post) string {
+	switch {
+	case bsm.config.Platform != "":
+		return bsm.config.Platform
+	case post.PlatformName != "":
+		return post.PlatformName
+	default:
+		return "telegram"
+	}
+}
+
+// QueryPosts is not supported: blob stores are written to but not listed or
+// read. The embedded LocalStateManager would find no posts on disk.
+func (bsm *BlobStateManager) QueryPosts(crawlID string, filter PostFilter) ([]model.Post, error) {
+	return nil, ErrQueryNotSupported
+}
+
+// StoreFile uploads a media file and deletes the local copy. It returns the
+// object key and the file name.
+func (bsm *BlobStateManager) StoreFile(channelID string, sourceFilePath string, fileName string) (string, string, error) {
+	if _, err := os.Stat(sourceFilePath); os.IsNotExist(err) {
+		return "", "", fmt.Errorf("source file does not exist: %w", err)
+	}
+
+	fileName = mediaFileName(sourceFilePath, fileName)
+	rendered, err := RenderMediaPath(bsm.mediaTemplate, MediaPathVars{
+		CrawlID:     bsm.config.CrawlID,
+		ExecutionID: bsm.config.CrawlExecutionID,
+		Platform:    bsm.platform(model.Post{}),
+		Channel:     channelID,
+		File:        fileName,
+		StoredAt:    time.Now(),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	key := path.Join(bsm.prefix, rendered)
+	if err := UploadBlobFileAndDelete(context.Background(), bsm.store, key, sourceFilePath); err != nil {
+		return "", "", err
+	}
+	return key, fileName, nil
+}
@@ -0,0 +1,266 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+)
+
+func TestParseStorageBackend(t *testing.T) {
+	tests := map[string]StorageBackend{
+		"":      StorageBackendDapr,
+		"dapr":  StorageBackendDapr,
+		"local": StorageBackendLocal,
+		"S3":    StorageBackendS3,
+		" gcs ": StorageBackendGCS,
+	}
+	for name, want := range tests {
+		got, err := ParseStorageBackend(name)
+		if err != nil {
+			t.Fatalf("ParseStorageBackend(%q) returned error: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("ParseStorageBackend(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	if _, err := ParseStorageBackend("azure"); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
+
+// recordingS3Client is an S3PutObjectAPI that keeps the uploaded objects
+type recordingS3Client struct {
+	mu      sync.Mutex
+	buckets map[string]string
+	objects map[string]string
+}
+
+func (c *recordingS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buckets[*params.Key] = *params.Bucket
+	c.objects[*params.Key] = string(body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+// TestFactory_S3Backend verifies that the S3 backend stores posts and media
+// as objects under the configured prefix and keeps the crawl state locally
+func TestFactory_S3Backend(t *testing.T) {
+	client := &recordingS3Client{buckets: map[string]string{}, objects: map[string]string{}}
+	original := newBlobStore
+	newBlobStore = func(ctx context.Context, backend StorageBackend, cfg BlobConfig) (BlobStore, error) {
+		if backend != StorageBackendS3 {
+			t.Fatalf("Expected the s3 backend, got %q", backend)
+		}
+		return NewS3BlobStore(client, cfg.Bucket), nil
+	}
+	defer func() { newBlobStore = original }()
+
+	storageRoot := t.TempDir()
+	sm, err := NewStateManagerFactory().Create(Config{
+		StorageRoot:    storageRoot,
+		CrawlID:        "test-crawl",
+		StorageBackend: StorageBackendS3,
+		DaprConfig:     &DaprConfig{StateStoreName: "statestore"},
+		BlobConfig:     &BlobConfig{Bucket: "crawls", Prefix: "/runs/"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create state manager: %v", err)
+	}
+	if _, ok := sm.(*BlobStateManager); !ok {
+		t.Fatalf("Expected a BlobStateManager, got %T", sm)
+	}
+
+	if err := sm.StorePost("channel", model.Post{PostUID: "1-channel", Description: "hello"}); err != nil {
+		t.Fatalf("StorePost failed: %v", err)
+	}
+	postKey := "runs/test-crawl/channel/posts/1-channel.jsonl"
+	var post model.Post
+	if err := json.Unmarshal([]byte(client.objects[postKey]), &post); err != nil {
+		t.Fatalf("Expected a JSON post at %s, got %q: %v", postKey, client.objects[postKey], err)
+	}
+	if post.Description != "hello" {
+		t.Errorf("Expected the stored post description %q, got %q", "hello", post.Description)
+	}
+	if client.buckets[postKey] != "crawls" {
+		t.Errorf("Expected the post in bucket %q, got %q", "crawls", client.buckets[postKey])
+	}
+	if !sm.HasPost("test-crawl", "1-channel") {
+		t.Error("Expected the stored post to be recorded")
+	}
+
+	source := filepath.Join(t.TempDir(), "download.jpg")
+	if err := os.WriteFile(source, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key, fileName, err := sm.StoreFile("channel", source, "photo")
+	if err != nil {
+		t.Fatalf("StoreFile failed: %v", err)
+	}
+	if want := "runs/test-crawl/media/channel/photo.jpg"; key != want {
+		t.Errorf("Expected media key %q, got %q", want, key)
+	}
+	if fileName != "photo.jpg" {
+		t.Errorf("Expected file name %q, got %q", "photo.jpg", fileName)
+	}
+	if client.objects[key] != "image" {
+		t.Errorf("Expected the media content to be uploaded, got %q", client.objects[key])
+	}
+	if _, err := os.Stat(source); !os.IsNotExist(err) {
+		t.Error("Expected the local media file to be deleted after upload")
+	}
+
+	// Crawl state stays under the storage root
+	if _, err := os.Stat(filepath.Join(storageRoot, "test-crawl")); err != nil {
+		t.Errorf("Expected the crawl state directory under the storage root: %v", err)
+	}
+}
+
+func TestFactory_LocalBackend(t *testing.T) {
+	storageRoot := t.TempDir()
+	sm, err := NewStateManagerFactory().Create(Config{
+		StorageRoot:    storageRoot,
+		CrawlID:        "test-crawl",
+		StorageBackend: StorageBackendLocal,
+		DaprConfig:     &DaprConfig{StateStoreName: "statestore"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create state manager: %v", err)
+	}
+	if _, ok := sm.(*LocalStateManager); !ok {
+		t.Fatalf("Expected a LocalStateManager, got %T", sm)
+	}
+}
+
+func TestFactory_BlobBackendRequiresBucket(t *testing.T) {
+	_, err := NewStateManagerFactory().Create(Config{
+		StorageRoot:    t.TempDir(),
+		CrawlID:        "test-crawl",
+		StorageBackend: StorageBackendGCS,
+	})
+	if err == nil {
+		t.Fatal("Expected an error without a bucket")
+	}
+}
+
+func TestLocalBlobStore_Put(t *testing.T) {
+	basePath := t.TempDir()
+	store := &LocalBlobStore{BasePath: basePath}
+	source := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(source, []byte("content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := UploadBlobFileAndDelete(context.Background(), store, "a/b/file.txt", source); err != nil {
+		t.Fatalf("UploadBlobFileAndDelete failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(basePath, "a", "b", "file.txt"))
+	if err != nil {
+		t.Fatalf("Expected the object on disk: %v", err)
+	}
+	if string(data) != "content" {
+		t.Errorf("Expected %q, got %q", "content", string(data))
+	}
+	if _, err := os.Stat(source); !os.IsNotExist(err) {
+		t.Error("Expected the source file to be deleted")
+	}
+}
+
+func TestBlobStateManager_PostPathTemplate(t *testing.T) {
+	client := &recordingS3Client{buckets: map[string]string{}, objects: map[string]string{}}
+	sm, err := NewBlobStateManager(Config{
+		CrawlID:     "test-crawl",
+		Platform:    "telegram",
+		LocalConfig: &LocalConfig{BasePath: t.TempDir()},
+		BlobConfig: &BlobConfig{
+			Bucket:           "lake",
+			Prefix:           "raw",
+			PostPathTemplate: "{platform}/{year}/{date}/{channel}/{msgid}.jsonl",
+		},
+	}, NewS3BlobStore(client, "lake"))
+	if err != nil {
+		t.Fatalf("Failed to create blob state manager: %v", err)
+	}
+
+	post := model.Post{PostUID: "1234-news", PublishedAt: time.Date(2024, 3, 9, 23, 30, 0, 0, time.UTC)}
+	if err := sm.StorePost("news", post); err != nil {
+		t.Fatalf("StorePost failed: %v", err)
+	}
+	want := "raw/telegram/2024/2024-03-09/news/1234.jsonl"
+	if _, ok := client.objects[want]; !ok {
+		t.Errorf("Expected the post at %s, got objects %v", want, client.objects)
+	}
+
+	_, err = NewBlobStateManager(Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &LocalConfig{BasePath: t.TempDir()},
+		BlobConfig:  &BlobConfig{PostPathTemplate: "{channel}/{msg_id}.jsonl"},
+	}, NewS3BlobStore(client, "lake"))
+	if err == nil || !strings.Contains(err.Error(), "{msg_id}") {
+		t.Errorf("Expected an error naming the unknown placeholder, got %v", err)
+	}
+}
+
+func TestBlobStateManager_RejectsTemplatesThatOverwrite(t *testing.T) {
+	client := &recordingS3Client{buckets: map[string]string{}, objects: map[string]string{}}
+	for _, blobCfg := range []*BlobConfig{
+		{PostPathTemplate: "{platform}/{date}/{channel}.jsonl"},
+		{MediaPathTemplate: "{platform}/{date}/{channel}/media"},
+	} {
+		_, err := NewBlobStateManager(Config{
+			CrawlID:     "test-crawl",
+			LocalConfig: &LocalConfig{BasePath: t.TempDir()},
+			BlobConfig:  blobCfg,
+		}, NewS3BlobStore(client, "lake"))
+		if err == nil {
+			t.Errorf("Expected templates %q and %q to be rejected", blobCfg.PostPathTemplate, blobCfg.MediaPathTemplate)
+		}
+	}
+}
+
+func TestBlobStateManager_MediaPathTemplate(t *testing.T) {
+	client := &recordingS3Client{buckets: map[string]string{}, objects: map[string]string{}}
+	sm, err := NewBlobStateManager(Config{
+		CrawlID:     "test-crawl",
+		Platform:    "telegram",
+		LocalConfig: &LocalConfig{BasePath: t.TempDir()},
+		BlobConfig: &BlobConfig{
+			Bucket:            "lake",
+			Prefix:            "raw",
+			MediaPathTemplate: "{platform}/{year}/{channel}/media/{file}",
+		},
+	}, NewS3BlobStore(client, "lake"))
+	if err != nil {
+		t.Fatalf("Failed to create blob state manager: %v", err)
+	}
+
+	source := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(source, []byte("jpeg"), 0644); err != nil {
+		t.Fatalf("Failed to write media file: %v", err)
+	}
+	key, _, err := sm.StoreFile("news", source, "remote-photo")
+	if err != nil {
+		t.Fatalf("StoreFile failed: %v", err)
+	}
+	want := "raw/telegram/" + time.Now().UTC().Format("2006") + "/news/media/remote-photo.jpg"
+	if key != want {
+		t.Errorf("Expected the media at %s, got %s", want, key)
+	}
+	if _, ok := client.objects[want]; !ok {
+		t.Errorf("Expected the media object at %s, got objects %v", want, client.objects)
+	}
+}
@@ -50,6 +50,8 @@ type DaprStateManager struct {
 	urlCache      map[string]string // Maps URL -> "crawlID:pageID" for all known URLs
 	urlCacheMutex sync.RWMutex      // Separate mutex for URL cache to reduce contention
 
+	historyMutex sync.Mutex // Serializes read-modify-write of channel histories
+
 	// Cache configuration
 	maxCacheItemsPerShard int // Maximum number of items per shard
 	cacheExpirationDays   int // Number of days after which cache entries are considered stale
@@ -924,7 +926,8 @@ func (dsm *DaprStateManager) SaveState() error {
 	return nil
 }
 
-// StorePost stores a post in Dapr
+// StorePost stores a post in Dapr. Every post is written to its own object
+// keyed by PostUID, so concurrent calls never contend for the same blob.
 func (dsm *DaprStateManager) StorePost(channelID string, post model.Post) error {
 	postData, err := json.Marshal(post)
 	if err != nil {
@@ -967,11 +970,56 @@ func (dsm *DaprStateManager) StorePost(channelID string, post model.Post) error
 	if err != nil {
 		return fmt.Errorf("failed to store post via Dapr: %w", err)
 	}
+	dsm.markPostStored(post.PostUID)
 
 	log.Debug().Str("channel", channelID).Str("postUID", post.PostUID).Msg("Post stored")
 	return nil
 }
 
+// HasPost reports whether a post with the given UID was stored for the crawl,
+// by this or an earlier run, looking it up in the Dapr state store when it is
+// not known in memory
+func (dsm *DaprStateManager) HasPost(crawlID string, postUID string) bool {
+	if dsm.BaseStateManager.HasPost(crawlID, postUID) {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	response, err := (*dsm.client).GetState(ctx, dsm.stateStoreName, dsm.getStoredPostKey(crawlID, postUID), nil)
+	if err != nil {
+		log.Warn().Err(err).Str("postUID", postUID).Msg("Failed to look up stored post")
+		return false
+	}
+	if response == nil || len(response.Value) == 0 {
+		return false
+	}
+	if crawlID == dsm.config.CrawlID {
+		dsm.BaseStateManager.markPostStored(postUID)
+	}
+	return true
+}
+
+// markPostStored records a stored post in memory and in the Dapr state store,
+// so HasPost still reports it after a restart
+func (dsm *DaprStateManager) markPostStored(postUID string) {
+	dsm.BaseStateManager.markPostStored(postUID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := (*dsm.client).SaveState(ctx, dsm.stateStoreName, dsm.getStoredPostKey(dsm.config.CrawlID, postUID), []byte("true"), nil); err != nil {
+		log.Warn().Err(err).Str("postUID", postUID).Msg("Failed to record stored post")
+	}
+}
+
+// QueryPosts is not supported: posts are written to an output binding and
+// cannot be read back through it.
+func (dsm *DaprStateManager) QueryPosts(crawlID string, filter PostFilter) ([]model.Post, error) {
+	return nil, ErrQueryNotSupported
+}
+
 // StoreFile stores a file via Dapr
 func (dsm *DaprStateManager) StoreFile(crawlId string, sourceFilePath string, fileName string) (string, string, error) {
 	// Check if the file exists
@@ -1196,28 +1244,189 @@ func (dsm *DaprStateManager) UpdatePage(page Page) error {
 
 // MarkMediaAsProcessed marks media as processed using the sharded cache architecture
 func (dsm *DaprStateManager) MarkMediaAsProcessed(mediaID string) error {
+	return dsm.MarkMediaAsStored(mediaID, "")
+}
+
+// MarkMediaAsStored marks media as processed and records the storage key of its
+// content in the sharded cache
+func (dsm *DaprStateManager) MarkMediaAsStored(mediaID string, storageKey string) error {
 	// Create a context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// For backward compatibility - also add to the old cache format
-	dsm.mediaCacheMutex.Lock()
-	dsm.mediaCache[mediaID] = MediaCacheItem{
-		ID:        mediaID,
-		FirstSeen: time.Now(),
-	}
-	dsm.mediaCacheMutex.Unlock()
-
 	// Create the new cache item
 	item := MediaCacheItem{
-		ID:        mediaID,
-		FirstSeen: time.Now(),
+		ID:         mediaID,
+		FirstSeen:  time.Now(),
+		StorageKey: storageKey,
 	}
 
+	// For backward compatibility - also add to the old cache format
+	dsm.mediaCacheMutex.Lock()
+	dsm.mediaCache[mediaID] = item
+	dsm.mediaCacheMutex.Unlock()
+
 	// Add to the sharded cache system
 	return dsm.addMediaToCacheWithSharding(ctx, mediaID, item)
 }
 
+// GetMediaStorageKey returns the storage key recorded for processed media,
+// loading the owning cache shard if necessary
+func (dsm *DaprStateManager) GetMediaStorageKey(mediaID string) (string, error) {
+	exists, err := dsm.HasProcessedMedia(mediaID)
+	if err != nil || !exists {
+		return "", err
+	}
+
+	dsm.mediaCacheIndexMutex.RLock()
+	if item, ok := dsm.activeMediaCache.Items[mediaID]; ok {
+		dsm.mediaCacheIndexMutex.RUnlock()
+		return item.StorageKey, nil
+	}
+	if shardID, ok := dsm.mediaCacheIndex.MediaIndex[mediaID]; ok {
+		if shard := dsm.mediaCacheShards[shardID]; shard != nil {
+			if item, ok := shard.Items[mediaID]; ok {
+				dsm.mediaCacheIndexMutex.RUnlock()
+				return item.StorageKey, nil
+			}
+		}
+	}
+	dsm.mediaCacheIndexMutex.RUnlock()
+
+	dsm.mediaCacheMutex.RLock()
+	defer dsm.mediaCacheMutex.RUnlock()
+	return dsm.mediaCache[mediaID].StorageKey, nil
+}
+
+// SaveSeenURLs stores the seen-URL set for the crawl in the Dapr state store
+func (dsm *DaprStateManager) SaveSeenURLs(urls []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(urls)
+	if err != nil {
+		return fmt.Errorf("failed to marshal seen URLs: %w", err)
+	}
+
+	if err := (*dsm.client).SaveState(ctx, dsm.stateStoreName, dsm.getSeenURLsKey(), data, nil); err != nil {
+		return fmt.Errorf("failed to save seen URLs: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSeenURLs fetches the seen-URL set for the crawl from the Dapr state store
+func (dsm *DaprStateManager) LoadSeenURLs() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	response, err := (*dsm.client).GetState(ctx, dsm.stateStoreName, dsm.getSeenURLsKey(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seen URLs from Dapr: %w", err)
+	}
+
+	if response == nil || response.Value == nil {
+		return []string{}, nil
+	}
+
+	var urls []string
+	if err := json.Unmarshal(response.Value, &urls); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal seen URLs: %w", err)
+	}
+
+	return urls, nil
+}
+
+// GetLastMessageID fetches the channel's last seen message ID from the Dapr state store
+func (dsm *DaprStateManager) GetLastMessageID(channelID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	response, err := (*dsm.client).GetState(ctx, dsm.stateStoreName, dsm.getLastMessageIDKey(channelID), nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last message ID from Dapr: %w", err)
+	}
+
+	if response == nil || response.Value == nil {
+		return 0, nil
+	}
+
+	var messageID int64
+	if err := json.Unmarshal(response.Value, &messageID); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal last message ID: %w", err)
+	}
+
+	return messageID, nil
+}
+
+// SaveLastMessageID stores the channel's last seen message ID in the Dapr state store
+func (dsm *DaprStateManager) SaveLastMessageID(channelID string, messageID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(messageID)
+	if err != nil {
+		return fmt.Errorf("failed to marshal last message ID: %w", err)
+	}
+
+	if err := (*dsm.client).SaveState(ctx, dsm.stateStoreName, dsm.getLastMessageIDKey(channelID), data, nil); err != nil {
+		return fmt.Errorf("failed to save last message ID: %w", err)
+	}
+
+	return nil
+}
+
+// StoreChannelSnapshot appends the snapshot to the channel's history in the
+// Dapr state store, which holds one record per channel
+func (dsm *DaprStateManager) StoreChannelSnapshot(snapshot ChannelSnapshot) error {
+	dsm.stampSnapshot(&snapshot)
+
+	dsm.historyMutex.Lock()
+	defer dsm.historyMutex.Unlock()
+
+	history, err := dsm.GetChannelHistory(snapshot.ChannelID)
+	if err != nil {
+		return err
+	}
+	history = append(history, snapshot)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to marshal channel history: %w", err)
+	}
+
+	if err := (*dsm.client).SaveState(ctx, dsm.stateStoreName, dsm.getChannelHistoryKey(snapshot.ChannelID), data, nil); err != nil {
+		return fmt.Errorf("failed to save channel history: %w", err)
+	}
+
+	return nil
+}
+
+// GetChannelHistory fetches the channel's snapshots from the Dapr state store
+func (dsm *DaprStateManager) GetChannelHistory(channelID string) ([]ChannelSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	response, err := (*dsm.client).GetState(ctx, dsm.stateStoreName, dsm.getChannelHistoryKey(channelID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel history from Dapr: %w", err)
+	}
+
+	if response == nil || response.Value == nil {
+		return []ChannelSnapshot{}, nil
+	}
+
+	var history []ChannelSnapshot
+	if err := json.Unmarshal(response.Value, &history); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal channel history: %w", err)
+	}
+
+	return history, nil
+}
+
 // addMediaToCacheWithSharding handles adding a media item to the sharded cache system
 func (dsm *DaprStateManager) addMediaToCacheWithSharding(ctx context.Context, mediaID string, item MediaCacheItem) error {
 	dsm.mediaCacheIndexMutex.Lock()
@@ -2084,6 +2293,27 @@ func (dsm *DaprStateManager) getMediaCacheKey() string {
 	return fmt.Sprintf("%s/media-cache", dsm.config.CrawlID)
 }
 
+// getSeenURLsKey generates a key for the crawl's seen-URL set in Dapr
+func (dsm *DaprStateManager) getSeenURLsKey() string {
+	return fmt.Sprintf("%s/seen-urls", dsm.config.CrawlID)
+}
+
+// getLastMessageIDKey generates a key for a channel's last seen message ID in Dapr
+func (dsm *DaprStateManager) getLastMessageIDKey(channelID string) string {
+	return fmt.Sprintf("%s/last-message-id/%s", dsm.config.CrawlID, channelID)
+}
+
+// getStoredPostKey generates a key marking a post of a crawl as stored in Dapr
+func (dsm *DaprStateManager) getStoredPostKey(crawlID string, postUID string) string {
+	return fmt.Sprintf("%s/stored-post/%s", crawlID, postUID)
+}
+
+// getChannelHistoryKey generates a key for a channel's snapshot history in
+// Dapr. It is not prefixed by the crawl ID so the history spans crawls.
+func (dsm *DaprStateManager) getChannelHistoryKey(channelID string) string {
+	return fmt.Sprintf("channel-history/%s/%s", dsm.historyPlatform(), channelID)
+}
+
 // getMediaCacheIndexKey generates a key for the media cache index in Dapr
 func (dsm *DaprStateManager) getMediaCacheIndexKey() string {
 	return fmt.Sprintf("%s/media-cache-index", dsm.config.CrawlID)
@@ -2597,7 +2827,14 @@ func (dsm *DaprStateManager) loadPagesIntoMemory(crawlID string, cont bool) erro
 
 			// Add page to in-memory page map
 			if !cont {
-				if !isResumingSameCrawlExecution {
+				if page.Status == PageStatusQuarantined {
+					// Quarantined pages stay skipped in every execution until retried
+					log.Debug().
+						Str("pageID", pageID).
+						Str("url", page.URL).
+						Int("error_count", page.ErrorCount).
+						Msg("Keeping quarantined page status")
+				} else if !isResumingSameCrawlExecution {
 					// When not resuming the same execution, mark ALL pages as unfetched
 					// to ensure they get reprocessed with the new execution ID
 					oldStatus := page.Status
@@ -2784,9 +3021,9 @@ func (dsm *DaprStateManager) GetLayerByDepth(depth int) ([]Page, error) {
 			continue
 		}
 
-		// Preserve the "fetched" status from Dapr storage
-		// Only reset non-fetched pages to "unfetched" status
-		if page.Status != "unfetched" && page.Status != "fetched" {
+		// Preserve the "fetched" and quarantined statuses from Dapr storage
+		// Only reset other pages to "unfetched" status
+		if page.Status != "unfetched" && page.Status != "fetched" && page.Status != PageStatusQuarantined {
 			page.Status = "unfetched"
 			page.Messages = []Message{}
 			page.Timestamp = time.Now()
@@ -34,22 +34,57 @@ type ChannelInfo struct {
 	Metadata    map[string]interface{} `json:"metadata"`    // Additional platform-specific metadata
 }
 
+// ChannelSnapshot records a channel's engagement counts as seen by one crawl
+// execution. Snapshots are appended rather than overwritten so a channel's
+// growth can be followed across crawls.
+type ChannelSnapshot struct {
+	ChannelID     string    `json:"channelId"`     // Channel identifier
+	CrawlID       string    `json:"crawlId"`       // Execution ID of the crawl that took the snapshot (default: the state manager's)
+	CapturedAt    time.Time `json:"capturedAt"`    // When the counts were read (default: when stored)
+	ViewsCount    int       `json:"viewsCount"`    // Total views across the channel
+	FollowerCount int       `json:"followerCount"` // Members or subscribers
+	PostCount     int       `json:"postCount"`     // Messages or videos published
+}
+
 // Page represents a URL/page being crawled
 type Page struct {
 	// Core page information
 	ID        string    `json:"id"`
 	URL       string    `json:"url"`
 	Depth     int       `json:"depth"`
-	Status    string    `json:"status"` // "unfetched", "fetching", "fetched", "error"
+	Status    string    `json:"status"` // "unfetched", "fetching", "fetched", "error", "quarantined"
 	Error     string    `json:"error,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
 	Platform  string    `json:"platform,omitempty"` // Added for multi-platform support
 
+	// Failure tracking
+	ErrorCount int `json:"errorCount,omitempty"` // Consecutive failed attempts; reset when the page is fetched
+
 	// Relationships
 	ParentID string    `json:"parentId,omitempty"`
 	Messages []Message `json:"messages,omitempty"`
 }
 
+// PageStatusQuarantined is the status of a page that failed too many times in a
+// row. Quarantined pages are skipped until they are explicitly retried.
+const PageStatusQuarantined = "quarantined"
+
+// RecordError marks the page as failed with err. Once the page has failed
+// threshold times in a row it is quarantined instead; a threshold of 0 never
+// quarantines. It reports whether the page is now quarantined.
+func (p *Page) RecordError(err error, threshold int) bool {
+	p.ErrorCount++
+	p.Status = "error"
+	if err != nil {
+		p.Error = err.Error()
+	}
+	if threshold > 0 && p.ErrorCount >= threshold {
+		p.Status = PageStatusQuarantined
+		return true
+	}
+	return false
+}
+
 // Message represents a message associated with a page
 type Message struct {
 	ChatID    int64  `json:"chatId"`
@@ -82,10 +117,11 @@ type CrawlMetadata struct {
 
 // MediaCacheItem represents an item in the media cache
 type MediaCacheItem struct {
-	ID        string    `json:"id"`
-	FirstSeen time.Time `json:"firstSeen"`
-	Metadata  string    `json:"metadata,omitempty"`
-	Platform  string    `json:"platform,omitempty"` // Added for multi-platform support
+	ID         string    `json:"id"`
+	FirstSeen  time.Time `json:"firstSeen"`
+	Metadata   string    `json:"metadata,omitempty"`
+	Platform   string    `json:"platform,omitempty"`   // Added for multi-platform support
+	StorageKey string    `json:"storageKey,omitempty"` // Canonical location of the stored content, shared by duplicates
 }
 
 // MediaCache represents a sharded cache for processed media items
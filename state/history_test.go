@@ -0,0 +1,90 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLocalStateManager_ChannelHistory verifies that snapshots are appended
+// per channel rather than overwritten and are read back in the order stored
+func TestLocalStateManager_ChannelHistory(t *testing.T) {
+	lsm, err := NewLocalStateManager(Config{
+		CrawlID:          "test-crawl",
+		CrawlExecutionID: "exec-2",
+		LocalConfig:      &LocalConfig{BasePath: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create local state manager: %v", err)
+	}
+
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []ChannelSnapshot{
+		{ChannelID: "news", CrawlID: "exec-1", CapturedAt: first, ViewsCount: 1000, FollowerCount: 50, PostCount: 10},
+		{ChannelID: "other", ViewsCount: 7, FollowerCount: 1, PostCount: 1},
+		{ChannelID: "news", ViewsCount: 1500, FollowerCount: 65, PostCount: 12},
+	}
+	for _, snapshot := range snapshots {
+		if err := lsm.StoreChannelSnapshot(snapshot); err != nil {
+			t.Fatalf("StoreChannelSnapshot failed: %v", err)
+		}
+	}
+
+	history, err := lsm.GetChannelHistory("news")
+	if err != nil {
+		t.Fatalf("GetChannelHistory failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 snapshots for news, got %d: %+v", len(history), history)
+	}
+	if history[0].CrawlID != "exec-1" || !history[0].CapturedAt.Equal(first) || history[0].FollowerCount != 50 {
+		t.Errorf("Unexpected first snapshot %+v", history[0])
+	}
+	if history[1].CrawlID != "exec-2" || history[1].CapturedAt.IsZero() || history[1].ViewsCount != 1500 || history[1].PostCount != 12 {
+		t.Errorf("Expected the second snapshot to default to this execution and now, got %+v", history[1])
+	}
+
+	empty, err := lsm.GetChannelHistory("unknown")
+	if err != nil {
+		t.Fatalf("GetChannelHistory failed: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("Expected no snapshots for an unknown channel, got %+v", empty)
+	}
+}
+
+// TestLocalStateManager_ChannelHistorySpansCrawls verifies that the history is
+// kept per platform and channel, so crawls with different IDs add to it
+func TestLocalStateManager_ChannelHistorySpansCrawls(t *testing.T) {
+	basePath := t.TempDir()
+	newManager := func(crawlID, platform string) *LocalStateManager {
+		lsm, err := NewLocalStateManager(Config{
+			CrawlID:     crawlID,
+			Platform:    platform,
+			LocalConfig: &LocalConfig{BasePath: basePath},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create local state manager: %v", err)
+		}
+		return lsm
+	}
+
+	for i, crawlID := range []string{"january", "february"} {
+		if err := newManager(crawlID, "telegram").StoreChannelSnapshot(ChannelSnapshot{ChannelID: "news", FollowerCount: 10 * (i + 1)}); err != nil {
+			t.Fatalf("StoreChannelSnapshot failed: %v", err)
+		}
+	}
+	if err := newManager("february", "youtube").StoreChannelSnapshot(ChannelSnapshot{ChannelID: "news", FollowerCount: 99}); err != nil {
+		t.Fatalf("StoreChannelSnapshot failed: %v", err)
+	}
+
+	history, err := newManager("march", "").GetChannelHistory("news")
+	if err != nil {
+		t.Fatalf("GetChannelHistory failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("Expected the two Telegram snapshots, got %+v", history)
+	}
+	if history[0].CrawlID != "january" || history[1].CrawlID != "february" || history[1].FollowerCount != 20 {
+		t.Errorf("Unexpected history %+v", history)
+	}
+}
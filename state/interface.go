@@ -7,6 +7,7 @@ package state
 
 import (
 	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
 )
 
 // StateManagementInterface defines the core functionality for state management
@@ -38,6 +39,10 @@ type StateManagementInterface interface {
 	// GetMaxDepth returns the highest depth value present in the state
 	GetMaxDepth() (int, error)
 
+	// GetQuarantinedPages returns the pages that were quarantined after failing
+	// repeatedly; they are skipped until explicitly retried
+	GetQuarantinedPages() ([]Page, error)
+
 	// State persistence
 	// SaveState persists the current state to the storage backend
 	SaveState() error
@@ -47,9 +52,20 @@ type StateManagementInterface interface {
 	ExportPagesToBinding(crawlID string) error
 
 	// Data storage
-	// StorePost saves a parsed Telegram post to persistent storage
+	// StorePost saves a parsed Telegram post to persistent storage.
+	// Implementations must be safe for concurrent use. Posts stored for the same
+	// channel are never interleaved and are persisted in the order the calls
+	// acquire the channel's write lock; no ordering is guaranteed across channels.
 	StorePost(channelID string, post model.Post) error
 
+	// HasPost reports whether a post with the given UID has already been stored
+	// for the crawl during this run, so revisited channels are not stored twice
+	HasPost(crawlID string, postUID string) bool
+
+	// QueryPosts returns the posts stored for a crawl that match filter, or
+	// ErrQueryNotSupported if the backend cannot read posts back
+	QueryPosts(crawlID string, filter PostFilter) ([]model.Post, error)
+
 	// StoreFile saves a media file to persistent storage and returns its new path
 	StoreFile(channelID string, sourceFilePath string, fileName string) (string, string, error)
 
@@ -73,6 +89,40 @@ type StateManagementInterface interface {
 	// MarkMediaAsProcessed marks a media item as processed in the cache
 	MarkMediaAsProcessed(mediaID string) error
 
+	// MarkMediaAsStored marks a media item as processed and records the storage
+	// key its content was written to, so later duplicates can reference it
+	MarkMediaAsStored(mediaID string, storageKey string) error
+
+	// GetMediaStorageKey returns the storage key recorded for a processed media
+	// item, or an empty string if the item is unknown or was stored without a key
+	GetMediaStorageKey(mediaID string) (string, error)
+
+	// Discovery tracking
+	// SaveSeenURLs persists every URL queued so far in the crawl, replacing any
+	// previously saved set for the same crawl ID
+	SaveSeenURLs(urls []string) error
+
+	// LoadSeenURLs returns the URLs saved for the crawl ID, or an empty slice
+	// if none have been saved yet
+	LoadSeenURLs() ([]string, error)
+
+	// Incremental crawling
+	// GetLastMessageID returns the highest message ID recorded for the channel
+	// by an earlier crawl, or 0 if none has been recorded
+	GetLastMessageID(channelID string) (int64, error)
+
+	// SaveLastMessageID records the highest message ID seen for the channel
+	SaveLastMessageID(channelID string, messageID int64) error
+
+	// Channel history
+	// StoreChannelSnapshot appends a snapshot of the channel's engagement
+	// counts to its history; earlier snapshots are kept
+	StoreChannelSnapshot(snapshot ChannelSnapshot) error
+
+	// GetChannelHistory returns the snapshots stored for the channel, oldest
+	// first, or an empty slice if none have been stored
+	GetChannelHistory(channelID string) ([]ChannelSnapshot, error)
+
 	// Cleanup
 	// Close performs cleanup operations when shutting down
 	Close() error
@@ -121,13 +171,24 @@ type Config struct {
 	// Values can be "telegram", "youtube", etc.
 	Platform string
 
+	// StorageBackend selects the state manager implementation. When empty,
+	// it is inferred from which of the provider configs below is set.
+	// The blob backends (S3, GCS) keep the crawl state under LocalConfig and
+	// write posts and media to the bucket in BlobConfig.
+	StorageBackend StorageBackend
+
 	// Specific configuration options for different backends
 	// Only one of these should typically be set, based on the
 	// storage backend being used
 	AzureConfig    *AzureConfig
 	DaprConfig     *DaprConfig
 	LocalConfig    *LocalConfig
+	BlobConfig     *BlobConfig
 	MaxPagesConfig *MaxPagesConfig
+
+	// OutputLimits rolls the posts.jsonl of each channel written by the local
+	// state manager into numbered files, like the file post sinks
+	OutputLimits sink.RollingLimits
 }
 
 // AzureConfig contains Azure Blob Storage-specific configuration options
@@ -156,6 +217,33 @@ type DaprConfig struct {
 	ComponentName string
 }
 
+// BlobConfig contains configuration for storing posts and media in an S3 or
+// Google Cloud Storage bucket.
+type BlobConfig struct {
+	// Bucket is the name of the bucket objects are written to
+	Bucket string
+
+	// Prefix is prepended to every object key, e.g. "crawls/2024"
+	Prefix string
+
+	// Region is the S3 region (default: from the AWS environment)
+	Region string
+
+	// Endpoint is the URL of an S3-compatible service such as MinIO
+	// (default: AWS)
+	Endpoint string
+
+	// PostPathTemplate lays out post object keys below Prefix, e.g.
+	// "{platform}/{date}/{channel}/{msgid}.jsonl"; see RenderPostPath for the
+	// placeholders (default: DefaultPostPathTemplate)
+	PostPathTemplate string
+
+	// MediaPathTemplate lays out media object keys below Prefix, e.g.
+	// "{platform}/{date}/{channel}/media/{file}"; see RenderMediaPath for the
+	// placeholders (default: DefaultMediaPathTemplate)
+	MediaPathTemplate string
+}
+
 // LocalConfig contains configuration for storing crawler state and
 // processed data on the local filesystem.
 type LocalConfig struct {
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
+	"github.com/rs/zerolog/log"
+)
+
+// NewPostSink returns a sink that stores posts through sm. This is the default
+// output of a crawl. Posts are stored under the channel set with
+// sink.WithChannel, or under the post's ChannelID if the context has none.
+func NewPostSink(sm StateManagementInterface) sink.PostSink {
+	return sink.PostSinkFunc(func(ctx context.Context, post model.Post) error {
+		channel := sink.ChannelFromContext(ctx)
+		if channel == "" {
+			channel = post.ChannelID
+		}
+		return sm.StorePost(channel, post)
+	})
+}
+
+// OpenPostSinks opens the post outputs named in specs. Each spec is "state"
+// (the state manager), "jsonl" (JSON lines on stdout), "jsonl=<path>", "csv"
+// (CSV on stdout), "csv=<path>" or "parquet=<dir>". The returned function
+// closes every opened output; calls after the first do nothing.
+func OpenPostSinks(specs []string, sm StateManagementInterface, limits sink.RollingLimits, parquetOpts sink.ParquetOptions) ([]sink.PostSink, func(), error) {
+	var sinks []sink.PostSink
+	var closers []func() error
+	var closeOnce sync.Once
+	closeAll := func() {
+		closeOnce.Do(func() {
+			for _, c := range closers {
+				if err := c(); err != nil {
+					log.Warn().Err(err).Msg("Failed to close post output")
+				}
+			}
+		})
+	}
+
+	for _, spec := range specs {
+		name, path, _ := strings.Cut(strings.TrimSpace(spec), "=")
+		switch name {
+		case "state":
+			sinks = append(sinks, NewPostSink(sm))
+		case "jsonl":
+			if path == "" || path == "-" {
+				s := sink.NewJSONLinesSink(os.Stdout)
+				sinks = append(sinks, s)
+				closers = append(closers, s.Close)
+				continue
+			}
+			s, err := sink.NewJSONLinesFileSink(path, limits)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			sinks = append(sinks, s)
+			closers = append(closers, s.Close)
+			log.Info().Str("path", path).Msg("Writing posts as JSON lines")
+		case "csv":
+			if path == "" || path == "-" {
+				s, err := sink.NewCSVSink(os.Stdout)
+				if err != nil {
+					closeAll()
+					return nil, nil, err
+				}
+				sinks = append(sinks, s)
+				closers = append(closers, s.Close)
+				continue
+			}
+			s, err := sink.NewCSVFileSink(path, limits)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			sinks = append(sinks, s)
+			closers = append(closers, s.Close)
+			log.Info().Str("path", path).Msg("Writing posts as CSV")
+		case "parquet":
+			if path == "" {
+				closeAll()
+				return nil, nil, fmt.Errorf("post sink %q needs an output directory, e.g. \"parquet=<dir>\"", spec)
+			}
+			s, err := sink.NewParquetSink(path, parquetOpts)
+			if err != nil {
+				closeAll()
+				return nil, nil, err
+			}
+			sinks = append(sinks, s)
+			closers = append(closers, s.Close)
+			log.Info().Str("dir", path).Str("partition_by", parquetOpts.PartitionBy).Msg("Writing posts as Parquet")
+		default:
+			closeAll()
+			return nil, nil, fmt.Errorf("unknown post sink %q, must be \"state\", \"jsonl\", \"jsonl=<path>\", \"csv\", \"csv=<path>\" or \"parquet=<dir>\"", spec)
+		}
+	}
+	return sinks, closeAll, nil
+}
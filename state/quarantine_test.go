@@ -0,0 +1,88 @@
+package state
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestLocalStateManager_QuarantinesRepeatedlyFailingPage verifies that a page
+// is quarantined once it reaches the failure threshold and that the
+// quarantine survives a reload of the saved state
+func TestLocalStateManager_QuarantinesRepeatedlyFailingPage(t *testing.T) {
+	basePath := t.TempDir()
+	newManager := func() *LocalStateManager {
+		lsm, err := NewLocalStateManager(Config{
+			CrawlID:     "test-crawl",
+			LocalConfig: &LocalConfig{BasePath: basePath},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create local state manager: %v", err)
+		}
+		return lsm
+	}
+
+	lsm := newManager()
+	if err := lsm.Initialize([]string{"broken", "healthy"}); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	pages, err := lsm.GetLayerByDepth(0)
+	if err != nil {
+		t.Fatalf("GetLayerByDepth failed: %v", err)
+	}
+	var broken Page
+	for _, page := range pages {
+		if page.URL == "broken" {
+			broken = page
+		}
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		quarantined := broken.RecordError(errors.New("channel unavailable"), 3)
+		if quarantined != (attempt == 3) {
+			t.Fatalf("Attempt %d: expected quarantined=%v, got %v", attempt, attempt == 3, quarantined)
+		}
+		if err := lsm.UpdatePage(broken); err != nil {
+			t.Fatalf("UpdatePage failed: %v", err)
+		}
+		if attempt < 3 {
+			if broken.Status != "error" {
+				t.Errorf("Attempt %d: expected status error, got %q", attempt, broken.Status)
+			}
+			if got, _ := lsm.GetQuarantinedPages(); len(got) != 0 {
+				t.Errorf("Attempt %d: expected no quarantined pages, got %v", attempt, got)
+			}
+		}
+	}
+	if err := lsm.SaveState(); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	reloaded := newManager()
+	if err := reloaded.Initialize(nil); err != nil {
+		t.Fatalf("Initialize on reload failed: %v", err)
+	}
+	quarantined, err := reloaded.GetQuarantinedPages()
+	if err != nil {
+		t.Fatalf("GetQuarantinedPages failed: %v", err)
+	}
+	if len(quarantined) != 1 || quarantined[0].URL != "broken" {
+		t.Fatalf("Expected only the broken page to be quarantined, got %v", quarantined)
+	}
+	if quarantined[0].Status != PageStatusQuarantined || quarantined[0].ErrorCount != 3 || quarantined[0].Error != "channel unavailable" {
+		t.Errorf("Unexpected quarantined page %+v", quarantined[0])
+	}
+}
+
+// TestPage_RecordErrorWithoutThreshold verifies that a zero threshold never
+// quarantines a page
+func TestPage_RecordErrorWithoutThreshold(t *testing.T) {
+	page := Page{URL: "flaky", Status: "unfetched"}
+	for i := 0; i < 10; i++ {
+		if page.RecordError(errors.New("timeout"), 0) {
+			t.Fatal("Expected a zero threshold to never quarantine")
+		}
+	}
+	if page.Status != "error" || page.ErrorCount != 10 {
+		t.Errorf("Expected status error with 10 failures, got %q with %d", page.Status, page.ErrorCount)
+	}
+}
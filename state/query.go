@@ -0,0 +1,50 @@
+package state
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+)
+
+// ErrQueryNotSupported is returned by QueryPosts when the storage backend
+// cannot read stored posts back.
+var ErrQueryNotSupported = errors.New("querying stored posts is not supported by this storage backend")
+
+// PostFilter selects the posts returned by QueryPosts. Zero-valued fields
+// match every post.
+type PostFilter struct {
+	ChannelName  string    // Channel the post was stored under or the channel's title, ignoring case
+	From         time.Time // Earliest PublishedAt, inclusive
+	To           time.Time // Latest PublishedAt, inclusive
+	ContentTypes []string  // Content types, e.g. "photo" or "messageVideo"; a post matches if any of its types is listed
+	MinViews     int       // Minimum ViewCount
+}
+
+// Matches reports whether a post stored under channelID passes the filter.
+func (f PostFilter) Matches(channelID string, post model.Post) bool {
+	if f.ChannelName != "" && !strings.EqualFold(f.ChannelName, channelID) && !strings.EqualFold(f.ChannelName, post.ChannelName) {
+		return false
+	}
+	if !f.From.IsZero() && post.PublishedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && post.PublishedAt.After(f.To) {
+		return false
+	}
+	if post.ViewCount < f.MinViews {
+		return false
+	}
+	if len(f.ContentTypes) > 0 {
+		types := common.ContentTypeFilter{Include: f.ContentTypes}
+		for _, postType := range post.PostType {
+			if types.Allows(postType) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package state
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+)
+
+// TestLocalStateManager_QueryPosts verifies that stored posts are read back
+// and narrowed down by each filter field
+func TestLocalStateManager_QueryPosts(t *testing.T) {
+	lsm, err := NewLocalStateManager(Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &LocalConfig{BasePath: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create local state manager: %v", err)
+	}
+
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	stored := map[string][]model.Post{
+		"news": {
+			{PostUID: "1-news", ChannelName: "Daily News", PublishedAt: day(1), ViewCount: 50, PostType: []string{"messageText"}},
+			{PostUID: "2-news", ChannelName: "Daily News", PublishedAt: day(2), ViewCount: 500, PostType: []string{"messagePhoto"}},
+			{PostUID: "3-news", ChannelName: "Daily News", PublishedAt: day(3), ViewCount: 5000, PostType: []string{"messageVideo"}},
+		},
+		"sport": {
+			{PostUID: "1-sport", ChannelName: "Sport", PublishedAt: day(2), ViewCount: 700, PostType: []string{"messagePhoto"}},
+		},
+	}
+	for channel, posts := range stored {
+		for _, post := range posts {
+			if err := lsm.StorePost(channel, post); err != nil {
+				t.Fatalf("StorePost failed: %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter PostFilter
+		want   []string
+	}{
+		{"no filter", PostFilter{}, []string{"1-news", "2-news", "3-news", "1-sport"}},
+		{"channel directory", PostFilter{ChannelName: "SPORT"}, []string{"1-sport"}},
+		{"channel title", PostFilter{ChannelName: "daily news"}, []string{"1-news", "2-news", "3-news"}},
+		{"date range", PostFilter{From: day(2), To: day(2)}, []string{"2-news", "1-sport"}},
+		{"open-ended date", PostFilter{From: day(3)}, []string{"3-news"}},
+		{"content type", PostFilter{ContentTypes: []string{"photo", "messageVideo"}}, []string{"2-news", "3-news", "1-sport"}},
+		{"min views", PostFilter{MinViews: 600}, []string{"3-news", "1-sport"}},
+		{"combined", PostFilter{ChannelName: "news", ContentTypes: []string{"photo"}, MinViews: 100}, []string{"2-news"}},
+		{"no match", PostFilter{ChannelName: "weather"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posts, err := lsm.QueryPosts("test-crawl", tt.filter)
+			if err != nil {
+				t.Fatalf("QueryPosts failed: %v", err)
+			}
+			var got []string
+			for _, post := range posts {
+				got = append(got, post.PostUID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryPosts returned %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	posts, err := lsm.QueryPosts("unknown-crawl", PostFilter{})
+	if err != nil {
+		t.Fatalf("QueryPosts of an unknown crawl failed: %v", err)
+	}
+	if posts == nil || len(posts) != 0 {
+		t.Errorf("Expected an empty result for an unknown crawl, got %v", posts)
+	}
+}
+
+func TestBlobStateManager_QueryPostsNotSupported(t *testing.T) {
+	bsm, err := NewBlobStateManager(Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &LocalConfig{BasePath: t.TempDir()},
+	}, &LocalBlobStore{BasePath: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create blob state manager: %v", err)
+	}
+	if _, err := bsm.QueryPosts("test-crawl", PostFilter{}); !errors.Is(err, ErrQueryNotSupported) {
+		t.Errorf("Expected ErrQueryNotSupported, got %v", err)
+	}
+}
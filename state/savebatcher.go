@@ -0,0 +1,104 @@
+package state
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SaveBatcher coalesces the SaveState calls made as pages finish, so a large
+// crawl doesn't rewrite its full state after every page. The state is saved
+// once every pages finished pages, every interval while finished pages are
+// unsaved, and by Flush and Close. It is safe for concurrent use.
+type SaveBatcher struct {
+	sm       StateManagementInterface
+	pages    int
+	interval time.Duration
+
+	mu      sync.Mutex // Serializes saves and guards pending
+	pending int        // Pages finished since the last successful save
+
+	stop      chan struct{}
+	stopped   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewSaveBatcher returns a SaveBatcher for sm. A pages value below 1 saves
+// after every page, as if there were no batching; a zero interval saves by
+// page count only. Close must be called to stop the interval timer.
+func NewSaveBatcher(sm StateManagementInterface, pages int, interval time.Duration) *SaveBatcher {
+	if pages < 1 {
+		pages = 1
+	}
+	b := &SaveBatcher{
+		sm:       sm,
+		pages:    pages,
+		interval: interval,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if interval > 0 {
+		go b.run()
+	} else {
+		close(b.stopped)
+	}
+	return b
+}
+
+// run flushes every interval until Close is called.
+func (b *SaveBatcher) run() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.Flush(); err != nil {
+				log.Error().Err(err).Msg("Failed to save state on the save interval")
+			}
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// PageDone records that a page finished and saves the state once a full batch
+// of pages is unsaved.
+func (b *SaveBatcher) PageDone() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending++
+	if b.pending < b.pages {
+		return nil
+	}
+	return b.saveLocked()
+}
+
+// Flush saves the state if any finished page is unsaved.
+func (b *SaveBatcher) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pending == 0 {
+		return nil
+	}
+	return b.saveLocked()
+}
+
+// Close stops the interval timer and flushes the finished pages that are
+// still unsaved.
+func (b *SaveBatcher) Close() error {
+	b.closeOnce.Do(func() { close(b.stop) })
+	<-b.stopped
+	return b.Flush()
+}
+
+// saveLocked saves the state. A failed save keeps the pages pending so the
+// next save retries them. b.mu must be held.
+func (b *SaveBatcher) saveLocked() error {
+	if err := b.sm.SaveState(); err != nil {
+		return err
+	}
+	b.pending = 0
+	return nil
+}
@@ -0,0 +1,155 @@
+package state
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingSaveManager is a local state manager that counts SaveState calls
+type countingSaveManager struct {
+	*LocalStateManager
+	mu    sync.Mutex
+	saves int
+	err   error
+}
+
+func (c *countingSaveManager) SaveState() error {
+	c.mu.Lock()
+	c.saves++
+	err := c.err
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return c.LocalStateManager.SaveState()
+}
+
+func (c *countingSaveManager) saveCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.saves
+}
+
+func newCountingSaveManager(t *testing.T, basePath string) *countingSaveManager {
+	t.Helper()
+	lsm, err := NewLocalStateManager(Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &LocalConfig{BasePath: basePath},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create local state manager: %v", err)
+	}
+	return &countingSaveManager{LocalStateManager: lsm}
+}
+
+// TestSaveBatcher_BatchesSavesAndPersistsFinalState verifies that pages
+// finished concurrently are saved in batches and that Close persists the
+// pages of the last, partial batch
+func TestSaveBatcher_BatchesSavesAndPersistsFinalState(t *testing.T) {
+	basePath := t.TempDir()
+	sm := newCountingSaveManager(t, basePath)
+	urls := make([]string, 25)
+	for i := range urls {
+		urls[i] = "channel" + string(rune('a'+i))
+	}
+	if err := sm.Initialize(urls); err != nil {
+		t.Fatalf("Initialize failed: %v", err)
+	}
+	pages, err := sm.GetLayerByDepth(0)
+	if err != nil {
+		t.Fatalf("GetLayerByDepth failed: %v", err)
+	}
+
+	batcher := NewSaveBatcher(sm, 10, 0)
+	var wg sync.WaitGroup
+	for _, page := range pages {
+		wg.Add(1)
+		go func(page Page) {
+			defer wg.Done()
+			page.Status = "fetched"
+			if err := sm.UpdatePage(page); err != nil {
+				t.Errorf("UpdatePage failed: %v", err)
+			}
+			if err := batcher.PageDone(); err != nil {
+				t.Errorf("PageDone failed: %v", err)
+			}
+		}(page)
+	}
+	wg.Wait()
+
+	if got := sm.saveCount(); got != 2 {
+		t.Errorf("Expected 2 saves for 25 pages in batches of 10, got %d", got)
+	}
+	if err := batcher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := sm.saveCount(); got != 3 {
+		t.Errorf("Expected Close to save the last 5 pages, got %d saves", got)
+	}
+	if err := batcher.Close(); err != nil || sm.saveCount() != 3 {
+		t.Errorf("Expected a second Close to have nothing to save, got %d saves, err %v", sm.saveCount(), err)
+	}
+
+	reloaded := newCountingSaveManager(t, basePath)
+	if err := reloaded.Initialize(nil); err != nil {
+		t.Fatalf("Initialize on reload failed: %v", err)
+	}
+	saved, err := reloaded.GetLayerByDepth(0)
+	if err != nil {
+		t.Fatalf("GetLayerByDepth on reload failed: %v", err)
+	}
+	if len(saved) != len(urls) {
+		t.Fatalf("Expected %d saved pages, got %d", len(urls), len(saved))
+	}
+	for _, page := range saved {
+		if page.Status != "fetched" {
+			t.Errorf("Expected page %s to be saved as fetched, got %q", page.URL, page.Status)
+		}
+	}
+}
+
+// TestSaveBatcher_SavesOnInterval verifies that an unfinished batch is saved
+// once the interval elapses
+func TestSaveBatcher_SavesOnInterval(t *testing.T) {
+	sm := newCountingSaveManager(t, t.TempDir())
+	batcher := NewSaveBatcher(sm, 100, 10*time.Millisecond)
+	defer batcher.Close()
+
+	if err := batcher.PageDone(); err != nil {
+		t.Fatalf("PageDone failed: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for sm.saveCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := sm.saveCount(); got != 1 {
+		t.Fatalf("Expected the interval to save the pending page once, got %d saves", got)
+	}
+
+	// Nothing is pending anymore, so further ticks don't save
+	time.Sleep(50 * time.Millisecond)
+	if got := sm.saveCount(); got != 1 {
+		t.Errorf("Expected no saves without pending pages, got %d", got)
+	}
+}
+
+// TestSaveBatcher_RetriesFailedSave verifies that pages of a failed save stay
+// pending until a later save succeeds
+func TestSaveBatcher_RetriesFailedSave(t *testing.T) {
+	sm := newCountingSaveManager(t, t.TempDir())
+	sm.err = errors.New("disk full")
+	batcher := NewSaveBatcher(sm, 1, 0)
+
+	if err := batcher.PageDone(); err == nil {
+		t.Fatal("Expected the failed save to be returned")
+	}
+	sm.err = nil
+	if err := batcher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := sm.saveCount(); got != 2 {
+		t.Errorf("Expected Close to retry the failed save, got %d saves", got)
+	}
+}
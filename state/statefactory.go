@@ -1,6 +1,7 @@
 package state
 
 import (
+	"context"
 	"fmt"
 	"github.com/rs/zerolog/log"
 )
@@ -20,6 +21,35 @@ func (f *DefaultStateManagerFactory) Create(config Config) (StateManagementInter
 	log.Debug().
 		Interface("config", config).
 		Msg("Creating new state manager")
+
+	// An explicitly selected backend takes precedence over the provider configs.
+	// The local and blob backends keep their state under the storage root
+	// unless a LocalConfig says otherwise.
+	if (config.StorageBackend == StorageBackendLocal || config.StorageBackend.IsBlob()) && config.LocalConfig == nil {
+		config.LocalConfig = &LocalConfig{BasePath: config.StorageRoot}
+	}
+	switch config.StorageBackend {
+	case StorageBackendLocal:
+		log.Info().
+			Str("crawl_id", config.CrawlID).
+			Msg("Creating local filesystem state manager")
+		return NewLocalStateManager(config)
+	case StorageBackendS3, StorageBackendGCS:
+		var blobCfg BlobConfig
+		if config.BlobConfig != nil {
+			blobCfg = *config.BlobConfig
+		}
+		store, err := newBlobStore(context.Background(), config.StorageBackend, blobCfg)
+		if err != nil {
+			return nil, err
+		}
+		log.Info().
+			Str("backend", string(config.StorageBackend)).
+			Str("bucket", blobCfg.Bucket).
+			Str("crawl_id", config.CrawlID).
+			Msg("Creating blob storage state manager")
+		return NewBlobStateManager(config, store)
+	}
 		
 	// Check for DAPR configuration
 	if config.DaprConfig != nil {
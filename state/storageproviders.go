@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
 	"github.com/rs/zerolog/log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -77,6 +80,11 @@ type LocalStateManager struct {
 	basePath        string
 	mediaCache      map[string]MediaCacheItem
 	mediaCacheMutex sync.RWMutex
+	postLocks       sync.Map   // channelID -> *sync.Mutex guarding that channel's posts file
+	postFiles       sync.Map   // channelID -> *sink.RollingFile of that channel's posts, when OutputLimits are set
+	lastIDsMutex    sync.Mutex // Serializes read-modify-write of the last message IDs file
+	historyMutex    sync.Mutex // Serializes appends to and reads of the channel history files
+	storedMutex     sync.Mutex // Serializes appends to the stored posts file
 }
 
 // NewLocalStateManager creates a new local filesystem-backed state manager
@@ -108,6 +116,9 @@ func NewLocalStateManager(config Config) (*LocalStateManager, error) {
 	if err := lsm.loadState(); err != nil {
 		log.Warn().Err(err).Msg("Failed to load existing state, starting fresh")
 	}
+	if err := lsm.loadStoredPosts(); err != nil {
+		log.Warn().Err(err).Msg("Failed to load stored posts, posts may be stored again")
+	}
 
 	return lsm, nil
 }
@@ -169,7 +180,15 @@ func (lsm *LocalStateManager) SaveState() error {
 	return nil
 }
 
-// StorePost saves a post to the filesystem
+// channelPostLock returns the mutex that serializes writes to a channel's posts file.
+func (lsm *LocalStateManager) channelPostLock(channelID string) *sync.Mutex {
+	lock, _ := lsm.postLocks.LoadOrStore(channelID, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// StorePost saves a post to the filesystem. Writes to the same channel are
+// serialized so each post occupies exactly one line of posts.jsonl, while
+// different channels can be written concurrently.
 func (lsm *LocalStateManager) StorePost(channelID string, post model.Post) error {
 	postData, err := json.Marshal(post)
 	if err != nil {
@@ -179,6 +198,10 @@ func (lsm *LocalStateManager) StorePost(channelID string, post model.Post) error
 	// Append newline for JSONL format
 	postData = append(postData, '\n')
 
+	lock := lsm.channelPostLock(channelID)
+	lock.Lock()
+	defer lock.Unlock()
+
 	// Create directory path
 	postsDir := filepath.Join(lsm.basePath, lsm.config.CrawlID, channelID, "posts")
 	if err := lsm.storageProvider.CreateDir(postsDir); err != nil {
@@ -187,14 +210,146 @@ func (lsm *LocalStateManager) StorePost(channelID string, post model.Post) error
 
 	// Append to JSONL file
 	postsFile := filepath.Join(postsDir, "posts.jsonl")
-	if err := lsm.storageProvider.AppendToFile(postsFile, postData); err != nil {
+	if lsm.config.OutputLimits.Enabled() {
+		if err := lsm.appendRolledPost(channelID, postsFile, postData); err != nil {
+			return err
+		}
+	} else if err := lsm.storageProvider.AppendToFile(postsFile, postData); err != nil {
 		return fmt.Errorf("failed to append post to file: %w", err)
 	}
+	lsm.markPostStored(post.PostUID)
 
 	log.Debug().Str("channel", channelID).Str("postID", post.PostUID).Msg("Post stored")
 	return nil
 }
 
+// appendRolledPost appends a post to the channel's numbered posts files,
+// starting a new file when OutputLimits are reached. The caller holds the
+// channel's post lock.
+func (lsm *LocalStateManager) appendRolledPost(channelID, postsFile string, postData []byte) error {
+	var postFile *sink.RollingFile
+	if open, ok := lsm.postFiles.Load(channelID); ok {
+		postFile = open.(*sink.RollingFile)
+	} else {
+		var err error
+		postFile, err = sink.NewRollingFile(postsFile, lsm.config.OutputLimits, nil)
+		if err != nil {
+			return fmt.Errorf("failed to open posts file: %w", err)
+		}
+		lsm.postFiles.Store(channelID, postFile)
+	}
+
+	if err := postFile.WriteRecord(postData); err != nil {
+		return fmt.Errorf("failed to append post to file: %w", err)
+	}
+	return nil
+}
+
+// markPostStored records a stored post in memory and appends its UID to the
+// crawl's stored posts file, so HasPost still reports it after a restart.
+func (lsm *LocalStateManager) markPostStored(postUID string) {
+	lsm.BaseStateManager.markPostStored(postUID)
+
+	lsm.storedMutex.Lock()
+	defer lsm.storedMutex.Unlock()
+	if err := lsm.storageProvider.AppendToFile(lsm.getStoredPostsFilePath(), []byte(postUID+"\n")); err != nil {
+		log.Warn().Err(err).Str("postUID", postUID).Msg("Failed to record stored post")
+	}
+}
+
+// loadStoredPosts reads the UIDs of the posts stored by earlier runs of the
+// crawl into the stored-post set.
+func (lsm *LocalStateManager) loadStoredPosts() error {
+	storedFile := lsm.getStoredPostsFilePath()
+	exists, err := lsm.storageProvider.FileExists(storedFile)
+	if err != nil || !exists {
+		return err
+	}
+
+	data, err := lsm.storageProvider.ReadFile(storedFile)
+	if err != nil {
+		return fmt.Errorf("failed to read stored posts file: %w", err)
+	}
+	for _, postUID := range strings.Split(string(data), "\n") {
+		if postUID != "" {
+			lsm.BaseStateManager.markPostStored(postUID)
+		}
+	}
+	return nil
+}
+
+// QueryPosts reads the posts stored for a crawl and returns those matching
+// filter, channel by channel in name order and in storage order within a
+// channel. A crawl without stored posts returns an empty slice.
+func (lsm *LocalStateManager) QueryPosts(crawlID string, filter PostFilter) ([]model.Post, error) {
+	crawlDir := filepath.Join(lsm.basePath, crawlID)
+	entries, err := os.ReadDir(crawlDir)
+	if os.IsNotExist(err) {
+		return []model.Post{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list crawl directory: %w", err)
+	}
+
+	posts := []model.Post{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		channelID := entry.Name()
+		postsDir := filepath.Join(crawlDir, channelID, "posts")
+
+		// Posts rolled by OutputLimits are in numbered files next to posts.jsonl
+		postsFiles, err := filepath.Glob(filepath.Join(postsDir, "posts-*.jsonl"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list posts files: %w", err)
+		}
+		sort.Strings(postsFiles)
+		postsFiles = append([]string{filepath.Join(postsDir, "posts.jsonl")}, postsFiles...)
+
+		for _, postsFile := range postsFiles {
+			channelPosts, err := lsm.readPostsFile(channelID, postsFile)
+			if err != nil {
+				return nil, err
+			}
+			for _, post := range channelPosts {
+				if filter.Matches(channelID, post) {
+					posts = append(posts, post)
+				}
+			}
+		}
+	}
+	return posts, nil
+}
+
+// readPostsFile decodes a channel's posts.jsonl, holding the channel's write
+// lock so a post being appended is not read half-written.
+func (lsm *LocalStateManager) readPostsFile(channelID string, postsFile string) ([]model.Post, error) {
+	lock := lsm.channelPostLock(channelID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	file, err := os.Open(postsFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open posts file: %w", err)
+	}
+	defer file.Close()
+
+	var posts []model.Post
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var post model.Post
+		if err := decoder.Decode(&post); err != nil {
+			return nil, fmt.Errorf("failed to decode post in %s: %w", postsFile, err)
+		}
+		posts = append(posts, post)
+	}
+	return posts, nil
+}
+
 // StoreFile stores a file in the filesystem
 func (lsm *LocalStateManager) StoreFile(channelID string, sourceFilePath string, fileName string) (string, string, error) {
 	// Check if source file exists
@@ -208,16 +363,7 @@ func (lsm *LocalStateManager) StoreFile(channelID string, sourceFilePath string,
 		return "", "", fmt.Errorf("failed to read source file: %w", err)
 	}
 
-	// Generate filename if not provided
-	if fileName == "" {
-		fileName = filepath.Base(sourceFilePath)
-	} else {
-		// Add extension from source file if needed
-		ext := filepath.Ext(sourceFilePath)
-		if ext != "" && !strings.HasSuffix(fileName, ext) {
-			fileName = fileName + ext
-		}
-	}
+	fileName = mediaFileName(sourceFilePath, fileName)
 
 	// Create media directory
 	mediaDir := filepath.Join(lsm.basePath, lsm.config.CrawlID, "media", channelID)
@@ -241,6 +387,20 @@ func (lsm *LocalStateManager) StoreFile(channelID string, sourceFilePath string,
 	return relPath, fileName, nil
 }
 
+// mediaFileName returns the name a media file is stored under: fileName with
+// the extension of the source file, or the source file's own name if fileName
+// is empty.
+func mediaFileName(sourceFilePath string, fileName string) string {
+	if fileName == "" {
+		return filepath.Base(sourceFilePath)
+	}
+	ext := filepath.Ext(sourceFilePath)
+	if ext != "" && !strings.HasSuffix(fileName, ext) {
+		fileName = fileName + ext
+	}
+	return fileName
+}
+
 // HasProcessedMedia checks if media has been processed before
 func (lsm *LocalStateManager) HasProcessedMedia(mediaID string) (bool, error) {
 	// First check memory cache
@@ -288,11 +448,17 @@ func (lsm *LocalStateManager) HasProcessedMedia(mediaID string) (bool, error) {
 
 // MarkMediaAsProcessed marks media as processed
 func (lsm *LocalStateManager) MarkMediaAsProcessed(mediaID string) error {
+	return lsm.MarkMediaAsStored(mediaID, "")
+}
+
+// MarkMediaAsStored marks media as processed and records its storage key
+func (lsm *LocalStateManager) MarkMediaAsStored(mediaID string, storageKey string) error {
 	// Add to memory cache
 	lsm.mediaCacheMutex.Lock()
 	lsm.mediaCache[mediaID] = MediaCacheItem{
-		ID:        mediaID,
-		FirstSeen: time.Now(),
+		ID:         mediaID,
+		FirstSeen:  time.Now(),
+		StorageKey: storageKey,
 	}
 
 	// Create a copy of the cache for saving
@@ -316,12 +482,182 @@ func (lsm *LocalStateManager) MarkMediaAsProcessed(mediaID string) error {
 	return nil
 }
 
+// GetMediaStorageKey returns the storage key recorded for processed media
+func (lsm *LocalStateManager) GetMediaStorageKey(mediaID string) (string, error) {
+	// HasProcessedMedia merges the on-disk cache into memory if needed
+	exists, err := lsm.HasProcessedMedia(mediaID)
+	if err != nil || !exists {
+		return "", err
+	}
+
+	lsm.mediaCacheMutex.RLock()
+	defer lsm.mediaCacheMutex.RUnlock()
+	return lsm.mediaCache[mediaID].StorageKey, nil
+}
+
+// SaveSeenURLs writes the seen-URL set for the crawl to disk
+func (lsm *LocalStateManager) SaveSeenURLs(urls []string) error {
+	data, err := json.Marshal(urls)
+	if err != nil {
+		return fmt.Errorf("failed to marshal seen URLs: %w", err)
+	}
+
+	if err := lsm.storageProvider.WriteFile(lsm.getSeenURLsFilePath(), data); err != nil {
+		return fmt.Errorf("failed to write seen URLs file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSeenURLs reads the seen-URL set for the crawl from disk
+func (lsm *LocalStateManager) LoadSeenURLs() ([]string, error) {
+	seenFile := lsm.getSeenURLsFilePath()
+	exists, err := lsm.storageProvider.FileExists(seenFile)
+	if err != nil {
+		return nil, fmt.Errorf("error checking seen URLs file: %w", err)
+	}
+	if !exists {
+		return []string{}, nil
+	}
+
+	data, err := lsm.storageProvider.ReadFile(seenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seen URLs file: %w", err)
+	}
+
+	var urls []string
+	if err := json.Unmarshal(data, &urls); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal seen URLs: %w", err)
+	}
+
+	return urls, nil
+}
+
+// GetLastMessageID reads the channel's last seen message ID from disk
+func (lsm *LocalStateManager) GetLastMessageID(channelID string) (int64, error) {
+	lsm.lastIDsMutex.Lock()
+	defer lsm.lastIDsMutex.Unlock()
+
+	ids, err := lsm.loadLastMessageIDs()
+	if err != nil {
+		return 0, err
+	}
+	return ids[channelID], nil
+}
+
+// SaveLastMessageID writes the channel's last seen message ID to disk
+func (lsm *LocalStateManager) SaveLastMessageID(channelID string, messageID int64) error {
+	lsm.lastIDsMutex.Lock()
+	defer lsm.lastIDsMutex.Unlock()
+
+	ids, err := lsm.loadLastMessageIDs()
+	if err != nil {
+		return err
+	}
+	ids[channelID] = messageID
+
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("failed to marshal last message IDs: %w", err)
+	}
+	if err := lsm.storageProvider.WriteFile(lsm.getLastMessageIDsFilePath(), data); err != nil {
+		return fmt.Errorf("failed to write last message IDs file: %w", err)
+	}
+	return nil
+}
+
+// loadLastMessageIDs reads the channel -> last message ID map, which is empty
+// if nothing has been saved yet. The caller must hold lastIDsMutex.
+func (lsm *LocalStateManager) loadLastMessageIDs() (map[string]int64, error) {
+	ids := make(map[string]int64)
+	idsFile := lsm.getLastMessageIDsFilePath()
+	exists, err := lsm.storageProvider.FileExists(idsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error checking last message IDs file: %w", err)
+	}
+	if !exists {
+		return ids, nil
+	}
+
+	data, err := lsm.storageProvider.ReadFile(idsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read last message IDs file: %w", err)
+	}
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal last message IDs: %w", err)
+	}
+	return ids, nil
+}
+
+// StoreChannelSnapshot appends the snapshot as a JSON line to the channel's
+// history file, which every crawl of the channel on the platform shares
+func (lsm *LocalStateManager) StoreChannelSnapshot(snapshot ChannelSnapshot) error {
+	lsm.stampSnapshot(&snapshot)
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal channel snapshot: %w", err)
+	}
+	data = append(data, '\n')
+
+	lsm.historyMutex.Lock()
+	defer lsm.historyMutex.Unlock()
+
+	historyFile := lsm.getChannelHistoryFilePath(snapshot.ChannelID)
+	if err := lsm.storageProvider.CreateDir(filepath.Dir(historyFile)); err != nil {
+		return fmt.Errorf("failed to create channel history directory: %w", err)
+	}
+	if err := lsm.storageProvider.AppendToFile(historyFile, data); err != nil {
+		return fmt.Errorf("failed to append to channel history file: %w", err)
+	}
+	return nil
+}
+
+// GetChannelHistory reads the channel's snapshots from its history file
+func (lsm *LocalStateManager) GetChannelHistory(channelID string) ([]ChannelSnapshot, error) {
+	lsm.historyMutex.Lock()
+	defer lsm.historyMutex.Unlock()
+
+	historyFile := lsm.getChannelHistoryFilePath(channelID)
+	file, err := os.Open(historyFile)
+	if os.IsNotExist(err) {
+		return []ChannelSnapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel history file: %w", err)
+	}
+	defer file.Close()
+
+	history := []ChannelSnapshot{}
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var snapshot ChannelSnapshot
+		if err := decoder.Decode(&snapshot); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot in %s: %w", historyFile, err)
+		}
+		history = append(history, snapshot)
+	}
+	return history, nil
+}
+
 // Close performs cleanup
 func (lsm *LocalStateManager) Close() error {
 	// Save state one last time
 	if err := lsm.SaveState(); err != nil {
 		log.Warn().Err(err).Msg("Failed to save state during close")
 	}
+
+	// Close the rolled posts files; a post stored later starts a new file
+	lsm.postFiles.Range(func(channelID, postFile interface{}) bool {
+		lock := lsm.channelPostLock(channelID.(string))
+		lock.Lock()
+		defer lock.Unlock()
+		if err := postFile.(*sink.RollingFile).Close(); err != nil {
+			log.Warn().Err(err).Str("channel", channelID.(string)).Msg("Failed to close posts file")
+		}
+		lsm.postFiles.Delete(channelID)
+		return true
+	})
 	return nil
 }
 
@@ -539,6 +875,28 @@ func (lsm *LocalStateManager) getMetadataFilePath() string {
 	return filepath.Join(lsm.basePath, lsm.config.CrawlID, "metadata.json")
 }
 
+// getSeenURLsFilePath returns the path to the seen URLs file
+func (lsm *LocalStateManager) getSeenURLsFilePath() string {
+	return filepath.Join(lsm.basePath, lsm.config.CrawlID, "seen-urls.json")
+}
+
+// getLastMessageIDsFilePath returns the path to the last message IDs file
+func (lsm *LocalStateManager) getLastMessageIDsFilePath() string {
+	return filepath.Join(lsm.basePath, lsm.config.CrawlID, "last-message-ids.json")
+}
+
+// getStoredPostsFilePath returns the path to the file listing the UIDs of
+// the crawl's stored posts
+func (lsm *LocalStateManager) getStoredPostsFilePath() string {
+	return filepath.Join(lsm.basePath, lsm.config.CrawlID, "stored-posts.txt")
+}
+
+// getChannelHistoryFilePath returns the path to a channel's history file. It
+// lies outside the crawl directories so the history spans crawl IDs.
+func (lsm *LocalStateManager) getChannelHistoryFilePath(channelID string) string {
+	return filepath.Join(lsm.basePath, "channel-history", lsm.historyPlatform(), url.PathEscape(channelID)+".jsonl")
+}
+
 // getMediaCacheFilePath returns the path to the media cache file
 func (lsm *LocalStateManager) getMediaCacheFilePath() string {
 	return filepath.Join(lsm.basePath, lsm.config.CrawlID, "media-cache.json")
@@ -0,0 +1,249 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
+)
+
+// TestLocalStateManager_StorePostConcurrent verifies that concurrent writers
+// to the same channel produce one well-formed JSONL line per post
+func TestLocalStateManager_StorePostConcurrent(t *testing.T) {
+	basePath := t.TempDir()
+	lsm, err := NewLocalStateManager(Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &LocalConfig{BasePath: basePath},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create local state manager: %v", err)
+	}
+
+	const writers = 20
+	const postsPerWriter = 25
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < postsPerWriter; i++ {
+				post := model.Post{
+					PostUID:     fmt.Sprintf("%d-%d", w, i),
+					Description: fmt.Sprintf("post body %d from writer %d", i, w),
+				}
+				if err := lsm.StorePost("channel", post); err != nil {
+					t.Errorf("StorePost failed: %v", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	file, err := os.Open(filepath.Join(basePath, "test-crawl", "channel", "posts", "posts.jsonl"))
+	if err != nil {
+		t.Fatalf("Failed to open posts file: %v", err)
+	}
+	defer file.Close()
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var post model.Post
+		if err := json.Unmarshal(scanner.Bytes(), &post); err != nil {
+			t.Fatalf("Corrupted line in posts file: %v", err)
+		}
+		seen[post.PostUID] = true
+	}
+
+	if len(seen) != writers*postsPerWriter {
+		t.Errorf("Expected %d posts, got %d", writers*postsPerWriter, len(seen))
+	}
+}
+
+// TestLocalStateManager_StorePostRollsFiles verifies that OutputLimits split a
+// channel's posts into numbered files that are all read back
+func TestLocalStateManager_StorePostRollsFiles(t *testing.T) {
+	basePath := t.TempDir()
+	lsm, err := NewLocalStateManager(Config{
+		CrawlID:      "test-crawl",
+		LocalConfig:  &LocalConfig{BasePath: basePath},
+		OutputLimits: sink.RollingLimits{MaxRecords: 2},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create local state manager: %v", err)
+	}
+
+	for i := 1; i <= 5; i++ {
+		if err := lsm.StorePost("channel", model.Post{PostUID: fmt.Sprintf("%d-channel", i)}); err != nil {
+			t.Fatalf("StorePost failed: %v", err)
+		}
+	}
+	if err := lsm.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	postsDir := filepath.Join(basePath, "test-crawl", "channel", "posts")
+	for file, want := range map[string]int{"posts-00001.jsonl": 2, "posts-00002.jsonl": 2, "posts-00003.jsonl": 1} {
+		data, err := os.ReadFile(filepath.Join(postsDir, file))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		if got := bytes.Count(data, []byte("\n")); got != want {
+			t.Errorf("Expected %d posts in %s, got %d", want, file, got)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(postsDir, "posts.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("Expected no unnumbered posts file when rolling, got %v", err)
+	}
+
+	posts, err := lsm.QueryPosts("test-crawl", PostFilter{})
+	if err != nil {
+		t.Fatalf("QueryPosts failed: %v", err)
+	}
+	if len(posts) != 5 {
+		t.Errorf("Expected 5 posts across the rolled files, got %d", len(posts))
+	}
+}
+
+// TestLocalStateManager_MediaStorageKey verifies that the canonical storage key
+// of processed media survives a restart of the state manager
+func TestLocalStateManager_MediaStorageKey(t *testing.T) {
+	cfg := Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &LocalConfig{BasePath: t.TempDir()},
+	}
+
+	lsm, err := NewLocalStateManager(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create local state manager: %v", err)
+	}
+
+	if err := lsm.MarkMediaAsStored("unique-1", "test-crawl/media/chan/unique-1.jpg"); err != nil {
+		t.Fatalf("MarkMediaAsStored failed: %v", err)
+	}
+	if err := lsm.MarkMediaAsProcessed("unique-2"); err != nil {
+		t.Fatalf("MarkMediaAsProcessed failed: %v", err)
+	}
+
+	reloaded, err := NewLocalStateManager(cfg)
+	if err != nil {
+		t.Fatalf("Failed to recreate local state manager: %v", err)
+	}
+
+	key, err := reloaded.GetMediaStorageKey("unique-1")
+	if err != nil {
+		t.Fatalf("GetMediaStorageKey failed: %v", err)
+	}
+	if key != "test-crawl/media/chan/unique-1.jpg" {
+		t.Errorf("Expected stored key, got %q", key)
+	}
+
+	for _, id := range []string{"unique-2", "unknown"} {
+		key, err := reloaded.GetMediaStorageKey(id)
+		if err != nil || key != "" {
+			t.Errorf("Expected empty key for %s, got %q (err: %v)", id, key, err)
+		}
+	}
+}
+
+// TestLocalStateManager_SeenURLs verifies that the seen-URL set survives a
+// restart and is empty for a crawl that never saved one
+func TestLocalStateManager_SeenURLs(t *testing.T) {
+	cfg := Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &LocalConfig{BasePath: t.TempDir()},
+	}
+
+	lsm, err := NewLocalStateManager(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create local state manager: %v", err)
+	}
+
+	urls, err := lsm.LoadSeenURLs()
+	if err != nil {
+		t.Fatalf("LoadSeenURLs failed: %v", err)
+	}
+	if len(urls) != 0 {
+		t.Errorf("Expected no seen URLs before saving, got %v", urls)
+	}
+
+	if err := lsm.SaveSeenURLs([]string{"channel-a", "channel-b"}); err != nil {
+		t.Fatalf("SaveSeenURLs failed: %v", err)
+	}
+
+	reloaded, err := NewLocalStateManager(cfg)
+	if err != nil {
+		t.Fatalf("Failed to recreate local state manager: %v", err)
+	}
+
+	urls, err = reloaded.LoadSeenURLs()
+	if err != nil {
+		t.Fatalf("LoadSeenURLs failed: %v", err)
+	}
+	if len(urls) != 2 || urls[0] != "channel-a" || urls[1] != "channel-b" {
+		t.Errorf("Expected saved URLs after restart, got %v", urls)
+	}
+}
+
+// TestLocalStateManager_HasPost verifies that stored posts are reported for
+// their crawl only
+func TestLocalStateManager_HasPost(t *testing.T) {
+	lsm, err := NewLocalStateManager(Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &LocalConfig{BasePath: t.TempDir()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create local state manager: %v", err)
+	}
+
+	if lsm.HasPost("test-crawl", "1-channel") {
+		t.Errorf("Expected post to be unknown before it is stored")
+	}
+
+	if err := lsm.StorePost("channel", model.Post{PostUID: "1-channel"}); err != nil {
+		t.Fatalf("StorePost failed: %v", err)
+	}
+
+	if !lsm.HasPost("test-crawl", "1-channel") {
+		t.Errorf("Expected stored post to be reported")
+	}
+	if lsm.HasPost("other-crawl", "1-channel") {
+		t.Errorf("Expected post to be unknown for a different crawl")
+	}
+}
+
+// TestLocalStateManager_HasPostAfterRestart verifies that stored posts are
+// still reported by a new state manager for the same crawl
+func TestLocalStateManager_HasPostAfterRestart(t *testing.T) {
+	config := Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &LocalConfig{BasePath: t.TempDir()},
+	}
+	lsm, err := NewLocalStateManager(config)
+	if err != nil {
+		t.Fatalf("Failed to create local state manager: %v", err)
+	}
+	if err := lsm.StorePost("channel", model.Post{PostUID: "1-channel"}); err != nil {
+		t.Fatalf("StorePost failed: %v", err)
+	}
+
+	restarted, err := NewLocalStateManager(config)
+	if err != nil {
+		t.Fatalf("Failed to create local state manager: %v", err)
+	}
+	if !restarted.HasPost("test-crawl", "1-channel") {
+		t.Errorf("Expected post stored before the restart to be reported")
+	}
+	if restarted.HasPost("test-crawl", "2-channel") {
+		t.Errorf("Expected post to be unknown before it is stored")
+	}
+}
@@ -0,0 +1,180 @@
+package telegramhelper
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/rs/zerolog/log"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// ParseAlbum is ParseAlbumContext with a background context. Like
+// ParseMessage, it is a variable so tests can replace it.
+var ParseAlbum = func(
+	crawlid string,
+	messages []*client.Message,
+	links []*client.MessageLink,
+	chat *client.Chat,
+	supergroup *client.Supergroup,
+	supergroupInfo *client.SupergroupFullInfo,
+	postcount int,
+	viewcount int,
+	channelName string,
+	tdlibClient crawler.TDLibClient,
+	sm state.StateManagementInterface,
+	cfg common.CrawlerConfig,
+) (model.Post, error) {
+	return ParseAlbumContext(context.Background(), crawlid, messages, links, chat, supergroup, supergroupInfo, postcount, viewcount, channelName, tdlibClient, sm, cfg)
+}
+
+// ParseAlbumContext parses the messages of a media album, which Telegram
+// delivers as separate messages sharing a MediaAlbumId, and stores them as a
+// single post the way ParseMessageContext stores one message. links holds the
+// link of each message.
+//
+// The post is that of the album's first message with the media, captions and
+// extracted text of the other messages merged in, and every message listed in
+// AlbumItems. Skipped messages, such as those outside the post date window,
+// are left out; if all of them are skipped an empty post is returned.
+func ParseAlbumContext(
+	ctx context.Context,
+	crawlid string,
+	messages []*client.Message,
+	links []*client.MessageLink,
+	chat *client.Chat,
+	supergroup *client.Supergroup,
+	supergroupInfo *client.SupergroupFullInfo,
+	postcount int,
+	viewcount int,
+	channelName string,
+	tdlibClient crawler.TDLibClient,
+	sm state.StateManagementInterface,
+	cfg common.CrawlerConfig,
+) (album model.Post, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().
+				Str("channel", channelName).
+				Interface("panic", r).
+				Str("stack", string(debug.Stack())).
+				Msg("Recovered from panic while parsing album")
+			err = fmt.Errorf("failed to parse album: %v", r)
+		}
+	}()
+
+	if len(links) != len(messages) {
+		return model.Post{}, fmt.Errorf("album has %d messages but %d links", len(messages), len(links))
+	}
+
+	// The album is stored under the UID of one of its messages
+	if postStored(crawlid, links, channelName, sm, cfg) {
+		return model.Post{}, nil
+	}
+
+	mediaFiles := 0
+	for i, message := range messages {
+		post, ok, err := buildPost(ctx, crawlid, message, links[i], chat, supergroup, supergroupInfo, postcount, viewcount, channelName, tdlibClient, sm, cfg)
+		if err != nil {
+			return model.Post{}, err
+		}
+		if !ok {
+			continue
+		}
+
+		if len(album.AlbumItems) == 0 {
+			album = post
+		} else {
+			mergeAlbumPost(&album, post)
+		}
+		album.AlbumItems = append(album.AlbumItems, albumItem(message.Id, post))
+		mediaFiles += dryRunMediaFiles(message, cfg)
+	}
+	if len(album.AlbumItems) == 0 {
+		return model.Post{}, nil
+	}
+
+	setSearchText(&album)
+	return storePost(ctx, album, mediaFiles, crawlid, channelName, sm, cfg)
+}
+
+// albumItem records the media of one parsed album message.
+func albumItem(messageID int64, post model.Post) model.MediaItem {
+	return model.MediaItem{
+		MessageID:        messageID,
+		PostLink:         post.PostLink,
+		PostType:         slices.Clone(post.PostType),
+		Description:      post.Description,
+		ThumbURL:         post.ThumbURL,
+		MediaURL:         post.MediaURL,
+		MediaStorageKeys: slices.Clone(post.MediaStorageKeys),
+		MediaErrors:      slices.Clone(post.MediaErrors),
+		Video:            post.Video,
+	}
+}
+
+// mergeAlbumPost adds the media and text of a later message of an album to the
+// album's post. Engagement counts, reactions and channel data stay those of the
+// album's first message.
+func mergeAlbumPost(album *model.Post, post model.Post) {
+	album.Description = joinNonEmpty(album.Description, post.Description)
+	album.FormattedText = joinNonEmpty(album.FormattedText, post.FormattedText)
+	album.TranscriptText = joinNonEmpty(album.TranscriptText, post.TranscriptText)
+	if album.TranscriptLanguage == "" {
+		album.TranscriptLanguage = post.TranscriptLanguage
+	}
+
+	if album.ThumbURL == "" {
+		album.ThumbURL = post.ThumbURL
+	}
+	if album.MediaURL == "" {
+		album.MediaURL = post.MediaURL
+	}
+	if album.Video == nil {
+		album.Video = post.Video
+		album.VideoLength = post.VideoLength
+	}
+	if album.Audio == nil {
+		album.Audio = post.Audio
+	}
+
+	album.MediaStorageKeys = append(album.MediaStorageKeys, post.MediaStorageKeys...)
+	album.MediaErrors = append(album.MediaErrors, post.MediaErrors...)
+	album.OCRData = append(album.OCRData, post.OCRData...)
+	album.ImageText = joinImageText(album.OCRData)
+	album.Comments = append(album.Comments, post.Comments...)
+
+	album.PostType = appendMissing(album.PostType, post.PostType...)
+	album.Outlinks = appendMissing(album.Outlinks, post.Outlinks...)
+	album.DiscoveredChannels = appendMissing(album.DiscoveredChannels, post.DiscoveredChannels...)
+	album.URLs = appendMissing(album.URLs, post.URLs...)
+	album.Mentions = appendMissing(album.Mentions, post.Mentions...)
+	album.Hashtags = appendMissing(album.Hashtags, post.Hashtags...)
+}
+
+// joinNonEmpty joins a and b with a newline, leaving out an empty one.
+func joinNonEmpty(a, b string) string {
+	if strings.TrimSpace(a) == "" {
+		return b
+	}
+	if strings.TrimSpace(b) == "" {
+		return a
+	}
+	return a + "\n" + b
+}
+
+// appendMissing appends the values that dst doesn't contain yet.
+func appendMissing(dst []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(dst, v) {
+			dst = append(dst, v)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,90 @@
+package telegramhelper
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// albumMessages returns three photos of one media album; only the first has a
+// caption
+func albumMessages() ([]*client.Message, []*client.MessageLink) {
+	var messages []*client.Message
+	var links []*client.MessageLink
+	for i := int64(1); i <= 3; i++ {
+		caption := ""
+		if i == 1 {
+			caption = "Three photos of the march"
+		}
+		messages = append(messages, &client.Message{
+			Id:           i << 20,
+			ChatId:       -1001,
+			Date:         int32(time.Now().Unix()),
+			MediaAlbumId: 777,
+			Content: &client.MessagePhoto{
+				Photo: &client.Photo{Sizes: []*client.PhotoSize{{
+					Photo: &client.File{Id: int32(i), Remote: &client.RemoteFile{Id: fmt.Sprintf("remote-photo-%d", i)}},
+				}}},
+				Caption: &client.FormattedText{Text: caption},
+			},
+		})
+		links = append(links, &client.MessageLink{Link: fmt.Sprintf("https://t.me/example/%d", i)})
+	}
+	return messages, links
+}
+
+func TestParseMessage_AlbumMessagesShareAlbumID(t *testing.T) {
+	tdlibClient := &remoteFileRecorder{dir: t.TempDir()}
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	messages, links := albumMessages()
+
+	for i, message := range messages {
+		post, err := ParseMessage("crawl", message, links[i], chat, nil, nil, 0, 0, "example", tdlibClient, newTestStateManager(t), common.CrawlerConfig{})
+		require.NoError(t, err)
+		assert.Equal(t, "777", post.AlbumID, "Consumers regroup album posts by their album ID")
+		assert.Empty(t, post.AlbumItems)
+	}
+}
+
+func TestParseAlbum_StoresOnePostWithEveryItem(t *testing.T) {
+	tdlibClient := &remoteFileRecorder{dir: t.TempDir()}
+	sm := &recordingStateManager{StateManagementInterface: newTestStateManager(t)}
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	messages, links := albumMessages()
+
+	post, err := ParseAlbum("crawl", messages, links, chat, nil, nil, 0, 0, "example", tdlibClient, sm, common.CrawlerConfig{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, sm.storePostCalls, "The album should be stored as one post")
+	assert.Equal(t, "777", post.AlbumID)
+	assert.Equal(t, "https://t.me/example/1", post.PostLink, "The album is the post of its first message")
+	assert.Equal(t, "Three photos of the march", post.Description)
+
+	require.Len(t, post.AlbumItems, 3)
+	for i, item := range post.AlbumItems {
+		assert.Equal(t, messages[i].Id, item.MessageID)
+		assert.Equal(t, links[i].Link, item.PostLink)
+		require.Len(t, item.MediaStorageKeys, 1)
+	}
+	assert.Equal(t, "Three photos of the march", post.AlbumItems[0].Description)
+	assert.Empty(t, post.AlbumItems[1].Description)
+
+	require.Len(t, post.MediaStorageKeys, 3, "The album should keep the media of every message")
+	for _, key := range post.MediaStorageKeys {
+		assert.Contains(t, key, "/album_777/")
+	}
+	assert.ElementsMatch(t, []string{"remote-photo-1", "remote-photo-2", "remote-photo-3"}, tdlibClient.remoteIDs)
+}
+
+func TestParseAlbum_RequiresALinkPerMessage(t *testing.T) {
+	messages, links := albumMessages()
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+
+	_, err := ParseAlbum("crawl", messages, links[:2], chat, nil, nil, 0, 0, "example", &remoteFileRecorder{dir: t.TempDir()}, nil, common.CrawlerConfig{})
+	assert.Error(t, err)
+}
@@ -3,18 +3,22 @@ package telegramhelper
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/researchaccelerator-hub/telegram-scraper/common"
 	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
 	"github.com/rs/zerolog/log"
 	"github.com/zelenin/go-tdlib/client"
-	"hash/fnv"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -65,6 +69,21 @@ type TelegramService interface {
 	GetMe(libClient crawler.TDLibClient) (*client.User, error)
 }
 
+// ContextTelegramService is a TelegramService whose calls can be cancelled or
+// given a deadline through a context. GenCodeContext and GetMeWithRetryContext
+// use these methods when a service provides them.
+type ContextTelegramService interface {
+	TelegramService
+
+	// InitializeClientContext is InitializeClientWithConfig with a context. It
+	// returns ctx's error as soon as ctx is done, including while a pre-seeded
+	// database is downloaded or TDLib is connecting.
+	InitializeClientContext(ctx context.Context, storagePrefix string, cfg common.CrawlerConfig) (crawler.TDLibClient, error)
+
+	// GetMeContext is GetMe with a context.
+	GetMeContext(ctx context.Context, libClient crawler.TDLibClient) (*client.User, error)
+}
+
 // RealTelegramService is the concrete implementation of the TelegramService interface
 // that uses the TDLib library for authenticating and communicating with Telegram servers.
 //
@@ -76,11 +95,16 @@ type TelegramService interface {
 //
 // The implementation supports both development and production use cases,
 // with options for configuring database locations and authentication methods.
-type RealTelegramService struct{}
+type RealTelegramService struct {
+	// knownUsers maps each client of the service to the user it last
+	// authenticated as, so GetMeWithRetry can fall back to it while Telegram
+	// is unreachable. Clients are removed when they are closed.
+	knownUsers sync.Map
+}
 
 // InitializeClient sets up a real TDLib client
 func (s *RealTelegramService) InitializeClient(storagePrefix string) (crawler.TDLibClient, error) {
-	return s.InitializeClientWithConfig(storagePrefix, common.CrawlerConfig{})
+	return s.InitializeClientContext(context.Background(), storagePrefix, common.CrawlerConfig{})
 }
 
 // Credentials stores Telegram API authentication details necessary for
@@ -215,56 +239,63 @@ func maskPhoneNumber(phoneNumber string) string {
 //
 // The function supports several advanced features:
 //   - Loading pre-seeded TDLib databases from remote URLs for faster startup
-//   - Reading authentication credentials from a credentials file or environment variables
-//   - Creating unique database directories for each client instance
+//   - Reading authentication credentials from a credentials file or environment variables,
+//     or logging in with the app in cfg.APICredential
+//   - Reopening the same session directory on every run, so an authorized session is reused
 //   - Handling the complete Telegram authentication flow
 //
 // Connection timeouts ensure the process doesn't hang indefinitely if authentication
 // or connection problems occur. If authentication requires user interaction for phone code,
-// the function will prompt for input through the CLI interactor.
+// the function will prompt for input through the CLI interactor. When cfg.BotToken is set
+// the client logs in as that bot instead and never prompts. The requests of the
+// returned client are paced by cfg.TDLibLimiter, if set.
 func (s *RealTelegramService) InitializeClientWithConfig(storagePrefix string, cfg common.CrawlerConfig) (crawler.TDLibClient, error) {
-	authorizer := client.ClientAuthorizer()
-
-	// We'll use the default CLI interactor but prepare environment variables
-	// so we need to track the phoneCode for later
-
-	// Create a unique subfolder based on URL hash plus unique process identifiers
-	h := fnv.New32a()
-	h.Write([]byte(cfg.TDLibDatabaseURL))
-
-	// Add unique components to ensure different processes get different folders
-	// even if they share the same database URL
-	uniqueComponent := fmt.Sprintf("%d_%d", time.Now().UnixNano(), os.Getpid())
-	h.Write([]byte(uniqueComponent))
-
-	uniqueSubfolder := fmt.Sprintf("conn_%d", h.Sum32())
-	// Create the full unique path
-	uniquePath := filepath.Join(storagePrefix, "state", uniqueSubfolder)
+	return s.InitializeClientContext(context.Background(), storagePrefix, cfg)
+}
 
-	if cfg.TDLibDatabaseURL != "" {
+// InitializeClientContext is InitializeClientWithConfig with a context. ctx
+// cancels the download of a pre-seeded database and stops waiting for TDLib
+// to connect.
+func (s *RealTelegramService) InitializeClientContext(ctx context.Context, storagePrefix string, cfg common.CrawlerConfig) (crawler.TDLibClient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-		// Ensure the directory exists
-		err := os.MkdirAll(uniquePath, 0755)
-		if err != nil {
-			log.Warn().Err(err).Msgf("Failed to create unique directory %s for database", uniquePath)
-		}
+	// Route downloads and TDLib traffic through the configured or environment proxy
+	proxyURL, err := resolveProxyURL(cfg.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	downloadClient := newDownloadClient(proxyURL, cfg.HTTP)
 
-		// Download and extract to the unique directory
-		if err := downloadAndExtractTarball(cfg.TDLibDatabaseURL, uniquePath); err != nil {
-			log.Warn().Err(err).Msg("Failed to download and extract pre-seeded TDLib database, proceeding with fresh database")
-			// Continue with a fresh database even if download fails
-		} else {
-			log.Info().Msgf("Successfully downloaded and extracted pre-seeded TDLib database to %s", uniquePath)
+	// Open the same session directory on every run so the stored authorization is reused
+	sessionPath, existingSession := prepareSession(ctx, storagePrefix, cfg, downloadClient)
+	initialized := false
+	defer func() {
+		if !initialized {
+			releaseSessionDir(sessionPath)
 		}
-	}
+	}()
 
 	// Try to read credentials from file first
 	var apiID int
 	var apiHash string
 	var phoneNumber, phoneCode string
 
-	creds, err := readCredentials(uniquePath)
-	if err == nil && creds != nil {
+	creds, err := readCredentials(sessionPath)
+	if cfg.APICredential != nil {
+		// The app was assigned by the session pool; only the login comes from the file or environment
+		log.Info().Int("api_id", cfg.APICredential.APIID).Msg("Using API credentials assigned to the session")
+		apiID = cfg.APICredential.APIID
+		apiHash = cfg.APICredential.APIHash
+		if err == nil && creds != nil {
+			phoneNumber = creds.PhoneNumber
+			phoneCode = creds.PhoneCode
+		} else {
+			phoneNumber = os.Getenv("TG_PHONE_NUMBER")
+			phoneCode = os.Getenv("TG_PHONE_CODE")
+		}
+	} else if err == nil && creds != nil {
 		log.Info().Msg("Using API credentials from stored file")
 		apiID, err = strconv.Atoi(creds.APIId)
 		if err != nil {
@@ -280,8 +311,8 @@ func (s *RealTelegramService) InitializeClientWithConfig(storagePrefix string, c
 		apiIdStr := os.Getenv("TG_API_ID")
 		apiID, err = strconv.Atoi(apiIdStr)
 		if err != nil {
-			log.Fatal().Err(err).Msg("Error converting TG_API_ID to int")
-			return nil, err
+			log.Error().Err(err).Msg("Error converting TG_API_ID to int")
+			return nil, fmt.Errorf("invalid TG_API_ID: %w", err)
 		}
 		apiHash = os.Getenv("TG_API_HASH")
 		phoneNumber = os.Getenv("TG_PHONE_NUMBER")
@@ -297,17 +328,8 @@ func (s *RealTelegramService) InitializeClientWithConfig(storagePrefix string, c
 	}
 
 	// Determine database and files directory paths
-	var dbDir, filesDir string
-
-	if uniqueSubfolder != "" {
-		// Use the unique subfolder path if we created one
-		dbDir = filepath.Join(storagePrefix, "state", uniqueSubfolder, ".tdlib", "database")
-		filesDir = filepath.Join(storagePrefix, "state", uniqueSubfolder, ".tdlib", "files")
-	} else {
-		// Use default paths
-		dbDir = filepath.Join(storagePrefix, "state", ".tdlib", "database")
-		filesDir = filepath.Join(storagePrefix, "state", ".tdlib", "files")
-	}
+	dbDir := filepath.Join(sessionPath, ".tdlib", "database")
+	filesDir := filepath.Join(sessionPath, ".tdlib", "files")
 
 	// Ensure directories exist
 	os.MkdirAll(dbDir, 0755)
@@ -315,7 +337,8 @@ func (s *RealTelegramService) InitializeClientWithConfig(storagePrefix string, c
 
 	log.Info().Msgf("Using TDLib database directory: %s", dbDir)
 
-	authorizer.TdlibParameters <- &client.SetTdlibParametersRequest{
+	authorizer, tdlibParameters := newAuthorizer(cfg, phoneNumber, phoneCode, existingSession)
+	tdlibParameters <- &client.SetTdlibParametersRequest{
 		UseTestDc:           false,
 		DatabaseDirectory:   dbDir,
 		FilesDirectory:      filesDir,
@@ -331,20 +354,22 @@ func (s *RealTelegramService) InitializeClientWithConfig(storagePrefix string, c
 		ApplicationVersion:  "1.0.0",
 	}
 
-	log.Warn().Msg("ABOUT TO CONNECT TO TELEGRAM. IF YOUR PHONE CODE IS INVALID, YOU MUST RE-RUN WITH A VALID CODE.")
-
-	// Set up authentication environment variables
-	// The phone number will be picked up by the default CLI interactor
-	SetupAuth(phoneNumber, phoneCode)
-
-	// Use the default CLI interactor which will read the environment variables
-	go client.CliInteractor(authorizer)
+	var clientOptions []client.Option
+	if proxyURL != nil {
+		proxyReq, err := tdlibProxyRequest(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		log.Info().Str("proxy", proxyURL.Redacted()).Msg("Routing TDLib traffic through proxy")
+		clientOptions = append(clientOptions, client.WithProxy(proxyReq))
+	}
 
-	clientReady := make(chan *client.Client)
-	errChan := make(chan error)
+	tdlibClient, err := waitForClient(ctx, func() (*client.Client, error) {
+		tdlibClient, err := client.NewClient(authorizer, clientOptions...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize TDLib client: %w", err)
+		}
 
-	go func() {
-		tdlibClient, err := client.NewClient(authorizer)
 		// Set verbosity level from config (default is 1, lower values increase verbosity)
 		verbosityLevel := 1 // Default value if not configured
 		if cfg.TDLibVerbosity > 0 {
@@ -354,45 +379,227 @@ func (s *RealTelegramService) InitializeClientWithConfig(storagePrefix string, c
 		log.Debug().Int("verbosity_level", verbosityLevel).Msg("Setting TDLib verbosity level")
 		verb := client.SetLogVerbosityLevelRequest{NewVerbosityLevel: int32(verbosityLevel)}
 		tdlibClient.SetLogVerbosityLevel(&verb)
+		return tdlibClient, nil
+	}, initTimeout(cfg))
+	if err != nil {
+		log.Error().Err(err).Msg("Error initializing client")
+		return nil, err
+	}
 
-		if err != nil {
-			errChan <- fmt.Errorf("failed to initialize TDLib client: %w", err)
-			return
-		}
-		clientReady <- tdlibClient
+	log.Info().Msg("Client initialized successfully")
+	initialized = true
+	session := &sessionClient{Client: tdlibClient, dir: sessionPath}
+	limited := WithRateLimit(session, cfg.TDLibLimiter)
+	session.onClose = func() { s.knownUsers.Delete(limited) }
+	return limited, nil
+}
+
+// Authentication methods chosen by authMethod.
+const (
+	authMethodPhone = "phone" // Interactive phone number and code login of a user account
+	authMethodBot   = "bot"   // Non-interactive bot token login
+)
+
+// authMethod returns how the client authenticates: with the bot token when one
+// is configured, otherwise with the phone login.
+func authMethod(cfg common.CrawlerConfig) string {
+	if cfg.BotToken != "" {
+		return authMethodBot
+	}
+	return authMethodPhone
+}
+
+// newAuthorizer returns the TDLib authorization handler for the configured
+// authentication method, along with the channel its TDLib parameters must be
+// sent on. For the phone login the CLI interactor is started to answer the
+// phone number and code prompts. A phone code is only set up for a new session;
+// an existing session is already authorized and TDLib does not ask for one.
+func newAuthorizer(cfg common.CrawlerConfig, phoneNumber, phoneCode string, existingSession bool) (client.AuthorizationStateHandler, chan<- *client.SetTdlibParametersRequest) {
+	if authMethod(cfg) == authMethodBot {
+		log.Info().Msg("Authenticating with bot token; bots can only read chats they are a member of")
+		authorizer := client.BotAuthorizer(cfg.BotToken)
+		return authorizer, authorizer.TdlibParameters
+	}
+
+	authorizer := client.ClientAuthorizer()
+	if existingSession {
+		log.Info().Msg("Logging in with the stored TDLib session; no phone code needed")
+	} else {
+		log.Warn().Msg("ABOUT TO CONNECT TO TELEGRAM. IF YOUR PHONE CODE IS INVALID, YOU MUST RE-RUN WITH A VALID CODE.")
+
+		// Set up authentication environment variables
+		// The phone number will be picked up by the default CLI interactor
+		SetupAuth(phoneNumber, phoneCode)
+	}
+
+	// Use the default CLI interactor which will read the environment variables
+	go client.CliInteractor(authorizer)
+
+	return authorizer, authorizer.TdlibParameters
+}
+
+// defaultInitTimeout is how long client initialization may take when CrawlerConfig.InitTimeout is unset.
+const defaultInitTimeout = 30 * time.Second
+
+// initTimeout returns the configured client initialization timeout, or the default if unset.
+func initTimeout(cfg common.CrawlerConfig) time.Duration {
+	if cfg.InitTimeout > 0 {
+		return cfg.InitTimeout
+	}
+	return defaultInitTimeout
+}
+
+// waitForClient runs newClient in the background and waits up to timeout for it
+// to return. On timeout an error is returned instead of exiting, so callers can
+// retry or shut down cleanly. If ctx is done first, ctx's error is returned.
+func waitForClient(ctx context.Context, newClient func() (*client.Client, error), timeout time.Duration) (*client.Client, error) {
+	type result struct {
+		client *client.Client
+		err    error
+	}
+	// Buffered so the goroutine can finish even if nobody is waiting anymore
+	done := make(chan result, 1)
+
+	go func() {
+		c, err := newClient()
+		done <- result{client: c, err: err}
 	}()
 
 	select {
-	case tdlibClient := <-clientReady:
-		log.Info().Msg("Client initialized successfully")
-		return tdlibClient, nil
-	case err := <-errChan:
-		log.Fatal().Err(err).Msg("Error initializing client")
-		return nil, err
-	case <-time.After(30 * time.Second):
-		log.Warn().Msg("Timeout reached. Exiting application.")
-		return nil, fmt.Errorf("timeout initializing TDLib client")
+	case r := <-done:
+		return r.client, r.err
+	case <-time.After(timeout):
+		log.Warn().Dur("timeout", timeout).Msg("Timeout reached while initializing TDLib client")
+		return nil, fmt.Errorf("timeout initializing TDLib client after %s", timeout)
+	case <-ctx.Done():
+		log.Warn().Err(ctx.Err()).Msg("Stopped waiting for TDLib client initialization")
+		return nil, ctx.Err()
 	}
-
 }
 
 // GetMe retrieves the authenticated Telegram user
 func (t *RealTelegramService) GetMe(tdlibClient crawler.TDLibClient) (*client.User, error) {
-	user, err := tdlibClient.GetMe()
+	return t.GetMeContext(context.Background(), tdlibClient)
+}
+
+// GetMeContext is GetMe with a context. If ctx is done before TDLib answers,
+// ctx's error is returned.
+func (t *RealTelegramService) GetMeContext(ctx context.Context, tdlibClient crawler.TDLibClient) (*client.User, error) {
+	user, err := callWithContext(ctx, tdlibClient.GetMe)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to retrieve authenticated user")
-		return nil, err
+		log.Error().Err(err).Msg("Failed to retrieve authenticated user")
+		return nil, fmt.Errorf("failed to retrieve authenticated user: %w", err)
 	}
 	log.Info().Msgf("Logged in as: %s %s", user.FirstName, user.LastName)
 	return user, nil
 }
 
-// GenCode initializes the TDLib client and retrieves the authenticated user
+// Ping checks that tdlibClient is connected and authorized by asking TDLib for
+// the logged in user, giving up once ctx is done. Unlike GetMe it doesn't log
+// the user, so it can back frequent health checks.
+func Ping(ctx context.Context, tdlibClient crawler.TDLibClient) error {
+	if tdlibClient == nil {
+		return errors.New("no TDLib client")
+	}
+	if _, err := callWithContext(ctx, tdlibClient.GetMe); err != nil {
+		return fmt.Errorf("TDLib client is not authorized: %w", err)
+	}
+	return nil
+}
+
+// Defaults used by GenCode when retrying GetMe.
+const (
+	defaultGetMeAttempts = 3
+	defaultGetMeDelay    = time.Second
+)
+
+// userCache is implemented by services that remember the user each of their
+// clients last authenticated as.
+type userCache interface {
+	rememberUser(tdlibClient crawler.TDLibClient, user *client.User)
+	knownUser(tdlibClient crawler.TDLibClient) (*client.User, bool)
+}
+
+// rememberUser implements userCache
+func (t *RealTelegramService) rememberUser(tdlibClient crawler.TDLibClient, user *client.User) {
+	t.knownUsers.Store(tdlibClient, user)
+}
+
+// knownUser implements userCache
+func (t *RealTelegramService) knownUser(tdlibClient crawler.TDLibClient) (*client.User, bool) {
+	user, ok := t.knownUsers.Load(tdlibClient)
+	if !ok {
+		return nil, false
+	}
+	return user.(*client.User), true
+}
+
+// GetMeWithRetry is service.GetMe for long-running processes. A failed call is
+// retried until attempts calls have been made, doubling delay after each one,
+// so a brief disconnect right after startup is not fatal. If every attempt
+// fails but an earlier call for the same client and service succeeded, the
+// user from that call is returned instead of the error. Only services that
+// remember users, such as RealTelegramService, support the fallback.
+func GetMeWithRetry(service TelegramService, tdlibClient crawler.TDLibClient, attempts int, delay time.Duration) (*client.User, error) {
+	return GetMeWithRetryContext(context.Background(), service, tdlibClient, attempts, delay)
+}
+
+// GetMeWithRetryContext is GetMeWithRetry with a context. Once ctx is done no
+// further attempts are made and ctx's error is returned, without falling back
+// to the cached user.
+func GetMeWithRetryContext(ctx context.Context, service TelegramService, tdlibClient crawler.TDLibClient, attempts int, delay time.Duration) (*client.User, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var user *client.User
+		if user, err = getMe(ctx, service, tdlibClient); err == nil {
+			if cache, ok := service.(userCache); ok {
+				cache.rememberUser(tdlibClient, user)
+			}
+			return user, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		log.Warn().Err(err).Int("attempt", attempt).Int("max_attempts", attempts).Msg("GetMe attempt failed")
+		if attempt < attempts {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			delay *= 2
+		}
+	}
+
+	if cache, ok := service.(userCache); ok {
+		if user, ok := cache.knownUser(tdlibClient); ok {
+			log.Warn().Err(err).Msg("Telegram unreachable, using the previously authenticated user")
+			return user, nil
+		}
+	}
+	return nil, err
+}
+
+// GenCode initializes the TDLib client and retrieves the authenticated user.
+// As a top-level entry point it exits the process if either step fails.
 func GenCode(service TelegramService, storagePrefix string) {
-	tdclient, err := service.InitializeClient(storagePrefix)
+	if err := GenCodeContext(context.Background(), service, storagePrefix); err != nil {
+		log.Fatal().Err(err).Msg("Failed to generate TDLib session")
+	}
+}
+
+// GenCodeContext is GenCode for callers embedding the scraper: instead of
+// exiting the process it returns the error of the step that failed, or ctx's
+// error once ctx is done.
+func GenCodeContext(ctx context.Context, service TelegramService, storagePrefix string) error {
+	tdclient, err := initializeClient(ctx, service, storagePrefix)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to initialize TDLib client")
-		return
+		return fmt.Errorf("failed to initialize TDLib client: %w", err)
 	}
 	defer func() {
 		if tdclient != nil {
@@ -400,56 +607,303 @@ func GenCode(service TelegramService, storagePrefix string) {
 		}
 	}()
 
-	user, err := service.GetMe(tdclient)
+	user, err := GetMeWithRetryContext(ctx, service, tdclient, defaultGetMeAttempts, defaultGetMeDelay)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to retrieve user information")
-		return
+		return fmt.Errorf("failed to retrieve user information: %w", err)
 	}
 
 	log.Info().Msgf("Authenticated as: %s %s", user.FirstName, user.LastName)
+	return nil
 }
 
+// initializeClient initializes a client with default settings through the
+// service's InitializeClientContext if it has one. Other services can't be
+// interrupted, so only the wait for them is cancelled; a client they return
+// after ctx is done is closed.
+func initializeClient(ctx context.Context, service TelegramService, storagePrefix string) (crawler.TDLibClient, error) {
+	if cs, ok := service.(ContextTelegramService); ok {
+		return cs.InitializeClientContext(ctx, storagePrefix, common.CrawlerConfig{})
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		client crawler.TDLibClient
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		c, err := service.InitializeClient(storagePrefix)
+		done <- result{client: c, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.client, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.client != nil {
+				r.client.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// getMe calls the service's GetMeContext if it has one, and otherwise stops
+// waiting for GetMe once ctx is done.
+func getMe(ctx context.Context, service TelegramService, tdlibClient crawler.TDLibClient) (*client.User, error) {
+	if cs, ok := service.(ContextTelegramService); ok {
+		return cs.GetMeContext(ctx, tdlibClient)
+	}
+	return callWithContext(ctx, func() (*client.User, error) {
+		return service.GetMe(tdlibClient)
+	})
+}
+
+// Attempts made to complete a tarball download. After a dropped connection the
+// download resumes from the bytes already received.
+const tarballDownloadAttempts = 5
+
+// tarballRetryDelay is the delay before the first resume attempt; it doubles
+// after every further attempt.
+var tarballRetryDelay = time.Second
+
 // downloadAndExtractTarball downloads a pre-configured TDLib database archive from a URL
 // and extracts it to the specified target directory. This is a key feature for performance
 // optimization, allowing the application to start with a pre-authenticated TDLib session
 // rather than going through the full authentication flow for each new instance.
 //
 // Parameters:
+//   - httpClient: The client used for the download (nil uses http.DefaultClient)
 //   - url: The URL of the gzipped tarball containing a pre-configured TDLib database
 //   - targetDir: The directory where the contents should be extracted
+//   - expectedSHA256: Hex SHA-256 digest the tarball must match; empty skips verification
 //
 // Returns:
-//   - An error if any step of the download or extraction process fails
+//   - An error if any step of the download or extraction process fails, or if the
+//     downloaded tarball does not match expectedSHA256
 //
 // The function:
-// 1. Downloads the tarball using a standard HTTP GET request with browser-like headers
-// 2. Checks for successful HTTP status code (200)
-// 3. Passes the response body to downloadAndExtractTarballFromReader for extraction
+// 1. Downloads the tarball to "<targetDir>.tar.gz.part" using HTTP GET requests with browser-like headers
+// 2. Resumes a dropped or earlier partial download with a Range request for the missing bytes
+// 3. Checks for a successful HTTP status code (200, or 206 when resuming)
+// 4. If a checksum is expected, hashes the completed tarball and aborts before extracting anything if the digest does not match
+// 5. Passes the tarball to downloadAndExtractTarballFromReader for extraction
 //
 // This approach allows for rapid deployment of new crawler instances with pre-authenticated
 // sessions, significantly reducing startup time and avoiding the need for repeated
 // authentication steps. It's especially valuable in distributed or containerized environments.
-func downloadAndExtractTarball(url, targetDir string) error {
-	req, err := http.NewRequest("GET", url, nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)")
-	req.Header.Set("Accept", "*/*")
-	if err != nil {
+func downloadAndExtractTarball(ctx context.Context, httpClient *http.Client, url, targetDir, expectedSHA256 string) error {
+	partPath := filepath.Clean(targetDir) + ".tar.gz.part"
+	if err := downloadTarball(ctx, httpClient, url, partPath); err != nil {
+		// A partial file is kept so the next attempt can resume it
+		if info, statErr := os.Stat(partPath); statErr == nil && info.Size() == 0 {
+			os.Remove(partPath)
+		}
 		return err
 	}
+	defer os.Remove(partPath)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	tarball, err := os.Open(partPath)
+	if err != nil {
+		return fmt.Errorf("failed to open downloaded tarball: %w", err)
+	}
+	defer tarball.Close()
+
+	if expectedSHA256 != "" {
+		// Hash the whole file first so nothing is extracted from an unverified archive
+		hash := sha256.New()
+		if _, err := io.Copy(hash, tarball); err != nil {
+			return fmt.Errorf("failed to hash tarball: %w", err)
+		}
+		actual := hex.EncodeToString(hash.Sum(nil))
+		if !strings.EqualFold(actual, expectedSHA256) {
+			return fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", url, expectedSHA256, actual)
+		}
+		log.Debug().Str("url", url).Str("sha256", actual).Msg("Verified tarball checksum")
+
+		if _, err := tarball.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind tarball: %w", err)
+		}
+	}
+	return downloadAndExtractTarballFromReader(tarball, targetDir)
+}
+
+// downloadTarball downloads url to partPath, resuming after interrupted
+// transfers. Error responses from the server and a done ctx are not retried.
+func downloadTarball(ctx context.Context, httpClient *http.Client, url, partPath string) error {
+	var err error
+	delay := tarballRetryDelay
+	for attempt := 1; attempt <= tarballDownloadAttempts; attempt++ {
+		var statusErr *tarballStatusError
+		if err = resumeTarballDownload(ctx, httpClient, url, partPath); err == nil || errors.As(err, &statusErr) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Warn().
+			Err(err).
+			Str("url", url).
+			Int("attempt", attempt).
+			Int("max_attempts", tarballDownloadAttempts).
+			Msg("Tarball download interrupted")
+
+		if attempt < tarballDownloadAttempts {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			delay *= 2
+		}
+	}
+	return fmt.Errorf("failed to download tarball after %d attempts: %w", tarballDownloadAttempts, err)
+}
+
+// resumeTarballDownload appends the bytes of url missing from partPath. A
+// server that ignores the Range header sends the whole file, which then
+// replaces the partial one.
+func resumeTarballDownload(ctx context.Context, httpClient *http.Client, url, partPath string) error {
+	part, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open partial tarball: %w", err)
+	}
+	defer part.Close()
+
+	offset, err := part.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to seek partial tarball: %w", err)
+	}
+
+	req, err := newTarballRequest(ctx, url)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		log.Info().Str("url", url).Int64("offset", offset).Msg("Resuming tarball download")
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			// Start over rather than append bytes from the wrong position
+			if err := part.Truncate(0); err != nil {
+				return fmt.Errorf("failed to truncate partial tarball: %w", err)
+			}
+			return fmt.Errorf("unexpected content range %q when resuming at byte %d", resp.Header.Get("Content-Range"), offset)
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file is already complete if it is as long as the tarball
+		if resp.Header.Get("Content-Range") == fmt.Sprintf("bytes */%d", offset) {
+			return nil
+		}
+		if err := part.Truncate(0); err != nil {
+			return fmt.Errorf("failed to truncate partial tarball: %w", err)
+		}
+		return fmt.Errorf("partial tarball of %d bytes does not match the remote file", offset)
+	case resp.StatusCode == http.StatusOK:
+		if offset > 0 {
+			if err := part.Truncate(0); err != nil {
+				return fmt.Errorf("failed to truncate partial tarball: %w", err)
+			}
+			if _, err := part.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to rewind partial tarball: %w", err)
+			}
+		}
+	default:
+		return &tarballStatusError{Status: resp.Status}
+	}
+
+	if _, err := io.Copy(part, resp.Body); err != nil {
+		return fmt.Errorf("failed to download tarball: %w", err)
+	}
+	return nil
+}
+
+// tarballStatusError is returned when the server answers a tarball download
+// with an error status. Unlike a dropped connection it is not retried.
+type tarballStatusError struct {
+	Status string
+}
+
+func (e *tarballStatusError) Error() string {
+	return fmt.Sprintf("non-200 status returned: %v", e.Status)
+}
+
+// fetchTarballChecksum reads the expected SHA-256 of the tarball at url from
+// the sidecar file "<url>.sha256". The sidecar may use the sha256sum output
+// format ("<digest>  <filename>"); only the digest is used.
+func fetchTarballChecksum(ctx context.Context, httpClient *http.Client, url string) (string, error) {
+	body, err := httpGetTarball(ctx, httpClient, url+".sha256")
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch checksum: %w", err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, 1024))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum: %w", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("checksum file for %s is empty", url)
+	}
+	digest := fields[0]
+	if _, err := hex.DecodeString(digest); err != nil || len(digest) != sha256.Size*2 {
+		return "", fmt.Errorf("checksum file for %s does not contain a SHA-256 digest", url)
+	}
+	return digest, nil
+}
+
+// httpGetTarball issues a GET request with browser-like default headers and
+// returns the response body if the server answered 200. The headers configured
+// on httpClient's transport take precedence. A nil httpClient uses
+// http.DefaultClient.
+func httpGetTarball(ctx context.Context, httpClient *http.Client, url string) (io.ReadCloser, error) {
+	req, err := newTarballRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("non-200 status returned: %v", resp.Status)
+		resp.Body.Close()
+		return nil, &tarballStatusError{Status: resp.Status}
 	}
+	return resp.Body, nil
+}
 
-	// Pass the response body to the extraction function
-	return downloadAndExtractTarballFromReader(resp.Body, targetDir)
+// newTarballRequest builds a GET request for url with browser-like default
+// headers, cancelled when ctx is done.
+func newTarballRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", common.DefaultUserAgent)
+	req.Header.Set("Accept", "*/*")
+	return req, nil
 }
 
 // downloadAndExtractTarballFromReader extracts files from a gzip-compressed tarball
@@ -499,8 +953,11 @@ func downloadAndExtractTarballFromReader(reader io.Reader, targetDir string) err
 			return err
 		}
 
-		// Determine target file path
-		targetPath := filepath.Join(targetDir, header.Name)
+		// Determine target file path, refusing entries that would escape targetDir
+		targetPath, err := tarEntryPath(targetDir, header.Name)
+		if err != nil {
+			return err
+		}
 
 		switch header.Typeflag {
 		case tar.TypeDir:
@@ -517,11 +974,15 @@ func downloadAndExtractTarballFromReader(reader io.Reader, targetDir string) err
 			if err != nil {
 				return err
 			}
-			defer file.Close()
 
-			_, err = io.Copy(file, tarReader)
-			if err != nil {
-				return err
+			// Close each file before moving on so large archives don't exhaust file handles
+			_, copyErr := io.Copy(file, tarReader)
+			closeErr := file.Close()
+			if copyErr != nil {
+				return copyErr
+			}
+			if closeErr != nil {
+				return closeErr
 			}
 		default:
 			log.Debug().Msgf("Ignoring unknown file type: %s\n", header.Name)
@@ -530,3 +991,15 @@ func downloadAndExtractTarballFromReader(reader io.Reader, targetDir string) err
 
 	return nil
 }
+
+// tarEntryPath returns the path an archive entry is extracted to. It returns an
+// error for entries such as "../escape" or absolute paths that would resolve
+// outside targetDir.
+func tarEntryPath(targetDir, name string) (string, error) {
+	targetPath := filepath.Join(targetDir, name)
+	rel, err := filepath.Rel(targetDir, targetPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("tar entry %q resolves outside target directory", name)
+	}
+	return targetPath, nil
+}
@@ -2,6 +2,7 @@ package telegramhelper
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/researchaccelerator-hub/telegram-scraper/common"
 	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
@@ -402,6 +403,33 @@ func (p *ConnectionPool) HandleConnectionError(ctx context.Context, connID strin
 	return newClient, connID, nil
 }
 
+// Ping checks every connection of the pool, available or in use, with Ping.
+// It returns an error if the pool hasn't connected to Telegram yet or any
+// connection fails to answer before ctx is done.
+func (p *ConnectionPool) Ping(ctx context.Context) error {
+	p.mu.Lock()
+	conns := make(map[string]crawler.TDLibClient, len(p.availableConns)+len(p.inUseConns))
+	for id, c := range p.availableConns {
+		conns[id] = c
+	}
+	for id, c := range p.inUseConns {
+		conns[id] = c
+	}
+	p.mu.Unlock()
+
+	if len(conns) == 0 {
+		return errors.New("no TDLib connection established yet")
+	}
+	// Clients are pinged without holding the lock, so a slow answer doesn't
+	// block the crawl from getting connections
+	for id, c := range conns {
+		if err := Ping(ctx, c); err != nil {
+			return fmt.Errorf("connection %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
 // Stats returns statistics about the current state of the connection pool,
 // including the number of available connections, in-use connections, and
 // the maximum pool size. This is useful for monitoring and debugging.
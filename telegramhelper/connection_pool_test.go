@@ -3,6 +3,7 @@ package telegramhelper
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/researchaccelerator-hub/telegram-scraper/common"
@@ -30,6 +31,8 @@ func (m *MockTDLibClient) GetRemoteFile(req *client.GetRemoteFileRequest) (*clie
 func (m *MockTDLibClient) DownloadFile(req *client.DownloadFileRequest) (*client.File, error) { return nil, nil }
 func (m *MockTDLibClient) GetChatHistory(req *client.GetChatHistoryRequest) (*client.Messages, error) { return nil, nil }
 func (m *MockTDLibClient) SearchPublicChat(req *client.SearchPublicChatRequest) (*client.Chat, error) { return nil, nil }
+func (m *MockTDLibClient) CheckChatInviteLink(req *client.CheckChatInviteLinkRequest) (*client.ChatInviteLinkInfo, error) { return nil, nil }
+func (m *MockTDLibClient) JoinChatByInviteLink(req *client.JoinChatByInviteLinkRequest) (*client.Chat, error) { return nil, nil }
 func (m *MockTDLibClient) GetChat(req *client.GetChatRequest) (*client.Chat, error) { return nil, nil }
 func (m *MockTDLibClient) GetSupergroup(req *client.GetSupergroupRequest) (*client.Supergroup, error) { return nil, nil }
 func (m *MockTDLibClient) GetSupergroupFullInfo(req *client.GetSupergroupFullInfoRequest) (*client.SupergroupFullInfo, error) { return nil, nil }
@@ -37,11 +40,16 @@ func (m *MockTDLibClient) GetMe() (*client.User, error) { return nil, nil }
 func (m *MockTDLibClient) GetBasicGroupFullInfo(req *client.GetBasicGroupFullInfoRequest) (*client.BasicGroupFullInfo, error) { return nil, nil }
 func (m *MockTDLibClient) GetUser(*client.GetUserRequest) (*client.User, error) { return nil, nil }
 func (m *MockTDLibClient) DeleteFile(req *client.DeleteFileRequest) (*client.Ok, error) { return nil, nil }
+func (m *MockTDLibClient) GetForumTopic(req *client.GetForumTopicRequest) (*client.ForumTopic, error) { return nil, nil }
 
 // MockPoolTelegramService is a mock service that creates mock clients for pool testing
 type MockPoolTelegramService struct {
-	CreatedClients []*MockTDLibClient
-	CreateError    error
+	CreatedClients  []*MockTDLibClient
+	CreateError     error
+	StoragePrefixes []string
+	DatabaseURLs    []string
+	APIIDs          []int
+	RejectedAPIIDs  map[int]error // Initialization error returned for these apps
 }
 
 func (m *MockPoolTelegramService) InitializeClient(storagePrefix string) (crawler.TDLibClient, error) {
@@ -49,6 +57,14 @@ func (m *MockPoolTelegramService) InitializeClient(storagePrefix string) (crawle
 }
 
 func (m *MockPoolTelegramService) InitializeClientWithConfig(storagePrefix string, config common.CrawlerConfig) (crawler.TDLibClient, error) {
+	m.StoragePrefixes = append(m.StoragePrefixes, storagePrefix)
+	m.DatabaseURLs = append(m.DatabaseURLs, config.TDLibDatabaseURL)
+	if config.APICredential != nil {
+		m.APIIDs = append(m.APIIDs, config.APICredential.APIID)
+		if err := m.RejectedAPIIDs[config.APICredential.APIID]; err != nil {
+			return nil, err
+		}
+	}
 	if m.CreateError != nil {
 		return nil, m.CreateError
 	}
@@ -198,4 +214,40 @@ func TestConnectionPoolErrorHandling(t *testing.T) {
 	if connID1 != connID2 {
 		t.Error("Expected the same connection ID after error handling")
 	}
-}
\ No newline at end of file
+}
+
+// unauthorizedClient is a connection whose session was logged out
+type unauthorizedClient struct {
+	MockTDLibClient
+}
+
+func (u *unauthorizedClient) GetMe() (*client.User, error) {
+	return nil, fmt.Errorf("Unauthorized")
+}
+
+func TestConnectionPoolPing(t *testing.T) {
+	pool := &ConnectionPool{
+		availableConns: make(map[string]crawler.TDLibClient),
+		inUseConns:     make(map[string]crawler.TDLibClient),
+		maxSize:        2,
+		service:        &MockPoolTelegramService{},
+		connDirMap:     make(map[string]string),
+	}
+	ctx := context.Background()
+
+	if err := pool.Ping(ctx); err == nil {
+		t.Error("Expected an error before any connection is established")
+	}
+
+	if _, _, err := pool.GetConnection(ctx); err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		t.Errorf("Expected an in-use connection to be healthy, got %v", err)
+	}
+
+	pool.availableConns["conn_logged_out"] = &unauthorizedClient{}
+	if err := pool.Ping(ctx); err == nil || !strings.Contains(err.Error(), "conn_logged_out") {
+		t.Errorf("Expected the logged out connection to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package telegramhelper
+
+import (
+	"sync"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/rs/zerolog/log"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// contentTypeSkips counts messages dropped by the content type filter, per content type.
+var contentTypeSkips = struct {
+	sync.Mutex
+	counts map[string]int
+}{counts: make(map[string]int)}
+
+// skipContentType reports whether the message's content type is excluded by the
+// filter, recording the skip if so.
+func skipContentType(message *client.Message, filter common.ContentTypeFilter) bool {
+	if !filter.Enabled() || message.Content == nil {
+		return false
+	}
+	contentType := message.Content.MessageContentType()
+	if filter.Allows(contentType) {
+		return false
+	}
+
+	contentTypeSkips.Lock()
+	contentTypeSkips.counts[contentType]++
+	contentTypeSkips.Unlock()
+
+	log.Debug().
+		Int64("message_id", message.Id).
+		Str("content_type", contentType).
+		Msg("Skipping message excluded by content type filter")
+	return true
+}
+
+// ContentTypeSkipCounts returns a snapshot of how many messages have been skipped
+// by the content type filter, keyed by TDLib content type.
+func ContentTypeSkipCounts() map[string]int {
+	contentTypeSkips.Lock()
+	defer contentTypeSkips.Unlock()
+
+	counts := make(map[string]int, len(contentTypeSkips.counts))
+	for k, v := range contentTypeSkips.counts {
+		counts[k] = v
+	}
+	return counts
+}
+
+// downloadsMedia reports whether the media of the message's content type should
+// be downloaded. Types are matched like ContentTypeFilter names, e.g. "photo" or
+// "videoNote"; an empty list downloads the media of every type.
+func downloadsMedia(message *client.Message, mediaTypes []string) bool {
+	if len(mediaTypes) == 0 || message.Content == nil {
+		return true
+	}
+	return common.ContentTypeFilter{Include: mediaTypes}.Allows(message.Content.MessageContentType())
+}
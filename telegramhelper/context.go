@@ -0,0 +1,172 @@
+package telegramhelper
+
+import (
+	"context"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// contextClient is a TDLib client whose requests fail with the context's error
+// once it is done. TDLib requests can't be interrupted, so a request that is
+// already running is left to finish in the background; only DownloadFile, which
+// can take minutes, stops waiting for it. DeleteFile and Close are never
+// cancelled, so downloaded files are still cleaned up.
+type contextClient struct {
+	crawler.TDLibClient
+	ctx context.Context
+}
+
+// withContext returns tdlibClient bound to ctx. A context that can never be
+// cancelled returns tdlibClient unchanged.
+func withContext(ctx context.Context, tdlibClient crawler.TDLibClient) crawler.TDLibClient {
+	if ctx.Done() == nil || tdlibClient == nil {
+		return tdlibClient
+	}
+	return &contextClient{TDLibClient: tdlibClient, ctx: ctx}
+}
+
+// callWithContext runs fn in the background and returns its result, or the
+// context's error if ctx is done first.
+func callWithContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	// Buffered so the goroutine can finish even if nobody is waiting anymore
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value: value, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+func (c *contextClient) GetMessage(req *client.GetMessageRequest) (*client.Message, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetMessage(req)
+}
+
+func (c *contextClient) GetMessageLink(req *client.GetMessageLinkRequest) (*client.MessageLink, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetMessageLink(req)
+}
+
+func (c *contextClient) GetMessageThreadHistory(req *client.GetMessageThreadHistoryRequest) (*client.Messages, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetMessageThreadHistory(req)
+}
+
+func (c *contextClient) GetMessageThread(req *client.GetMessageThreadRequest) (*client.MessageThreadInfo, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetMessageThread(req)
+}
+
+func (c *contextClient) GetRemoteFile(req *client.GetRemoteFileRequest) (*client.File, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetRemoteFile(req)
+}
+
+func (c *contextClient) DownloadFile(req *client.DownloadFileRequest) (*client.File, error) {
+	return callWithContext(c.ctx, func() (*client.File, error) {
+		return c.TDLibClient.DownloadFile(req)
+	})
+}
+
+func (c *contextClient) GetChatHistory(req *client.GetChatHistoryRequest) (*client.Messages, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetChatHistory(req)
+}
+
+func (c *contextClient) SearchPublicChat(req *client.SearchPublicChatRequest) (*client.Chat, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.SearchPublicChat(req)
+}
+
+func (c *contextClient) CheckChatInviteLink(req *client.CheckChatInviteLinkRequest) (*client.ChatInviteLinkInfo, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.CheckChatInviteLink(req)
+}
+
+func (c *contextClient) JoinChatByInviteLink(req *client.JoinChatByInviteLinkRequest) (*client.Chat, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.JoinChatByInviteLink(req)
+}
+
+func (c *contextClient) GetChat(req *client.GetChatRequest) (*client.Chat, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetChat(req)
+}
+
+func (c *contextClient) GetSupergroup(req *client.GetSupergroupRequest) (*client.Supergroup, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetSupergroup(req)
+}
+
+func (c *contextClient) GetSupergroupFullInfo(req *client.GetSupergroupFullInfoRequest) (*client.SupergroupFullInfo, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetSupergroupFullInfo(req)
+}
+
+func (c *contextClient) GetForumTopic(req *client.GetForumTopicRequest) (*client.ForumTopic, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetForumTopic(req)
+}
+
+func (c *contextClient) GetBasicGroupFullInfo(req *client.GetBasicGroupFullInfoRequest) (*client.BasicGroupFullInfo, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetBasicGroupFullInfo(req)
+}
+
+func (c *contextClient) GetUser(req *client.GetUserRequest) (*client.User, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetUser(req)
+}
+
+func (c *contextClient) GetMe() (*client.User, error) {
+	return callWithContext(c.ctx, c.TDLibClient.GetMe)
+}
@@ -0,0 +1,176 @@
+package telegramhelper
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// cancellingDownloadClient cancels the crawl while a download is running and
+// then never finishes it, like a large file on a stalled connection
+type cancellingDownloadClient struct {
+	flakyDownloadClient
+	cancel  context.CancelFunc
+	release chan struct{}
+}
+
+func (c *cancellingDownloadClient) DownloadFile(req *client.DownloadFileRequest) (*client.File, error) {
+	c.downloadCalls++
+	c.cancel()
+	<-c.release
+	return nil, context.Canceled
+}
+
+func TestParseMessageContext_CancelledMidDownload(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tdlibClient := &cancellingDownloadClient{cancel: cancel, release: make(chan struct{})}
+	defer close(tdlibClient.release)
+	sm := &recordingStateManager{StateManagementInterface: newTestStateManager(t)}
+
+	message := &client.Message{
+		Id:     1,
+		ChatId: -1001,
+		Date:   int32(time.Now().Unix()),
+		Content: &client.MessagePhoto{
+			Caption: &client.FormattedText{Text: "photo caption"},
+			Photo: &client.Photo{Sizes: []*client.PhotoSize{
+				{Photo: &client.File{Id: 7, Remote: &client.RemoteFile{Id: "remote-photo"}}},
+			}},
+		},
+	}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+
+	start := time.Now()
+	_, err := ParseMessageContext(ctx, "crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, sm, common.CrawlerConfig{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second, "Cancellation should not wait for the download or retry it")
+	assert.Equal(t, 1, tdlibClient.downloadCalls)
+	assert.Zero(t, sm.storeFileCalls)
+	assert.Zero(t, sm.storePostCalls, "A cancelled post should not be stored")
+}
+
+func TestParseMessageContext_AlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sm := &recordingStateManager{StateManagementInterface: newTestStateManager(t)}
+	message := &client.Message{
+		Id:      1,
+		ChatId:  -1001,
+		Date:    int32(time.Now().Unix()),
+		Content: &client.MessageText{Text: &client.FormattedText{Text: "hello"}},
+	}
+
+	_, err := ParseMessageContext(ctx, "crawl", message, &client.MessageLink{Link: "https://t.me/example/1"}, &client.Chat{Id: -1001}, nil, nil, 0, 0, "example", &flakyDownloadClient{}, sm, common.CrawlerConfig{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, sm.storePostCalls)
+}
+
+// blockingGetMeClient never answers GetMe until released
+type blockingGetMeClient struct {
+	MockTDLibClient
+	release chan struct{}
+}
+
+func (b *blockingGetMeClient) GetMe() (*client.User, error) {
+	<-b.release
+	return &client.User{Id: 42}, nil
+}
+
+func TestRealTelegramService_GetMeContextDeadline(t *testing.T) {
+	tdlibClient := &blockingGetMeClient{release: make(chan struct{})}
+	defer close(tdlibClient.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	user, err := (&RealTelegramService{}).GetMeContext(ctx, tdlibClient)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, user)
+}
+
+func TestGetMeWithRetryContext_CancelledDuringBackoff(t *testing.T) {
+	service := &RealTelegramService{}
+	tdlibClient := &flakyGetMeClient{}
+	_, err := GetMeWithRetry(service, tdlibClient, 1, 0)
+	require.NoError(t, err)
+
+	tdlibClient.failures = 100
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	user, err := GetMeWithRetryContext(ctx, service, tdlibClient, 3, time.Hour)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, user, "A cancelled call should not fall back to the cached user")
+	assert.Less(t, time.Since(start), time.Second, "The backoff should end when the context is cancelled")
+}
+
+func TestGenCodeContext_ReturnsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := GenCodeContext(ctx, &MockTelegramService{}, t.TempDir())
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.NoError(t, GenCodeContext(context.Background(), &MockTelegramService{}, t.TempDir()))
+}
+
+func TestWaitForClient_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	_, err := waitForClient(ctx, func() (*client.Client, error) {
+		<-release
+		return nil, nil
+	}, time.Hour)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDownloadAndExtractTarball_CancelledMidDownload(t *testing.T) {
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzWriter)
+	content := bytes.Repeat([]byte("session data "), 4096)
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "td.binlog", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tarWriter.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzWriter.Close())
+	tarball := buf.Bytes()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// Send half the tarball, then stall until the client gives up
+		w.Header().Set("Content-Length", strconv.Itoa(len(tarball)))
+		w.Write(tarball[:len(tarball)/2])
+		w.(http.Flusher).Flush()
+		cancel()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	targetDir := filepath.Join(t.TempDir(), "session")
+	err = downloadAndExtractTarball(ctx, nil, server.URL+"/db.tar.gz", targetDir, "")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(1), requests.Load(), "A cancelled download should not be resumed")
+	assert.NoDirExists(t, targetDir, "Nothing should be extracted")
+}
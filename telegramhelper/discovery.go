@@ -0,0 +1,140 @@
+package telegramhelper
+
+import (
+	"sync"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/rs/zerolog/log"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// DiscoveryStrategy finds the channels a crawl should visit next in a parsed
+// message. Candidates are channel usernames, the form Post.Outlinks stores
+// them in; they are kept in Post.DiscoveredChannels, so the post's own links
+// are recorded unchanged. tdlibClient may be nil, in which case strategies
+// that need to look channels up find nothing.
+type DiscoveryStrategy interface {
+	Discover(tdlibClient crawler.TDLibClient, message *client.Message, post *model.Post) []string
+}
+
+// discoveryStrategies holds the built-in strategies by their
+// CrawlerConfig.DiscoveryStrategy name.
+var discoveryStrategies = map[string]DiscoveryStrategy{
+	common.DiscoveryLinks:     linkDiscovery{},
+	common.DiscoveryMentions:  mentionDiscovery{},
+	common.DiscoveryForwarded: forwardedDiscovery{},
+	common.DiscoveryPinned:    pinnedDiscovery{},
+}
+
+// newDiscoveryStrategy returns the built-in strategy called name. Empty and
+// unknown names get the links strategy; the launcher rejects unknown names
+// before a crawl starts.
+func newDiscoveryStrategy(name string) DiscoveryStrategy {
+	if strategy, ok := discoveryStrategies[name]; ok {
+		return strategy
+	}
+	if name != "" {
+		log.Warn().Str("strategy", name).Msg("Unknown discovery strategy, using links")
+	}
+	return linkDiscovery{}
+}
+
+// linkDiscovery finds the channels linked or @mentioned in text messages.
+type linkDiscovery struct{}
+
+func (linkDiscovery) Discover(_ crawler.TDLibClient, message *client.Message, _ *model.Post) []string {
+	return extractChannelLinksFromMessage(message)
+}
+
+// mentionDiscovery finds the @mentioned usernames in the text or caption.
+type mentionDiscovery struct{}
+
+func (mentionDiscovery) Discover(_ crawler.TDLibClient, _ *client.Message, post *model.Post) []string {
+	return post.Mentions
+}
+
+// pinnedDiscovery finds the channels linked or @mentioned in pinned messages.
+type pinnedDiscovery struct{}
+
+func (pinnedDiscovery) Discover(tdlibClient crawler.TDLibClient, message *client.Message, post *model.Post) []string {
+	if !message.IsPinned {
+		return nil
+	}
+	return linkDiscovery{}.Discover(tdlibClient, message, post)
+}
+
+// forwardedDiscovery finds the public channel a post was forwarded from.
+// Forwards only carry the origin's chat ID, so its username is looked up.
+type forwardedDiscovery struct{}
+
+// originUsernames caches the usernames of the chats posts were forwarded
+// from, so the forwards of one channel don't each look it up again. Chats
+// without a username are cached as "".
+var originUsernames sync.Map
+
+func (forwardedDiscovery) Discover(tdlibClient crawler.TDLibClient, message *client.Message, _ *model.Post) []string {
+	if tdlibClient == nil || message.ForwardInfo == nil {
+		return nil
+	}
+
+	var chatID int64
+	switch origin := message.ForwardInfo.Origin.(type) {
+	case *client.MessageOriginChannel:
+		chatID = origin.ChatId
+	case *client.MessageOriginChat:
+		chatID = origin.SenderChatId
+	default:
+		return nil
+	}
+
+	var username string
+	if cached, ok := originUsernames.Load(chatID); ok {
+		username = cached.(string)
+	} else {
+		var err error
+		username, err = chatUsername(tdlibClient, chatID)
+		if err != nil {
+			log.Debug().Err(err).Int64("chat_id", chatID).Msg("Failed to look up the channel a post was forwarded from")
+			return nil
+		}
+		originUsernames.Store(chatID, username)
+	}
+	if username == "" {
+		return nil
+	}
+	return []string{username}
+}
+
+// chatUsername returns the first active username of a supergroup or channel
+// chat, or "" if it has none.
+func chatUsername(tdlibClient crawler.TDLibClient, chatID int64) (string, error) {
+	var chat *client.Chat
+	err := withFloodWait("get_chat", func() error {
+		var err error
+		chat, err = tdlibClient.GetChat(&client.GetChatRequest{ChatId: chatID})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	supergroupType, ok := chat.Type.(*client.ChatTypeSupergroup)
+	if !ok {
+		return "", nil
+	}
+
+	var supergroup *client.Supergroup
+	err = withFloodWait("get_supergroup", func() error {
+		var err error
+		supergroup, err = tdlibClient.GetSupergroup(&client.GetSupergroupRequest{SupergroupId: supergroupType.SupergroupId})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if supergroup.Usernames == nil || len(supergroup.Usernames.ActiveUsernames) == 0 {
+		return "", nil
+	}
+	return supergroup.Usernames.ActiveUsernames[0], nil
+}
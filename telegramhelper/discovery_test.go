@@ -0,0 +1,129 @@
+package telegramhelper
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// channelLookupClient resolves the chats in usernames to public channels
+type channelLookupClient struct {
+	MockTDLibClient
+	usernames map[int64]string
+}
+
+func (c *channelLookupClient) GetChat(req *client.GetChatRequest) (*client.Chat, error) {
+	if _, ok := c.usernames[req.ChatId]; !ok {
+		return nil, fmt.Errorf("chat %d not found", req.ChatId)
+	}
+	return &client.Chat{Id: req.ChatId, Type: &client.ChatTypeSupergroup{SupergroupId: -req.ChatId, IsChannel: true}}, nil
+}
+
+func (c *channelLookupClient) GetSupergroup(req *client.GetSupergroupRequest) (*client.Supergroup, error) {
+	username := c.usernames[-req.SupergroupId]
+	if username == "" {
+		return &client.Supergroup{Id: req.SupergroupId}, nil
+	}
+	return &client.Supergroup{Id: req.SupergroupId, Usernames: &client.Usernames{ActiveUsernames: []string{username}}}, nil
+}
+
+func (c *channelLookupClient) GetMessage(req *client.GetMessageRequest) (*client.Message, error) {
+	return &client.Message{Id: req.MessageId, ChatId: req.ChatId}, nil
+}
+
+// discoveryTestMessage is a text message linking to one channel and
+// mentioning another
+func discoveryTestMessage() *client.Message {
+	text := "See t.me/linkedchannel and @mentionedchannel"
+	return &client.Message{
+		Id:     1,
+		ChatId: -1001,
+		Date:   int32(time.Now().Unix()),
+		Content: &client.MessageText{Text: &client.FormattedText{
+			Text:     text,
+			Entities: []*client.TextEntity{{Offset: 27, Length: 17, Type: &client.TextEntityTypeMention{}}},
+		}},
+	}
+}
+
+func TestForwardedDiscovery(t *testing.T) {
+	tdlibClient := &channelLookupClient{usernames: map[int64]string{-1002: "originchannel", -1003: ""}}
+	strategy := newDiscoveryStrategy(common.DiscoveryForwarded)
+
+	message := discoveryTestMessage()
+	assert.Empty(t, strategy.Discover(tdlibClient, message, nil), "A post that wasn't forwarded has no origin")
+
+	message.ForwardInfo = &client.MessageForwardInfo{Origin: &client.MessageOriginChannel{ChatId: -1002, MessageId: 9}}
+	assert.Equal(t, []string{"originchannel"}, strategy.Discover(tdlibClient, message, nil))
+
+	message.ForwardInfo = &client.MessageForwardInfo{Origin: &client.MessageOriginChannel{ChatId: -1003}}
+	assert.Empty(t, strategy.Discover(tdlibClient, message, nil), "A private origin channel can't be crawled")
+
+	message.ForwardInfo = &client.MessageForwardInfo{Origin: &client.MessageOriginChannel{ChatId: -1004}}
+	assert.Empty(t, strategy.Discover(tdlibClient, message, nil), "An origin that can't be looked up is skipped")
+
+	message.ForwardInfo = &client.MessageForwardInfo{Origin: &client.MessageOriginUser{SenderUserId: 5}}
+	assert.Empty(t, strategy.Discover(tdlibClient, message, nil), "Users are not channels")
+
+	message.ForwardInfo = &client.MessageForwardInfo{Origin: &client.MessageOriginChannel{ChatId: -1002}}
+	assert.Empty(t, strategy.Discover(nil, message, nil), "Origins can't be looked up without a client")
+}
+
+func TestParseMessage_DiscoveryStrategies(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	tdlibClient := &channelLookupClient{usernames: map[int64]string{-1002: "originchannel"}}
+
+	tests := []struct {
+		strategy string
+		pinned   bool
+		want     []string
+	}{
+		{"", false, []string{"linkedchannel", "mentionedchannel"}},
+		{common.DiscoveryLinks, false, []string{"linkedchannel", "mentionedchannel"}},
+		{common.DiscoveryMentions, false, []string{"mentionedchannel"}},
+		{common.DiscoveryForwarded, false, []string{"originchannel"}},
+		{common.DiscoveryPinned, false, nil},
+		{common.DiscoveryPinned, true, []string{"linkedchannel", "mentionedchannel"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s pinned=%v", tt.strategy, tt.pinned), func(t *testing.T) {
+			message := discoveryTestMessage()
+			message.IsPinned = tt.pinned
+			message.ForwardInfo = &client.MessageForwardInfo{Origin: &client.MessageOriginChannel{ChatId: -1002}}
+
+			post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, nil, common.CrawlerConfig{DiscoveryStrategy: tt.strategy})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, post.DiscoveredChannels)
+			assert.ElementsMatch(t, []string{"linkedchannel", "mentionedchannel"}, post.Outlinks, "The post's own links are kept whatever the strategy")
+		})
+	}
+}
+
+// countingLookupClient counts the chat lookups made through it
+type countingLookupClient struct {
+	channelLookupClient
+	getChatCalls int
+}
+
+func (c *countingLookupClient) GetChat(req *client.GetChatRequest) (*client.Chat, error) {
+	c.getChatCalls++
+	return c.channelLookupClient.GetChat(req)
+}
+
+func TestForwardedDiscovery_CachesOrigins(t *testing.T) {
+	tdlibClient := &countingLookupClient{channelLookupClient: channelLookupClient{usernames: map[int64]string{-1005: "cachedorigin", -1006: ""}}}
+	strategy := newDiscoveryStrategy(common.DiscoveryForwarded)
+
+	for _, chatID := range []int64{-1005, -1005, -1006, -1006} {
+		message := discoveryTestMessage()
+		message.ForwardInfo = &client.MessageForwardInfo{Origin: &client.MessageOriginChannel{ChatId: chatID}}
+		strategy.Discover(tdlibClient, message, nil)
+	}
+	assert.Equal(t, 2, tdlibClient.getChatCalls, "Each origin should be looked up once")
+}
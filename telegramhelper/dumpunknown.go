@@ -0,0 +1,46 @@
+package telegramhelper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// unknownMessageDump is the record saved for a message whose content type
+// ParseMessage does not handle, so support for it can be added later.
+type unknownMessageDump struct {
+	ChatID      int64           `json:"chat_id"`
+	MessageID   int64           `json:"message_id"`
+	ContentType string          `json:"content_type"`
+	Content     json.RawMessage `json:"content"` // The content as TDLib sent it
+}
+
+// dumpUnknownMessage writes message to "<dir>/<chat id>_<message id>.json".
+// A message seen again overwrites its earlier dump.
+func dumpUnknownMessage(dir string, message *client.Message) (string, error) {
+	content, err := json.Marshal(message.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal content of message %d: %w", message.Id, err)
+	}
+	data, err := json.MarshalIndent(unknownMessageDump{
+		ChatID:      message.ChatId,
+		MessageID:   message.Id,
+		ContentType: message.Content.MessageContentType(),
+		Content:     content,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal message %d: %w", message.Id, err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create dump directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%d_%d.json", message.ChatId, message.Id))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write message dump: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,63 @@
+package telegramhelper
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+func TestParseMessage_DumpsUnknownContentTypes(t *testing.T) {
+	dumpDir := filepath.Join(t.TempDir(), "unknown")
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/5"}
+	message := &client.Message{
+		Id:      5 << 20,
+		ChatId:  chat.Id,
+		Date:    int32(time.Now().Unix()),
+		Content: &client.MessageStory{StorySenderChatId: -1002, StoryId: 17},
+	}
+
+	_, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{DumpUnknownTo: dumpDir})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dumpDir, "-1001_5242880.json"))
+	require.NoError(t, err, "The message should be dumped")
+
+	var dump struct {
+		ChatID      int64          `json:"chat_id"`
+		MessageID   int64          `json:"message_id"`
+		ContentType string         `json:"content_type"`
+		Content     map[string]any `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal(data, &dump))
+	assert.Equal(t, int64(-1001), dump.ChatID)
+	assert.Equal(t, int64(5<<20), dump.MessageID)
+	assert.Equal(t, "messageStory", dump.ContentType)
+	assert.Equal(t, "messageStory", dump.Content["@type"])
+	assert.Equal(t, float64(17), dump.Content["story_id"])
+}
+
+func TestParseMessage_DoesNotDumpHandledContentTypes(t *testing.T) {
+	dumpDir := t.TempDir()
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	message := &client.Message{
+		Id:      6 << 20,
+		ChatId:  chat.Id,
+		Date:    int32(time.Now().Unix()),
+		Content: &client.MessageText{Text: &client.FormattedText{Text: "hello"}},
+	}
+
+	_, err := ParseMessage("crawl", message, &client.MessageLink{Link: "https://t.me/example/6"}, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{DumpUnknownTo: dumpDir})
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dumpDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
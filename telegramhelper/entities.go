@@ -0,0 +1,91 @@
+package telegramhelper
+
+import (
+	"strings"
+	"unicode/utf16"
+
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// messageEntities holds the structured references found in a message's text
+// entities, each deduplicated and in order of appearance.
+type messageEntities struct {
+	URLs     []string
+	Mentions []string // Usernames without the leading @
+	Hashtags []string // Tags without the leading #
+}
+
+// messageFormattedText returns the text of a text message, or the caption of a
+// media message, or nil if the message carries neither.
+func messageFormattedText(message *client.Message) *client.FormattedText {
+	if message == nil {
+		return nil
+	}
+	switch content := message.Content.(type) {
+	case *client.MessageText:
+		return content.Text
+	case *client.MessagePhoto:
+		return content.Caption
+	case *client.MessageVideo:
+		return content.Caption
+	case *client.MessageAnimation:
+		return content.Caption
+	case *client.MessageDocument:
+		return content.Caption
+	case *client.MessageAudio:
+		return content.Caption
+	case *client.MessageVoiceNote:
+		return content.Caption
+	case *client.MessagePaidMedia:
+		return content.Caption
+	}
+	return nil
+}
+
+// extractMessageEntities collects the URLs, mentions and hashtags marked up in
+// the message's text entities. Text-URL entities (hyperlinks with custom text)
+// are resolved to their underlying href.
+func extractMessageEntities(message *client.Message) messageEntities {
+	var result messageEntities
+	text := messageFormattedText(message)
+	if text == nil || len(text.Entities) == 0 {
+		return result
+	}
+
+	// Entity offsets and lengths are counted in UTF-16 code units
+	units := utf16.Encode([]rune(text.Text))
+	entityText := func(entity *client.TextEntity) string {
+		start, end := int(entity.Offset), int(entity.Offset+entity.Length)
+		if start < 0 || end > len(units) || start > end {
+			return ""
+		}
+		return string(utf16.Decode(units[start:end]))
+	}
+
+	seen := make(map[string]bool)
+	add := func(list *[]string, kind, value string) {
+		key := kind + ":" + value
+		if value == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		*list = append(*list, value)
+	}
+
+	for _, entity := range text.Entities {
+		if entity == nil {
+			continue
+		}
+		switch entityType := entity.Type.(type) {
+		case *client.TextEntityTypeUrl:
+			add(&result.URLs, "url", entityText(entity))
+		case *client.TextEntityTypeTextUrl:
+			add(&result.URLs, "url", entityType.Url)
+		case *client.TextEntityTypeMention:
+			add(&result.Mentions, "mention", strings.TrimPrefix(entityText(entity), "@"))
+		case *client.TextEntityTypeHashtag:
+			add(&result.Hashtags, "hashtag", strings.TrimPrefix(entityText(entity), "#"))
+		}
+	}
+	return result
+}
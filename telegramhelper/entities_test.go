@@ -0,0 +1,44 @@
+package telegramhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+func TestExtractMessageEntities(t *testing.T) {
+	// The emoji takes two UTF-16 code units, shifting every later offset
+	text := "🔥 #breaking via @newsdesk, read more here or at https://example.org/a #breaking"
+	message := &client.Message{
+		Content: &client.MessageText{Text: &client.FormattedText{
+			Text: text,
+			Entities: []*client.TextEntity{
+				{Offset: 3, Length: 9, Type: &client.TextEntityTypeHashtag{}},
+				{Offset: 17, Length: 9, Type: &client.TextEntityTypeMention{}},
+				{Offset: 38, Length: 4, Type: &client.TextEntityTypeTextUrl{Url: "https://example.com/story"}},
+				{Offset: 49, Length: 21, Type: &client.TextEntityTypeUrl{}},
+				{Offset: 71, Length: 9, Type: &client.TextEntityTypeHashtag{}},
+				{Offset: 0, Length: 2, Type: &client.TextEntityTypeBold{}},
+			},
+		}},
+	}
+
+	entities := extractMessageEntities(message)
+	assert.Equal(t, []string{"https://example.com/story", "https://example.org/a"}, entities.URLs)
+	assert.Equal(t, []string{"newsdesk"}, entities.Mentions)
+	assert.Equal(t, []string{"breaking"}, entities.Hashtags, "Repeated hashtags are recorded once")
+}
+
+func TestExtractMessageEntities_ReadsCaptions(t *testing.T) {
+	message := &client.Message{
+		Content: &client.MessagePhoto{Caption: &client.FormattedText{
+			Text:     "#photo",
+			Entities: []*client.TextEntity{{Offset: 0, Length: 6, Type: &client.TextEntityTypeHashtag{}}},
+		}},
+	}
+	assert.Equal(t, []string{"photo"}, extractMessageEntities(message).Hashtags)
+
+	assert.Empty(t, extractMessageEntities(&client.Message{Content: &client.MessageText{Text: &client.FormattedText{Text: "plain"}}}).URLs)
+	assert.Empty(t, extractMessageEntities(nil).URLs)
+}
@@ -0,0 +1,76 @@
+package telegramhelper
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Limits for waiting out TDLib rate limiting.
+const (
+	maxFloodWaitRetries = 5               // Retries before the flood-wait error is returned
+	maxFloodWait        = 5 * time.Minute // Upper bound for a single wait
+	minFloodWait        = 1 * time.Second // Initial backoff between retries
+)
+
+// floodWaitPattern matches the wait TDLib asks for, e.g. "420 FLOOD_WAIT_17"
+// or "429 Too Many Requests: retry after 17".
+var floodWaitPattern = regexp.MustCompile(`(?:FLOOD_WAIT_|retry after )(\d+)`)
+
+// rateLimitPattern matches rate-limit errors that carry no wait duration.
+var rateLimitPattern = regexp.MustCompile(`^429 |FLOOD_WAIT|Too Many Requests`)
+
+// floodWaitSleep is replaced in tests to avoid real delays.
+var floodWaitSleep = time.Sleep
+
+// floodWaitDuration reports whether err is a TDLib flood-wait error and, if so,
+// how long TDLib asked the client to wait. The duration is zero when the error
+// is a rate limit without an explicit wait.
+func floodWaitDuration(err error) (time.Duration, bool) {
+	if err == nil {
+		return 0, false
+	}
+	msg := err.Error()
+	if m := floodWaitPattern.FindStringSubmatch(msg); m != nil {
+		seconds, convErr := strconv.Atoi(m[1])
+		if convErr == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return 0, rateLimitPattern.MatchString(msg)
+}
+
+// withFloodWait calls fn and, while it fails with a flood-wait error, sleeps
+// and calls it again. Each wait is the longer of what TDLib asked for and an
+// exponential backoff starting at minFloodWait, capped at maxFloodWait. Other
+// errors, and the last flood-wait error once retries run out, are returned.
+func withFloodWait(operation string, fn func() error) error {
+	backoff := minFloodWait
+	for retry := 0; ; retry++ {
+		err := fn()
+		requested, isFloodWait := floodWaitDuration(err)
+		if !isFloodWait || retry >= maxFloodWaitRetries {
+			return err
+		}
+
+		wait := requested
+		if wait < backoff {
+			wait = backoff
+		}
+		if wait > maxFloodWait {
+			wait = maxFloodWait
+		}
+		backoff *= 2
+
+		log.Warn().
+			Err(err).
+			Str("operation", operation).
+			Dur("wait", wait).
+			Int("retry", retry+1).
+			Int("max_retries", maxFloodWaitRetries).
+			Msg("TDLib flood wait, retrying after delay")
+		floodWaitSleep(wait)
+	}
+}
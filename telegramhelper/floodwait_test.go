@@ -0,0 +1,103 @@
+package telegramhelper
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// floodWaitClient returns a FLOOD_WAIT error for the first floods GetMessage calls
+type floodWaitClient struct {
+	MockTDLibClient
+	floods int
+	calls  int
+}
+
+func (f *floodWaitClient) GetMessage(req *client.GetMessageRequest) (*client.Message, error) {
+	f.calls++
+	if f.calls <= f.floods {
+		return nil, client.ResponseError{Err: &client.Error{Code: 420, Message: "FLOOD_WAIT_3"}}
+	}
+	return &client.Message{
+		Id:              req.MessageId,
+		ChatId:          req.ChatId,
+		InteractionInfo: &client.MessageInteractionInfo{ForwardCount: 7},
+	}, nil
+}
+
+// recordFloodWaits replaces floodWaitSleep for the duration of the test
+func recordFloodWaits(t *testing.T) *[]time.Duration {
+	var waits []time.Duration
+	original := floodWaitSleep
+	floodWaitSleep = func(d time.Duration) { waits = append(waits, d) }
+	t.Cleanup(func() { floodWaitSleep = original })
+	return &waits
+}
+
+func TestFloodWaitDuration(t *testing.T) {
+	tests := []struct {
+		err      error
+		wantWait time.Duration
+		wantOK   bool
+	}{
+		{client.ResponseError{Err: &client.Error{Code: 420, Message: "FLOOD_WAIT_17"}}, 17 * time.Second, true},
+		{client.ResponseError{Err: &client.Error{Code: 429, Message: "Too Many Requests: retry after 5"}}, 5 * time.Second, true},
+		{fmt.Errorf("wrapped: %w", client.ResponseError{Err: &client.Error{Code: 429, Message: "Too Many Requests"}}), 0, true},
+		{client.ResponseError{Err: &client.Error{Code: 400, Message: "MESSAGE_ID_INVALID"}}, 0, false},
+		{errors.New("connection reset"), 0, false},
+		{nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		wait, ok := floodWaitDuration(tt.err)
+		assert.Equal(t, tt.wantOK, ok, "error: %v", tt.err)
+		assert.Equal(t, tt.wantWait, wait, "error: %v", tt.err)
+	}
+}
+
+func TestGetMessageShareCount_RetriesAfterFloodWait(t *testing.T) {
+	waits := recordFloodWaits(t)
+	tdlibClient := &floodWaitClient{floods: 1}
+
+	count, err := GetMessageShareCount(tdlibClient, -1001, 1, "example")
+	require.NoError(t, err)
+	assert.Equal(t, 7, count)
+	assert.Equal(t, 2, tdlibClient.calls)
+	assert.Equal(t, []time.Duration{3 * time.Second}, *waits)
+}
+
+func TestWithFloodWait_CapsWaitAndGivesUp(t *testing.T) {
+	waits := recordFloodWaits(t)
+	floodErr := client.ResponseError{Err: &client.Error{Code: 420, Message: "FLOOD_WAIT_86400"}}
+
+	calls := 0
+	err := withFloodWait("test", func() error {
+		calls++
+		return floodErr
+	})
+	assert.Equal(t, floodErr, err)
+	assert.Equal(t, maxFloodWaitRetries+1, calls)
+	require.Len(t, *waits, maxFloodWaitRetries)
+	for _, wait := range *waits {
+		assert.Equal(t, maxFloodWait, wait)
+	}
+}
+
+func TestWithFloodWait_ReturnsOtherErrorsImmediately(t *testing.T) {
+	waits := recordFloodWaits(t)
+	otherErr := errors.New("not found")
+
+	calls := 0
+	err := withFloodWait("test", func() error {
+		calls++
+		return otherErr
+	})
+	assert.Equal(t, otherErr, err)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, *waits)
+}
@@ -0,0 +1,184 @@
+package telegramhelper
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// markdownEscaper escapes the characters that would otherwise be read as
+// Markdown formatting in plain text.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "~", `\~`, "`", "\\`", "[", `\[`, "]", `\]`, "|", `\|`,
+)
+
+// formattedEntity is a text entity with the markup that opens and closes it.
+type formattedEntity struct {
+	start, end  int // UTF-16 offsets
+	open, close string
+	verbatim    bool // Code and pre blocks, whose content is not Markdown-escaped
+	blockquote  bool
+}
+
+// renderFormattedText renders text with the formatting of its entities as
+// Markdown or HTML, depending on format. Entities that only classify text
+// (plain URLs, mentions, hashtags) are left as plain text. It returns "" if
+// text is nil or format is not a supported text format.
+func renderFormattedText(text *client.FormattedText, format string) string {
+	if text == nil || (format != common.TextFormatMarkdown && format != common.TextFormatHTML) {
+		return ""
+	}
+
+	// Entity offsets and lengths are counted in UTF-16 code units
+	units := utf16.Encode([]rune(text.Text))
+	var entities []formattedEntity
+	for _, entity := range text.Entities {
+		if entity == nil || entity.Length <= 0 {
+			continue
+		}
+		start, end := int(entity.Offset), int(entity.Offset+entity.Length)
+		if start < 0 || end > len(units) {
+			continue
+		}
+		if fe, ok := entityMarkup(entity.Type, format); ok {
+			fe.start, fe.end = start, end
+			entities = append(entities, fe)
+		}
+	}
+	// Outer entities open before the entities nested in them
+	sort.SliceStable(entities, func(i, j int) bool {
+		if entities[i].start != entities[j].start {
+			return entities[i].start < entities[j].start
+		}
+		return entities[i].end > entities[j].end
+	})
+
+	var b strings.Builder
+	var open []formattedEntity
+	verbatim, blockquote := 0, 0
+	writeText := func(from, to int) {
+		if from >= to {
+			return
+		}
+		segment := string(utf16.Decode(units[from:to]))
+		if format == common.TextFormatHTML {
+			segment = html.EscapeString(segment)
+		} else if verbatim == 0 {
+			segment = markdownEscaper.Replace(segment)
+		}
+		if blockquote > 0 && format == common.TextFormatMarkdown {
+			segment = strings.ReplaceAll(segment, "\n", "\n> ")
+		}
+		b.WriteString(segment)
+	}
+	closeUntil := func(pos int) {
+		for len(open) > 0 && open[len(open)-1].end <= pos {
+			fe := open[len(open)-1]
+			open = open[:len(open)-1]
+			b.WriteString(fe.close)
+			if fe.verbatim {
+				verbatim--
+			}
+			if fe.blockquote {
+				blockquote--
+			}
+		}
+	}
+
+	pos := 0
+	for _, fe := range entities {
+		for len(open) > 0 && open[len(open)-1].end <= fe.start {
+			writeText(pos, open[len(open)-1].end)
+			pos = open[len(open)-1].end
+			closeUntil(pos)
+		}
+		// Entities may nest but not overlap; an overlapping one is dropped
+		if len(open) > 0 && fe.end > open[len(open)-1].end {
+			continue
+		}
+		writeText(pos, fe.start)
+		pos = fe.start
+		b.WriteString(fe.open)
+		if fe.verbatim {
+			verbatim++
+		}
+		if fe.blockquote {
+			blockquote++
+		}
+		open = append(open, fe)
+	}
+	for len(open) > 0 {
+		writeText(pos, open[len(open)-1].end)
+		pos = open[len(open)-1].end
+		closeUntil(pos)
+	}
+	writeText(pos, len(units))
+	return b.String()
+}
+
+// entityMarkup returns the markup of a formatting entity type, or false if the
+// type carries no formatting.
+func entityMarkup(entityType client.TextEntityType, format string) (formattedEntity, bool) {
+	markdown := format == common.TextFormatMarkdown
+	pick := func(md, htmlTag string) (string, string) {
+		if markdown {
+			return md, md
+		}
+		return "<" + htmlTag + ">", "</" + htmlTag + ">"
+	}
+	link := func(url string) formattedEntity {
+		if markdown {
+			return formattedEntity{open: "[", close: "](" + url + ")"}
+		}
+		return formattedEntity{open: `<a href="` + html.EscapeString(url) + `">`, close: "</a>"}
+	}
+
+	var fe formattedEntity
+	switch t := entityType.(type) {
+	case *client.TextEntityTypeBold:
+		fe.open, fe.close = pick("**", "b")
+	case *client.TextEntityTypeItalic:
+		fe.open, fe.close = pick("_", "i")
+	case *client.TextEntityTypeUnderline:
+		fe.open, fe.close = pick("__", "u")
+	case *client.TextEntityTypeStrikethrough:
+		fe.open, fe.close = pick("~~", "s")
+	case *client.TextEntityTypeSpoiler:
+		fe.open, fe.close = pick("||", "tg-spoiler")
+	case *client.TextEntityTypeCode:
+		fe.open, fe.close = pick("`", "code")
+		fe.verbatim = true
+	case *client.TextEntityTypePre:
+		fe.open, fe.close = pick("```\n", "pre")
+		if markdown {
+			fe.close = "\n```"
+		}
+		fe.verbatim = true
+	case *client.TextEntityTypePreCode:
+		if markdown {
+			fe.open, fe.close = "```"+t.Language+"\n", "\n```"
+		} else {
+			fe.open = `<pre><code class="language-` + html.EscapeString(t.Language) + `">`
+			fe.close = "</code></pre>"
+		}
+		fe.verbatim = true
+	case *client.TextEntityTypeBlockQuote, *client.TextEntityTypeExpandableBlockQuote:
+		fe.open, fe.close = "> ", ""
+		if !markdown {
+			fe.open, fe.close = "<blockquote>", "</blockquote>"
+		}
+		fe.blockquote = true
+	case *client.TextEntityTypeTextUrl:
+		fe = link(t.Url)
+	case *client.TextEntityTypeMentionName:
+		fe = link(fmt.Sprintf("tg://user?id=%d", t.UserId))
+	default:
+		return fe, false
+	}
+	return fe, true
+}
@@ -0,0 +1,81 @@
+package telegramhelper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+func TestRenderFormattedText(t *testing.T) {
+	// The emoji takes two UTF-16 code units, shifting every later offset
+	text := &client.FormattedText{
+		Text: "🔥 Breaking: read the story, ending hidden <here>",
+		Entities: []*client.TextEntity{
+			{Offset: 3, Length: 9, Type: &client.TextEntityTypeBold{}},
+			{Offset: 3, Length: 8, Type: &client.TextEntityTypeItalic{}},
+			{Offset: 18, Length: 9, Type: &client.TextEntityTypeTextUrl{Url: "https://example.org/a?b=1&c=2"}},
+			{Offset: 29, Length: 20, Type: &client.TextEntityTypeSpoiler{}},
+			{Offset: 36, Length: 6, Type: &client.TextEntityTypeCode{}},
+			// Overlaps the spoiler without nesting in it, so it is dropped
+			{Offset: 40, Length: 10, Type: &client.TextEntityTypeUnderline{}},
+			// Plain URLs, mentions and hashtags carry no formatting
+			{Offset: 22, Length: 5, Type: &client.TextEntityTypeHashtag{}},
+		},
+	}
+
+	assert.Equal(t,
+		"🔥 **_Breaking_:** read [the story](https://example.org/a?b=1&c=2), ||ending `hidden` <here>||",
+		renderFormattedText(text, common.TextFormatMarkdown))
+	assert.Equal(t,
+		`🔥 <b><i>Breaking</i>:</b> read <a href="https://example.org/a?b=1&amp;c=2">the story</a>, <tg-spoiler>ending <code>hidden</code> &lt;here&gt;</tg-spoiler>`,
+		renderFormattedText(text, common.TextFormatHTML))
+}
+
+func TestRenderFormattedText_Blocks(t *testing.T) {
+	text := &client.FormattedText{
+		Text: "quote\nsecond line\nfmt.Println(\"*\")",
+		Entities: []*client.TextEntity{
+			{Offset: 0, Length: 17, Type: &client.TextEntityTypeBlockQuote{}},
+			{Offset: 18, Length: 16, Type: &client.TextEntityTypePreCode{Language: "go"}},
+		},
+	}
+
+	assert.Equal(t, "> quote\n> second line\n```go\nfmt.Println(\"*\")\n```", renderFormattedText(text, common.TextFormatMarkdown))
+	assert.Equal(t,
+		"<blockquote>quote\nsecond line</blockquote>\n<pre><code class=\"language-go\">fmt.Println(&#34;*&#34;)</code></pre>",
+		renderFormattedText(text, common.TextFormatHTML))
+}
+
+func TestRenderFormattedText_Disabled(t *testing.T) {
+	text := &client.FormattedText{Text: "bold", Entities: []*client.TextEntity{{Offset: 0, Length: 4, Type: &client.TextEntityTypeBold{}}}}
+	assert.Empty(t, renderFormattedText(text, ""))
+	assert.Empty(t, renderFormattedText(nil, common.TextFormatHTML))
+	assert.Equal(t, "plain", renderFormattedText(&client.FormattedText{Text: "plain"}, common.TextFormatHTML))
+}
+
+func TestParseMessage_FormattedText(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	message := &client.Message{
+		Id:     1,
+		ChatId: chat.Id,
+		Date:   int32(time.Now().Unix()),
+		Content: &client.MessagePhoto{Photo: &client.Photo{}, Caption: &client.FormattedText{
+			Text:     "spoiler alert",
+			Entities: []*client.TextEntity{{Offset: 0, Length: 7, Type: &client.TextEntityTypeSpoiler{}}},
+		}},
+	}
+
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{TextFormat: common.TextFormatHTML})
+	require.NoError(t, err)
+	assert.Equal(t, "spoiler alert", post.Description)
+	assert.Equal(t, "<tg-spoiler>spoiler</tg-spoiler> alert", post.FormattedText)
+
+	post, err = ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Empty(t, post.FormattedText)
+}
@@ -0,0 +1,60 @@
+package telegramhelper
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/rs/zerolog/log"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// inviteLinkPattern matches t.me/+hash and t.me/joinchat/hash invite links,
+// with or without a scheme, and their telegram.me equivalents.
+var inviteLinkPattern = regexp.MustCompile(`^(?:https?://)?(?:www\.)?(?:t|telegram)\.me/(?:\+|joinchat/)([A-Za-z0-9_-]+)/?$`)
+
+// IsInviteLink reports whether a seed is a private chat invite link rather
+// than a public username.
+func IsInviteLink(seed string) bool {
+	return inviteLinkPattern.MatchString(seed)
+}
+
+// normalizeInviteLink rewrites an invite link to the https://t.me/+hash form
+// accepted by TDLib.
+func normalizeInviteLink(seed string) string {
+	match := inviteLinkPattern.FindStringSubmatch(seed)
+	if match == nil {
+		return seed
+	}
+	return "https://t.me/+" + match[1]
+}
+
+// ResolveInviteLink returns the chat behind an invite link. Chats the account
+// can already access are returned directly; otherwise the account joins the
+// chat if autoJoin is set, and an error is returned if it isn't.
+func ResolveInviteLink(tdlibClient crawler.TDLibClient, link string, autoJoin bool) (*client.Chat, error) {
+	inviteLink := normalizeInviteLink(link)
+	info, err := tdlibClient.CheckChatInviteLink(&client.CheckChatInviteLinkRequest{
+		InviteLink: inviteLink,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check invite link %s: %w", link, err)
+	}
+
+	if info.ChatId != 0 {
+		return tdlibClient.GetChat(&client.GetChatRequest{ChatId: info.ChatId})
+	}
+
+	if !autoJoin {
+		return nil, fmt.Errorf("invite link %s is for chat %q, which requires joining; enable auto-join to crawl it", link, info.Title)
+	}
+
+	log.Info().Str("link", link).Str("title", info.Title).Msg("Joining chat by invite link")
+	chat, err := tdlibClient.JoinChatByInviteLink(&client.JoinChatByInviteLinkRequest{
+		InviteLink: inviteLink,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to join chat by invite link %s: %w", link, err)
+	}
+	return chat, nil
+}
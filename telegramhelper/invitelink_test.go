@@ -0,0 +1,17 @@
+package telegramhelper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsInviteLink(t *testing.T) {
+	for _, seed := range []string{"https://t.me/+AbC_1-2", "t.me/joinchat/AbC", "http://telegram.me/+AbC/", "www.t.me/+AbC"} {
+		assert.True(t, IsInviteLink(seed), seed)
+		assert.Contains(t, normalizeInviteLink(seed), "https://t.me/+AbC")
+	}
+	for _, seed := range []string{"channelname", "https://t.me/channelname", "t.me/c/12345", "+AbC"} {
+		assert.False(t, IsInviteLink(seed), seed)
+	}
+}
@@ -0,0 +1,78 @@
+package telegramhelper
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/enrich"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxParallelMediaDownloads bounds how many media files of one message, such
+// as a video and its thumbnail, are downloaded at the same time.
+const maxParallelMediaDownloads = 4
+
+// mediaDownload is the outcome of downloading and storing one media file of a
+// message.
+type mediaDownload struct {
+	fileID     string
+	dst        *string // Receives remoteID once all downloads have finished; may be nil
+	remoteID   string
+	storageKey string
+	err        error
+	imageText  string             // OCR text of a downloaded image
+	transcript *enrich.Transcript // Transcript of downloaded audio or video
+}
+
+// mediaDownloads runs the media downloads of a message concurrently and
+// collects their results. The zero value runs any number of downloads at once.
+type mediaDownloads struct {
+	group   errgroup.Group
+	results []*mediaDownload
+}
+
+// SetLimit bounds the number of downloads running at once. It must be called
+// before the first Go.
+func (d *mediaDownloads) SetLimit(n int) {
+	d.group.SetLimit(n)
+}
+
+// Go starts fetch, blocking while the limit of running downloads is reached.
+// fetch returns the remote ID, storage key and error of the download and may
+// fill in the OCR text or transcript of result. A panic in fetch is recovered
+// and reported as the download's error.
+func (d *mediaDownloads) Go(dst *string, fileID string, fetch func(result *mediaDownload) (string, string, error)) {
+	result := &mediaDownload{fileID: fileID, dst: dst}
+	d.results = append(d.results, result)
+	d.group.Go(func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error().
+					Interface("panic", r).
+					Str("stack", string(debug.Stack())).
+					Str("file_id", fileID).
+					Msg("Recovered from panic in media download")
+				err = fmt.Errorf("panic while downloading media: %v", r)
+				result.err = err
+			}
+		}()
+		result.remoteID, result.storageKey, result.err = fetch(result)
+		return result.err
+	})
+}
+
+// Wait waits for all started downloads, writes their remote IDs to their
+// destinations and returns their results in the order they were started.
+// Every failed download is in the results, not only the first.
+func (d *mediaDownloads) Wait() []*mediaDownload {
+	_ = d.group.Wait()
+	for _, result := range d.results {
+		if result.dst != nil {
+			*result.dst = result.remoteID
+		}
+	}
+	results := d.results
+	d.results = nil
+	return results
+}
@@ -0,0 +1,114 @@
+package telegramhelper
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// concurrentDownloadClient holds each download until another one is running,
+// or a timeout passes, and records how many ran at the same time
+type concurrentDownloadClient struct {
+	remoteFileRecorder
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+	overlap     chan struct{}
+}
+
+func (c *concurrentDownloadClient) DownloadFile(req *client.DownloadFileRequest) (*client.File, error) {
+	c.mu.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	if c.inFlight == 2 {
+		close(c.overlap)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-c.overlap:
+	case <-time.After(time.Second):
+	}
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return c.remoteFileRecorder.DownloadFile(req)
+}
+
+// failingRemoteFileClient can't look up any remote file
+type failingRemoteFileClient struct {
+	remoteFileRecorder
+}
+
+func (f *failingRemoteFileClient) GetRemoteFile(req *client.GetRemoteFileRequest) (*client.File, error) {
+	f.remoteFileRecorder.GetRemoteFile(req)
+	return nil, errors.New("file reference expired")
+}
+
+// videoNoteMessage is a video note with a thumbnail, so it has two files
+func videoNoteMessage() *client.Message {
+	return &client.Message{Id: 1, ChatId: -1001, Date: int32(time.Now().Unix()), Content: &client.MessageVideoNote{VideoNote: &client.VideoNote{
+		Thumbnail: &client.Thumbnail{File: &client.File{Id: 2, Remote: &client.RemoteFile{Id: "remote-thumb"}}},
+		Video:     &client.File{Id: 3, Remote: &client.RemoteFile{Id: "remote-video"}},
+	}}}
+}
+
+func TestParseMessage_DownloadsMediaConcurrently(t *testing.T) {
+	tdlibClient := &concurrentDownloadClient{remoteFileRecorder: remoteFileRecorder{dir: t.TempDir()}, overlap: make(chan struct{})}
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+
+	start := time.Now()
+	post, err := ParseMessage("crawl", videoNoteMessage(), &client.MessageLink{Link: "https://t.me/example/1"}, chat, nil, nil, 0, 0, "example", tdlibClient, newTestStateManager(t), common.CrawlerConfig{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, tdlibClient.maxInFlight, "The thumbnail and the video should be downloaded at the same time")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ElementsMatch(t, []string{"remote-thumb", "remote-video"}, tdlibClient.remoteIDs)
+	assert.Equal(t, "unique-remote-thumb", post.ThumbURL)
+	assert.Equal(t, "unique-remote-video", post.MediaURL)
+	assert.Len(t, post.MediaStorageKeys, 2)
+	assert.Empty(t, post.MediaErrors)
+}
+
+func TestParseMessage_AggregatesMediaDownloadErrors(t *testing.T) {
+	tdlibClient := &failingRemoteFileClient{remoteFileRecorder: remoteFileRecorder{dir: t.TempDir()}}
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	cfg := common.CrawlerConfig{DownloadMaxAttempts: 1}
+
+	post, err := ParseMessage("crawl", videoNoteMessage(), &client.MessageLink{Link: "https://t.me/example/1"}, chat, nil, nil, 0, 0, "example", tdlibClient, newTestStateManager(t), cfg)
+	require.NoError(t, err, "Failed media should not fail the post")
+
+	require.Len(t, post.MediaErrors, 2, "Every failed download should be reported")
+	assert.Contains(t, post.MediaErrors[0], "remote-thumb")
+	assert.Contains(t, post.MediaErrors[1], "remote-video")
+	assert.Empty(t, post.ThumbURL)
+	assert.Empty(t, post.MediaURL)
+	assert.Empty(t, post.MediaStorageKeys)
+}
+
+func TestMediaDownloads_RecoversPanics(t *testing.T) {
+	var downloads mediaDownloads
+	var first, second string
+	downloads.Go(&first, "panicking", func(result *mediaDownload) (string, string, error) {
+		panic("corrupt file")
+	})
+	downloads.Go(&second, "working", func(result *mediaDownload) (string, string, error) {
+		return "remote-working", "key", nil
+	})
+
+	results := downloads.Wait()
+	require.Len(t, results, 2)
+	assert.ErrorContains(t, results[0].err, "corrupt file")
+	assert.NoError(t, results[1].err)
+	assert.Empty(t, first)
+	assert.Equal(t, "remote-working", second)
+}
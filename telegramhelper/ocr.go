@@ -0,0 +1,42 @@
+package telegramhelper
+
+import (
+	"context"
+	"strings"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/enrich"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/rs/zerolog/log"
+)
+
+// ocrEngine returns the OCR engine configured by cfg, or nil if OCR is disabled.
+func ocrEngine(cfg common.CrawlerConfig) enrich.OCREngine {
+	if !cfg.EnableOCR {
+		return nil
+	}
+	if cfg.OCREngine != nil {
+		return cfg.OCREngine
+	}
+	return enrich.TesseractOCR{Languages: cfg.OCRLanguages}
+}
+
+// extractImageText runs OCR on a downloaded image. A failure is logged and
+// yields no text, since OCR must not keep the post from being stored.
+func extractImageText(engine enrich.OCREngine, path string) string {
+	text, err := engine.ExtractText(context.Background(), path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("OCR failed")
+		return ""
+	}
+	return strings.TrimSpace(text)
+}
+
+// joinImageText returns the text of every OCR result, one image per line.
+func joinImageText(results []model.OCRData) string {
+	texts := make([]string, 0, len(results))
+	for _, r := range results {
+		texts = append(texts, r.OCRText)
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,68 @@
+package telegramhelper
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// mockOCR returns fixed text and records the images it was given
+type mockOCR struct {
+	mu       sync.Mutex
+	text     string
+	contents []string
+}
+
+func (m *mockOCR) ExtractText(ctx context.Context, imagePath string) (string, error) {
+	data, err := os.ReadFile(imagePath)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.contents = append(m.contents, string(data))
+	return m.text, nil
+}
+
+func TestParseMessage_OCR(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	message := &client.Message{Id: 1, ChatId: chat.Id, Date: int32(time.Now().Unix()), Content: &client.MessagePhoto{
+		Caption: &client.FormattedText{Text: "poster"},
+		Photo: &client.Photo{Sizes: []*client.PhotoSize{
+			{Photo: &client.File{Id: 4, Remote: &client.RemoteFile{Id: "remote-photo"}}},
+		}},
+	}}
+
+	newClient := func() *flakyDownloadClient {
+		downloaded := filepath.Join(t.TempDir(), "photo.jpg")
+		require.NoError(t, os.WriteFile(downloaded, []byte("image bytes"), 0644))
+		return &flakyDownloadClient{downloadedPath: downloaded}
+	}
+
+	engine := &mockOCR{text: " PROTEST AT NOON \n"}
+	cfg := common.CrawlerConfig{EnableOCR: true, OCREngine: engine}
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", newClient(), newTestStateManager(t), cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"image bytes"}, engine.contents, "OCR should run on the downloaded image before it is stored")
+	assert.Equal(t, "PROTEST AT NOON", post.ImageText)
+	assert.Equal(t, []model.OCRData{{OCRText: "PROTEST AT NOON", ThumbURL: "unique-1"}}, post.OCRData)
+	assert.Contains(t, post.AllText, "PROTEST AT NOON")
+
+	// OCR is off unless enabled
+	cfg.EnableOCR = false
+	post, err = ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", newClient(), newTestStateManager(t), cfg)
+	require.NoError(t, err)
+	assert.Len(t, engine.contents, 1)
+	assert.Empty(t, post.ImageText)
+}
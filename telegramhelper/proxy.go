@@ -0,0 +1,91 @@
+package telegramhelper
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/rs/zerolog/log"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// resolveProxyURL returns the proxy to use for downloads and TDLib. A configured
+// URL takes precedence and must be valid; otherwise HTTPS_PROXY and HTTP_PROXY
+// (or their lower-case forms) are consulted, and an unusable environment value
+// is logged and ignored. It returns nil when no proxy is set.
+func resolveProxyURL(configured string) (*url.URL, error) {
+	if configured != "" {
+		return parseProxyURL(configured)
+	}
+
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		raw := os.Getenv(name)
+		if raw == "" {
+			continue
+		}
+		proxyURL, err := parseProxyURL(raw)
+		if err != nil {
+			log.Warn().Err(err).Str("variable", name).Msg("Ignoring proxy from environment")
+			return nil, nil
+		}
+		return proxyURL, nil
+	}
+	return nil, nil
+}
+
+// parseProxyURL parses and validates an http, https or socks5 proxy URL.
+func parseProxyURL(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, must be http, https or socks5", proxyURL.Scheme)
+	}
+	if proxyURL.Hostname() == "" || proxyURL.Port() == "" {
+		return nil, fmt.Errorf("proxy URL %s must include a host and port", proxyURL.Redacted())
+	}
+	return proxyURL, nil
+}
+
+// newDownloadClient returns the HTTP client used for database downloads, which
+// sends the User-Agent and headers of httpCfg. Without a proxy it uses
+// http.DefaultTransport, which honours the environment.
+func newDownloadClient(proxyURL *url.URL, httpCfg common.HTTPConfig) *http.Client {
+	if proxyURL == nil {
+		return &http.Client{Transport: httpCfg.Transport(nil)}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	return &http.Client{Transport: httpCfg.Transport(transport)}
+}
+
+// tdlibProxyRequest converts a proxy URL into the TDLib addProxy request.
+func tdlibProxyRequest(proxyURL *url.URL) (*client.AddProxyRequest, error) {
+	port, err := strconv.ParseInt(proxyURL.Port(), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy port %q: %w", proxyURL.Port(), err)
+	}
+
+	username := proxyURL.User.Username()
+	password, _ := proxyURL.User.Password()
+
+	var proxyType client.ProxyType
+	if proxyURL.Scheme == "socks5" {
+		proxyType = &client.ProxyTypeSocks5{Username: username, Password: password}
+	} else {
+		proxyType = &client.ProxyTypeHttp{Username: username, Password: password}
+	}
+
+	return &client.AddProxyRequest{
+		Server: proxyURL.Hostname(),
+		Port:   int32(port),
+		Enable: true,
+		Type:   proxyType,
+	}, nil
+}
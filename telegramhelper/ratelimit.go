@@ -0,0 +1,158 @@
+package telegramhelper
+
+import (
+	"context"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// rateLimitedClient is a TDLib client whose requests all wait for the shared
+// rate limiter, so concurrent workers stay below Telegram's flood limits.
+// Close is not limited, so shutdown is never delayed.
+type rateLimitedClient struct {
+	crawler.TDLibClient
+	limiter common.RateLimiter
+}
+
+// WithRateLimit returns tdlibClient with every request paced by limiter. A nil
+// limiter returns tdlibClient unchanged. Passing the same limiter to several
+// clients shares the request budget between them.
+func WithRateLimit(tdlibClient crawler.TDLibClient, limiter common.RateLimiter) crawler.TDLibClient {
+	if limiter == nil || tdlibClient == nil {
+		return tdlibClient
+	}
+	return &rateLimitedClient{TDLibClient: tdlibClient, limiter: limiter}
+}
+
+// wait blocks until the limiter allows the next request.
+func (c *rateLimitedClient) wait() error {
+	return c.limiter.Wait(context.Background())
+}
+
+func (c *rateLimitedClient) GetMessage(req *client.GetMessageRequest) (*client.Message, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetMessage(req)
+}
+
+func (c *rateLimitedClient) GetMessageLink(req *client.GetMessageLinkRequest) (*client.MessageLink, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetMessageLink(req)
+}
+
+func (c *rateLimitedClient) GetMessageThreadHistory(req *client.GetMessageThreadHistoryRequest) (*client.Messages, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetMessageThreadHistory(req)
+}
+
+func (c *rateLimitedClient) GetMessageThread(req *client.GetMessageThreadRequest) (*client.MessageThreadInfo, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetMessageThread(req)
+}
+
+func (c *rateLimitedClient) GetRemoteFile(req *client.GetRemoteFileRequest) (*client.File, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetRemoteFile(req)
+}
+
+func (c *rateLimitedClient) DownloadFile(req *client.DownloadFileRequest) (*client.File, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.DownloadFile(req)
+}
+
+func (c *rateLimitedClient) GetChatHistory(req *client.GetChatHistoryRequest) (*client.Messages, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetChatHistory(req)
+}
+
+func (c *rateLimitedClient) SearchPublicChat(req *client.SearchPublicChatRequest) (*client.Chat, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.SearchPublicChat(req)
+}
+
+func (c *rateLimitedClient) CheckChatInviteLink(req *client.CheckChatInviteLinkRequest) (*client.ChatInviteLinkInfo, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.CheckChatInviteLink(req)
+}
+
+func (c *rateLimitedClient) JoinChatByInviteLink(req *client.JoinChatByInviteLinkRequest) (*client.Chat, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.JoinChatByInviteLink(req)
+}
+
+func (c *rateLimitedClient) GetChat(req *client.GetChatRequest) (*client.Chat, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetChat(req)
+}
+
+func (c *rateLimitedClient) GetSupergroup(req *client.GetSupergroupRequest) (*client.Supergroup, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetSupergroup(req)
+}
+
+func (c *rateLimitedClient) GetSupergroupFullInfo(req *client.GetSupergroupFullInfoRequest) (*client.SupergroupFullInfo, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetSupergroupFullInfo(req)
+}
+
+func (c *rateLimitedClient) GetBasicGroupFullInfo(req *client.GetBasicGroupFullInfoRequest) (*client.BasicGroupFullInfo, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetBasicGroupFullInfo(req)
+}
+
+func (c *rateLimitedClient) GetUser(req *client.GetUserRequest) (*client.User, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetUser(req)
+}
+
+func (c *rateLimitedClient) DeleteFile(req *client.DeleteFileRequest) (*client.Ok, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.DeleteFile(req)
+}
+
+func (c *rateLimitedClient) GetForumTopic(req *client.GetForumTopicRequest) (*client.ForumTopic, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetForumTopic(req)
+}
+
+func (c *rateLimitedClient) GetMe() (*client.User, error) {
+	if err := c.wait(); err != nil {
+		return nil, err
+	}
+	return c.TDLibClient.GetMe()
+}
@@ -0,0 +1,67 @@
+package telegramhelper
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// countingLimiter lets every request through and counts them
+type countingLimiter struct {
+	waits int
+	err   error
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits++
+	return l.err
+}
+
+func TestWithRateLimit_PacesRequests(t *testing.T) {
+	const requestsPerSecond = 20
+	threads := newThreadClient(5)
+	tdlibClient := WithRateLimit(threads, common.NewRateLimiter(requestsPerSecond, 1))
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		comments, err := GetMessageComments(tdlibClient, -1001, int64(i+1), "example", 1, 5, false, 0)
+		require.NoError(t, err)
+		require.Len(t, comments, 1)
+	}
+	elapsed := time.Since(start)
+
+	// With a burst of one, every request after the first waits a full interval
+	requests := len(threads.limits)
+	require.GreaterOrEqual(t, requests, 5)
+	assert.GreaterOrEqual(t, elapsed, time.Duration(requests-1)*time.Second/requestsPerSecond)
+}
+
+func TestWithRateLimit_EveryRequestWaits(t *testing.T) {
+	downloaded := filepath.Join(t.TempDir(), "file.jpg")
+	require.NoError(t, os.WriteFile(downloaded, []byte("data"), 0644))
+	limiter := &countingLimiter{}
+	tdlibClient := WithRateLimit(&flakyDownloadClient{downloadedPath: downloaded}, limiter)
+
+	_, _, err := fetchfilefromtelegram(tdlibClient, newTestStateManager(t), "remote-1", common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Positive(t, limiter.waits, "Downloads should pass through the limiter")
+
+	waits := limiter.waits
+	_, err = tdlibClient.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, waits, limiter.waits, "Close should not be rate limited")
+
+	limiter.err = errors.New("context canceled")
+	_, err = tdlibClient.GetChat(&client.GetChatRequest{ChatId: 1})
+	assert.ErrorIs(t, err, limiter.err)
+
+	assert.Same(t, tdlibClient, WithRateLimit(tdlibClient, nil), "A nil limiter should leave the client unchanged")
+}
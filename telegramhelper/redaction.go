@@ -0,0 +1,64 @@
+package telegramhelper
+
+import (
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+)
+
+// redactPost replaces the PII fields selected in cfg with salted pseudonyms.
+// The post and its comments are modified in place; a disabled config is a no-op.
+// Text derived from redacted fields, such as the description of a contact card
+// and the search text, is built after redaction by the caller.
+func redactPost(post *model.Post, cfg common.RedactionConfig) {
+	if post == nil || !cfg.Enabled() {
+		return
+	}
+
+	redactHandle := cfg.ShouldRedact(common.RedactFieldHandle)
+	redactSender := cfg.ShouldRedact(common.RedactFieldSenderID)
+
+	if redactHandle {
+		post.Handle = cfg.Pseudonymize(post.Handle)
+	}
+	if redactSender {
+		post.SenderID = cfg.Pseudonymize(post.SenderID)
+	}
+
+	if post.ForwardedFrom != nil {
+		if redactHandle {
+			post.ForwardedFrom.SenderName = cfg.Pseudonymize(post.ForwardedFrom.SenderName)
+		}
+		if redactSender {
+			post.ForwardedFrom.SenderID = cfg.Pseudonymize(post.ForwardedFrom.SenderID)
+		}
+	}
+
+	// The actor of a service event is its sender, and the members it added or
+	// removed are users too
+	if post.ServiceEvent != nil && redactSender {
+		post.ServiceEvent.ActorID = cfg.Pseudonymize(post.ServiceEvent.ActorID)
+		for i, id := range post.ServiceEvent.UserIDs {
+			post.ServiceEvent.UserIDs[i] = cfg.Pseudonymize(id)
+		}
+	}
+
+	if post.Contact != nil {
+		if cfg.ShouldRedact(common.RedactFieldPhone) {
+			post.Contact.PhoneNumber = cfg.Pseudonymize(post.Contact.PhoneNumber)
+		}
+		if cfg.ShouldRedact(common.RedactFieldContact) {
+			post.Contact.FirstName = cfg.Pseudonymize(post.Contact.FirstName)
+			post.Contact.LastName = cfg.Pseudonymize(post.Contact.LastName)
+			post.Contact.UserID = cfg.Pseudonymize(post.Contact.UserID)
+		}
+	}
+
+	for i := range post.Comments {
+		if redactHandle {
+			post.Comments[i].Handle = cfg.Pseudonymize(post.Comments[i].Handle)
+		}
+		if redactSender {
+			post.Comments[i].SenderID = cfg.Pseudonymize(post.Comments[i].SenderID)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package telegramhelper
+
+import (
+	"testing"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactPost(t *testing.T) {
+	cfg := common.RedactionConfig{
+		Fields: []string{common.RedactFieldHandle, common.RedactFieldSenderID},
+		Salt:   "study-salt",
+	}
+
+	post := model.Post{
+		Handle:   "alice",
+		SenderID: "1234",
+		Comments: []model.Comment{
+			{Handle: "alice", SenderID: "1234"},
+			{Handle: "bob", SenderID: ""},
+		},
+		ForwardedFrom: &model.ForwardedFrom{SenderID: "1234", SenderName: "alice"},
+	}
+
+	redactPost(&post, cfg)
+
+	assert.NotEqual(t, "alice", post.Handle)
+	assert.NotEqual(t, "1234", post.SenderID)
+	assert.Len(t, post.Handle, 64, "Pseudonyms should be hex-encoded SHA-256 digests")
+	assert.Equal(t, post.Handle, post.Comments[0].Handle, "Pseudonyms should be stable for the same input")
+	assert.Equal(t, post.SenderID, post.Comments[0].SenderID, "Pseudonyms should be stable for the same input")
+	assert.Empty(t, post.Comments[1].SenderID, "Empty values should stay empty")
+	assert.Equal(t, post.SenderID, post.ForwardedFrom.SenderID, "Forward origins should be redacted too")
+	assert.Equal(t, post.Handle, post.ForwardedFrom.SenderName, "Forward origins should be redacted too")
+
+	// A different salt must produce different pseudonyms
+	other := common.RedactionConfig{Fields: cfg.Fields, Salt: "other-salt"}
+	assert.NotEqual(t, cfg.Pseudonymize("alice"), other.Pseudonymize("alice"))
+}
+
+func TestRedactPost_OnlyConfiguredFields(t *testing.T) {
+	post := model.Post{Handle: "alice", SenderID: "1234"}
+
+	redactPost(&post, common.RedactionConfig{})
+	assert.Equal(t, "alice", post.Handle, "Disabled redaction should leave the post untouched")
+
+	redactPost(&post, common.RedactionConfig{Fields: []string{common.RedactFieldSenderID}, Salt: "s"})
+	assert.Equal(t, "alice", post.Handle)
+	assert.NotEqual(t, "1234", post.SenderID)
+}
+
+func TestRedactPost_Contact(t *testing.T) {
+	contact := model.ContactData{FirstName: "Jane", LastName: "Doe", PhoneNumber: "+15550100", UserID: "424242"}
+	cfg := common.RedactionConfig{Fields: []string{common.RedactFieldPhone}, Salt: "s"}
+
+	post := model.Post{Contact: &contact}
+	redactPost(&post, cfg)
+	assert.Equal(t, cfg.Pseudonymize("+15550100"), post.Contact.PhoneNumber)
+	assert.Equal(t, "Jane", post.Contact.FirstName, "Names are only redacted with the contact field")
+
+	cfg.Fields = []string{common.RedactFieldContact}
+	redactPost(&post, cfg)
+	assert.Equal(t, cfg.Pseudonymize("Jane"), post.Contact.FirstName)
+	assert.Equal(t, cfg.Pseudonymize("Doe"), post.Contact.LastName)
+	assert.Equal(t, cfg.Pseudonymize("424242"), post.Contact.UserID)
+}
+
+func TestRedactPost_ServiceEvent(t *testing.T) {
+	cfg := common.RedactionConfig{Fields: []string{common.RedactFieldSenderID}, Salt: "s"}
+	post := model.Post{
+		SenderID:     "1234",
+		ServiceEvent: &model.ServiceEventData{Event: model.ServiceEventAddMembers, ActorID: "1234", UserIDs: []string{"7", "8"}},
+	}
+
+	redactPost(&post, cfg)
+
+	assert.Equal(t, post.SenderID, post.ServiceEvent.ActorID, "The actor should be redacted like the sender")
+	assert.Equal(t, []string{cfg.Pseudonymize("7"), cfg.Pseudonymize("8")}, post.ServiceEvent.UserIDs)
+
+	post = model.Post{ServiceEvent: &model.ServiceEventData{ActorID: "1234", UserIDs: []string{"7"}}}
+	redactPost(&post, common.RedactionConfig{Fields: []string{common.RedactFieldHandle}, Salt: "s"})
+	assert.Equal(t, "1234", post.ServiceEvent.ActorID)
+	assert.Equal(t, []string{"7"}, post.ServiceEvent.UserIDs)
+}
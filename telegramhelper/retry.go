@@ -0,0 +1,92 @@
+package telegramhelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/rs/zerolog/log"
+)
+
+// Defaults used when the crawler config leaves the download retry policy unset.
+const (
+	defaultDownloadMaxAttempts = 3
+	defaultDownloadRetryDelay  = time.Second
+)
+
+// MediaDownloadError is returned when a media file could not be fetched from
+// Telegram after all retry attempts. It lets callers distinguish a failed
+// download from a message that simply had no media.
+type MediaDownloadError struct {
+	DownloadID string // Remote file ID that was requested
+	Stage      string // Step that failed: "get_remote_file" or "download_file"
+	Attempts   int    // Number of attempts made before giving up
+	Err        error  // Error returned by the last attempt
+}
+
+func (e *MediaDownloadError) Error() string {
+	return fmt.Sprintf("media download %s failed at %s after %d attempts: %v", e.DownloadID, e.Stage, e.Attempts, e.Err)
+}
+
+func (e *MediaDownloadError) Unwrap() error {
+	return e.Err
+}
+
+// downloadRetryPolicy returns the configured attempt count and base delay,
+// applying defaults for unset values.
+func downloadRetryPolicy(cfg common.CrawlerConfig) (int, time.Duration) {
+	attempts := cfg.DownloadMaxAttempts
+	if attempts <= 0 {
+		attempts = defaultDownloadMaxAttempts
+	}
+	delay := cfg.DownloadRetryDelay
+	if delay < 0 {
+		delay = 0
+	} else if delay == 0 {
+		delay = defaultDownloadRetryDelay
+	}
+	return attempts, delay
+}
+
+// retryDownload calls fn until it succeeds or maxAttempts is reached, doubling
+// the delay between attempts. The final failure is wrapped in a MediaDownloadError.
+// A cancelled context is not retried.
+func retryDownload(downloadID, stage string, maxAttempts int, baseDelay time.Duration, fn func() error) error {
+	var err error
+	delay := baseDelay
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return &MediaDownloadError{
+				DownloadID: downloadID,
+				Stage:      stage,
+				Attempts:   attempt,
+				Err:        err,
+			}
+		}
+
+		log.Warn().
+			Err(err).
+			Str("download_id", downloadID).
+			Str("stage", stage).
+			Int("attempt", attempt).
+			Int("max_attempts", maxAttempts).
+			Msg("Media download attempt failed")
+
+		if attempt < maxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	return &MediaDownloadError{
+		DownloadID: downloadID,
+		Stage:      stage,
+		Attempts:   maxAttempts,
+		Err:        err,
+	}
+}
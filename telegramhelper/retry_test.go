@@ -0,0 +1,408 @@
+package telegramhelper
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/researchaccelerator-hub/telegram-scraper/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// flakyDownloadClient fails GetRemoteFile and DownloadFile a fixed number of times before succeeding
+type flakyDownloadClient struct {
+	MockTDLibClient
+	failures       int
+	remoteCalls    int
+	downloadCalls  int
+	downloadedPath string
+}
+
+func (f *flakyDownloadClient) GetRemoteFile(req *client.GetRemoteFileRequest) (*client.File, error) {
+	f.remoteCalls++
+	if f.remoteCalls <= f.failures {
+		return nil, errors.New("temporary lookup failure")
+	}
+	return &client.File{Id: 1, Remote: &client.RemoteFile{Id: req.RemoteFileId, UniqueId: "unique-1"}}, nil
+}
+
+func (f *flakyDownloadClient) DownloadFile(req *client.DownloadFileRequest) (*client.File, error) {
+	f.downloadCalls++
+	if f.downloadCalls <= f.failures {
+		return nil, errors.New("temporary download failure")
+	}
+	return &client.File{Id: req.FileId, Local: &client.LocalFile{Path: f.downloadedPath}}, nil
+}
+
+func (f *flakyDownloadClient) GetMessage(req *client.GetMessageRequest) (*client.Message, error) {
+	return &client.Message{Id: req.MessageId, ChatId: req.ChatId}, nil
+}
+
+func newTestStateManager(t *testing.T) state.StateManagementInterface {
+	sm, err := state.NewLocalStateManager(state.Config{
+		CrawlID:     "test-crawl",
+		LocalConfig: &state.LocalConfig{BasePath: t.TempDir()},
+	})
+	require.NoError(t, err)
+	return sm
+}
+
+func TestFetchFileFromTelegram_RetriesTransientFailures(t *testing.T) {
+	downloaded := filepath.Join(t.TempDir(), "file.jpg")
+	require.NoError(t, os.WriteFile(downloaded, []byte("data"), 0644))
+
+	tdlibClient := &flakyDownloadClient{failures: 2, downloadedPath: downloaded}
+	cfg := common.CrawlerConfig{DownloadMaxAttempts: 3, DownloadRetryDelay: time.Millisecond}
+
+	path, remoteID, err := fetchfilefromtelegram(tdlibClient, newTestStateManager(t), "remote-1", cfg)
+	require.NoError(t, err)
+	assert.Equal(t, downloaded, path)
+	assert.Equal(t, "unique-1", remoteID)
+	assert.Equal(t, 3, tdlibClient.remoteCalls, "Remote file lookup should be retried until it succeeds")
+	assert.Equal(t, 3, tdlibClient.downloadCalls, "Download should be retried until it succeeds")
+}
+
+func TestFetchFileFromTelegram_ReturnsTypedErrorWhenRetriesExhausted(t *testing.T) {
+	tdlibClient := &flakyDownloadClient{failures: 5}
+	cfg := common.CrawlerConfig{DownloadMaxAttempts: 2, DownloadRetryDelay: time.Millisecond}
+
+	path, _, err := fetchfilefromtelegram(tdlibClient, newTestStateManager(t), "remote-1", cfg)
+	assert.Empty(t, path)
+
+	var downloadErr *MediaDownloadError
+	require.True(t, errors.As(err, &downloadErr), "Expected a MediaDownloadError, got %v", err)
+	assert.Equal(t, "get_remote_file", downloadErr.Stage)
+	assert.Equal(t, 2, downloadErr.Attempts)
+	assert.Equal(t, 2, tdlibClient.remoteCalls)
+}
+
+// resumingDownloadClient reports a partially or fully downloaded local file and
+// records the offset requested by DownloadFile
+type resumingDownloadClient struct {
+	MockTDLibClient
+	local         *client.LocalFile
+	size          int64
+	downloadCalls int
+	offset        int64
+}
+
+func (r *resumingDownloadClient) GetRemoteFile(req *client.GetRemoteFileRequest) (*client.File, error) {
+	return &client.File{Id: 7, Size: r.size, Local: r.local, Remote: &client.RemoteFile{Id: req.RemoteFileId, UniqueId: "unique-7"}}, nil
+}
+
+func (r *resumingDownloadClient) DownloadFile(req *client.DownloadFileRequest) (*client.File, error) {
+	r.downloadCalls++
+	r.offset = req.Offset
+	return &client.File{Id: req.FileId, Size: r.size, Local: &client.LocalFile{Path: r.local.Path, IsDownloadingCompleted: true}}, nil
+}
+
+func TestFetchFileFromTelegram_ResumesPartialDownload(t *testing.T) {
+	partial := filepath.Join(t.TempDir(), "video.mp4")
+	require.NoError(t, os.WriteFile(partial, []byte("0123"), 0644))
+
+	tdlibClient := &resumingDownloadClient{
+		size:  10,
+		local: &client.LocalFile{Path: partial, DownloadedPrefixSize: 4},
+	}
+	path, _, err := fetchfilefromtelegram(tdlibClient, newTestStateManager(t), "remote-7", common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, partial, path)
+	assert.Equal(t, 1, tdlibClient.downloadCalls)
+	assert.Equal(t, int64(4), tdlibClient.offset, "Download should resume after the bytes already on disk")
+}
+
+func TestFetchFileFromTelegram_SkipsCompletedLocalFile(t *testing.T) {
+	complete := filepath.Join(t.TempDir(), "video.mp4")
+	require.NoError(t, os.WriteFile(complete, []byte("0123456789"), 0644))
+
+	tdlibClient := &resumingDownloadClient{
+		size:  10,
+		local: &client.LocalFile{Path: complete, DownloadedPrefixSize: 10, IsDownloadingCompleted: true},
+	}
+	path, _, err := fetchfilefromtelegram(tdlibClient, newTestStateManager(t), "remote-7", common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, complete, path)
+	assert.Zero(t, tdlibClient.downloadCalls, "A completed local file should not be downloaded again")
+}
+
+func TestFetchFileFromTelegram_MovesDownloadToMediaDownloadDir(t *testing.T) {
+	downloaded := filepath.Join(t.TempDir(), "tdlib", "photos", "file_1.jpg")
+	require.NoError(t, os.MkdirAll(filepath.Dir(downloaded), 0755))
+	require.NoError(t, os.WriteFile(downloaded, []byte("data"), 0644))
+	downloadDir := filepath.Join(t.TempDir(), "media")
+
+	tdlibClient := &flakyDownloadClient{downloadedPath: downloaded}
+	path, _, err := fetchfilefromtelegram(tdlibClient, newTestStateManager(t), "remote-1", common.CrawlerConfig{MediaDownloadDir: downloadDir})
+	require.NoError(t, err)
+
+	assert.Equal(t, downloadDir, filepath.Dir(path))
+	assert.True(t, strings.HasPrefix(filepath.Base(path), "unique-1-"), "The file should be named after its unique ID, got %s", path)
+	assert.Equal(t, ".jpg", filepath.Ext(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+	assert.NoFileExists(t, downloaded, "The download should be moved out of TDLib's files directory")
+}
+
+func TestMoveToDownloadDir_SameFileTwice(t *testing.T) {
+	downloadDir := t.TempDir()
+	first := filepath.Join(t.TempDir(), "file_1.jpg")
+	second := filepath.Join(t.TempDir(), "file_2.jpg")
+	require.NoError(t, os.WriteFile(first, []byte("first"), 0644))
+	require.NoError(t, os.WriteFile(second, []byte("second"), 0644))
+
+	// Two workers downloading the same file each get their own copy
+	firstPath := moveToDownloadDir(first, "unique-1", downloadDir)
+	secondPath := moveToDownloadDir(second, "unique-1", downloadDir)
+	require.NotEqual(t, firstPath, secondPath)
+
+	require.NoError(t, os.Remove(firstPath))
+	data, err := os.ReadFile(secondPath)
+	require.NoError(t, err, "Removing one copy once it is stored must not remove the other")
+	assert.Equal(t, "second", string(data))
+}
+
+func TestDownloadResumeOffset_TrustsOnlyBytesOnDisk(t *testing.T) {
+	truncated := filepath.Join(t.TempDir(), "video.mp4")
+	require.NoError(t, os.WriteFile(truncated, []byte("01"), 0644))
+
+	offset, complete := downloadResumeOffset(&client.File{Size: 10, Local: &client.LocalFile{Path: truncated, DownloadedPrefixSize: 8}})
+	assert.Equal(t, int64(2), offset)
+	assert.False(t, complete)
+
+	offset, complete = downloadResumeOffset(&client.File{Size: 10, Local: &client.LocalFile{Path: filepath.Join(t.TempDir(), "missing"), DownloadedPrefixSize: 8}})
+	assert.Zero(t, offset)
+	assert.False(t, complete)
+
+	offset, complete = downloadResumeOffset(&client.File{Size: 10})
+	assert.Zero(t, offset)
+	assert.False(t, complete)
+}
+
+// failingStoreStateManager is a local state manager whose file uploads always fail
+type failingStoreStateManager struct {
+	state.StateManagementInterface
+}
+
+func (f *failingStoreStateManager) StoreFile(channelID string, sourceFilePath string, fileName string) (string, string, error) {
+	return "", "", errors.New("upload failed")
+}
+
+func TestParseMessage_RecordsMediaErrorsOnPhotoUploadFailure(t *testing.T) {
+	downloaded := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(downloaded, []byte("data"), 0644))
+
+	tdlibClient := &flakyDownloadClient{downloadedPath: downloaded}
+	sm := &failingStoreStateManager{newTestStateManager(t)}
+
+	message := &client.Message{
+		Id:     1,
+		ChatId: -1001,
+		Date:   int32(time.Now().Unix()),
+		Content: &client.MessagePhoto{
+			Caption: &client.FormattedText{Text: "photo caption"},
+			Photo: &client.Photo{Sizes: []*client.PhotoSize{
+				{Photo: &client.File{Id: 7, Remote: &client.RemoteFile{Id: "remote-photo"}}},
+			}},
+		},
+	}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, sm, common.CrawlerConfig{})
+	require.NoError(t, err, "Media failures should not prevent the post from being parsed")
+	assert.Equal(t, "photo caption", post.Description)
+	require.Len(t, post.MediaErrors, 1)
+	assert.Contains(t, post.MediaErrors[0], "upload failed")
+	assert.Empty(t, post.MediaStorageKeys)
+
+	processed, err := sm.HasProcessedMedia("unique-1")
+	require.NoError(t, err)
+	assert.False(t, processed, "Failed uploads should not be marked as processed")
+}
+
+// recordingStateManager is a local state manager that counts storage calls
+type recordingStateManager struct {
+	state.StateManagementInterface
+	storeFileCalls int
+	storePostCalls int
+}
+
+func (r *recordingStateManager) StoreFile(channelID string, sourceFilePath string, fileName string) (string, string, error) {
+	r.storeFileCalls++
+	return r.StateManagementInterface.StoreFile(channelID, sourceFilePath, fileName)
+}
+
+func (r *recordingStateManager) StorePost(channelID string, post model.Post) error {
+	r.storePostCalls++
+	return r.StateManagementInterface.StorePost(channelID, post)
+}
+
+func TestParseMessage_DryRunSkipsDownloadsAndStorage(t *testing.T) {
+	downloaded := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(downloaded, []byte("data"), 0644))
+
+	tdlibClient := &flakyDownloadClient{downloadedPath: downloaded}
+	sm := &recordingStateManager{StateManagementInterface: newTestStateManager(t)}
+
+	message := &client.Message{
+		Id:     1,
+		ChatId: -1001,
+		Date:   int32(time.Now().Unix()),
+		Content: &client.MessagePhoto{
+			Caption: &client.FormattedText{Text: "photo caption"},
+			Photo: &client.Photo{
+				Minithumbnail: &client.Minithumbnail{Data: []byte("thumb")},
+				Sizes: []*client.PhotoSize{
+					{Photo: &client.File{Id: 7, Remote: &client.RemoteFile{Id: "remote-photo"}}},
+				},
+			},
+		},
+	}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, sm, common.CrawlerConfig{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, "photo caption", post.Description)
+	assert.Zero(t, tdlibClient.remoteCalls, "Dry run should not look up media")
+	assert.Zero(t, tdlibClient.downloadCalls, "Dry run should not download media")
+	assert.Zero(t, sm.storeFileCalls, "Dry run should not upload files")
+	assert.Zero(t, sm.storePostCalls, "Dry run should not store the post")
+}
+
+func TestParseMessage_SkipsPostAlreadyStored(t *testing.T) {
+	sm := &recordingStateManager{StateManagementInterface: newTestStateManager(t)}
+
+	message := &client.Message{
+		Id:      1,
+		ChatId:  -1001,
+		Date:    int32(time.Now().Unix()),
+		Content: &client.MessageText{Text: &client.FormattedText{Text: "hello"}},
+	}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+
+	post, err := ParseMessage("test-crawl", message, mlr, chat, nil, nil, 0, 0, "example", &flakyDownloadClient{}, sm, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", post.Description)
+
+	post, err = ParseMessage("test-crawl", message, mlr, chat, nil, nil, 0, 0, "example", &flakyDownloadClient{}, sm, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Empty(t, post.PostUID, "A stored post is skipped before it is parsed")
+
+	assert.Equal(t, 1, sm.storePostCalls, "Revisiting a message should store a single record")
+}
+
+func TestParseMessage_RejectsInvalidPostBeforeDownloadingMedia(t *testing.T) {
+	tdlibClient := &flakyDownloadClient{}
+	sm := &recordingStateManager{StateManagementInterface: newTestStateManager(t)}
+
+	message := &client.Message{
+		Id:     1,
+		ChatId: 0,
+		Date:   int32(time.Now().Unix()),
+		Content: &client.MessagePhoto{
+			Photo:   &client.Photo{Sizes: []*client.PhotoSize{{Photo: &client.File{Id: 1, Remote: &client.RemoteFile{Id: "remote-photo"}}}}},
+			Caption: &client.FormattedText{Text: "photo caption"},
+		},
+	}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	chat := &client.Chat{Id: 0, Title: "Example"}
+
+	_, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, sm, common.CrawlerConfig{})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, model.ErrInvalidPost, "Invalid posts should be reported to the caller")
+	assert.Zero(t, tdlibClient.remoteCalls, "Media of an invalid post should not be looked up")
+	assert.Zero(t, sm.storeFileCalls, "Media of an invalid post should not be uploaded")
+	assert.Zero(t, sm.storePostCalls)
+}
+
+func TestParseMessage_RecordsReplyTarget(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/3"}
+	reply := &client.Message{
+		Id:      3 << 20,
+		ChatId:  -1001,
+		Date:    int32(time.Now().Unix()),
+		Content: &client.MessageText{Text: &client.FormattedText{Text: "replying"}},
+		ReplyTo: &client.MessageReplyToMessage{ChatId: -1001, MessageId: 2 << 20},
+	}
+
+	post, err := ParseMessage("test-crawl", reply, mlr, chat, nil, nil, 0, 0, "example", &flakyDownloadClient{}, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2<<20), post.ReplyToMessageID)
+	assert.Equal(t, int64(0), post.ReplyToChatID, "Replies within the channel have no separate chat")
+	require.NotNil(t, post.IsReply)
+	assert.True(t, *post.IsReply)
+
+	reply.ReplyTo = &client.MessageReplyToMessage{ChatId: -1002, MessageId: 7 << 20}
+	post, err = ParseMessage("test-crawl", reply, mlr, chat, nil, nil, 0, 0, "example", &flakyDownloadClient{}, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7<<20), post.ReplyToMessageID)
+	assert.Equal(t, int64(-1002), post.ReplyToChatID)
+
+	reply.ReplyTo = nil
+	post, err = ParseMessage("test-crawl", reply, mlr, chat, nil, nil, 0, 0, "example", &flakyDownloadClient{}, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Zero(t, post.ReplyToMessageID)
+	assert.Nil(t, post.IsReply)
+}
+
+// repostClient serves the same content under a different remote ID for every
+// file, as happens when an image is re-uploaded rather than forwarded
+type repostClient struct {
+	MockTDLibClient
+	dir     string
+	content []byte
+}
+
+func (r *repostClient) GetRemoteFile(req *client.GetRemoteFileRequest) (*client.File, error) {
+	return &client.File{Id: 1, Remote: &client.RemoteFile{Id: req.RemoteFileId, UniqueId: "unique-" + req.RemoteFileId}}, nil
+}
+
+func (r *repostClient) DownloadFile(req *client.DownloadFileRequest) (*client.File, error) {
+	path := filepath.Join(r.dir, fmt.Sprintf("download-%d.jpg", time.Now().UnixNano()))
+	if err := os.WriteFile(path, r.content, 0644); err != nil {
+		return nil, err
+	}
+	return &client.File{Id: req.FileId, Local: &client.LocalFile{Path: path}}, nil
+}
+
+func TestFetchAndUploadMedia_DeduplicatesByContentHash(t *testing.T) {
+	tdlibClient := &repostClient{dir: t.TempDir(), content: []byte("same image bytes")}
+	sm := &recordingStateManager{StateManagementInterface: newTestStateManager(t)}
+
+	firstID, firstKey, err := fetchAndUploadMedia(tdlibClient, sm, "crawl", "example", "remote-a", "https://t.me/example/1", 1, 0, common.CrawlerConfig{}, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, firstKey)
+	assert.Equal(t, "unique-remote-a", firstID)
+
+	secondID, secondKey, err := fetchAndUploadMedia(tdlibClient, sm, "crawl", "example", "remote-b", "https://t.me/example/2", 2, 0, common.CrawlerConfig{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "unique-remote-b", secondID)
+	assert.Equal(t, firstKey, secondKey, "Identical content should reference the first stored copy")
+	assert.Equal(t, 1, sm.storeFileCalls, "Identical content should only be uploaded once")
+
+	processed, err := sm.HasProcessedMedia(secondID)
+	require.NoError(t, err)
+	assert.True(t, processed, "The duplicate should be marked as processed under its own ID")
+
+	entries, err := os.ReadDir(tdlibClient.dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "Downloaded files should be cleaned up")
+
+	tdlibClient.content = []byte("different image bytes")
+	_, thirdKey, err := fetchAndUploadMedia(tdlibClient, sm, "crawl", "example", "remote-c", "https://t.me/example/3", 3, 0, common.CrawlerConfig{}, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, firstKey, thirdKey)
+	assert.Equal(t, 2, sm.storeFileCalls)
+}
@@ -0,0 +1,151 @@
+package telegramhelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/rs/zerolog/log"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// sessionDirs records the session directories held by open clients in this
+// process, with the lock file held for each, so two clients never share a
+// TDLib database.
+var (
+	sessionDirsMu sync.Mutex
+	sessionDirs   = make(map[string]*os.File)
+)
+
+// errSessionLocked is returned by lockSessionFile when another process holds
+// the lock.
+var errSessionLocked = errors.New("session directory is in use by another process")
+
+// sessionDirName returns the directory name of the session seeded from
+// databaseURL. It depends only on the URL, so a re-run finds the session, and
+// the authorization stored in it, again.
+func sessionDirName(databaseURL string) string {
+	h := fnv.New32a()
+	h.Write([]byte(databaseURL))
+	return fmt.Sprintf("conn_%d", h.Sum32())
+}
+
+// claimSessionDir reserves a session directory under base for one client. The
+// first client for a database URL gets conn_<hash>; while that directory is
+// held by another client, in this process or in another process sharing the
+// storage root, conn_<hash>_1, conn_<hash>_2 and so on are tried, which are
+// just as stable across runs. A directory is held through an exclusive lock
+// on its <dir>.lock file, which the operating system releases if the process
+// dies.
+func claimSessionDir(base, databaseURL string) string {
+	sessionDirsMu.Lock()
+	defer sessionDirsMu.Unlock()
+
+	if err := os.MkdirAll(base, 0755); err != nil {
+		log.Warn().Err(err).Str("dir", base).Msg("Failed to create session base directory")
+	}
+
+	name := sessionDirName(databaseURL)
+	for i := 0; ; i++ {
+		dir := filepath.Join(base, name)
+		if i > 0 {
+			dir = filepath.Join(base, fmt.Sprintf("%s_%d", name, i))
+		}
+		if _, held := sessionDirs[dir]; held {
+			continue
+		}
+
+		lock, err := lockSessionFile(dir + ".lock")
+		if errors.Is(err, errSessionLocked) {
+			log.Info().Str("dir", dir).Msg("Session directory is in use by another process, trying the next one")
+			continue
+		}
+		if err != nil {
+			// Without a lock only clients of this process are kept apart
+			log.Warn().Err(err).Str("dir", dir).Msg("Failed to lock session directory")
+		}
+		sessionDirs[dir] = lock
+		return dir
+	}
+}
+
+// releaseSessionDir makes a directory claimed by claimSessionDir available
+// again and releases its lock.
+func releaseSessionDir(dir string) {
+	sessionDirsMu.Lock()
+	defer sessionDirsMu.Unlock()
+	if lock := sessionDirs[dir]; lock != nil {
+		lock.Close()
+	}
+	delete(sessionDirs, dir)
+}
+
+// hasExistingSession reports whether dbDir holds the TDLib database of an
+// earlier run. TDLib keeps its authorization in the binlog, so a client opened
+// on it logs in without asking for a phone code.
+func hasExistingSession(dbDir string) bool {
+	info, err := os.Stat(filepath.Join(dbDir, "td.binlog"))
+	return err == nil && info.Size() > 0
+}
+
+// prepareSession claims the session directory for cfg and reports whether it
+// already holds a session. Only a new session is seeded from the pre-seeded
+// database archive; extracting it over an existing one would discard the
+// authorization obtained since.
+func prepareSession(ctx context.Context, storagePrefix string, cfg common.CrawlerConfig, downloadClient *http.Client) (dir string, existing bool) {
+	dir = claimSessionDir(filepath.Join(storagePrefix, "state"), cfg.TDLibDatabaseURL)
+
+	if hasExistingSession(filepath.Join(dir, ".tdlib", "database")) {
+		log.Info().Str("dir", dir).Msg("Reusing existing TDLib session")
+		return dir, true
+	}
+
+	if cfg.TDLibDatabaseURL != "" {
+		// Ensure the directory exists
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Warn().Err(err).Msgf("Failed to create session directory %s for database", dir)
+		}
+
+		// Look up the expected digest first so a tampered archive is never extracted
+		var expectedSHA256 string
+		var checksumErr error
+		if cfg.VerifyTDLibDatabase {
+			expectedSHA256, checksumErr = fetchTarballChecksum(ctx, downloadClient, cfg.TDLibDatabaseURL)
+		}
+
+		// Download and extract to the session directory
+		if checksumErr != nil {
+			log.Warn().Err(checksumErr).Msg("Failed to fetch checksum of pre-seeded TDLib database, proceeding with fresh database")
+		} else if err := downloadAndExtractTarball(ctx, downloadClient, cfg.TDLibDatabaseURL, dir, expectedSHA256); err != nil {
+			log.Warn().Err(err).Msg("Failed to download and extract pre-seeded TDLib database, proceeding with fresh database")
+			// Continue with a fresh database even if download fails
+		} else {
+			log.Info().Msgf("Successfully downloaded and extracted pre-seeded TDLib database to %s", dir)
+		}
+	}
+
+	return dir, false
+}
+
+// sessionClient is a TDLib client that gives up its session directory when
+// closed, so a replacement client can open the same session.
+type sessionClient struct {
+	*client.Client
+	dir     string
+	onClose func() // Called once the client is closed; may be nil
+}
+
+// Close closes the client and releases its session directory.
+func (c *sessionClient) Close() (*client.Ok, error) {
+	defer releaseSessionDir(c.dir)
+	if c.onClose != nil {
+		defer c.onClose()
+	}
+	return c.Client.Close()
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package telegramhelper
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockSessionFile opens the lock file at path and takes an exclusive,
+// non-blocking flock on it. It returns errSessionLocked if the lock is held
+// through another open file, whether by this or another process. Closing the
+// returned file releases the lock.
+func lockSessionFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errSessionLocked
+		}
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package telegramhelper
+
+import "os"
+
+// lockSessionFile does not lock on platforms without flock, so only the
+// clients of one process are kept from sharing a session directory.
+func lockSessionFile(path string) (*os.File, error) {
+	return nil, nil
+}
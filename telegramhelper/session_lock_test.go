@@ -0,0 +1,34 @@
+//go:build unix
+
+package telegramhelper
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimSessionDir_SkipsDirectoryLockedByAnotherProcess(t *testing.T) {
+	base := t.TempDir()
+	url := "https://example.com/db.tar.gz"
+	stable := filepath.Join(base, sessionDirName(url))
+
+	// A lock taken through another open file stands in for another process
+	other, err := lockSessionFile(stable + ".lock")
+	require.NoError(t, err)
+
+	dir := claimSessionDir(base, url)
+	assert.Equal(t, stable+"_1", dir, "A session held by another process must not be opened")
+	releaseSessionDir(dir)
+
+	// Once the other process is gone its session is claimed again
+	require.NoError(t, other.Close())
+	dir = claimSessionDir(base, url)
+	assert.Equal(t, stable, dir)
+
+	_, err = lockSessionFile(stable + ".lock")
+	assert.ErrorIs(t, err, errSessionLocked, "The claimed session should stay locked")
+	releaseSessionDir(dir)
+}
@@ -0,0 +1,138 @@
+package telegramhelper
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/rs/zerolog/log"
+)
+
+// Session is a TDLib client owned by a SessionPool. Each session keeps its
+// database and files under its own directory so concurrent sessions never
+// share TDLib state.
+type Session struct {
+	ID     int                 // Index of the session within the pool
+	Dir    string              // Storage prefix the session's TDLib data lives under
+	Client crawler.TDLibClient // Initialized client, used by one worker at a time
+	APIID  int                 // Telegram app the session logged in with (0 = credentials file or environment)
+}
+
+// SessionPool hands out a fixed set of TDLib sessions to concurrent workers.
+// Unlike ConnectionPool, all sessions are initialized up front and Acquire
+// blocks until a session is free, so the pool size bounds crawl concurrency.
+type SessionPool struct {
+	sessions  []*Session
+	available chan *Session
+	closeOnce sync.Once
+}
+
+// NewSessionPool initializes size TDLib clients, each under
+// storagePrefix/sessions/<index>. Pre-seeded database URLs and API
+// credentials from cfg are assigned to sessions round-robin; when a session's
+// app is rejected or rate limited during initialization, the next credential
+// is tried. Sessions that fail to initialize are skipped; an error is returned
+// only if none could be created.
+func NewSessionPool(service TelegramService, size int, storagePrefix string, cfg common.CrawlerConfig) (*SessionPool, error) {
+	if size < 1 {
+		size = 1
+	}
+
+	pool := &SessionPool{}
+	var lastErr error
+	for i := 0; i < size; i++ {
+		sessionCfg := cfg
+		if len(cfg.TDLibDatabaseURLs) > 0 {
+			sessionCfg.TDLibDatabaseURL = cfg.TDLibDatabaseURLs[i%len(cfg.TDLibDatabaseURLs)]
+		}
+
+		dir := filepath.Join(storagePrefix, "sessions", fmt.Sprintf("%d", i))
+		var tdlibClient crawler.TDLibClient
+		var err error
+		for attempt := 0; ; attempt++ {
+			if len(cfg.APICredentials) > 0 {
+				credential := cfg.APICredentials[(i+attempt)%len(cfg.APICredentials)]
+				sessionCfg.APICredential = &credential
+			}
+			tdlibClient, err = service.InitializeClientWithConfig(dir, sessionCfg)
+			if err == nil || attempt+1 >= len(cfg.APICredentials) || !isCredentialError(err) {
+				break
+			}
+			log.Warn().Err(err).Int("session", i).Int("api_id", sessionCfg.APICredential.APIID).Msg("TDLib session rejected by its app, rotating to the next API credential")
+		}
+		if err != nil {
+			log.Error().Err(err).Int("session", i).Str("dir", dir).Msg("Failed to initialize TDLib session")
+			lastErr = err
+			continue
+		}
+
+		session := &Session{ID: i, Dir: dir, Client: tdlibClient}
+		if sessionCfg.APICredential != nil {
+			session.APIID = sessionCfg.APICredential.APIID
+		}
+		pool.sessions = append(pool.sessions, session)
+		log.Info().Int("session", i).Str("dir", dir).Int("api_id", session.APIID).Msg("Initialized TDLib session")
+	}
+
+	if len(pool.sessions) == 0 {
+		return nil, fmt.Errorf("failed to initialize any of %d TDLib sessions: %w", size, lastErr)
+	}
+
+	pool.available = make(chan *Session, len(pool.sessions))
+	for _, session := range pool.sessions {
+		pool.available <- session
+	}
+
+	log.Info().Int("requested", size).Int("initialized", len(pool.sessions)).Msg("TDLib session pool ready")
+	return pool, nil
+}
+
+// credentialErrorPattern matches initialization errors caused by the Telegram
+// app rather than the session, e.g. a revoked or publicly leaked API ID.
+var credentialErrorPattern = regexp.MustCompile(`API_ID_INVALID|API_ID_PUBLISHED_FLOOD|APP_OUTDATED`)
+
+// isCredentialError reports whether err suggests another app may succeed
+// where this one failed: the app was rejected or is being rate limited.
+func isCredentialError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, rateLimited := floodWaitDuration(err); rateLimited {
+		return true
+	}
+	return credentialErrorPattern.MatchString(err.Error())
+}
+
+// Size returns the number of sessions in the pool.
+func (p *SessionPool) Size() int {
+	return len(p.sessions)
+}
+
+// Acquire waits for a free session. The session must be returned with Release.
+func (p *SessionPool) Acquire(ctx context.Context) (*Session, error) {
+	select {
+	case session := <-p.available:
+		return session, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Release returns a session acquired with Acquire to the pool.
+func (p *SessionPool) Release(session *Session) {
+	p.available <- session
+}
+
+// Close closes the clients of all sessions. The pool must not be used afterwards.
+func (p *SessionPool) Close() {
+	p.closeOnce.Do(func() {
+		for _, session := range p.sessions {
+			log.Debug().Int("session", session.ID).Msg("Closing TDLib session")
+			closeClientSafe(session.Client)
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package telegramhelper
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionPool_DistinctDirectoriesAndDatabaseURLs(t *testing.T) {
+	service := &MockPoolTelegramService{}
+	cfg := common.CrawlerConfig{TDLibDatabaseURLs: []string{"db-a", "db-b"}}
+
+	pool, err := NewSessionPool(service, 3, "/storage", cfg)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	assert.Equal(t, 3, pool.Size())
+	assert.Equal(t, []string{
+		filepath.Join("/storage", "sessions", "0"),
+		filepath.Join("/storage", "sessions", "1"),
+		filepath.Join("/storage", "sessions", "2"),
+	}, service.StoragePrefixes)
+	assert.Equal(t, []string{"db-a", "db-b", "db-a"}, service.DatabaseURLs)
+}
+
+func TestSessionPool_DistinctAPICredentials(t *testing.T) {
+	service := &MockPoolTelegramService{}
+	cfg := common.CrawlerConfig{APICredentials: []common.APICredential{
+		{APIID: 111, APIHash: "hash-a"},
+		{APIID: 222, APIHash: "hash-b"},
+	}}
+
+	pool, err := NewSessionPool(service, 2, "/storage", cfg)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	assert.Equal(t, []int{111, 222}, service.APIIDs)
+	assert.Equal(t, 111, pool.sessions[0].APIID)
+	assert.Equal(t, 222, pool.sessions[1].APIID)
+}
+
+func TestSessionPool_RotatesRejectedAPICredentials(t *testing.T) {
+	service := &MockPoolTelegramService{RejectedAPIIDs: map[int]error{
+		111: errors.New("400 API_ID_PUBLISHED_FLOOD"),
+	}}
+	cfg := common.CrawlerConfig{APICredentials: []common.APICredential{
+		{APIID: 111, APIHash: "hash-a"},
+		{APIID: 222, APIHash: "hash-b"},
+	}}
+
+	pool, err := NewSessionPool(service, 2, "/storage", cfg)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	assert.Equal(t, []int{111, 222, 222}, service.APIIDs, "The first session should move on to the second app")
+	assert.Equal(t, 222, pool.sessions[0].APIID)
+	assert.Equal(t, 222, pool.sessions[1].APIID)
+
+	// Errors unrelated to the app are not retried with another one
+	service = &MockPoolTelegramService{RejectedAPIIDs: map[int]error{111: errors.New("database is locked")}}
+	_, err = NewSessionPool(service, 1, "/storage", cfg)
+	assert.ErrorContains(t, err, "database is locked")
+	assert.Equal(t, []int{111}, service.APIIDs)
+}
+
+func TestSessionPool_BoundsConcurrentWorkers(t *testing.T) {
+	service := &MockPoolTelegramService{}
+	pool, err := NewSessionPool(service, 2, t.TempDir(), common.CrawlerConfig{})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	inUse := make(map[int]bool)
+	maxInUse := 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			session, err := pool.Acquire(context.Background())
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			mu.Lock()
+			assert.False(t, inUse[session.ID], "session %d handed out twice", session.ID)
+			inUse[session.ID] = true
+			if len(inUse) > maxInUse {
+				maxInUse = len(inUse)
+			}
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			delete(inUse, session.ID)
+			mu.Unlock()
+			pool.Release(session)
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, maxInUse, 2)
+
+	// Acquire gives up when the context ends while all sessions are busy
+	first, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	second, err := pool.Acquire(context.Background())
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = pool.Acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	pool.Release(first)
+	pool.Release(second)
+
+	pool.Close()
+	for _, c := range service.CreatedClients {
+		assert.True(t, c.Closed)
+	}
+}
+
+func TestSessionPool_AllSessionsFail(t *testing.T) {
+	service := &MockPoolTelegramService{CreateError: errors.New("auth failed")}
+
+	_, err := NewSessionPool(service, 2, t.TempDir(), common.CrawlerConfig{})
+	assert.ErrorContains(t, err, "auth failed")
+}
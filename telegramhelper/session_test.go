@@ -0,0 +1,76 @@
+package telegramhelper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimSessionDir_StableAcrossRuns(t *testing.T) {
+	base := t.TempDir()
+	url := "https://example.com/db.tar.gz"
+
+	first := claimSessionDir(base, url)
+	second := claimSessionDir(base, url)
+	assert.Equal(t, filepath.Join(base, sessionDirName(url)), first)
+	assert.Equal(t, first+"_1", second, "Concurrent clients must not share a session")
+
+	releaseSessionDir(first)
+	releaseSessionDir(second)
+
+	// The next run opens the same directories again
+	assert.Equal(t, first, claimSessionDir(base, url))
+	assert.Equal(t, second, claimSessionDir(base, url))
+	releaseSessionDir(first)
+	releaseSessionDir(second)
+}
+
+func TestPrepareSession_ReusesExistingSession(t *testing.T) {
+	downloads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	storagePrefix := t.TempDir()
+	cfg := common.CrawlerConfig{TDLibDatabaseURL: server.URL + "/db.tar.gz"}
+
+	// First run starts a new session, seeded from the archive
+	dir, existing := prepareSession(context.Background(), storagePrefix, cfg, nil)
+	assert.False(t, existing)
+	assert.Equal(t, 1, downloads)
+
+	// TDLib stores the authorization in its binlog
+	dbDir := filepath.Join(dir, ".tdlib", "database")
+	require.NoError(t, os.MkdirAll(dbDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dbDir, "td.binlog"), []byte("session"), 0644))
+	releaseSessionDir(dir)
+
+	// Second run finds it and leaves it untouched
+	again, existing := prepareSession(context.Background(), storagePrefix, cfg, nil)
+	defer releaseSessionDir(again)
+	assert.Equal(t, dir, again)
+	assert.True(t, existing)
+	assert.Equal(t, 1, downloads, "An existing session must not be overwritten by the pre-seeded archive")
+}
+
+func TestNewAuthorizer_ExistingSessionSkipsPhoneCode(t *testing.T) {
+	t.Setenv("TG_PHONE_NUMBER", "")
+	t.Setenv("TG_PHONE_CODE", "")
+
+	authorizer, _ := newAuthorizer(common.CrawlerConfig{}, "+15550100", "12345", true)
+	authorizer.Close()
+	assert.Empty(t, os.Getenv("TG_PHONE_CODE"), "A stored session needs no phone code")
+
+	authorizer, _ = newAuthorizer(common.CrawlerConfig{}, "+15550100", "12345", false)
+	authorizer.Close()
+	assert.Equal(t, "12345", os.Getenv("TG_PHONE_CODE"))
+}
@@ -1,120 +1,146 @@
 package telegramhelper
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/researchaccelerator-hub/telegram-scraper/common"
 	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/researchaccelerator-hub/telegram-scraper/enrich"
+	"github.com/researchaccelerator-hub/telegram-scraper/metrics"
 	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
 	"github.com/researchaccelerator-hub/telegram-scraper/state"
 	"github.com/rs/zerolog/log"
 	"github.com/zelenin/go-tdlib/client"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime/debug"
 	"strings"
 	"time"
 )
 
-//// removeMultimedia removes all files and subdirectories in the specified directory.
-//// If the directory does not exist, it does nothing.
-////
-//// Parameters:
-////   - filedir: The path to the directory whose contents are to be removed.
-////
-//// Returns:
-////   - An error if there is a failure during removal; otherwise, nil.
-//func removeMultimedia(filedir string) error {
-//	log.Debug().Str("directory", filedir).Msg("Attempting to remove multimedia directory contents")
+// removeMultimedia removes all files and subdirectories in the specified directory,
+// leaving the directory itself in place. If the directory does not exist, it does nothing.
+// Entries that disappear while it runs, e.g. because a FileCleaner removed them
+// concurrently, are not treated as errors.
 //
-//	// Check if the directory exists
-//	info, err := os.Stat(filedir)
-//	if os.IsNotExist(err) {
-//		// Directory does not exist, nothing to do
-//		log.Debug().Str("directory", filedir).Msg("Directory does not exist, nothing to remove")
-//		return nil
-//	}
-//	if err != nil {
-//		log.Error().Err(err).Str("directory", filedir).Msg("Failed to check directory status")
-//		return err
-//	}
-//
-//	// Ensure it is a directory
-//	if !info.IsDir() {
-//		log.Error().Str("path", filedir).Msg("Path is not a directory")
-//		return fmt.Errorf("path %s is not a directory", filedir)
-//	}
-//
-//	// Get file count before removal for logging
-//	var fileCount int
-//	filepath.Walk(filedir, func(path string, info os.FileInfo, err error) error {
-//		if err == nil && path != filedir {
-//			fileCount++
-//		}
-//		return nil
-//	})
-//
-//	log.Debug().
-//		Str("directory", filedir).
-//		Int("file_count", fileCount).
-//		Msg("Removing files and subdirectories")
-//
-//	// Remove contents of the directory
-//	err = filepath.Walk(filedir, func(path string, info os.FileInfo, err error) error {
-//		if err != nil {
-//			log.Warn().Err(err).Str("path", path).Msg("Error accessing path during cleanup")
-//			return err
-//		}
-//
-//		// Skip the root directory itself
-//		if path == filedir {
-//			return nil
-//		}
-//
-//		// Remove files and subdirectories
-//		if err := os.RemoveAll(path); err != nil {
-//			log.Error().Err(err).Str("path", path).Msg("Failed to remove path")
-//			return err
-//		}
-//
-//		log.Debug().Str("path", path).Msg("Removed path successfully")
-//		return nil
-//	})
-//
-//	if err != nil {
-//		log.Error().Err(err).Str("directory", filedir).Msg("Error removing directory contents")
-//		return err
-//	}
+// Parameters:
+//   - filedir: The path to the directory whose contents are to be removed.
 //
-//	log.Debug().
-//		Str("directory", filedir).
-//		Int("files_removed", fileCount).
-//		Msg("Directory contents removed successfully")
-//	return nil
-//}
-
-// processMessageSafely extracts and returns the thumbnail path, video path, and description
-// from a given Telegram video message. It ensures the message structure is valid and not corrupt.
+// Returns:
+//   - An error if filedir is not a directory or any entry could not be removed; otherwise, nil.
+func removeMultimedia(filedir string) error {
+	log.Debug().Str("directory", filedir).Msg("Attempting to remove multimedia directory contents")
+
+	// Check if the directory exists
+	info, err := os.Stat(filedir)
+	if os.IsNotExist(err) {
+		// Directory does not exist, nothing to do
+		log.Debug().Str("directory", filedir).Msg("Directory does not exist, nothing to remove")
+		return nil
+	}
+	if err != nil {
+		log.Error().Err(err).Str("directory", filedir).Msg("Failed to check directory status")
+		return fmt.Errorf("failed to stat %s: %w", filedir, err)
+	}
+
+	// Ensure it is a directory
+	if !info.IsDir() {
+		log.Error().Str("path", filedir).Msg("Path is not a directory")
+		return fmt.Errorf("cannot remove multimedia from %s: not a directory", filedir)
+	}
+
+	entries, err := os.ReadDir(filedir)
+	if err != nil {
+		log.Error().Err(err).Str("directory", filedir).Msg("Failed to read directory")
+		return fmt.Errorf("failed to read directory %s: %w", filedir, err)
+	}
+
+	log.Debug().
+		Str("directory", filedir).
+		Int("entry_count", len(entries)).
+		Msg("Removing files and subdirectories")
+
+	// Remove each entry; RemoveAll returns nil for entries that are already gone
+	var errs []error
+	for _, entry := range entries {
+		path := filepath.Join(filedir, entry.Name())
+		if err := os.RemoveAll(path); err != nil {
+			log.Error().Err(err).Str("path", path).Msg("Failed to remove path")
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to remove contents of %s: %w", filedir, errors.Join(errs...))
+	}
+
+	log.Debug().
+		Str("directory", filedir).
+		Int("entries_removed", len(entries)).
+		Msg("Directory contents removed successfully")
+	return nil
+}
+
+// videoMessage holds what processMessageSafely extracts from a video message.
+type videoMessage struct {
+	ThumbnailPath   string           // Remote ID of the video's thumbnail
+	ThumbnailFileID int32            // TDLib file ID of the thumbnail
+	VideoPath       string           // Remote ID of the video
+	VideoFileID     int32            // TDLib file ID of the video
+	Description     string           // Text caption of the video
+	Video           *model.VideoData // Duration, dimensions, file name and size of the video
+}
+
+// processMessageSafely extracts the thumbnail, video, caption and video
+// metadata from a given Telegram video message. It ensures the message
+// structure is valid and not corrupt.
 //
 // Parameters:
 // - mymsg: A pointer to a client.MessageVideo object containing the video message details.
-// - tdlibClient: A pointer to a client.Client used for potential future operations.
 //
 // Returns:
-// - thumbnailPath: The remote ID of the video's thumbnail.
-// - videoPath: The remote ID of the video.
-// - description: The text caption of the video.
+// - The parts of the message that could be read. The video metadata is filled
+//   in whenever the message has a video, even if its thumbnail is missing.
 // - err: An error if the message structure is invalid or corrupt.
-func processMessageSafely(mymsg *client.MessageVideo) (thumbnailPath, videoPath, description string, videofileid int32, thumbnailfileid int32, err error) {
-	if mymsg == nil || mymsg.Video == nil || mymsg.Video.Thumbnail == nil {
-		return "", "", "", 0, 0, fmt.Errorf("invalid or corrupt message structure")
+func processMessageSafely(mymsg *client.MessageVideo) (videoMessage, error) {
+	var result videoMessage
+	if mymsg == nil || mymsg.Video == nil {
+		return result, fmt.Errorf("invalid or corrupt message structure")
 	}
 
-	thumbnailPath = mymsg.Video.Thumbnail.File.Remote.Id
-	thumbnailfileid = mymsg.Video.Thumbnail.File.Id
-	videoPath = mymsg.Video.Video.Remote.Id
-	videofileid = mymsg.Video.Video.Id
-	description = mymsg.Caption.Text
-	return thumbnailPath, videoPath, description, videofileid, thumbnailfileid, nil
+	video := mymsg.Video
+	result.Video = &model.VideoData{
+		DurationSeconds: int(video.Duration),
+		Width:           int(video.Width),
+		Height:          int(video.Height),
+		FileName:        video.FileName,
+		MimeType:        video.MimeType,
+	}
+	if video.Video != nil {
+		result.VideoFileID = video.Video.Id
+		result.Video.FileSize = video.Video.Size
+		if result.Video.FileSize == 0 {
+			result.Video.FileSize = video.Video.ExpectedSize
+		}
+		if video.Video.Remote != nil {
+			result.VideoPath = video.Video.Remote.Id
+		}
+	}
+	if mymsg.Caption != nil {
+		result.Description = mymsg.Caption.Text
+	}
+
+	if video.Thumbnail == nil || video.Thumbnail.File == nil || video.Thumbnail.File.Remote == nil {
+		return result, fmt.Errorf("invalid or corrupt message structure")
+	}
+	result.ThumbnailPath = video.Thumbnail.File.Remote.Id
+	result.ThumbnailFileID = video.Thumbnail.File.Id
+	return result, nil
 }
 
 // fetchAndUploadMedia fetches a media file from Telegram using the provided TDLibClient
@@ -128,10 +154,18 @@ func processMessageSafely(mymsg *client.MessageVideo) (thumbnailPath, videoPath,
 //   - channelName: Name of the channel from which the file originates
 //   - fileID: Telegram's identifier for the file to download
 //   - postLink: Link to the post containing the media
+//   - cfid: TDLib's local file identifier, used to delete the cached copy
+//   - albumID: MediaAlbumId of the message, or 0 if it is not part of an album
 //   - cfg: CrawlerConfig containing runtime configuration options
+//   - onDownload: Called with the path of the downloaded file before it is
+//     stored and deleted, e.g. to run OCR on it (may be nil)
 //
 // Returns:
 //   - The unique remote ID of the file for future reference if successful
+//   - The storage key of the file's content. Media already stored earlier in the
+//     crawl (e.g. forwarded duplicates) are not downloaded again, and files whose
+//     content matches an already stored file are not uploaded again; the key of
+//     the first stored copy is returned instead.
 //   - An error if any step in the process fails
 //
 // The function follows these steps:
@@ -139,22 +173,25 @@ func processMessageSafely(mymsg *client.MessageVideo) (thumbnailPath, videoPath,
 // 2. Check if media downloads should be skipped based on configuration
 // 3. Download the file from Telegram (if not skipped)
 // 4. Verify file existence and size limits
-// 5. Store the file via the state manager
-// 6. Clean up the local file
-// 7. Mark the media as processed to prevent redundant downloads
-func fetchAndUploadMedia(tdlibClient crawler.TDLibClient, sm state.StateManagementInterface, crawlid, channelName, fileID, postLink string, cfid int32, cfg common.CrawlerConfig) (string, error) {
+// 5. Hash the file's content and reference an existing copy with the same hash
+// 6. Store the file via the state manager
+// 7. Clean up the local file
+// 8. Mark the media and its content hash as processed to prevent redundant
+//    downloads and uploads
+func fetchAndUploadMedia(tdlibClient crawler.TDLibClient, sm state.StateManagementInterface, crawlid, channelName, fileID, postLink string, cfid int32, albumID int64, cfg common.CrawlerConfig, onDownload func(path string)) (string, string, error) {
 	if fileID == "" {
 		log.Debug().Msg("Empty file ID provided, nothing to fetch")
-		return "", nil
+		return "", "", nil
 	}
 	
 	// Check if media downloads should be skipped
-	if cfg.SkipMediaDownload {
+	if cfg.SkipMediaDownload || cfg.DryRun {
 		log.Debug().
 			Str("file_id", fileID).
 			Str("channel", channelName).
+			Bool("dry_run", cfg.DryRun).
 			Msg("Skipping media download as per configuration")
-		return "", nil
+		return "", "", nil
 	}
 
 	log.Debug().
@@ -164,19 +201,32 @@ func fetchAndUploadMedia(tdlibClient crawler.TDLibClient, sm state.StateManageme
 		Str("post_link", postLink).
 		Msg("Fetching and uploading media file")
 
-	path, remoteid, err := fetchfilefromtelegram(tdlibClient, sm, fileID)
+	path, remoteid, err := fetchfilefromtelegram(tdlibClient, sm, fileID, cfg)
 	if err != nil {
 		log.Error().
 			Err(err).
 			Str("file_id", fileID).
 			Str("channel", channelName).
 			Msg("Failed to fetch file from Telegram")
-		return "", err
+		return "", "", err
 	}
 
 	if path == "" {
-		log.Debug().Str("file_id", fileID).Msg("Empty path returned from fetch operation, file likely already processed")
-		return "", nil // Not a real error if we already processed it
+		if remoteid == "" {
+			log.Debug().Str("file_id", fileID).Msg("Empty path returned from fetch operation, file likely already processed")
+			return "", "", nil // Not a real error if we already processed it
+		}
+
+		// Already stored earlier in the crawl; reference the canonical copy
+		storageKey, err := sm.GetMediaStorageKey(remoteid)
+		if err != nil {
+			log.Warn().Err(err).Str("remote_id", remoteid).Msg("Failed to look up storage key of processed media")
+		}
+		log.Debug().
+			Str("remote_id", remoteid).
+			Str("storage_key", storageKey).
+			Msg("Media already stored, referencing existing copy")
+		return remoteid, storageKey, nil
 	}
 
 	fileInfo, err := os.Stat(path)
@@ -193,7 +243,7 @@ func fetchAndUploadMedia(tdlibClient crawler.TDLibClient, sm state.StateManageme
 				Str("file_id", fileID).
 				Msg("Error checking downloaded file")
 		}
-		return "", err
+		return "", "", err
 	}
 
 	// Get file size in bytes
@@ -226,13 +276,46 @@ func fetchAndUploadMedia(tdlibClient crawler.TDLibClient, sm state.StateManageme
 		deleteFileReq := client.DeleteFileRequest{FileId: cfid}
 		_, err := tdlibClient.DeleteFile(&deleteFileReq)
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
-		return "", fmt.Errorf("file size is too large (%.2f MB)", sizeInMB)
+		return "", "", fmt.Errorf("file size is too large (%.2f MB)", sizeInMB)
+	}
+
+	if onDownload != nil {
+		onDownload(path)
+	}
+
+	// The same content is often reposted under a different remote ID, so look
+	// it up by hash before uploading another copy
+	hashID, err := contentHashID(path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to hash downloaded file, uploading without deduplication")
+	} else if existingKey, err := sm.GetMediaStorageKey(hashID); err != nil {
+		log.Warn().Err(err).Str("content_hash", hashID).Msg("Failed to look up media by content hash")
+	} else if existingKey != "" {
+		if e := os.Remove(path); e != nil {
+			log.Warn().Err(e).Str("path", path).Msg("Failed to remove duplicate file")
+		}
+		if _, e := tdlibClient.DeleteFile(&client.DeleteFileRequest{FileId: cfid}); e != nil {
+			log.Error().Err(e).Msg("Failed to delete file from Telegram")
+		}
+		if err := sm.MarkMediaAsStored(remoteid, existingKey); err != nil {
+			log.Error().
+				Err(err).
+				Str("remote_id", remoteid).
+				Msg("Failed to mark media as processed")
+			return "", "", err
+		}
+		log.Debug().
+			Str("remote_id", remoteid).
+			Str("content_hash", hashID).
+			Str("storage_key", existingKey).
+			Msg("Media content already stored, referencing existing copy")
+		return remoteid, existingKey, nil
 	}
 
 	// Store the file
-	storageLocation, filep, err := sm.StoreFile(channelName, path, remoteid)
+	storageLocation, filep, err := sm.StoreFile(channelName, path, mediaStorageKey(albumID, remoteid))
 	if err != nil {
 		log.Error().
 			Err(err).
@@ -240,13 +323,18 @@ func fetchAndUploadMedia(tdlibClient crawler.TDLibClient, sm state.StateManageme
 			Str("channel", channelName).
 			Str("remote_id", remoteid).
 			Msg("Failed to store file")
-	} else {
-		log.Debug().
-			Str("storage_location", storageLocation).
-			Str("channel", channelName).
-			Float64("size_mb", sizeInMB).
-			Msg("File stored successfully")
+
+		// Leave the media unmarked so a later occurrence can retry the upload
+		if e := os.Remove(path); e != nil {
+			log.Warn().Err(e).Str("path", path).Msg("Failed to remove file after failed upload")
+		}
+		return "", "", fmt.Errorf("failed to store media %s: %w", remoteid, err)
 	}
+	log.Debug().
+		Str("storage_location", storageLocation).
+		Str("channel", channelName).
+		Float64("size_mb", sizeInMB).
+		Msg("File stored successfully")
 
 	// Delete original file after successful upload
 	err = os.Remove(filep)
@@ -259,13 +347,22 @@ func fetchAndUploadMedia(tdlibClient crawler.TDLibClient, sm state.StateManageme
 		log.Error().Err(err).Msg("Failed to delete file from Telegram")
 	}
 	log.Debug().Msgf("Response from TD for file deletion: %v", ok)
-	// Mark as processed to avoid future downloads
-	if err := sm.MarkMediaAsProcessed(remoteid); err != nil {
+	// Mark as processed to avoid future downloads, recording where the content
+	// lives so duplicates elsewhere in the crawl can reference it
+	if err := sm.MarkMediaAsStored(remoteid, storageLocation); err != nil {
 		log.Error().
 			Err(err).
 			Str("remote_id", remoteid).
 			Msg("Failed to mark media as processed")
-		return "", err
+		return "", "", err
+	}
+	if hashID != "" {
+		if err := sm.MarkMediaAsStored(hashID, storageLocation); err != nil {
+			log.Warn().
+				Err(err).
+				Str("content_hash", hashID).
+				Msg("Failed to record content hash of stored media")
+		}
 	}
 
 	log.Debug().
@@ -273,7 +370,312 @@ func fetchAndUploadMedia(tdlibClient crawler.TDLibClient, sm state.StateManageme
 		Str("channel", channelName).
 		Msg("Media processing complete")
 
-	return remoteid, nil
+	return remoteid, storageLocation, nil
+}
+
+// fetchAndTranscribe downloads a media file only to transcribe it. Unlike
+// fetchAndUploadMedia it does not store the file: the local and TDLib copies
+// are deleted once the transcript is made. It returns no transcript when
+// downloads are skipped, the file was already stored, or transcription fails.
+func fetchAndTranscribe(tdlibClient crawler.TDLibClient, sm state.StateManagementInterface, speech enrich.Transcriber, fileID string, cfid int32, cfg common.CrawlerConfig) (*enrich.Transcript, error) {
+	if fileID == "" || cfg.SkipMediaDownload || cfg.DryRun {
+		return nil, nil
+	}
+
+	path, _, err := fetchfilefromtelegram(tdlibClient, sm, fileID, cfg)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	defer func() {
+		if e := os.Remove(path); e != nil {
+			log.Warn().Err(e).Str("path", path).Msg("Failed to remove transcribed file")
+		}
+		if _, e := tdlibClient.DeleteFile(&client.DeleteFileRequest{FileId: cfid}); e != nil {
+			log.Error().Err(e).Msg("Failed to delete file from Telegram")
+		}
+	}()
+
+	if transcript, ok := transcribeMedia(speech, path); ok {
+		return &transcript, nil
+	}
+	return nil, nil
+}
+
+// mediaStorageKey returns the file name used when storing a media file. Files that
+// belong to a media album are placed under a shared "album_<id>/" prefix so that
+// the images of a multi-media post can be reassembled downstream.
+func mediaStorageKey(albumID int64, remoteID string) string {
+	if albumID == 0 {
+		return remoteID
+	}
+	return fmt.Sprintf("album_%d/%s", albumID, remoteID)
+}
+
+// contentHashID returns the media ID under which a file's content is recorded in
+// the media cache, "sha256:" followed by the hex SHA-256 of the file.
+func contentHashID(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// parsePaidMedia extracts the price and lock state of a paid media message.
+// Accessible items (photos and videos that have been unlocked) are downloaded
+// through fetchMedia, while locked items only expose an inline minithumbnail
+// which is handed to storePreview. storePreview returns the stored key, or an
+// empty string if nothing was stored.
+func parsePaidMedia(content *client.MessagePaidMedia, fetchMedia func(fileID string, localFileID int32), storePreview func(index int, thumb *client.Minithumbnail) string) *model.PaidMediaData {
+	data := &model.PaidMediaData{
+		StarCount:   content.StarCount,
+		ItemCount:   len(content.Media),
+		PreviewKeys: make([]string, 0),
+	}
+
+	for i, media := range content.Media {
+		switch item := media.(type) {
+		case *client.PaidMediaPreview:
+			data.LockedCount++
+			if item != nil && item.Minithumbnail != nil && len(item.Minithumbnail.Data) > 0 {
+				if key := storePreview(i, item.Minithumbnail); key != "" {
+					data.PreviewKeys = append(data.PreviewKeys, key)
+				}
+			}
+
+		case *client.PaidMediaPhoto:
+			if item != nil && item.Photo != nil && len(item.Photo.Sizes) > 0 &&
+				item.Photo.Sizes[0].Photo != nil && item.Photo.Sizes[0].Photo.Remote != nil {
+				fetchMedia(item.Photo.Sizes[0].Photo.Remote.Id, item.Photo.Sizes[0].Photo.Id)
+			}
+
+		case *client.PaidMediaVideo:
+			if item != nil && item.Video != nil && item.Video.Thumbnail != nil &&
+				item.Video.Thumbnail.File != nil && item.Video.Thumbnail.File.Remote != nil {
+				fetchMedia(item.Video.Thumbnail.File.Remote.Id, item.Video.Thumbnail.File.Id)
+			}
+
+		default:
+			// Unsupported paid media cannot be viewed without buying it
+			data.LockedCount++
+		}
+	}
+
+	data.IsPaywalled = data.LockedCount > 0
+	return data
+}
+
+// mediaErrorStrings converts media errors into their messages for storage on a post.
+// It returns nil when there were no errors so complete posts omit the field.
+func mediaErrorStrings(errs []error) []string {
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return msgs
+}
+
+// defaultPlatformName is recorded on posts when CrawlerConfig.PlatformName is unset.
+const defaultPlatformName = "Telegram"
+
+// platformName returns the platform name to record on posts.
+func platformName(cfg common.CrawlerConfig) string {
+	if cfg.PlatformName != "" {
+		return cfg.PlatformName
+	}
+	return defaultPlatformName
+}
+
+// withinPostDateWindow reports whether a post published at publishedAt falls inside
+// the configured MinPostDate..MaxPostDate window. Unset bounds are not enforced.
+func withinPostDateWindow(publishedAt time.Time, cfg common.CrawlerConfig) bool {
+	if !cfg.MinPostDate.IsZero() && publishedAt.Before(cfg.MinPostDate) {
+		return false
+	}
+	if !cfg.MaxPostDate.IsZero() && publishedAt.After(cfg.MaxPostDate) {
+		return false
+	}
+	return true
+}
+
+// parsePoll extracts the options, vote counts and settings of a regular poll or quiz.
+func parsePoll(poll *client.Poll) *model.PollData {
+	data := &model.PollData{
+		TotalVoterCount: int(poll.TotalVoterCount),
+		IsAnonymous:     poll.IsAnonymous,
+		IsClosed:        poll.IsClosed,
+		Options:         make([]model.PollOption, 0, len(poll.Options)),
+	}
+	if poll.Question != nil {
+		data.Question = poll.Question.Text
+	}
+
+	for _, option := range poll.Options {
+		if option == nil {
+			continue
+		}
+		text := ""
+		if option.Text != nil {
+			text = option.Text.Text
+		}
+		data.Options = append(data.Options, model.PollOption{
+			Text:           text,
+			VoterCount:     int(option.VoterCount),
+			VotePercentage: int(option.VotePercentage),
+		})
+	}
+
+	switch pollType := poll.Type.(type) {
+	case *client.PollTypeQuiz:
+		data.IsQuiz = true
+		// TDLib reports -1 until the crawling account has answered or the quiz is closed
+		if pollType.CorrectOptionId >= 0 {
+			index := int(pollType.CorrectOptionId)
+			data.CorrectOptionIndex = &index
+		}
+	case *client.PollTypeRegular:
+		data.AllowMultipleAnswers = pollType.AllowMultipleAnswers
+	}
+
+	return data
+}
+
+// parseContact converts a shared contact card. The user ID is left empty for
+// contacts that are not linked to a Telegram account.
+func parseContact(c *client.Contact) *model.ContactData {
+	contact := &model.ContactData{
+		FirstName:   c.FirstName,
+		LastName:    c.LastName,
+		PhoneNumber: c.PhoneNumber,
+	}
+	if c.UserId != 0 {
+		contact.UserID = fmt.Sprintf("%d", c.UserId)
+	}
+	return contact
+}
+
+// parseGame converts the description of a game shared in a post.
+func parseGame(g *client.Game) *model.GameData {
+	game := &model.GameData{
+		ShortName:   g.ShortName,
+		Title:       g.Title,
+		Description: g.Description,
+	}
+	if g.Text != nil {
+		game.Text = g.Text.Text
+	}
+	return game
+}
+
+// parseLiveEvent converts a video chat service message into a live event record.
+// It returns nil for content that is not a video chat event.
+func parseLiveEvent(content client.MessageContent, occurredAt time.Time) *model.LiveEventData {
+	switch c := content.(type) {
+	case *client.MessageVideoChatScheduled:
+		start := time.Unix(int64(c.StartDate), 0)
+		return &model.LiveEventData{
+			Event:          model.LiveEventScheduled,
+			GroupCallID:    c.GroupCallId,
+			OccurredAt:     occurredAt,
+			ScheduledStart: &start,
+		}
+	case *client.MessageVideoChatStarted:
+		return &model.LiveEventData{
+			Event:       model.LiveEventStarted,
+			GroupCallID: c.GroupCallId,
+			OccurredAt:  occurredAt,
+		}
+	case *client.MessageVideoChatEnded:
+		// The ended message carries no group call ID, only the duration
+		return &model.LiveEventData{
+			Event:           model.LiveEventEnded,
+			OccurredAt:      occurredAt,
+			DurationSeconds: int(c.Duration),
+		}
+	case *client.MessageInviteVideoChatParticipants:
+		return &model.LiveEventData{
+			Event:        model.LiveEventInvite,
+			GroupCallID:  c.GroupCallId,
+			OccurredAt:   occurredAt,
+			InvitedCount: len(c.UserIds),
+		}
+	}
+	return nil
+}
+
+// parseServiceEvent converts a chat service message, such as a pin or a title
+// change, into a service event record. It returns nil for other content.
+func parseServiceEvent(message *client.Message, occurredAt time.Time) *model.ServiceEventData {
+	event := &model.ServiceEventData{
+		ActorID:    GetSenderID(message),
+		OccurredAt: occurredAt,
+	}
+	switch c := message.Content.(type) {
+	case *client.MessagePinMessage:
+		event.Event = model.ServiceEventPinMessage
+		event.MessageID = c.MessageId
+	case *client.MessageChatAddMembers:
+		event.Event = model.ServiceEventAddMembers
+		for _, id := range c.MemberUserIds {
+			event.UserIDs = append(event.UserIDs, fmt.Sprintf("%d", id))
+		}
+	case *client.MessageChatDeleteMember:
+		event.Event = model.ServiceEventDeleteMember
+		event.UserIDs = []string{fmt.Sprintf("%d", c.UserId)}
+	case *client.MessageChatJoinByLink:
+		event.Event = model.ServiceEventJoinByLink
+	case *client.MessageChatJoinByRequest:
+		event.Event = model.ServiceEventJoinByRequest
+	case *client.MessageChatChangeTitle:
+		event.Event = model.ServiceEventChangeTitle
+		event.Title = c.Title
+	case *client.MessageChatChangePhoto:
+		event.Event = model.ServiceEventChangePhoto
+	case *client.MessageChatDeletePhoto:
+		event.Event = model.ServiceEventDeletePhoto
+	default:
+		return nil
+	}
+	return event
+}
+
+// storeMinithumbnail writes an inline JPEG minithumbnail to a temporary file and
+// stores it via the state manager, returning its storage key. Nothing is stored
+// when media downloads are disabled.
+func storeMinithumbnail(sm state.StateManagementInterface, channelName, name string, thumb *client.Minithumbnail, cfg common.CrawlerConfig) (string, error) {
+	if cfg.SkipMediaDownload || cfg.DryRun || sm == nil || thumb == nil || len(thumb.Data) == 0 {
+		return "", nil
+	}
+
+	tmp, err := os.CreateTemp("", "minithumb-*.jpg")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(thumb.Data); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write minithumbnail: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to close minithumbnail file: %w", err)
+	}
+
+	storageKey, _, err := sm.StoreFile(channelName, tmpPath, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to store minithumbnail: %w", err)
+	}
+	return storageKey, nil
 }
 
 // ParseMessage processes a Telegram message and extracts relevant information to create a Post model.
@@ -309,6 +711,28 @@ var ParseMessage = func(
 	tdlibClient crawler.TDLibClient,
 	sm state.StateManagementInterface,
 	cfg common.CrawlerConfig,
+) (model.Post, error) {
+	return ParseMessageContext(context.Background(), crawlid, message, mlr, chat, supergroup, supergroupInfo, postcount, viewcount, channelName, tdlibClient, sm, cfg)
+}
+
+// ParseMessageContext is ParseMessage with a context. Once ctx is done, further
+// TDLib requests fail, media downloads stop waiting, and the post is returned
+// with ctx's error instead of being stored. ctx is also passed on to the post
+// processors and sinks.
+func ParseMessageContext(
+	ctx context.Context,
+	crawlid string,
+	message *client.Message,
+	mlr *client.MessageLink,
+	chat *client.Chat,
+	supergroup *client.Supergroup,
+	supergroupInfo *client.SupergroupFullInfo,
+	postcount int,
+	viewcount int,
+	channelName string,
+	tdlibClient crawler.TDLibClient,
+	sm state.StateManagementInterface,
+	cfg common.CrawlerConfig,
 ) (post model.Post, err error) {
 	// Defer to recover from panics and ensure the crawl continues
 	defer func() {
@@ -323,33 +747,96 @@ var ParseMessage = func(
 		}
 	}()
 
+	// Don't download the media of a post again when its channel is revisited
+	// or the crawl is resumed
+	if postStored(crawlid, []*client.MessageLink{mlr}, channelName, sm, cfg) {
+		return model.Post{}, nil
+	}
+
+	var ok bool
+	post, ok, err = buildPost(ctx, crawlid, message, mlr, chat, supergroup, supergroupInfo, postcount, viewcount, channelName, tdlibClient, sm, cfg)
+	if err != nil || !ok {
+		return post, err
+	}
+	return storePost(ctx, post, dryRunMediaFiles(message, cfg), crawlid, channelName, sm, cfg)
+}
+
+// buildPost parses a message into a post, downloading its media, without
+// storing it. ok is false for messages that are skipped, such as those outside
+// the post date window.
+func buildPost(
+	ctx context.Context,
+	crawlid string,
+	message *client.Message,
+	mlr *client.MessageLink,
+	chat *client.Chat,
+	supergroup *client.Supergroup,
+	supergroupInfo *client.SupergroupFullInfo,
+	postcount int,
+	viewcount int,
+	channelName string,
+	tdlibClient crawler.TDLibClient,
+	sm state.StateManagementInterface,
+	cfg common.CrawlerConfig,
+) (post model.Post, ok bool, err error) {
 	// Validate required inputs
 	if message == nil {
-		return model.Post{}, fmt.Errorf("message is nil")
+		return model.Post{}, false, fmt.Errorf("message is nil")
 	}
 	if mlr == nil {
-		return model.Post{}, fmt.Errorf("message link is nil")
+		return model.Post{}, false, fmt.Errorf("message link is nil")
 	}
 	if chat == nil {
-		return model.Post{}, fmt.Errorf("chat is nil")
+		return model.Post{}, false, fmt.Errorf("chat is nil")
+	}
+	if err := ctx.Err(); err != nil {
+		return model.Post{}, false, err
 	}
+	tdlibClient = withContext(ctx, tdlibClient)
 
 	publishedAt := time.Unix(int64(message.Date), 0)
 
-	if !cfg.MinPostDate.IsZero() && publishedAt.Before(cfg.MinPostDate) {
-		return model.Post{}, nil // Skip messages earlier than MinPostDate
+	if !withinPostDateWindow(publishedAt, cfg) {
+		return model.Post{}, false, nil // Skip messages outside MinPostDate..MaxPostDate
 	}
 
-	var messageNumber string
-	if mlr.Link != "" {
-		linkParts := strings.Split(mlr.Link, "/")
-		if len(linkParts) > 0 {
-			messageNumber = linkParts[len(linkParts)-1]
-		}
+	// Drop excluded content types before any media is downloaded or stored
+	if skipContentType(message, cfg.ContentTypeFilter) {
+		return model.Post{}, false, nil
+	}
+
+	// Keep only the text and metadata of content types whose media isn't wanted
+	if !downloadsMedia(message, cfg.MediaTypes) {
+		cfg.SkipMediaDownload = true
 	}
 
+	messageNumber := messageNumberFromLink(mlr.Link)
 	if messageNumber == "" {
-		return model.Post{}, fmt.Errorf("could not determine message number")
+		return model.Post{}, false, fmt.Errorf("could not determine message number")
+	}
+
+	// A zero EditDate means the message was never edited
+	createdAt := publishedAt
+	var editedAt *time.Time
+	if message.EditDate > 0 {
+		edited := time.Unix(int64(message.EditDate), 0)
+		createdAt = edited
+		editedAt = &edited
+	}
+	postUid := fmt.Sprintf("%s-%s", messageNumber, channelName)
+
+	// Check the required fields before any media is downloaded, so an invalid
+	// post doesn't leave orphaned blobs behind
+	required := model.Post{
+		PostUID:     postUid,
+		ChannelID:   fmt.Sprintf("%d", message.ChatId),
+		PublishedAt: publishedAt,
+		EditedAt:    editedAt,
+		CaptureTime: time.Now(),
+	}
+	if err := required.Validate(); err != nil {
+		metrics.PostsInvalid.Inc()
+		return model.Post{}, false, err
 	}
 
 	// Initialize variables
@@ -359,11 +846,85 @@ var ParseMessage = func(
 	videoPath := ""
 	//videofileid := int32(0)
 	thumbnailfileid := int32(0)
+	mediaStorageKeys := make([]string, 0)
+	mediaErrors := make([]error, 0)
+	var paidMedia *model.PaidMediaData
+	var liveEvent *model.LiveEventData
+	var serviceEvent *model.ServiceEventData
+	var location *client.Location
+	livePeriod := 0
+	venueName, venueAddress := "", ""
+	var audio *model.AudioData
+	var video *model.VideoData
+	var pollData *model.PollData
+	var contact *model.ContactData
+	var dice *model.DiceData
+	var game *model.GameData
+
+	// The media of a message download concurrently. fetchMediaWith starts
+	// downloading and storing a media file; once downloads.Wait returns, its
+	// remote ID is in dst (if not nil) and its storage key and any error are
+	// collected for the post, in the order the downloads were started.
+	var downloads mediaDownloads
+	downloads.SetLimit(maxParallelMediaDownloads)
+	fetchMediaWith := func(dst *string, fileID string, localFileID int32, onDownload func(path string, result *mediaDownload)) {
+		downloads.Go(dst, fileID, func(result *mediaDownload) (string, string, error) {
+			var hook func(path string)
+			if onDownload != nil {
+				hook = func(path string) { onDownload(path, result) }
+			}
+			return fetchAndUploadMedia(tdlibClient, sm, crawlid, channelName, fileID, mlr.Link, localFileID, int64(message.MediaAlbumId), cfg, hook)
+		})
+	}
+	fetchMedia := func(dst *string, fileID string, localFileID int32) {
+		fetchMediaWith(dst, fileID, localFileID, nil)
+	}
+
+	// fetchImage is fetchMedia for images, running OCR on the downloaded file
+	// when it is enabled
+	ocr := ocrEngine(cfg)
+	var ocrResults []model.OCRData
+	fetchImage := func(dst *string, fileID string, localFileID int32) {
+		if ocr == nil {
+			fetchMedia(dst, fileID, localFileID)
+			return
+		}
+		fetchMediaWith(dst, fileID, localFileID, func(path string, result *mediaDownload) {
+			result.imageText = extractImageText(ocr, path)
+		})
+	}
+
+	// fetchSpeech is fetchMedia for audio and video, transcribing the
+	// downloaded file when transcription is enabled
+	speech := transcriber(cfg)
+	var transcripts []enrich.Transcript
+	fetchSpeech := func(dst *string, fileID string, localFileID int32) {
+		if speech == nil {
+			fetchMedia(dst, fileID, localFileID)
+			return
+		}
+		fetchMediaWith(dst, fileID, localFileID, func(path string, result *mediaDownload) {
+			if transcript, ok := transcribeMedia(speech, path); ok {
+				result.transcript = &transcript
+			}
+		})
+	}
+
+	// transcribeVideo downloads a video only to transcribe it; unlike audio,
+	// videos are not stored
+	transcribeVideo := func(fileID string, localFileID int32) {
+		downloads.Go(nil, fileID, func(result *mediaDownload) (string, string, error) {
+			transcript, err := fetchAndTranscribe(tdlibClient, sm, speech, fileID, localFileID, cfg)
+			result.transcript = transcript
+			return "", "", err
+		})
+	}
 	// Safely fetch comments if available
-	if message.InteractionInfo != nil &&
+	if !cfg.SkipComments &&
+		message.InteractionInfo != nil &&
 		message.InteractionInfo.ReplyInfo != nil &&
 		message.InteractionInfo.ReplyInfo.ReplyCount > 0 {
-		fetchedComments, fetchErr := GetMessageComments(tdlibClient, chat.Id, message.Id, channelName, cfg.MaxComments, int(message.InteractionInfo.ReplyInfo.ReplyCount))
+		fetchedComments, fetchErr := GetMessageComments(tdlibClient, chat.Id, message.Id, channelName, cfg.MaxComments, int(message.InteractionInfo.ReplyInfo.ReplyCount), cfg.CaptureSenderFlags, cfg.CommentReplyDepth)
 		if fetchErr != nil {
 			log.Error().Stack().Err(fetchErr).Msg("Failed to fetch comments")
 		}
@@ -382,18 +943,22 @@ var ParseMessage = func(
 		case *client.MessageVideo:
 			// Safe processing with nil checks
 			if content != nil {
-				thumbnailPath, videoPath, description, _, thumbnailfileid, err = processMessageSafely(content)
+				parsed, videoErr := processMessageSafely(content)
+				if videoErr != nil {
+					mediaErrors = append(mediaErrors, videoErr)
+				}
+				thumbnailPath, videoPath, description = parsed.ThumbnailPath, parsed.VideoPath, parsed.Description
+				thumbnailfileid = parsed.ThumbnailFileID
+				video = parsed.Video
 
 				if thumbnailPath != "" {
-					thumbnailPath, _ = fetchAndUploadMedia(tdlibClient, sm, crawlid, channelName, thumbnailPath, mlr.Link, thumbnailfileid, cfg)
+					fetchImage(&thumbnailPath, thumbnailPath, thumbnailfileid)
 				}
 
-				//if videoPath != "" {
-				//	videoPath, _ = fetchAndUploadMedia(tdlibClient, sm, crawlid, channelName, videoPath, mlr.Link, videofileid, cfg)
-				//}
-
-				if content.Caption != nil {
-					description = content.Caption.Text
+				// The video itself is only downloaded to be transcribed, and
+				// deleted instead of stored afterwards
+				if videoPath != "" && speech != nil {
+					transcribeVideo(videoPath, parsed.VideoFileID)
 				}
 			}
 
@@ -409,7 +974,7 @@ var ParseMessage = func(
 					content.Photo.Sizes[0].Photo.Remote != nil {
 					thumbnailPath = content.Photo.Sizes[0].Photo.Remote.Id
 					if thumbnailPath != "" {
-						thumbnailPath, _ = fetchAndUploadMedia(tdlibClient, sm, crawlid, channelName, thumbnailPath, mlr.Link, thumbnailfileid, cfg)
+						fetchImage(&thumbnailPath, thumbnailPath, thumbnailfileid)
 					}
 				}
 			}
@@ -426,7 +991,7 @@ var ParseMessage = func(
 					content.Animation.Thumbnail.File.Remote != nil {
 					thumbnailPath = content.Animation.Thumbnail.File.Remote.Id
 					if thumbnailPath != "" {
-						thumbnailPath, _ = fetchAndUploadMedia(tdlibClient, sm, crawlid, channelName, thumbnailPath, mlr.Link, thumbnailfileid, cfg)
+						fetchMedia(&thumbnailPath, thumbnailPath, thumbnailfileid)
 					}
 				}
 			}
@@ -437,8 +1002,11 @@ var ParseMessage = func(
 			}
 
 		case *client.MessagePoll:
-			if content != nil && content.Poll != nil && content.Poll.Question != nil {
-				description = content.Poll.Question.Text
+			if content != nil && content.Poll != nil {
+				if content.Poll.Question != nil {
+					description = content.Poll.Question.Text
+				}
+				pollData = parsePoll(content.Poll)
 			}
 
 		case *client.MessageGiveaway:
@@ -447,8 +1015,21 @@ var ParseMessage = func(
 			}
 
 		case *client.MessagePaidMedia:
-			if content != nil && content.Caption != nil {
-				description = content.Caption.Text
+			if content != nil {
+				if content.Caption != nil {
+					description = content.Caption.Text
+				}
+				paidMedia = parsePaidMedia(content, func(fileID string, localFileID int32) {
+					fetchMedia(nil, fileID, localFileID)
+				}, func(index int, thumb *client.Minithumbnail) string {
+					name := fmt.Sprintf("paid_preview_%d_%d_%d", message.ChatId, message.Id, index)
+					key, err := storeMinithumbnail(sm, channelName, name, thumb, cfg)
+					if err != nil {
+						log.Warn().Err(err).Str("name", name).Msg("Failed to store paid media preview")
+						mediaErrors = append(mediaErrors, fmt.Errorf("paid media preview %d: %w", index, err))
+					}
+					return key
+				})
 			}
 
 		case *client.MessageSticker:
@@ -458,10 +1039,51 @@ var ParseMessage = func(
 				content.Sticker.Sticker.Remote != nil {
 				thumbnailPath = content.Sticker.Sticker.Remote.Id
 				if thumbnailPath != "" {
-					thumbnailPath, _ = fetchAndUploadMedia(tdlibClient, sm, crawlid, channelName, thumbnailPath, mlr.Link, thumbnailfileid, cfg)
+					fetchImage(&thumbnailPath, thumbnailPath, thumbnailfileid)
 				}
 			}
 
+		case *client.MessageLocation:
+			if content != nil {
+				location = content.Location
+				livePeriod = int(content.LivePeriod)
+			}
+
+		case *client.MessageVenue:
+			if content != nil && content.Venue != nil {
+				location = content.Venue.Location
+				venueName = content.Venue.Title
+				venueAddress = content.Venue.Address
+				description = content.Venue.Title
+			}
+
+		case *client.MessageContact:
+			if content != nil && content.Contact != nil {
+				contact = parseContact(content.Contact)
+			}
+
+		case *client.MessageDice:
+			if content != nil {
+				dice = &model.DiceData{Emoji: content.Emoji, Value: int(content.Value)}
+				description = content.Emoji
+			}
+
+		case *client.MessageGame:
+			if content != nil && content.Game != nil {
+				game = parseGame(content.Game)
+				description = game.Title
+			}
+
+		case *client.MessageVideoChatScheduled, *client.MessageVideoChatStarted,
+			*client.MessageVideoChatEnded, *client.MessageInviteVideoChatParticipants:
+			liveEvent = parseLiveEvent(content, publishedAt)
+
+		case *client.MessagePinMessage, *client.MessageChatAddMembers,
+			*client.MessageChatDeleteMember, *client.MessageChatJoinByLink,
+			*client.MessageChatJoinByRequest, *client.MessageChatChangeTitle,
+			*client.MessageChatChangePhoto, *client.MessageChatDeletePhoto:
+			serviceEvent = parseServiceEvent(message, publishedAt)
+
 		case *client.MessageGiveawayWinners:
 			log.Debug().Msgf("This message is a giveaway winner: %+v", content)
 
@@ -476,16 +1098,55 @@ var ParseMessage = func(
 						content.VideoNote.Thumbnail.File.Remote != nil {
 						thumbnailPath = content.VideoNote.Thumbnail.File.Remote.Id
 						if thumbnailPath != "" {
-							thumbnailPath, _ = fetchAndUploadMedia(tdlibClient, sm, crawlid, channelName, thumbnailPath, mlr.Link, thumbnailfileid, cfg)
+							fetchMedia(&thumbnailPath, thumbnailPath, thumbnailfileid)
 						}
 					}
 
 					if content.VideoNote.Video != nil &&
-						content.VideoNote.Video.Remote != nil {
-						videoPath = content.VideoNote.Video.Remote.Id
-						//if videoPath != "" {
-						//	videoPath, _ = fetchAndUploadMedia(tdlibClient, sm, crawlid, channelName, videoPath, mlr.Link, thumbnailfileid, cfg)
-						//}
+						content.VideoNote.Video.Remote != nil &&
+						content.VideoNote.Video.Remote.Id != "" {
+						fetchSpeech(&videoPath, content.VideoNote.Video.Remote.Id, content.VideoNote.Video.Id)
+					}
+				}
+			}
+
+		case *client.MessageVoiceNote:
+			if content != nil {
+				if content.Caption != nil {
+					description = content.Caption.Text
+				}
+				if content.VoiceNote != nil {
+					audio = &model.AudioData{
+						Kind:            model.AudioKindVoiceNote,
+						DurationSeconds: int(content.VoiceNote.Duration),
+						MimeType:        content.VoiceNote.MimeType,
+					}
+					if content.VoiceNote.Voice != nil &&
+						content.VoiceNote.Voice.Remote != nil &&
+						content.VoiceNote.Voice.Remote.Id != "" {
+						fetchSpeech(&videoPath, content.VoiceNote.Voice.Remote.Id, content.VoiceNote.Voice.Id)
+					}
+				}
+			}
+
+		case *client.MessageAudio:
+			if content != nil {
+				if content.Caption != nil {
+					description = content.Caption.Text
+				}
+				if content.Audio != nil {
+					audio = &model.AudioData{
+						Kind:            model.AudioKindAudio,
+						DurationSeconds: int(content.Audio.Duration),
+						MimeType:        content.Audio.MimeType,
+						Performer:       content.Audio.Performer,
+						Title:           content.Audio.Title,
+						FileName:        content.Audio.FileName,
+					}
+					if content.Audio.Audio != nil &&
+						content.Audio.Audio.Remote != nil &&
+						content.Audio.Audio.Remote.Id != "" {
+						fetchSpeech(&videoPath, content.Audio.Audio.Remote.Id, content.Audio.Audio.Id)
 					}
 				}
 			}
@@ -500,27 +1161,49 @@ var ParseMessage = func(
 						content.Document.Thumbnail.File.Remote != nil {
 						thumbnailPath = content.Document.Thumbnail.File.Remote.Id
 						if thumbnailPath != "" {
-							thumbnailPath, _ = fetchAndUploadMedia(tdlibClient, sm, crawlid, channelName, thumbnailPath, mlr.Link, thumbnailfileid, cfg)
+							fetchMedia(&thumbnailPath, thumbnailPath, thumbnailfileid)
 						}
 					}
 
 					if content.Document.Document != nil &&
-						content.Document.Document.Remote != nil {
-						videoPath = content.Document.Document.Remote.Id
-						//if videoPath != "" {
-						//	videoPath, _ = fetchAndUploadMedia(tdlibClient, sm, crawlid, channelName, videoPath, mlr.Link, videofileid, cfg)
-						//}
+						content.Document.Document.Remote != nil &&
+						content.Document.Document.Remote.Id != "" {
+						fetchMedia(&videoPath, content.Document.Document.Remote.Id, content.Document.Document.Id)
 					}
 				}
 			}
 
 		default:
 			log.Debug().Str("type", fmt.Sprintf("%T", content)).Msg("Unknown message content type")
+			if cfg.DumpUnknownTo != "" {
+				if path, err := dumpUnknownMessage(cfg.DumpUnknownTo, message); err != nil {
+					log.Warn().Err(err).Int64("message_id", message.Id).Msg("Failed to dump message of unknown content type")
+				} else {
+					log.Info().Str("path", path).Str("type", content.MessageContentType()).Msg("Dumped message of unknown content type")
+				}
+			}
+		}
+	}
+
+	// Collect the media downloads started above
+	for _, result := range downloads.Wait() {
+		if result.err != nil {
+			mediaErrors = append(mediaErrors, fmt.Errorf("media %s: %w", result.fileID, result.err))
+		}
+		if result.storageKey != "" {
+			mediaStorageKeys = append(mediaStorageKeys, result.storageKey)
+		}
+		if result.imageText != "" {
+			ocrResults = append(ocrResults, model.OCRData{OCRText: result.imageText, ThumbURL: result.remoteID})
+		}
+		if result.transcript != nil {
+			transcripts = append(transcripts, *result.transcript)
 		}
 	}
 
-	// Safely extract outlinks and reactions
+	// Safely extract outlinks, entities and reactions
 	outlinks := extractChannelLinksFromMessage(message)
+	entities := extractMessageEntities(message)
 	reactions := make(map[string]int)
 
 	if message.InteractionInfo != nil &&
@@ -541,18 +1224,24 @@ var ParseMessage = func(
 		posttype = []string{message.Content.MessageContentType()}
 	}
 
-	createdAt := time.Now()
-	if message.EditDate > 0 {
-		createdAt = time.Unix(int64(message.EditDate), 0)
-	}
-
 	vc := GetViewCount(message, channelName)
-	postUid := fmt.Sprintf("%s-%s", messageNumber, channelName)
-	var sharecount int = 0
 
-	// Safely get share count
+	// The forward count the message arrived with is used when the current
+	// share count can't be fetched
+	sharecount := 0
+	if message.InteractionInfo != nil {
+		sharecount = int(message.InteractionInfo.ForwardCount)
+	}
 	if tdlibClient != nil {
-		sharecount, _ = GetMessageShareCount(tdlibClient, chat.Id, message.Id, channelName)
+		if current, err := GetMessageShareCount(tdlibClient, chat.Id, message.Id, channelName); err != nil {
+			log.Warn().
+				Err(err).
+				Int64("message_id", message.Id).
+				Int("forward_count", sharecount).
+				Msg("Failed to fetch share count, using the forward count of the message")
+		} else {
+			sharecount = current
+		}
 	}
 
 	username := GetPoster(tdlibClient, message)
@@ -563,6 +1252,11 @@ var ParseMessage = func(
 		memberCount = int(supergroupInfo.MemberCount)
 	}
 
+	albumID := ""
+	if message.MediaAlbumId != 0 {
+		albumID = fmt.Sprintf("%d", message.MediaAlbumId)
+	}
+
 	post = model.Post{
 		PostLink:       mlr.Link,
 		ChannelID:      fmt.Sprintf("%d", message.ChatId), // Convert int64 to string
@@ -570,7 +1264,9 @@ var ParseMessage = func(
 		URL:            mlr.Link,
 		PublishedAt:    publishedAt,
 		CreatedAt:      createdAt,
-		LanguageCode:   "",
+		EditedAt:       editedAt,
+		WasEdited:      editedAt != nil,
+		LanguageCode:   common.ResolveLanguage(description, cfg.DefaultLanguage),
 		Engagement:     vc,
 		ViewCount:      vc,
 		LikeCount:      0,
@@ -580,15 +1276,10 @@ var ParseMessage = func(
 		Description:    description,
 		IsAd:           false,
 		PostType:       posttype,
-		TranscriptText: "",
-		ImageText:      "",
-		PlatformName:   "Telegram",
-		LikesCount:     0,
-		SharesCount:    sharecount,
-		CommentsCount:  len(comments),
-		ViewsCount:     vc,
-		SearchableText: "",
-		AllText:        "",
+		TranscriptText: joinTranscripts(transcripts),
+		ImageText:      joinImageText(ocrResults),
+		OCRData:        ocrResults,
+		PlatformName:   platformName(cfg),
 		ThumbURL:       thumbnailPath,
 		MediaURL:       videoPath,
 		Outlinks:       outlinks,
@@ -609,25 +1300,260 @@ var ParseMessage = func(
 				CommentCount:   0,
 				ShareCount:     0,
 			},
-			ChannelURLExternal: fmt.Sprintf("https://t.me/c/%s", channelName),
+			ChannelURLExternal: ChannelExternalURL(chat, supergroup, channelName),
 			ChannelURL:         "",
 		},
 		Comments:  comments,
 		Reactions: reactions,
 		Handle:    username,
+		AlbumID:   albumID,
+		SenderID:  GetSenderID(message),
+
+		MediaStorageKeys: mediaStorageKeys,
+		PaidMedia:        paidMedia,
+		LiveEvent:        liveEvent,
+		ServiceEvent:     serviceEvent,
+		ForwardedFrom:    GetForwardedFrom(message),
+		MediaErrors:      mediaErrorStrings(mediaErrors),
+		LivePeriod:       livePeriod,
+		VenueName:        venueName,
+		VenueAddress:     venueAddress,
+		Audio:            audio,
+		Video:            video,
+		PollData:         pollData,
+		ReplyToMessageID: GetReplyToMessageID(message),
+		ReplyToChatID:    GetReplyToChatID(message),
+		URLs:             entities.URLs,
+		Mentions:         entities.Mentions,
+		Hashtags:         entities.Hashtags,
+		Contact:          contact,
+		Dice:             dice,
+		Game:             game,
+
+		TranscriptLanguage: transcriptLanguage(transcripts),
+		FormattedText:      renderFormattedText(messageFormattedText(message), cfg.TextFormat),
+	}
+
+	if post.ReplyToMessageID != 0 {
+		isReply := true
+		post.IsReply = &isReply
+	}
+
+	if topicID := GetTopicID(message, supergroup); topicID != 0 {
+		post.TopicID = topicID
+		post.TopicName = GetTopicName(tdlibClient, message.ChatId, topicID)
+	}
+
+	if video != nil {
+		videoLength := video.DurationSeconds
+		post.VideoLength = &videoLength
+	}
+
+	if location != nil {
+		latitude, longitude := location.Latitude, location.Longitude
+		post.Latitude = &latitude
+		post.Longitude = &longitude
 	}
 
-	// Store the post but don't return an error if storage fails
-	if sm != nil {
-		storeErr := sm.StorePost(channelName, post)
-		if storeErr != nil {
-			log.Error().Err(storeErr).Msg("Failed to store data")
+	if len(mediaErrors) > 0 {
+		log.Warn().
+			Err(errors.Join(mediaErrors...)).
+			Str("post_link", mlr.Link).
+			Msg("Post stored with incomplete media")
+	}
+
+	if cfg.CaptureSenderFlags {
+		post.SenderFlags = GetSenderFlags(tdlibClient, message)
+	}
+
+	// Discovery reads the forward origin, so it runs before redaction
+	post.DiscoveredChannels = newDiscoveryStrategy(cfg.DiscoveryStrategy).Discover(tdlibClient, message, &post)
+
+	// Pseudonymize configured PII fields before the post reaches any sink
+	redactPost(&post, cfg.Redaction)
+
+	// The text of a contact card is its name, so it is built from the redacted
+	// contact, and the search text from the redacted post
+	if post.Contact != nil {
+		post.Description = strings.TrimSpace(post.Contact.FirstName + " " + post.Contact.LastName)
+	}
+	setSearchText(&post)
+
+	return post, true, nil
+}
+
+// storePost runs the post processors on a parsed post and writes it to the
+// configured sinks, unless it is invalid, already stored in this crawl or ctx
+// is done. An invalid post is returned with an error wrapping
+// model.ErrInvalidPost. In a dry run it only logs the post and the number of
+// media files that would have been downloaded.
+func storePost(ctx context.Context, post model.Post, mediaFiles int, crawlid, channelName string, sm state.StateManagementInterface, cfg common.CrawlerConfig) (model.Post, error) {
+	// Media and comments may have been cut short, so a cancelled post is not stored
+	if err := ctx.Err(); err != nil {
+		return post, err
+	}
+
+	// Malformed posts would break downstream consumers, so they are not stored
+	if err := post.Validate(); err != nil {
+		log.Warn().
+			Err(err).
+			Str("post_link", post.PostLink).
+			Str("channel", channelName).
+			Msg("Skipping invalid post")
+		metrics.PostsInvalid.Inc()
+		return post, err
+	}
+
+	// In a dry run only report what would have been collected
+	if cfg.DryRun {
+		logDryRunPost(post, mediaFiles)
+		metrics.PostsParsed.Inc()
+		return post, nil
+	}
+
+	// Don't store a post again when its channel is revisited in the same crawl
+	if sm != nil && sm.HasPost(crawlid, post.PostUID) {
+		log.Debug().
+			Str("post_uid", post.PostUID).
+			Str("channel", channelName).
+			Msg("Post already stored in this crawl, skipping")
+		metrics.PostsParsed.Inc()
+		return post, nil
+	}
+
+	ctx = sink.WithChannel(ctx, channelName)
+
+	// Run the enrichment chain; a failing processor doesn't keep the post from
+	// being stored
+	if err := cfg.PostProcessors.Process(ctx, &post); err != nil {
+		log.Warn().
+			Err(err).
+			Str("post_uid", post.PostUID).
+			Msg("Post processing failed")
+	}
+
+	// Write the post to every configured sink (the state manager by default)
+	// but don't return an error if storage fails
+	sinks := cfg.PostSinks
+	if len(sinks) == 0 && sm != nil {
+		sinks = []sink.PostSink{state.NewPostSink(sm)}
+	}
+	if storeErr := sink.WriteAll(ctx, sinks, post); storeErr != nil {
+		log.Error().Err(storeErr).Msg("Failed to store data")
+	}
+
+	if cfg.CommonSchemaOutput != nil {
+		if err := cfg.CommonSchemaOutput.Write(post); err != nil {
+			log.Error().Err(err).Msg("Failed to write common schema record")
 		}
 	}
 
+	metrics.PostsParsed.Inc()
 	return post, nil
 }
 
+
+// messageNumberFromLink returns the message number at the end of a message
+// link such as "https://t.me/channel/123", or "" for an empty link.
+func messageNumberFromLink(link string) string {
+	if link == "" {
+		return ""
+	}
+	linkParts := strings.Split(link, "/")
+	return linkParts[len(linkParts)-1]
+}
+
+// postStored reports whether the post of any of the message links was already
+// stored in the crawl, by this or an earlier run. It is checked before the
+// post is built, so stored posts don't have their media downloaded again.
+func postStored(crawlid string, links []*client.MessageLink, channelName string, sm state.StateManagementInterface, cfg common.CrawlerConfig) bool {
+	if sm == nil || cfg.DryRun {
+		return false
+	}
+	for _, mlr := range links {
+		if mlr == nil {
+			continue
+		}
+		messageNumber := messageNumberFromLink(mlr.Link)
+		if messageNumber == "" {
+			continue
+		}
+		postUID := fmt.Sprintf("%s-%s", messageNumber, channelName)
+		if sm.HasPost(crawlid, postUID) {
+			log.Debug().
+				Str("post_uid", postUID).
+				Str("channel", channelName).
+				Msg("Post already stored in this crawl, skipping")
+			metrics.PostsParsed.Inc()
+			return true
+		}
+	}
+	return false
+}
+
+// dryRunMediaFiles returns how many media files of the message a dry run
+// reports as would-be downloads.
+func dryRunMediaFiles(message *client.Message, cfg common.CrawlerConfig) int {
+	if cfg.SkipMediaDownload || !downloadsMedia(message, cfg.MediaTypes) {
+		return 0
+	}
+	return mediaFileCount(message)
+}
+
+// logDryRunPost logs a structured summary of a post that a dry run would have
+// stored, including how many media files would have been downloaded.
+func logDryRunPost(post model.Post, mediaFiles int) {
+	log.Info().
+		Str("post_uid", post.PostUID).
+		Str("channel", post.ChannelName).
+		Str("url", post.URL).
+		Str("post_type", strings.Join(post.PostType, ",")).
+		Time("published_at", post.PublishedAt).
+		Int("text_length", len(post.Description)).
+		Int("views", post.ViewCount).
+		Int("comments", post.CommentCount).
+		Int("outlinks", len(post.Outlinks)).
+		Int("media_files", mediaFiles).
+		Msg("Dry run: would store post")
+}
+
+// setSearchText fills in the post's AllText, every piece of text collected for
+// it (description or caption, OCR text, transcript and comment bodies) one per
+// line, and SearchableText, its normalized form for full-text search.
+func setSearchText(post *model.Post) {
+	parts := []string{post.Description, post.ImageText, post.TranscriptText}
+	for _, comment := range post.Comments {
+		parts = append(parts, comment.Text)
+	}
+
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			texts = append(texts, part)
+		}
+	}
+	post.AllText = strings.Join(texts, "\n")
+	post.SearchableText = common.NormalizeSearchText(post.AllText)
+}
+
+// mediaFileCount returns how many media files ParseMessage would download for
+// the message's main content.
+func mediaFileCount(message *client.Message) int {
+	switch content := message.Content.(type) {
+	case *client.MessagePhoto, *client.MessageAnimation, *client.MessageAudio,
+		*client.MessageDocument, *client.MessageVoiceNote, *client.MessageVideoNote:
+		return 1
+	case *client.MessageVideo:
+		// Video plus its thumbnail
+		return 2
+	case *client.MessagePaidMedia:
+		if content != nil {
+			return len(content.Media)
+		}
+	}
+	return 0
+}
+
 // checkFileCache checks if a media file with the given unique ID has already
 // been processed and stored, avoiding redundant downloads and processing.
 //
@@ -667,14 +1593,18 @@ func checkFileCache(sm state.StateManagementInterface, uniqueid string) (string,
 //   - tdlibClient: A pointer to the tdlib client used for interacting with Telegram.
 //   - sm: State manager interface for checking if the file has already been processed
 //   - downloadid: A string representing the ID of the file to be downloaded.
+//   - cfg: Crawler configuration providing the download retry policy.
 //
 // Returns:
-//   - A string containing the local path of the downloaded file. Returns an empty string if an error occurs during fetching or downloading.
+//   - A string containing the local path of the downloaded file. Returns an empty string if an error occurs
+//     during fetching or downloading, or if the file has already been processed in this crawl.
 //   - A string containing the unique ID of the remote file
-//   - An error if any of the steps fail
+//   - An error if any of the steps fail; a *MediaDownloadError once retries are exhausted
 //
-// The function includes error handling and logs relevant information, including any panics that are recovered.
-func fetchfilefromtelegram(tdlibClient crawler.TDLibClient, sm state.StateManagementInterface, downloadid string) (string, string, error) {
+// Both the remote file lookup and the download are retried with exponential backoff,
+// as TDLib downloads routinely fail transiently under load. The function includes
+// error handling and logs relevant information, including any panics that are recovered.
+func fetchfilefromtelegram(tdlibClient crawler.TDLibClient, sm state.StateManagementInterface, downloadid string, cfg common.CrawlerConfig) (string, string, error) {
 	log.Debug().Str("download_id", downloadid).Msg("Fetching file from Telegram")
 
 	defer func() {
@@ -688,9 +1618,18 @@ func fetchfilefromtelegram(tdlibClient crawler.TDLibClient, sm state.StateManage
 		}
 	}()
 
+	maxAttempts, retryDelay := downloadRetryPolicy(cfg)
+
 	// Fetch the remote file
-	f, err := tdlibClient.GetRemoteFile(&client.GetRemoteFileRequest{
-		RemoteFileId: downloadid,
+	var f *client.File
+	err := retryDownload(downloadid, "get_remote_file", maxAttempts, retryDelay, func() error {
+		return withFloodWait("get_remote_file", func() error {
+			var err error
+			f, err = tdlibClient.GetRemoteFile(&client.GetRemoteFileRequest{
+				RemoteFileId: downloadid,
+			})
+			return err
+		})
 	})
 
 	if err != nil {
@@ -699,6 +1638,7 @@ func fetchfilefromtelegram(tdlibClient crawler.TDLibClient, sm state.StateManage
 			Stack().
 			Str("download_id", downloadid).
 			Msg("Failed to get remote file information")
+		metrics.DownloadErrors.Inc()
 		return "", "", err
 	}
 
@@ -722,21 +1662,40 @@ func fetchfilefromtelegram(tdlibClient crawler.TDLibClient, sm state.StateManage
 			Str("path", existingPath).
 			Str("unique_id", f.Remote.UniqueId).
 			Msg("File already processed, skipping download")
-		return "", "", nil
+		return "", f.Remote.UniqueId, nil
+	}
+
+	// Resume from the bytes a previous run left on disk instead of starting over
+	offset, complete := downloadResumeOffset(f)
+	if complete {
+		log.Debug().
+			Str("path", f.Local.Path).
+			Str("unique_id", f.Remote.UniqueId).
+			Msg("File already downloaded locally, skipping download")
+		metrics.MediaDownloaded.Inc()
+		return moveToDownloadDir(f.Local.Path, f.Remote.UniqueId, cfg.MediaDownloadDir), f.Remote.UniqueId, nil
 	}
 
 	// Download the file
 	log.Debug().
 		Str("download_id", downloadid).
 		Str("file_id", fmt.Sprintf("%d", f.Id)).
+		Int64("offset", offset).
 		Msg("Downloading file from Telegram")
 
-	downloadedFile, err := tdlibClient.DownloadFile(&client.DownloadFileRequest{
-		FileId:      f.Id,
-		Priority:    1,
-		Offset:      0,
-		Limit:       0,
-		Synchronous: true,
+	var downloadedFile *client.File
+	err = retryDownload(downloadid, "download_file", maxAttempts, retryDelay, func() error {
+		return withFloodWait("download_file", func() error {
+			var err error
+			downloadedFile, err = tdlibClient.DownloadFile(&client.DownloadFileRequest{
+				FileId:      f.Id,
+				Priority:    1,
+				Offset:      offset,
+				Limit:       0,
+				Synchronous: true,
+			})
+			return err
+		})
 	})
 	if err != nil {
 		log.Error().
@@ -745,6 +1704,7 @@ func fetchfilefromtelegram(tdlibClient crawler.TDLibClient, sm state.StateManage
 			Str("download_id", downloadid).
 			Str("file_id", fmt.Sprintf("%d", f.Id)).
 			Msg("Error downloading file")
+		metrics.DownloadErrors.Inc()
 		return "", "", err
 	}
 
@@ -754,6 +1714,7 @@ func fetchfilefromtelegram(tdlibClient crawler.TDLibClient, sm state.StateManage
 			Str("download_id", downloadid).
 			Str("file_id", fmt.Sprintf("%d", f.Id)).
 			Msg("Downloaded file path is empty")
+		metrics.DownloadErrors.Inc()
 		return "", "", fmt.Errorf("empty file path received from TDLib")
 	}
 
@@ -763,8 +1724,107 @@ func fetchfilefromtelegram(tdlibClient crawler.TDLibClient, sm state.StateManage
 		Int32("downloaded_size", int32(downloadedFile.Size)).
 		Bool("downloaded_from_memory", downloadedFile.Local.IsDownloadingCompleted).
 		Msg("File downloaded successfully")
+	metrics.MediaDownloaded.Inc()
+
+	return moveToDownloadDir(downloadedFile.Local.Path, f.Remote.UniqueId, cfg.MediaDownloadDir), f.Remote.UniqueId, nil
+}
+
+// PurgeMediaDownloadDir removes the media left in cfg.MediaDownloadDir, e.g.
+// by a run that was stopped between downloading and storing a file. It must be
+// called before the crawl downloads any media, since files in the directory
+// are removed regardless of which worker owns them.
+func PurgeMediaDownloadDir(cfg common.CrawlerConfig) {
+	if cfg.MediaDownloadDir == "" {
+		return
+	}
+	if err := removeMultimedia(cfg.MediaDownloadDir); err != nil {
+		log.Warn().Err(err).Str("dir", cfg.MediaDownloadDir).Msg("Failed to purge media download directory")
+	}
+}
+
+// moveToDownloadDir moves a completed download out of TDLib's files directory
+// into dir and returns its new path. This keeps downloads apart from TDLib's
+// own files, so dir can be purged safely. The file is named after its unique
+// ID plus a random suffix, since the same file can be downloaded by several
+// workers at once and each removes its copy once it is stored. If dir is
+// empty or the file can't be moved, the original path is returned.
+func moveToDownloadDir(path, uniqueID, dir string) string {
+	if dir == "" {
+		return path
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Warn().Err(err).Str("dir", dir).Msg("Failed to create media download directory, leaving file in place")
+		return path
+	}
 
-	return downloadedFile.Local.Path, f.Remote.UniqueId, nil
+	// Reserve a name no other download uses; the move then replaces it
+	reserved, err := os.CreateTemp(dir, uniqueID+"-*"+filepath.Ext(path))
+	if err != nil {
+		log.Warn().Err(err).Str("dir", dir).Msg("Failed to create file in media download directory, leaving file in place")
+		return path
+	}
+	reserved.Close()
+	dst := reserved.Name()
+
+	if err := os.Rename(path, dst); err == nil {
+		return dst
+	}
+
+	// Rename fails across file systems, so copy the file instead
+	if err := copyFile(path, dst); err != nil {
+		log.Warn().Err(err).Str("path", path).Str("dir", dir).Msg("Failed to move download to media download directory, leaving file in place")
+		os.Remove(dst)
+		return path
+	}
+	if err := os.Remove(path); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to remove download after copying it")
+	}
+	return dst
+}
+
+// copyFile copies the file at src to dst, replacing dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// downloadResumeOffset inspects the local state TDLib reports for f and returns
+// the offset a download should start from, and whether the file is already
+// complete on disk. Only bytes that actually exist in the local file are
+// trusted, so a deleted or truncated file is fetched again from the start.
+func downloadResumeOffset(f *client.File) (int64, bool) {
+	if f.Local == nil || f.Local.Path == "" {
+		return 0, false
+	}
+	info, err := os.Stat(f.Local.Path)
+	if err != nil {
+		return 0, false
+	}
+	if f.Local.IsDownloadingCompleted && (f.Size == 0 || info.Size() >= f.Size) {
+		return info.Size(), true
+	}
+
+	offset := f.Local.DownloadedPrefixSize
+	if offset > info.Size() {
+		offset = info.Size()
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return offset, false
 }
 
 // extractChannelLinksFromMessage extracts all unique Telegram channel links and mentions
@@ -4,18 +4,30 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"github.com/researchaccelerator-hub/telegram-scraper/common"
 	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/researchaccelerator-hub/telegram-scraper/enrich"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/researchaccelerator-hub/telegram-scraper/sink"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/zelenin/go-tdlib/client"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // Example demonstrates how to use the downloadAndExtractTarball function
@@ -65,7 +77,7 @@ func Example_downloadAndExtractTarball() {
 	defer server.Close()
 
 	// Step 3: Call function to download and extract
-	err = downloadAndExtractTarball(server.URL, targetDir)
+	err = downloadAndExtractTarball(context.Background(), nil, server.URL, targetDir, "")
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		return
@@ -149,6 +161,48 @@ func TestDownloadAndExtractTarballFromReader(t *testing.T) {
 	}
 }
 
+func TestDownloadAndExtractTarballFromReader_RejectsPathTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	content := []byte("escaped")
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{
+		Name:     "../escape",
+		Mode:     0644,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	}))
+	_, err := tarWriter.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzWriter.Close())
+
+	parent := t.TempDir()
+	targetDir := filepath.Join(parent, "target")
+	require.NoError(t, os.Mkdir(targetDir, 0755))
+
+	err = downloadAndExtractTarballFromReader(&buf, targetDir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outside target directory")
+	assert.NoFileExists(t, filepath.Join(parent, "escape"), "The entry must not be written outside the target directory")
+}
+
+func TestTarEntryPath(t *testing.T) {
+	path, err := tarEntryPath("/data/target", "dir/file.txt")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/data/target", "dir", "file.txt"), path)
+
+	_, err = tarEntryPath("/data/target", "dir/../../escape")
+	assert.Error(t, err)
+	_, err = tarEntryPath("/data/target", "..")
+	assert.Error(t, err)
+
+	// Names that merely start with dots stay inside the target
+	_, err = tarEntryPath("/data/target", "..hidden")
+	assert.NoError(t, err)
+}
+
 func TestDownloadAndExtractTarball(t *testing.T) {
 	// Create a test server that serves a mock tarball
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -209,7 +263,7 @@ func TestDownloadAndExtractTarball(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	err = downloadAndExtractTarball(server.URL, tempDir)
+	err = downloadAndExtractTarball(context.Background(), nil, server.URL, tempDir, "")
 	if err != nil {
 		t.Fatalf("downloadAndExtractTarball failed: %v", err)
 	}
@@ -237,7 +291,7 @@ func TestDownloadError(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	err = downloadAndExtractTarball(server.URL, tempDir)
+	err = downloadAndExtractTarball(context.Background(), nil, server.URL, tempDir, "")
 	if err == nil {
 		t.Error("Expected error for 404 response, got nil")
 	}
@@ -257,7 +311,7 @@ func TestInvalidGzipFormat(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	err = downloadAndExtractTarball(server.URL, tempDir)
+	err = downloadAndExtractTarball(context.Background(), nil, server.URL, tempDir, "")
 	if err == nil {
 		t.Error("Expected error for invalid gzip data, got nil")
 	}
@@ -282,7 +336,7 @@ func TestCorruptedTarFile(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	err = downloadAndExtractTarball(server.URL, tempDir)
+	err = downloadAndExtractTarball(context.Background(), nil, server.URL, tempDir, "")
 	if err == nil {
 		t.Error("Expected error for corrupted tar data, got nil")
 	}
@@ -319,6 +373,43 @@ func TestMockTelegramService_InitializeClient(t *testing.T) {
 	assert.Nil(t, tdlibClient, "MockTelegramService should return nil")
 }
 
+// TestNewAuthorizer_SelectsAuthMethod tests that a bot token switches authentication away from the phone login
+func TestNewAuthorizer_SelectsAuthMethod(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        common.CrawlerConfig
+		method     string
+		authorizer string
+	}{
+		{"bot token", common.CrawlerConfig{BotToken: "123456:ABC-DEF"}, authMethodBot, "*client.botAuthorizer"},
+		{"phone login", common.CrawlerConfig{}, authMethodPhone, "*client.clientAuthorizer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.method, authMethod(tt.cfg))
+
+			// An existing session keeps the phone login from setting up a code
+			authorizer, _ := newAuthorizer(tt.cfg, "", "", true)
+			defer authorizer.Close()
+			assert.Equal(t, tt.authorizer, fmt.Sprintf("%T", authorizer))
+		})
+	}
+}
+
+// TestNewAuthorizer_BotToken tests that a bot token yields TDLib's bot authorizer
+func TestNewAuthorizer_BotToken(t *testing.T) {
+	authorizer, tdlibParameters := newAuthorizer(common.CrawlerConfig{BotToken: "123456:ABC-DEF"}, "", "", false)
+	defer authorizer.Close()
+
+	assert.Equal(t, "*client.botAuthorizer", fmt.Sprintf("%T", authorizer))
+	select {
+	case tdlibParameters <- &client.SetTdlibParametersRequest{}:
+	default:
+		t.Fatal("TDLib parameters should be accepted without a running client")
+	}
+}
+
 // TestMockTelegramService_GetMe tests mock retrieval of a user
 func TestMockTelegramService_GetMe(t *testing.T) {
 	service := &MockTelegramService{}
@@ -336,6 +427,114 @@ func TestGenCode_MockService(t *testing.T) {
 	assert.NotPanics(t, func() { GenCode(service, "/tmp") }, "GenCode should not panic")
 }
 
+// TestWaitForClient_Timeout verifies that a slow client initialization returns
+// an error after the configured timeout instead of exiting the process
+func TestWaitForClient_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+	tdlibClient, err := waitForClient(context.Background(), func() (*client.Client, error) {
+		<-release
+		return nil, nil
+	}, initTimeout(common.CrawlerConfig{InitTimeout: 20 * time.Millisecond}))
+
+	assert.Error(t, err, "Expected a timeout error")
+	assert.Nil(t, tdlibClient)
+	assert.Less(t, time.Since(start), time.Second, "Should give up after the configured timeout")
+}
+
+// TestWaitForClient_Error verifies that initialization errors are returned to the caller
+func TestWaitForClient_Error(t *testing.T) {
+	_, err := waitForClient(context.Background(), func() (*client.Client, error) {
+		return nil, fmt.Errorf("bad credentials")
+	}, time.Second)
+	assert.EqualError(t, err, "bad credentials")
+}
+
+func TestInitTimeout_Default(t *testing.T) {
+	assert.Equal(t, 30*time.Second, initTimeout(common.CrawlerConfig{}))
+}
+
+// failingGetMeClient is a TDLib client whose GetMe call always fails
+type failingGetMeClient struct {
+	MockTDLibClient
+}
+
+func (f *failingGetMeClient) GetMe() (*client.User, error) {
+	return nil, fmt.Errorf("unauthorized")
+}
+
+// TestRealTelegramService_GetMeReturnsError verifies that a failed GetMe is
+// returned to the caller rather than terminating the process
+func TestRealTelegramService_GetMeReturnsError(t *testing.T) {
+	service := &RealTelegramService{}
+	user, err := service.GetMe(&failingGetMeClient{})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unauthorized")
+	assert.Nil(t, user)
+}
+
+// flakyGetMeClient fails GetMe a set number of times before succeeding
+type flakyGetMeClient struct {
+	MockTDLibClient
+	failures int
+	calls    int
+}
+
+func (f *flakyGetMeClient) GetMe() (*client.User, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, fmt.Errorf("network is unreachable")
+	}
+	return &client.User{Id: 42, FirstName: "Test", LastName: "User"}, nil
+}
+
+func TestGetMeWithRetry_RetriesTransientFailure(t *testing.T) {
+	tdlibClient := &flakyGetMeClient{failures: 1}
+	user, err := GetMeWithRetry(&RealTelegramService{}, tdlibClient, 3, 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), user.Id)
+	assert.Equal(t, 2, tdlibClient.calls, "The second attempt should succeed")
+}
+
+func TestGetMeWithRetry_FallsBackToCachedUser(t *testing.T) {
+	service := &RealTelegramService{}
+	tdlibClient := &flakyGetMeClient{}
+	_, err := GetMeWithRetry(service, tdlibClient, 3, 0)
+	require.NoError(t, err)
+
+	// Telegram becomes unreachable after the first successful call
+	tdlibClient.failures = 100
+	user, err := GetMeWithRetry(service, tdlibClient, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), user.Id)
+	assert.Equal(t, 3, tdlibClient.calls)
+
+	// A client that never authenticated has nothing to fall back to
+	_, err = GetMeWithRetry(service, &flakyGetMeClient{failures: 100}, 2, 0)
+	assert.ErrorContains(t, err, "network is unreachable")
+
+	// Nor does another service, since each service keeps its own users
+	_, err = GetMeWithRetry(&RealTelegramService{}, tdlibClient, 2, 0)
+	assert.ErrorContains(t, err, "network is unreachable")
+}
+
+// TestRealTelegramService_InvalidAPIIDReturnsError verifies that a malformed
+// TG_API_ID is reported as an error instead of exiting
+func TestRealTelegramService_InvalidAPIIDReturnsError(t *testing.T) {
+	t.Setenv("TG_API_ID", "not-a-number")
+
+	service := &RealTelegramService{}
+	tdlibClient, err := service.InitializeClientWithConfig(t.TempDir(), common.CrawlerConfig{})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid TG_API_ID")
+	assert.Nil(t, tdlibClient)
+}
+
 // ExampleGenCode demonstrates how GenCode is used
 func ExampleGenCode() {
 	// Store the original logger configuration
@@ -374,3 +573,873 @@ func ExampleGenCode() {
 	// MockTelegramService: Simulating client initialization
 	// Authenticated as: Mock User
 }
+
+// TestMediaStorageKey verifies that album media share a common storage prefix
+func TestMediaStorageKey(t *testing.T) {
+	assert.Equal(t, "remote-1", mediaStorageKey(0, "remote-1"), "Non-album media should keep the remote ID")
+	assert.Equal(t, "album_42/remote-1", mediaStorageKey(42, "remote-1"), "Album media should be grouped by album ID")
+	assert.Equal(t, "album_42/remote-2", mediaStorageKey(42, "remote-2"), "Album media should be grouped by album ID")
+}
+
+// TestParsePaidMedia verifies price and lock accounting for paid media messages
+func TestParsePaidMedia(t *testing.T) {
+	content := &client.MessagePaidMedia{
+		StarCount: 50,
+		Media: []client.PaidMedia{
+			&client.PaidMediaPreview{Minithumbnail: &client.Minithumbnail{Data: []byte{0xff, 0xd8}}},
+			&client.PaidMediaPreview{},
+			&client.PaidMediaPhoto{Photo: &client.Photo{Sizes: []*client.PhotoSize{
+				{Photo: &client.File{Id: 7, Remote: &client.RemoteFile{Id: "remote-photo"}}},
+			}}},
+		},
+	}
+
+	var fetched []string
+	fetchMedia := func(fileID string, localFileID int32) {
+		fetched = append(fetched, fileID)
+	}
+	storePreview := func(index int, thumb *client.Minithumbnail) string {
+		return fmt.Sprintf("preview-%d", index)
+	}
+
+	data := parsePaidMedia(content, fetchMedia, storePreview)
+
+	assert.Equal(t, int64(50), data.StarCount)
+	assert.Equal(t, 3, data.ItemCount)
+	assert.Equal(t, 2, data.LockedCount)
+	assert.True(t, data.IsPaywalled)
+	assert.Equal(t, []string{"preview-0"}, data.PreviewKeys, "Only previews with a minithumbnail should be stored")
+	assert.Equal(t, []string{"remote-photo"}, fetched, "Unlocked photos should be downloaded")
+}
+
+func TestParseLiveEvent(t *testing.T) {
+	occurredAt := time.Unix(1700000000, 0)
+
+	scheduled := parseLiveEvent(&client.MessageVideoChatScheduled{GroupCallId: 9, StartDate: 1700003600}, occurredAt)
+	require.NotNil(t, scheduled)
+	assert.Equal(t, model.LiveEventScheduled, scheduled.Event)
+	assert.Equal(t, int32(9), scheduled.GroupCallID)
+	assert.Equal(t, occurredAt, scheduled.OccurredAt)
+	require.NotNil(t, scheduled.ScheduledStart)
+	assert.Equal(t, time.Unix(1700003600, 0), *scheduled.ScheduledStart)
+
+	started := parseLiveEvent(&client.MessageVideoChatStarted{GroupCallId: 9}, occurredAt)
+	require.NotNil(t, started)
+	assert.Equal(t, model.LiveEventStarted, started.Event)
+	assert.Nil(t, started.ScheduledStart)
+
+	ended := parseLiveEvent(&client.MessageVideoChatEnded{Duration: 3600}, occurredAt)
+	require.NotNil(t, ended)
+	assert.Equal(t, model.LiveEventEnded, ended.Event)
+	assert.Equal(t, 3600, ended.DurationSeconds)
+
+	invite := parseLiveEvent(&client.MessageInviteVideoChatParticipants{GroupCallId: 9, UserIds: []int64{1, 2}}, occurredAt)
+	require.NotNil(t, invite)
+	assert.Equal(t, 2, invite.InvitedCount)
+
+	assert.Nil(t, parseLiveEvent(&client.MessageText{}, occurredAt))
+}
+
+func TestParseServiceEvent(t *testing.T) {
+	occurredAt := time.Unix(1700000000, 0)
+	admin := &client.MessageSenderUser{UserId: 42}
+
+	pin := parseServiceEvent(&client.Message{SenderId: admin, Content: &client.MessagePinMessage{MessageId: 1048576}}, occurredAt)
+	require.NotNil(t, pin)
+	assert.Equal(t, model.ServiceEventPinMessage, pin.Event)
+	assert.Equal(t, int64(1048576), pin.MessageID)
+	assert.Equal(t, "42", pin.ActorID)
+	assert.Equal(t, occurredAt, pin.OccurredAt)
+
+	retitle := parseServiceEvent(&client.Message{
+		SenderId: &client.MessageSenderChat{ChatId: -1001},
+		Content:  &client.MessageChatChangeTitle{Title: "Renamed Channel"},
+	}, occurredAt)
+	require.NotNil(t, retitle)
+	assert.Equal(t, model.ServiceEventChangeTitle, retitle.Event)
+	assert.Equal(t, "Renamed Channel", retitle.Title)
+	assert.Equal(t, "-1001", retitle.ActorID)
+
+	added := parseServiceEvent(&client.Message{SenderId: admin, Content: &client.MessageChatAddMembers{MemberUserIds: []int64{7, 8}}}, occurredAt)
+	require.NotNil(t, added)
+	assert.Equal(t, []string{"7", "8"}, added.UserIDs)
+
+	assert.Nil(t, parseServiceEvent(&client.Message{Content: &client.MessageText{}}, occurredAt))
+}
+
+func TestParseMessage_ServiceEvent(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	message := &client.Message{
+		Id:       5,
+		ChatId:   chat.Id,
+		Date:     1700000000,
+		SenderId: &client.MessageSenderChat{ChatId: chat.Id},
+		Content:  &client.MessageChatChangeTitle{Title: "Example Reloaded"},
+	}
+	mlr := &client.MessageLink{Link: "https://t.me/example/5"}
+
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.ServiceEvent)
+	assert.Equal(t, model.ServiceEventChangeTitle, post.ServiceEvent.Event)
+	assert.Equal(t, "Example Reloaded", post.ServiceEvent.Title)
+	assert.Equal(t, time.Unix(1700000000, 0), post.ServiceEvent.OccurredAt)
+	assert.Equal(t, []string{"messageChatChangeTitle"}, post.PostType)
+}
+
+func TestParseMessage_EditTimestamps(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	published := time.Unix(1700000000, 0)
+
+	unedited := &client.Message{
+		Id:      1,
+		ChatId:  chat.Id,
+		Date:    int32(published.Unix()),
+		Content: &client.MessageText{Text: &client.FormattedText{Text: "original"}},
+	}
+	post, err := ParseMessage("crawl", unedited, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, published, post.CreatedAt, "An unedited post should be created when it was published")
+	assert.Nil(t, post.EditedAt)
+	assert.False(t, post.WasEdited)
+
+	edited := *unedited
+	edited.EditDate = int32(published.Add(2 * time.Hour).Unix())
+	post, err = ParseMessage("crawl", &edited, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.EditedAt)
+	assert.Equal(t, published.Add(2*time.Hour), *post.EditedAt)
+	assert.Equal(t, *post.EditedAt, post.CreatedAt)
+	assert.Equal(t, published, post.PublishedAt)
+	assert.True(t, post.WasEdited)
+}
+
+// failingGetMessageClient can't fetch messages, so the share count of a post
+// can't be looked up
+type failingGetMessageClient struct {
+	MockTDLibClient
+}
+
+func (f *failingGetMessageClient) GetMessage(req *client.GetMessageRequest) (*client.Message, error) {
+	return nil, fmt.Errorf("message %d not found", req.MessageId)
+}
+
+func TestParseMessage_ShareCountFallsBackToForwardCount(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	message := &client.Message{
+		Id:              1,
+		ChatId:          chat.Id,
+		Date:            int32(time.Now().Unix()),
+		Content:         &client.MessageText{Text: &client.FormattedText{Text: "hello"}},
+		InteractionInfo: &client.MessageInteractionInfo{ViewCount: 100, ForwardCount: 12},
+	}
+
+	post, err := ParseMessage("crawl", message, &client.MessageLink{Link: "https://t.me/example/1"}, chat, nil, nil, 0, 0, "example", &failingGetMessageClient{}, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, 12, post.ShareCount, "The forward count should be used when the share count can't be fetched")
+	assert.Equal(t, 12, post.SharesCount())
+}
+
+func TestParseMessage_CountAliasesMatchCanonicalCounts(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	message := &client.Message{
+		Id:              1,
+		ChatId:          chat.Id,
+		Date:            int32(time.Now().Unix()),
+		Content:         &client.MessageText{Text: &client.FormattedText{Text: "hello"}},
+		InteractionInfo: &client.MessageInteractionInfo{ViewCount: 100, ForwardCount: 12},
+	}
+
+	post, err := ParseMessage("crawl", message, &client.MessageLink{Link: "https://t.me/example/1"}, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, 100, post.ViewCount)
+	assert.Equal(t, 12, post.ShareCount)
+	assert.Equal(t, post.ViewCount, post.ViewsCount())
+	assert.Equal(t, post.LikeCount, post.LikesCount())
+	assert.Equal(t, post.ShareCount, post.SharesCount())
+	assert.Equal(t, post.CommentCount, post.CommentsCount())
+
+	data, err := json.Marshal(post)
+	require.NoError(t, err)
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(data, &record))
+	assert.Equal(t, record["view_count"], record["views_count"])
+	assert.Equal(t, record["like_count"], record["likes_count"])
+	assert.Equal(t, record["share_count"], record["shares_count"])
+	assert.Equal(t, record["comment_count"], record["comments_count"])
+}
+
+func TestParseMessage_PostDateWindow(t *testing.T) {
+	cfg := common.CrawlerConfig{
+		MinPostDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		MaxPostDate: time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC),
+	}
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+
+	tests := []struct {
+		name     string
+		date     time.Time
+		wantPost bool
+	}{
+		{"before window", time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), false},
+		{"inside window", time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC), true},
+		{"after window", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := &client.Message{
+				Id:      1,
+				ChatId:  chat.Id,
+				Date:    int32(tt.date.Unix()),
+				Content: &client.MessageText{Text: &client.FormattedText{Text: "hello"}},
+			}
+			mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+
+			post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, cfg)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantPost, post.PostUID != "", "Only posts inside the window should be parsed")
+		})
+	}
+
+	// Without bounds every date is accepted
+	assert.True(t, withinPostDateWindow(time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC), common.CrawlerConfig{}))
+}
+
+func TestWithinPostDateWindow_MaxPostDateIsInclusive(t *testing.T) {
+	cfg := common.CrawlerConfig{MaxPostDate: common.EndOfDay(time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC))}
+
+	assert.True(t, withinPostDateWindow(time.Date(2022, 12, 31, 23, 59, 59, 0, time.UTC), cfg), "Posts on the max date should be kept")
+	assert.False(t, withinPostDateWindow(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), cfg), "Posts on the next day should be dropped")
+}
+
+func TestParseMessage_LocationAndVenue(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+
+	location := &client.Message{
+		Id:     1,
+		ChatId: chat.Id,
+		Date:   int32(time.Now().Unix()),
+		Content: &client.MessageLocation{
+			Location:   &client.Location{Latitude: 50.4501, Longitude: 30.5234},
+			LivePeriod: 900,
+		},
+	}
+	post, err := ParseMessage("crawl", location, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.Latitude)
+	require.NotNil(t, post.Longitude)
+	assert.Equal(t, 50.4501, *post.Latitude)
+	assert.Equal(t, 30.5234, *post.Longitude)
+	assert.Equal(t, 900, post.LivePeriod)
+	assert.Empty(t, post.VenueName)
+
+	venue := &client.Message{
+		Id:     2,
+		ChatId: chat.Id,
+		Date:   int32(time.Now().Unix()),
+		Content: &client.MessageVenue{Venue: &client.Venue{
+			Location: &client.Location{Latitude: 48.8584, Longitude: 2.2945},
+			Title:    "Eiffel Tower",
+			Address:  "Champ de Mars, 5 Av. Anatole France, Paris",
+		}},
+	}
+	post, err = ParseMessage("crawl", venue, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.Latitude)
+	assert.Equal(t, 48.8584, *post.Latitude)
+	assert.Equal(t, "Eiffel Tower", post.VenueName)
+	assert.Equal(t, "Champ de Mars, 5 Av. Anatole France, Paris", post.VenueAddress)
+	assert.Zero(t, post.LivePeriod)
+
+	// Posts without a location leave the coordinates unset
+	text := &client.Message{Id: 3, ChatId: chat.Id, Date: int32(time.Now().Unix()), Content: &client.MessageText{Text: &client.FormattedText{Text: "hi"}}}
+	post, err = ParseMessage("crawl", text, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, post.Latitude)
+}
+
+func TestParseMessage_Contact(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+
+	message := &client.Message{
+		Id:     1,
+		ChatId: chat.Id,
+		Date:   int32(time.Now().Unix()),
+		Content: &client.MessageContact{Contact: &client.Contact{
+			FirstName:   "Jane",
+			LastName:    "Doe",
+			PhoneNumber: "+15550100",
+			UserId:      424242,
+		}},
+	}
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.Contact)
+	assert.Equal(t, model.ContactData{FirstName: "Jane", LastName: "Doe", PhoneNumber: "+15550100", UserID: "424242"}, *post.Contact)
+	assert.Equal(t, "Jane Doe", post.Description)
+
+	// Contacts without a Telegram account have no user ID
+	message.Content = &client.MessageContact{Contact: &client.Contact{FirstName: "Front desk", PhoneNumber: "+15550199"}}
+	post, err = ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.Contact)
+	assert.Empty(t, post.Contact.UserID)
+	assert.Equal(t, "+15550199", post.Contact.PhoneNumber)
+	assert.Equal(t, "Front desk", post.Description)
+}
+
+func TestParseMessage_RedactsContacts(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	message := &client.Message{
+		Id:     1,
+		ChatId: chat.Id,
+		Date:   int32(time.Now().Unix()),
+		Content: &client.MessageContact{Contact: &client.Contact{
+			FirstName:   "Jane",
+			LastName:    "Doe",
+			PhoneNumber: "+15550100",
+			UserId:      424242,
+		}},
+	}
+	redaction := common.RedactionConfig{Fields: []string{common.RedactFieldPhone, common.RedactFieldContact}, Salt: "s"}
+
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{Redaction: redaction})
+	require.NoError(t, err)
+	require.NotNil(t, post.Contact)
+	assert.Equal(t, redaction.Pseudonymize("+15550100"), post.Contact.PhoneNumber)
+	assert.Equal(t, redaction.Pseudonymize("424242"), post.Contact.UserID)
+
+	data, err := json.Marshal(post)
+	require.NoError(t, err)
+	for _, pii := range []string{"+15550100", "Jane", "Doe", "424242"} {
+		assert.NotContains(t, string(data), pii, "The contact should not be stored in the clear anywhere in the post")
+	}
+	assert.Equal(t, redaction.Pseudonymize("Jane")+" "+redaction.Pseudonymize("Doe"), post.Description)
+	assert.Equal(t, post.Description, post.AllText, "The search text should be built from the redacted contact")
+
+	// Only the phone number
+	redaction.Fields = []string{common.RedactFieldPhone}
+	post, err = ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{Redaction: redaction})
+	require.NoError(t, err)
+	assert.Equal(t, redaction.Pseudonymize("+15550100"), post.Contact.PhoneNumber)
+	assert.Equal(t, "Jane Doe", post.Description)
+}
+
+func TestParseMessage_DiceAndGame(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+
+	message := &client.Message{
+		Id:      1,
+		ChatId:  chat.Id,
+		Date:    int32(time.Now().Unix()),
+		Content: &client.MessageDice{Emoji: "🎯", Value: 6},
+	}
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.Dice)
+	assert.Equal(t, model.DiceData{Emoji: "🎯", Value: 6}, *post.Dice)
+	assert.Equal(t, "🎯", post.Description)
+	assert.Nil(t, post.Game)
+
+	message.Content = &client.MessageGame{Game: &client.Game{
+		ShortName:   "tower",
+		Title:       "Tower Builder",
+		Description: "Stack blocks as high as you can",
+		Text:        &client.FormattedText{Text: "1. alice 42"},
+	}}
+	post, err = ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.Game)
+	assert.Equal(t, model.GameData{
+		ShortName:   "tower",
+		Title:       "Tower Builder",
+		Description: "Stack blocks as high as you can",
+		Text:        "1. alice 42",
+	}, *post.Game)
+	assert.Equal(t, "Tower Builder", post.Description)
+	assert.Nil(t, post.Dice)
+}
+
+// remoteFileRecorder records the remote file IDs whose download is requested.
+// Each remote file is a separate file in dir, with the unique ID
+// "unique-<remote ID>". It is safe for concurrent use.
+type remoteFileRecorder struct {
+	MockTDLibClient
+	dir       string
+	mu        sync.Mutex
+	remoteIDs []string
+}
+
+func (r *remoteFileRecorder) GetRemoteFile(req *client.GetRemoteFileRequest) (*client.File, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remoteIDs = append(r.remoteIDs, req.RemoteFileId)
+	return &client.File{Id: int32(len(r.remoteIDs)), Remote: &client.RemoteFile{Id: req.RemoteFileId, UniqueId: "unique-" + req.RemoteFileId}}, nil
+}
+
+func (r *remoteFileRecorder) DownloadFile(req *client.DownloadFileRequest) (*client.File, error) {
+	path := filepath.Join(r.dir, fmt.Sprintf("file-%d", req.FileId))
+	if err := os.WriteFile(path, []byte(path), 0644); err != nil {
+		return nil, err
+	}
+	return &client.File{Id: req.FileId, Local: &client.LocalFile{Path: path}}, nil
+}
+
+func (r *remoteFileRecorder) GetMessage(req *client.GetMessageRequest) (*client.Message, error) {
+	return &client.Message{Id: req.MessageId, ChatId: req.ChatId}, nil
+}
+
+func TestParseMessage_VideoNoteAndDocumentDownloadTheFile(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	thumbnail := &client.Thumbnail{File: &client.File{Id: 2, Remote: &client.RemoteFile{Id: "remote-thumb"}}}
+
+	tests := []struct {
+		name     string
+		content  client.MessageContent
+		remoteID string
+	}{
+		{
+			name: "video note",
+			content: &client.MessageVideoNote{VideoNote: &client.VideoNote{
+				Thumbnail: thumbnail,
+				Video:     &client.File{Id: 3, Remote: &client.RemoteFile{Id: "remote-video"}},
+			}},
+			remoteID: "remote-video",
+		},
+		{
+			name: "document",
+			content: &client.MessageDocument{Document: &client.Document{
+				FileName:  "report.pdf",
+				Thumbnail: thumbnail,
+				Document:  &client.File{Id: 4, Remote: &client.RemoteFile{Id: "remote-document"}},
+			}},
+			remoteID: "remote-document",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tdlibClient := &remoteFileRecorder{dir: t.TempDir()}
+
+			message := &client.Message{Id: 1, ChatId: chat.Id, Date: int32(time.Now().Unix()), Content: tt.content}
+			post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, newTestStateManager(t), common.CrawlerConfig{})
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, []string{"remote-thumb", tt.remoteID}, tdlibClient.remoteIDs, "The thumbnail and the file itself should be downloaded")
+			assert.Equal(t, "unique-"+tt.remoteID, post.MediaURL)
+			assert.Len(t, post.MediaStorageKeys, 2)
+		})
+	}
+}
+
+func TestParseMessage_MediaTypesRestrictDownloads(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	cfg := common.CrawlerConfig{MediaTypes: []string{"photo"}}
+
+	tdlibClient := &remoteFileRecorder{dir: t.TempDir()}
+	sm := newTestStateManager(t)
+
+	video := &client.Message{Id: 1, ChatId: chat.Id, Date: int32(time.Now().Unix()), Content: &client.MessageVideo{
+		Caption: &client.FormattedText{Text: "watch this"},
+		Video: &client.Video{
+			Duration:  12,
+			Thumbnail: &client.Thumbnail{File: &client.File{Id: 2, Remote: &client.RemoteFile{Id: "remote-thumb"}}},
+			Video:     &client.File{Id: 3, Remote: &client.RemoteFile{Id: "remote-video"}},
+		},
+	}}
+	post, err := ParseMessage("crawl", video, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, sm, cfg)
+	require.NoError(t, err)
+	assert.Empty(t, tdlibClient.remoteIDs, "Video media should not be downloaded when only photos are configured")
+	assert.Empty(t, post.MediaStorageKeys)
+	assert.Equal(t, "watch this", post.Description, "The description of a skipped type should still be captured")
+	assert.Equal(t, []string{"messageVideo"}, post.PostType)
+
+	photo := &client.Message{Id: 2, ChatId: chat.Id, Date: int32(time.Now().Unix()), Content: &client.MessagePhoto{
+		Caption: &client.FormattedText{Text: "look at this"},
+		Photo: &client.Photo{Sizes: []*client.PhotoSize{
+			{Photo: &client.File{Id: 4, Remote: &client.RemoteFile{Id: "remote-photo"}}},
+		}},
+	}}
+	post, err = ParseMessage("crawl", photo, &client.MessageLink{Link: "https://t.me/example/2"}, chat, nil, nil, 0, 0, "example", tdlibClient, sm, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"remote-photo"}, tdlibClient.remoteIDs, "Photos should still be downloaded")
+	assert.Len(t, post.MediaStorageKeys, 1)
+}
+
+func TestParseMessage_VideoMetadata(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+
+	message := &client.Message{Id: 1, ChatId: chat.Id, Date: int32(time.Now().Unix()), Content: &client.MessageVideo{
+		Caption: &client.FormattedText{Text: "watch this"},
+		Video: &client.Video{
+			Duration:  95,
+			Width:     1920,
+			Height:    1080,
+			FileName:  "clip.mp4",
+			MimeType:  "video/mp4",
+			Thumbnail: &client.Thumbnail{File: &client.File{Id: 2, Remote: &client.RemoteFile{Id: "remote-thumb"}}},
+			Video:     &client.File{Id: 3, Size: 7340032, Remote: &client.RemoteFile{Id: "remote-video"}},
+		},
+	}}
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.Video)
+	assert.Equal(t, model.VideoData{
+		DurationSeconds: 95,
+		Width:           1920,
+		Height:          1080,
+		FileName:        "clip.mp4",
+		FileSize:        7340032,
+		MimeType:        "video/mp4",
+	}, *post.Video)
+	require.NotNil(t, post.VideoLength)
+	assert.Equal(t, 95, *post.VideoLength)
+	assert.Equal(t, "watch this", post.Description)
+
+	// A video without a thumbnail is reported, but its metadata is kept
+	message.Content.(*client.MessageVideo).Video.Thumbnail = nil
+	post, err = ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.Video)
+	assert.Equal(t, 1920, post.Video.Width)
+	assert.Equal(t, 1080, post.Video.Height)
+	assert.Len(t, post.MediaErrors, 1)
+}
+
+func TestParseMessage_VoiceNoteAndAudio(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+
+	tests := []struct {
+		name    string
+		content client.MessageContent
+		want    model.AudioData
+	}{
+		{
+			name: "voice note",
+			content: &client.MessageVoiceNote{
+				Caption: &client.FormattedText{Text: "listen to this"},
+				VoiceNote: &client.VoiceNote{
+					Duration: 42,
+					MimeType: "audio/ogg",
+					Voice:    &client.File{Id: 3, Remote: &client.RemoteFile{Id: "remote-voice"}},
+				},
+			},
+			want: model.AudioData{Kind: model.AudioKindVoiceNote, DurationSeconds: 42, MimeType: "audio/ogg"},
+		},
+		{
+			name: "audio",
+			content: &client.MessageAudio{
+				Caption: &client.FormattedText{Text: "listen to this"},
+				Audio: &client.Audio{
+					Duration:  180,
+					Title:     "Anthem",
+					Performer: "Choir",
+					FileName:  "anthem.mp3",
+					MimeType:  "audio/mpeg",
+					Audio:     &client.File{Id: 4, Remote: &client.RemoteFile{Id: "remote-audio"}},
+				},
+			},
+			want: model.AudioData{Kind: model.AudioKindAudio, DurationSeconds: 180, MimeType: "audio/mpeg", Performer: "Choir", Title: "Anthem", FileName: "anthem.mp3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloaded := filepath.Join(t.TempDir(), "audio.ogg")
+			require.NoError(t, os.WriteFile(downloaded, []byte("data"), 0644))
+			tdlibClient := &flakyDownloadClient{downloadedPath: downloaded}
+
+			message := &client.Message{Id: 1, ChatId: chat.Id, Date: int32(time.Now().Unix()), Content: tt.content}
+			post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, newTestStateManager(t), common.CrawlerConfig{})
+			require.NoError(t, err)
+
+			assert.Equal(t, "listen to this", post.Description)
+			require.NotNil(t, post.Audio)
+			assert.Equal(t, tt.want, *post.Audio)
+			assert.Equal(t, "unique-1", post.MediaURL, "The audio file should be downloaded and referenced")
+			assert.Equal(t, 1, tdlibClient.downloadCalls)
+		})
+	}
+}
+
+func TestParsePoll(t *testing.T) {
+	poll := &client.Poll{
+		Question: &client.FormattedText{Text: "Favourite colour?"},
+		Options: []*client.PollOption{
+			{Text: &client.FormattedText{Text: "Red"}, VoterCount: 5, VotePercentage: 50},
+			{Text: &client.FormattedText{Text: "Green"}, VoterCount: 3, VotePercentage: 30},
+			{Text: &client.FormattedText{Text: "Blue"}, VoterCount: 2, VotePercentage: 20},
+		},
+		TotalVoterCount: 10,
+		IsAnonymous:     true,
+		Type:            &client.PollTypeRegular{AllowMultipleAnswers: true},
+	}
+
+	data := parsePoll(poll)
+	assert.Equal(t, "Favourite colour?", data.Question)
+	assert.Equal(t, 10, data.TotalVoterCount)
+	assert.True(t, data.IsAnonymous)
+	assert.False(t, data.IsQuiz)
+	assert.True(t, data.AllowMultipleAnswers)
+	assert.Equal(t, []model.PollOption{
+		{Text: "Red", VoterCount: 5, VotePercentage: 50},
+		{Text: "Green", VoterCount: 3, VotePercentage: 30},
+		{Text: "Blue", VoterCount: 2, VotePercentage: 20},
+	}, data.Options)
+	assert.Nil(t, data.CorrectOptionIndex)
+
+	poll.Type = &client.PollTypeQuiz{CorrectOptionId: 1}
+	quiz := parsePoll(poll)
+	assert.True(t, quiz.IsQuiz)
+	require.NotNil(t, quiz.CorrectOptionIndex)
+	assert.Equal(t, 1, *quiz.CorrectOptionIndex)
+
+	poll.Type = &client.PollTypeQuiz{CorrectOptionId: -1}
+	assert.Nil(t, parsePoll(poll).CorrectOptionIndex, "Unknown quiz answers should be left unset")
+}
+
+func TestRemoveMultimedia_NotADirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "video.mp4")
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+
+	err := removeMultimedia(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a directory")
+	assert.FileExists(t, path, "A file passed by mistake must not be removed")
+}
+
+func TestRemoveMultimedia_MissingDirectory(t *testing.T) {
+	assert.NoError(t, removeMultimedia(filepath.Join(t.TempDir(), "missing")))
+}
+
+func TestRemoveMultimedia_EmptyDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, removeMultimedia(dir))
+	assert.DirExists(t, dir)
+}
+
+func TestRemoveMultimedia_PopulatedDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "videos", "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "photo.jpg"), []byte("p"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "videos", "a.mp4"), []byte("v"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "videos", "nested", "b.mp4"), []byte("v"), 0644))
+
+	require.NoError(t, removeMultimedia(dir))
+
+	assert.DirExists(t, dir, "The directory itself should be kept")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestPurgeMediaDownloadDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "leftover.mp4"), []byte("v"), 0644))
+
+	PurgeMediaDownloadDir(common.CrawlerConfig{MediaDownloadDir: dir})
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "Media left by an earlier run should be removed")
+
+	assert.NotPanics(t, func() { PurgeMediaDownloadDir(common.CrawlerConfig{}) }, "Nothing is purged without a download directory")
+}
+
+// newTarballServer serves a tarball containing one file and its sha256sum-style
+// sidecar at "/db.tar.gz.sha256".
+func newTarballServer(t *testing.T, sidecar func(digest string) string) (*httptest.Server, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzWriter)
+	content := []byte("test file content")
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "td.binlog", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tarWriter.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzWriter.Close())
+
+	sum := sha256.Sum256(buf.Bytes())
+	digest := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/db.tar.gz":
+			w.Write(buf.Bytes())
+		case "/db.tar.gz.sha256":
+			fmt.Fprint(w, sidecar(digest))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, digest
+}
+
+func TestDownloadAndExtractTarball_ChecksumMismatchAbortsExtraction(t *testing.T) {
+	server, _ := newTarballServer(t, func(string) string { return "" })
+	targetDir := t.TempDir()
+
+	wrong := hex.EncodeToString(make([]byte, sha256.Size))
+	err := downloadAndExtractTarball(context.Background(), nil, server.URL+"/db.tar.gz", targetDir, wrong)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+
+	entries, err := os.ReadDir(targetDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "Nothing should be extracted from an archive with the wrong checksum")
+}
+
+func TestDownloadAndExtractTarball_SidecarChecksum(t *testing.T) {
+	server, digest := newTarballServer(t, func(digest string) string { return digest + "  db.tar.gz\n" })
+	targetDir := t.TempDir()
+
+	expected, err := fetchTarballChecksum(context.Background(), nil, server.URL+"/db.tar.gz")
+	require.NoError(t, err)
+	assert.Equal(t, digest, expected)
+
+	require.NoError(t, downloadAndExtractTarball(context.Background(), nil, server.URL+"/db.tar.gz", targetDir, strings.ToUpper(expected)))
+	assert.FileExists(t, filepath.Join(targetDir, "td.binlog"))
+}
+
+func TestFetchTarballChecksum_InvalidSidecar(t *testing.T) {
+	server, _ := newTarballServer(t, func(string) string { return "not-a-digest\n" })
+	_, err := fetchTarballChecksum(context.Background(), nil, server.URL+"/db.tar.gz")
+	assert.Error(t, err)
+
+	_, err = fetchTarballChecksum(context.Background(), nil, server.URL+"/missing.tar.gz")
+	assert.Error(t, err)
+}
+
+func TestDownloadAndExtractTarball_ResumesInterruptedDownload(t *testing.T) {
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzWriter)
+	content := bytes.Repeat([]byte("session data "), 4096)
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "td.binlog", Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tarWriter.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzWriter.Close())
+	tarball := buf.Bytes()
+	half := len(tarball) / 2
+
+	previous := tarballRetryDelay
+	tarballRetryDelay = 0
+	t.Cleanup(func() { tarballRetryDelay = previous })
+
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			// Announce the full tarball but drop the connection halfway through
+			w.Header().Set("Content-Length", strconv.Itoa(len(tarball)))
+			w.Write(tarball[:half])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "db.tar.gz", time.Time{}, bytes.NewReader(tarball))
+	}))
+	defer server.Close()
+
+	targetDir := filepath.Join(t.TempDir(), "session")
+	require.NoError(t, downloadAndExtractTarball(context.Background(), nil, server.URL+"/db.tar.gz", targetDir, ""))
+	assert.Equal(t, []string{"", fmt.Sprintf("bytes=%d-", half)}, ranges, "The second request should only ask for the missing bytes")
+
+	data, err := os.ReadFile(filepath.Join(targetDir, "td.binlog"))
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+	assert.NoFileExists(t, targetDir+".tar.gz.part", "The partial file should be removed after extraction")
+}
+
+func TestParseMessage_RunsPostProcessorsBeforeStoring(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	message := &client.Message{
+		Id:      1,
+		ChatId:  chat.Id,
+		Date:    int32(time.Now().Unix()),
+		Content: &client.MessageText{Text: &client.FormattedText{Text: "Breaking NEWS"}},
+	}
+
+	var stored []model.Post
+	cfg := common.CrawlerConfig{
+		PostProcessors: enrich.Chain{
+			enrich.PostProcessorFunc(func(ctx context.Context, post *model.Post) error {
+				post.SearchableText = strings.ToLower(post.Description)
+				return nil
+			}),
+		},
+		PostSinks: []sink.PostSink{sink.PostSinkFunc(func(ctx context.Context, post model.Post) error {
+			stored = append(stored, post)
+			return nil
+		})},
+	}
+
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "breaking news", post.SearchableText)
+	require.Len(t, stored, 1)
+	assert.Equal(t, "breaking news", stored[0].SearchableText, "The processed post should be the one stored")
+}
+
+func TestParseMessage_SkipsInvalidPosts(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+
+	var stored []model.Post
+	cfg := common.CrawlerConfig{
+		PostSinks: []sink.PostSink{sink.PostSinkFunc(func(ctx context.Context, post model.Post) error {
+			stored = append(stored, post)
+			return nil
+		})},
+	}
+
+	// A message without a date would be stored as published in 1970
+	undated := &client.Message{
+		Id:      1,
+		ChatId:  chat.Id,
+		Content: &client.MessageText{Text: &client.FormattedText{Text: "no date"}},
+	}
+	_, err := ParseMessage("crawl", undated, mlr, chat, nil, nil, 0, 0, "example", nil, nil, cfg)
+	assert.ErrorIs(t, err, model.ErrInvalidPost)
+	assert.Empty(t, stored, "An invalid post should not reach the sinks")
+
+	dated := *undated
+	dated.Date = int32(time.Now().Unix())
+	_, err = ParseMessage("crawl", &dated, mlr, chat, nil, nil, 0, 0, "example", nil, nil, cfg)
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+}
+
+func TestParseMessage_SkipsStoredPostsBeforeDownloadingMedia(t *testing.T) {
+	sm := newTestStateManager(t)
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	messages, links := albumMessages()
+
+	first := &remoteFileRecorder{dir: t.TempDir()}
+	_, err := ParseMessage("test-crawl", messages[0], links[0], chat, nil, nil, 0, 0, "example", first, sm, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.Len(t, first.remoteIDs, 1)
+	require.True(t, sm.HasPost("test-crawl", "1-example"))
+
+	again := &remoteFileRecorder{dir: t.TempDir()}
+	post, err := ParseMessage("test-crawl", messages[0], links[0], chat, nil, nil, 0, 0, "example", again, sm, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Empty(t, post.PostUID)
+	assert.Empty(t, again.remoteIDs, "A stored post's media should not be downloaded again")
+
+	_, err = ParseAlbum("test-crawl", messages, links, chat, nil, nil, 0, 0, "example", again, sm, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Empty(t, again.remoteIDs, "An album stored under one of its messages should be skipped")
+}
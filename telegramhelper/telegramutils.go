@@ -22,7 +22,18 @@ func FetchChannelMessagesWithDateRange(tdlibClient crawler.TDLibClient, chatID i
 }
 
 func FetchChannelMessagesWithSampling(tdlibClient crawler.TDLibClient, chatID int64, page *state.Page, minPostDate time.Time, maxPostDate time.Time, maxPosts int, sampleSize int) ([]*client.Message, error) {
+	return FetchChannelMessagesAfter(tdlibClient, chatID, page, minPostDate, maxPostDate, maxPosts, sampleSize, 0)
+}
+
+// FetchChannelMessagesAfter fetches the channel's messages like
+// FetchChannelMessagesWithSampling, but stops at the first message whose ID is
+// not greater than afterMessageID, so an incremental crawl only sees messages
+// posted since the previous one. An afterMessageID of 0 fetches everything.
+func FetchChannelMessagesAfter(tdlibClient crawler.TDLibClient, chatID int64, page *state.Page, minPostDate time.Time, maxPostDate time.Time, maxPosts int, sampleSize int, afterMessageID int64) ([]*client.Message, error) {
 	log.Debug().Msgf("Fetching messages for channel %s since %s", page.URL, minPostDate.Format("2006-01-02 15:04:05"))
+	if afterMessageID > 0 {
+		log.Debug().Msgf("Only fetching messages newer than message ID %d", afterMessageID)
+	}
 	if !maxPostDate.IsZero() {
 		log.Debug().Msgf("Max post date filter: %s", maxPostDate.Format("2006-01-02 15:04:05"))
 	}
@@ -57,6 +68,13 @@ func FetchChannelMessagesWithSampling(tdlibClient crawler.TDLibClient, chatID in
 		// Check messages and add only those within the date range
 		reachedOldMessages := false
 		for _, msg := range chatHistory.Messages {
+			// History is returned newest first, so everything from here on was seen by the last crawl
+			if afterMessageID > 0 && msg.Id <= afterMessageID {
+				log.Debug().Msgf("Reached message %d already seen by the previous crawl", msg.Id)
+				reachedOldMessages = true
+				break
+			}
+
 			msgUnix := int64(msg.Date)
 			
 			// Compare message timestamp with minPostDate
@@ -226,9 +244,14 @@ func GetViewCount(message *client.Message, channelname string) int {
 func GetMessageShareCount(tdlibClient crawler.TDLibClient, chatID, messageID int64, channelname string) (int, error) {
 	// Fetch the message details
 	log.Debug().Msgf("Getting message share count for channel %s", channelname)
-	message, err := tdlibClient.GetMessage(&client.GetMessageRequest{
-		ChatId:    chatID,
-		MessageId: messageID,
+	var message *client.Message
+	err := withFloodWait("get_message", func() error {
+		var err error
+		message, err = tdlibClient.GetMessage(&client.GetMessageRequest{
+			ChatId:    chatID,
+			MessageId: messageID,
+		})
+		return err
 	})
 	if err != nil {
 		return 0, err
@@ -275,6 +298,10 @@ func GetTotalChannelViews(tdlibClient crawler.TDLibClient, messages []*client.Me
 // - tdlibClient: A pointer to the TDLib client used to interact with Telegram.
 // - chatID: The ID of the chat containing the message.
 // - messageID: The ID of the message whose comments are to be fetched.
+// - maxcomments: The most comments to fetch; -1 fetches all and 0 fetches none.
+// - commentcount: The reply count reported on the message, used to size the last batch.
+// - captureSenderFlags: Whether to resolve each commenter's premium/verified/scam flags (one extra API call per comment).
+// - replyDepth: How many levels of each comment's reply chain to resolve, fetching parents that were not fetched; they count against maxcomments. Every comment is kept whatever its depth. 0 resolves nothing.
 //
 // Returns:
 // - A slice of Comment structs representing the comments in the message thread.
@@ -282,7 +309,7 @@ func GetTotalChannelViews(tdlibClient crawler.TDLibClient, messages []*client.Me
 //
 // The function fetches comments in batches of up to 100 and continues until no more comments are available.
 // It extracts the text, reactions, view count, and reply count for each comment.
-func GetMessageComments(tdlibClient crawler.TDLibClient, chatID, messageID int64, channelname string, maxcomments int, commentcount int) ([]model.Comment, error) {
+func GetMessageComments(tdlibClient crawler.TDLibClient, chatID, messageID int64, channelname string, maxcomments int, commentcount int, captureSenderFlags bool, replyDepth int) ([]model.Comment, error) {
 	// Check if tdlibClient is nil
 	if tdlibClient == nil {
 		log.Error().
@@ -293,6 +320,11 @@ func GetMessageComments(tdlibClient crawler.TDLibClient, chatID, messageID int64
 		return nil, fmt.Errorf("tdlibClient is nil")
 	}
 
+	// A cap of zero means comments are not wanted; avoid the thread history calls
+	if maxcomments == 0 {
+		return []model.Comment{}, nil
+	}
+
 	log.Debug().
 		Str("channel", channelname).
 		Int64("chatID", chatID).
@@ -304,6 +336,7 @@ func GetMessageComments(tdlibClient crawler.TDLibClient, chatID, messageID int64
 	// Fetch the comments in the thread
 	comments := make([]model.Comment, 0)
 	var fromMessageId int64 = 0
+	var threadChatID int64 // The discussion group the comments live in
 	done := false
 	iterationCount := 0
 
@@ -451,12 +484,17 @@ func GetMessageComments(tdlibClient crawler.TDLibClient, chatID, messageID int64
 			Int("iteration", iterationCount).
 			Msg("Attempting to get message thread history")
 
-		// Get the message thread history with proper batch size
-		threadHistory, err = tdlibClient.GetMessageThreadHistory(&client.GetMessageThreadHistoryRequest{
-			ChatId:        chatID,
-			MessageId:     messageID,
-			FromMessageId: fromMessageId,
-			Limit:         int32(batchSize), // Fetch comments in appropriate batch size
+		// Get the message thread history with proper batch size,
+		// waiting out any flood-wait TDLib imposes instead of losing the batch
+		err = withFloodWait("get_message_thread_history", func() error {
+			var historyErr error
+			threadHistory, historyErr = tdlibClient.GetMessageThreadHistory(&client.GetMessageThreadHistoryRequest{
+				ChatId:        chatID,
+				MessageId:     messageID,
+				FromMessageId: fromMessageId,
+				Limit:         int32(batchSize), // Fetch comments in appropriate batch size
+			})
+			return historyErr
 		})
 
 		// Log the result of the GetMessageThreadHistory call
@@ -556,53 +594,8 @@ func GetMessageComments(tdlibClient crawler.TDLibClient, chatID, messageID int64
 				continue
 			}
 
-			comment := model.Comment{}
-
-			// Safely get username
-			username := GetPoster(tdlibClient, msg)
-			comment.Handle = username
-
-			// Safely extract message text
-			messageText := ""
-			if msg.Content != nil {
-				if textContent, ok := msg.Content.(*client.MessageText); ok && textContent != nil && textContent.Text != nil {
-					messageText = textContent.Text.Text
-					comment.Text = textContent.Text.Text
-				}
-			}
-
-			// Safely extract reactions
-			reactionCount := 0
-			if msg.InteractionInfo != nil &&
-				msg.InteractionInfo.Reactions != nil &&
-				len(msg.InteractionInfo.Reactions.Reactions) > 0 {
-
-				comment.Reactions = make(map[string]int)
-				reactionCount = len(msg.InteractionInfo.Reactions.Reactions)
-
-				for _, reaction := range msg.InteractionInfo.Reactions.Reactions {
-					if reaction.Type != nil {
-						if emojiReaction, ok := reaction.Type.(*client.ReactionTypeEmoji); ok && emojiReaction != nil {
-							comment.Reactions[emojiReaction.Emoji] = int(reaction.TotalCount)
-						}
-					}
-				}
-			}
-
-			// Safely extract view count and reply count
-			viewCount := 0
-			replyCount := 0
-			if msg.InteractionInfo != nil {
-				// Extract reply count
-				if msg.InteractionInfo.ReplyInfo != nil {
-					replyCount = int(msg.InteractionInfo.ReplyInfo.ReplyCount)
-					comment.ReplyCount = replyCount
-				}
-
-				// Extract view count
-				viewCount = int(msg.InteractionInfo.ViewCount)
-				comment.ViewCount = viewCount
-			}
+			threadChatID = msg.ChatId
+			comment := commentFromMessage(tdlibClient, msg, captureSenderFlags)
 
 			// Log message processing details
 			log.Debug().
@@ -611,10 +604,10 @@ func GetMessageComments(tdlibClient crawler.TDLibClient, chatID, messageID int64
 				Int64("messageID", messageID).
 				Int64("commentID", msg.Id).
 				Str("handle", comment.Handle).
-				Int("textLength", len(messageText)).
-				Int("viewCount", viewCount).
-				Int("replyCount", replyCount).
-				Int("reactionCount", reactionCount).
+				Int("textLength", len(comment.Text)).
+				Int("viewCount", comment.ViewCount).
+				Int("replyCount", comment.ReplyCount).
+				Int("reactionCount", len(comment.Reactions)).
 				Int("index", i).
 				Int("iteration", iterationCount).
 				Msg("Processed comment")
@@ -705,6 +698,15 @@ func GetMessageComments(tdlibClient crawler.TDLibClient, chatID, messageID int64
 		}
 	}
 
+	if replyDepth > 0 && threadChatID != 0 {
+		// Resolved parents count against the comment cap
+		maxParents := -1
+		if maxcomments >= 0 {
+			maxParents = max(maxcomments-len(comments), 0)
+		}
+		comments = resolveReplyChains(tdlibClient, threadChatID, comments, replyDepth, maxParents, captureSenderFlags)
+	}
+
 	log.Debug().
 		Str("channel", channelname).
 		Int64("chatID", chatID).
@@ -715,6 +717,63 @@ func GetMessageComments(tdlibClient crawler.TDLibClient, chatID, messageID int64
 
 	return comments, nil
 }
+
+// ChannelExternalURL returns the public link to a channel. Channels with a
+// username are linked as https://t.me/<username>; private channels, which can
+// only be opened by members, as https://t.me/c/<id>. When the supergroup could
+// not be fetched, channelName, the username the channel was resolved by, is used.
+func ChannelExternalURL(chat *client.Chat, supergroup *client.Supergroup, channelName string) string {
+	if supergroup != nil {
+		if supergroup.Usernames != nil && len(supergroup.Usernames.ActiveUsernames) > 0 {
+			return "https://t.me/" + supergroup.Usernames.ActiveUsernames[0]
+		}
+		return fmt.Sprintf("https://t.me/c/%d", supergroup.Id)
+	}
+	if channelName != "" {
+		return "https://t.me/" + channelName
+	}
+	if chat != nil {
+		if supergroupType, ok := chat.Type.(*client.ChatTypeSupergroup); ok {
+			return fmt.Sprintf("https://t.me/c/%d", supergroupType.SupergroupId)
+		}
+	}
+	return ""
+}
+
+// GetForwardedFrom extracts the origin of a forwarded message.
+// It returns nil if the message was not forwarded or its origin is unknown.
+func GetForwardedFrom(msg *client.Message) *model.ForwardedFrom {
+	if msg == nil || msg.ForwardInfo == nil || msg.ForwardInfo.Origin == nil {
+		return nil
+	}
+
+	forwarded := &model.ForwardedFrom{
+		OriginalDate: time.Unix(int64(msg.ForwardInfo.Date), 0),
+	}
+
+	switch origin := msg.ForwardInfo.Origin.(type) {
+	case *client.MessageOriginChannel:
+		forwarded.OriginType = model.ForwardOriginChannel
+		forwarded.ChannelID = fmt.Sprintf("%d", origin.ChatId)
+		forwarded.MessageID = origin.MessageId
+		forwarded.AuthorSignature = origin.AuthorSignature
+	case *client.MessageOriginUser:
+		forwarded.OriginType = model.ForwardOriginUser
+		forwarded.SenderID = fmt.Sprintf("%d", origin.SenderUserId)
+	case *client.MessageOriginHiddenUser:
+		forwarded.OriginType = model.ForwardOriginHiddenUser
+		forwarded.SenderName = origin.SenderName
+	case *client.MessageOriginChat:
+		forwarded.OriginType = model.ForwardOriginChat
+		forwarded.ChannelID = fmt.Sprintf("%d", origin.SenderChatId)
+		forwarded.AuthorSignature = origin.AuthorSignature
+	default:
+		log.Debug().Str("type", fmt.Sprintf("%T", origin)).Msg("Unknown forward origin type")
+		return nil
+	}
+
+	return forwarded
+}
 func GetPoster(tdlibClient crawler.TDLibClient, msg *client.Message) string {
 	// Set default username
 	username := "unknown"
@@ -820,4 +879,179 @@ func GetPoster(tdlibClient crawler.TDLibClient, msg *client.Message) string {
 	}
 
 	return username
-}
\ No newline at end of file
+}
+
+// GetSenderID returns the numeric identifier of the user or chat that sent the
+// message, formatted as a string. An empty string is returned when the sender
+// is unknown.
+func GetSenderID(msg *client.Message) string {
+	if msg == nil || msg.SenderId == nil {
+		return ""
+	}
+
+	switch sender := msg.SenderId.(type) {
+	case *client.MessageSenderUser:
+		if sender != nil {
+			return fmt.Sprintf("%d", sender.UserId)
+		}
+	case *client.MessageSenderChat:
+		if sender != nil {
+			return fmt.Sprintf("%d", sender.ChatId)
+		}
+	}
+	return ""
+}
+
+// GetReplyToMessageID returns the identifier of the message that msg replies to,
+// or 0 if msg is not a reply to a message.
+func GetReplyToMessageID(msg *client.Message) int64 {
+	if msg == nil || msg.ReplyTo == nil {
+		return 0
+	}
+	if reply, ok := msg.ReplyTo.(*client.MessageReplyToMessage); ok && reply != nil {
+		return reply.MessageId
+	}
+	return 0
+}
+
+// GetReplyToChatID returns the identifier of the chat holding the message that
+// msg replies to when it differs from msg's own chat, e.g. a channel post that
+// replies to a message in another channel. It returns 0 for replies within the
+// same chat, for non-replies, and when TDLib does not know the other chat.
+func GetReplyToChatID(msg *client.Message) int64 {
+	if msg == nil || msg.ReplyTo == nil {
+		return 0
+	}
+	reply, ok := msg.ReplyTo.(*client.MessageReplyToMessage)
+	if !ok || reply == nil || reply.ChatId == msg.ChatId {
+		return 0
+	}
+	return reply.ChatId
+}
+
+// commentFromMessage converts a message in a discussion thread to a comment.
+// Sender flags are only resolved when captureSenderFlags is set, since each
+// costs one GetUser request.
+func commentFromMessage(tdlibClient crawler.TDLibClient, msg *client.Message, captureSenderFlags bool) model.Comment {
+	comment := model.Comment{
+		Handle:    GetPoster(tdlibClient, msg),
+		SenderID:  GetSenderID(msg),
+		MessageID: msg.Id,
+		ParentID:  getCommentParentID(msg),
+	}
+	if captureSenderFlags {
+		comment.SenderFlags = GetSenderFlags(tdlibClient, msg)
+	}
+
+	if textContent, ok := msg.Content.(*client.MessageText); ok && textContent != nil && textContent.Text != nil {
+		comment.Text = textContent.Text.Text
+	}
+
+	if msg.InteractionInfo != nil {
+		if msg.InteractionInfo.Reactions != nil && len(msg.InteractionInfo.Reactions.Reactions) > 0 {
+			comment.Reactions = make(map[string]int)
+			for _, reaction := range msg.InteractionInfo.Reactions.Reactions {
+				if emojiReaction, ok := reaction.Type.(*client.ReactionTypeEmoji); ok && emojiReaction != nil {
+					comment.Reactions[emojiReaction.Emoji] = int(reaction.TotalCount)
+				}
+			}
+		}
+		if msg.InteractionInfo.ReplyInfo != nil {
+			comment.ReplyCount = int(msg.InteractionInfo.ReplyInfo.ReplyCount)
+		}
+		comment.ViewCount = int(msg.InteractionInfo.ViewCount)
+	}
+
+	return comment
+}
+
+// resolveReplyChains follows each comment's ParentID up through its
+// ancestors, fetching parents from threadChatID that were not among the
+// fetched comments (e.g. because the comment cap cut the thread short), so the
+// thread's structure can be rebuilt. Each chain is followed for at most
+// maxDepth replies; comments nested deeper are kept, only their chains are
+// left unresolved beyond that. At most maxParents parents are fetched (-1 for
+// no limit), so they count against the comment cap. A parent that cannot be
+// fetched, e.g. because it was deleted, ends its chain. Resolved parents are
+// appended after the fetched comments.
+func resolveReplyChains(tdlibClient crawler.TDLibClient, threadChatID int64, comments []model.Comment, maxDepth int, maxParents int, captureSenderFlags bool) []model.Comment {
+	byID := make(map[int64]model.Comment, len(comments))
+	for _, c := range comments {
+		byID[c.MessageID] = c
+	}
+	unavailable := make(map[int64]bool)
+	var resolved []model.Comment
+
+	for _, c := range comments {
+		current := c
+		for hops := 0; hops < maxDepth && current.ParentID != 0; hops++ {
+			parent, ok := byID[current.ParentID]
+			if !ok {
+				if unavailable[current.ParentID] || (maxParents >= 0 && len(resolved) >= maxParents) {
+					break
+				}
+				var msg *client.Message
+				err := withFloodWait("get_message", func() error {
+					var err error
+					msg, err = tdlibClient.GetMessage(&client.GetMessageRequest{ChatId: threadChatID, MessageId: current.ParentID})
+					return err
+				})
+				if err != nil || msg == nil {
+					log.Debug().Err(err).Int64("commentID", current.MessageID).Int64("parentID", current.ParentID).Msg("Could not fetch parent comment, leaving the reply chain unresolved")
+					unavailable[current.ParentID] = true
+					break
+				}
+				parent = commentFromMessage(tdlibClient, msg, captureSenderFlags)
+				byID[parent.MessageID] = parent
+				resolved = append(resolved, parent)
+			}
+			current = parent
+		}
+	}
+	return append(comments, resolved...)
+}
+
+// getCommentParentID returns the message ID of the comment that msg replies to
+// within a discussion thread. Replies to the thread's root message are top-level
+// comments and have no parent, so 0 is returned for them.
+func getCommentParentID(msg *client.Message) int64 {
+	parentID := GetReplyToMessageID(msg)
+	if parentID == msg.MessageThreadId {
+		return 0
+	}
+	return parentID
+}
+
+// GetSenderFlags resolves the sender of msg and returns its premium, verified,
+// scam and fake flags. It returns nil when the sender is not a user or the user
+// cannot be fetched. Each call costs one GetUser request.
+func GetSenderFlags(tdlibClient crawler.TDLibClient, msg *client.Message) (flags *model.SenderFlags) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().Interface("panic", r).Msg("Recovered from panic in GetSenderFlags")
+			flags = nil
+		}
+	}()
+
+	if tdlibClient == nil || msg == nil {
+		return nil
+	}
+
+	sender, ok := msg.SenderId.(*client.MessageSenderUser)
+	if !ok || sender == nil {
+		return nil
+	}
+
+	user, err := tdlibClient.GetUser(&client.GetUserRequest{UserId: sender.UserId})
+	if err != nil || user == nil {
+		log.Debug().Err(err).Int64("userId", sender.UserId).Msg("Unable to fetch user for sender flags")
+		return nil
+	}
+
+	return &model.SenderFlags{
+		IsPremium:  user.IsPremium,
+		IsVerified: user.IsVerified,
+		IsScam:     user.IsScam,
+		IsFake:     user.IsFake,
+	}
+}
@@ -0,0 +1,386 @@
+package telegramhelper
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+func TestGetCommentParentID(t *testing.T) {
+	// Thread root is message 100; comment 101 replies to the root, 102 replies to 101
+	topLevel := &client.Message{
+		Id:              101,
+		MessageThreadId: 100,
+		ReplyTo:         &client.MessageReplyToMessage{MessageId: 100},
+	}
+	nested := &client.Message{
+		Id:              102,
+		MessageThreadId: 100,
+		ReplyTo:         &client.MessageReplyToMessage{MessageId: 101},
+	}
+	noReply := &client.Message{Id: 103, MessageThreadId: 100}
+
+	assert.Equal(t, int64(0), getCommentParentID(topLevel), "Replies to the thread root are top-level")
+	assert.Equal(t, int64(101), getCommentParentID(nested), "Nested replies should point to their parent comment")
+	assert.Equal(t, int64(0), getCommentParentID(noReply))
+	assert.Equal(t, int64(101), GetReplyToMessageID(nested))
+	assert.Equal(t, int64(0), GetReplyToMessageID(nil))
+}
+
+func TestGetForwardedFrom(t *testing.T) {
+	assert.Nil(t, GetForwardedFrom(&client.Message{}), "Original messages have no forward origin")
+
+	tests := []struct {
+		name   string
+		origin client.MessageOrigin
+		want   model.ForwardedFrom
+	}{
+		{
+			name:   "channel",
+			origin: &client.MessageOriginChannel{ChatId: -1001, MessageId: 42, AuthorSignature: "editor"},
+			want:   model.ForwardedFrom{OriginType: model.ForwardOriginChannel, ChannelID: "-1001", MessageID: 42, AuthorSignature: "editor"},
+		},
+		{
+			name:   "user",
+			origin: &client.MessageOriginUser{SenderUserId: 7},
+			want:   model.ForwardedFrom{OriginType: model.ForwardOriginUser, SenderID: "7"},
+		},
+		{
+			name:   "hidden user",
+			origin: &client.MessageOriginHiddenUser{SenderName: "Anon"},
+			want:   model.ForwardedFrom{OriginType: model.ForwardOriginHiddenUser, SenderName: "Anon"},
+		},
+		{
+			name:   "chat",
+			origin: &client.MessageOriginChat{SenderChatId: -1002},
+			want:   model.ForwardedFrom{OriginType: model.ForwardOriginChat, ChannelID: "-1002"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &client.Message{ForwardInfo: &client.MessageForwardInfo{Origin: tt.origin, Date: 1600000000}}
+			tt.want.OriginalDate = time.Unix(1600000000, 0)
+
+			got := GetForwardedFrom(msg)
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+}
+
+// userClient returns its users by ID and counts GetUser requests
+type userClient struct {
+	MockTDLibClient
+	users    map[int64]*client.User
+	requests int
+}
+
+func (c *userClient) GetUser(req *client.GetUserRequest) (*client.User, error) {
+	c.requests++
+	user, ok := c.users[req.UserId]
+	if !ok {
+		return nil, fmt.Errorf("user %d not found", req.UserId)
+	}
+	return user, nil
+}
+
+func TestGetSenderFlags(t *testing.T) {
+	tdlibClient := &userClient{users: map[int64]*client.User{
+		7: {Id: 7, IsPremium: true, IsVerified: true},
+		8: {Id: 8, IsScam: true, IsFake: true},
+	}}
+
+	flags := GetSenderFlags(tdlibClient, &client.Message{SenderId: &client.MessageSenderUser{UserId: 7}})
+	require.NotNil(t, flags)
+	assert.Equal(t, model.SenderFlags{IsPremium: true, IsVerified: true}, *flags)
+
+	flags = GetSenderFlags(tdlibClient, &client.Message{SenderId: &client.MessageSenderUser{UserId: 8}})
+	require.NotNil(t, flags)
+	assert.Equal(t, model.SenderFlags{IsScam: true, IsFake: true}, *flags)
+	assert.Equal(t, 2, tdlibClient.requests)
+
+	assert.Nil(t, GetSenderFlags(tdlibClient, &client.Message{SenderId: &client.MessageSenderUser{UserId: 9}}), "Users that can't be fetched have no flags")
+	assert.Nil(t, GetSenderFlags(tdlibClient, &client.Message{SenderId: &client.MessageSenderChat{ChatId: -1001}}), "Channels have no user flags")
+	assert.Equal(t, 3, tdlibClient.requests, "Chat senders should not be looked up")
+	assert.Nil(t, GetSenderFlags(nil, &client.Message{SenderId: &client.MessageSenderUser{UserId: 7}}))
+}
+
+func TestParseMessage_ForwardedFrom(t *testing.T) {
+	message := &client.Message{
+		Id:      5,
+		ChatId:  -1003,
+		Date:    int32(time.Now().Unix()),
+		Content: &client.MessageText{Text: &client.FormattedText{Text: "reshared"}},
+		ForwardInfo: &client.MessageForwardInfo{
+			Origin: &client.MessageOriginChannel{ChatId: -1001, MessageId: 42},
+			Date:   1600000000,
+		},
+	}
+	mlr := &client.MessageLink{Link: "https://t.me/example/5"}
+	chat := &client.Chat{Id: -1003, Title: "Example"}
+
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	require.NotNil(t, post.ForwardedFrom)
+	assert.Equal(t, model.ForwardOriginChannel, post.ForwardedFrom.OriginType)
+	assert.Equal(t, "-1001", post.ForwardedFrom.ChannelID)
+	assert.Equal(t, int64(42), post.ForwardedFrom.MessageID)
+	assert.Equal(t, time.Unix(1600000000, 0), post.ForwardedFrom.OriginalDate)
+	assert.Equal(t, "reshared", post.Description)
+}
+
+func TestChannelExternalURL(t *testing.T) {
+	chat := &client.Chat{Id: -1001234567890, Type: &client.ChatTypeSupergroup{SupergroupId: 1234567890, IsChannel: true}}
+
+	tests := []struct {
+		name        string
+		supergroup  *client.Supergroup
+		channelName string
+		want        string
+	}{
+		{
+			name:        "public channel",
+			supergroup:  &client.Supergroup{Id: 1234567890, Usernames: &client.Usernames{ActiveUsernames: []string{"examplenews", "examplenews_alt"}}},
+			channelName: "examplenews_alt",
+			want:        "https://t.me/examplenews",
+		},
+		{
+			name:       "private channel",
+			supergroup: &client.Supergroup{Id: 1234567890, Usernames: &client.Usernames{}},
+			want:       "https://t.me/c/1234567890",
+		},
+		{
+			name:        "supergroup unavailable, resolved by username",
+			channelName: "examplenews",
+			want:        "https://t.me/examplenews",
+		},
+		{
+			name: "supergroup unavailable, no username",
+			want: "https://t.me/c/1234567890",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ChannelExternalURL(chat, tt.supergroup, tt.channelName))
+		})
+	}
+}
+
+func TestParseMessage_PlatformName(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	message := &client.Message{Id: 1, ChatId: chat.Id, Date: int32(time.Now().Unix()), Content: &client.MessageText{Text: &client.FormattedText{Text: "hi"}}}
+
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, "Telegram", post.PlatformName)
+	assert.Equal(t, "https://t.me/example", post.ChannelData.ChannelURLExternal)
+
+	post, err = ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", nil, nil, common.CrawlerConfig{PlatformName: "Telegram Archive"})
+	require.NoError(t, err)
+	assert.Equal(t, "Telegram Archive", post.PlatformName)
+}
+
+// threadClient serves a discussion thread of comments, newest first, and
+// records the batch sizes requested from GetMessageThreadHistory
+type threadClient struct {
+	flakyDownloadClient
+	comments []*client.Message
+	limits   []int32
+}
+
+func newThreadClient(count int) *threadClient {
+	c := &threadClient{}
+	for id := int64(count); id > 0; id-- {
+		c.comments = append(c.comments, &client.Message{
+			Id:      id,
+			Content: &client.MessageText{Text: &client.FormattedText{Text: fmt.Sprintf("comment %d", id)}},
+		})
+	}
+	return c
+}
+
+func (c *threadClient) GetMessageThreadHistory(req *client.GetMessageThreadHistoryRequest) (*client.Messages, error) {
+	c.limits = append(c.limits, req.Limit)
+	var batch []*client.Message
+	for _, m := range c.comments {
+		if req.FromMessageId != 0 && m.Id >= req.FromMessageId {
+			continue
+		}
+		if len(batch) == int(req.Limit) {
+			break
+		}
+		batch = append(batch, m)
+	}
+	return &client.Messages{TotalCount: int32(len(batch)), Messages: batch}, nil
+}
+
+func TestGetMessageComments_RespectsMaxComments(t *testing.T) {
+	tdlibClient := newThreadClient(500)
+	comments, err := GetMessageComments(tdlibClient, -1001, 1, "example", 150, 500, false, 0)
+	require.NoError(t, err)
+	assert.Len(t, comments, 150)
+	assert.Equal(t, []int32{100, 50}, tdlibClient.limits, "The last batch should only request the comments still needed")
+
+	tdlibClient = newThreadClient(500)
+	comments, err = GetMessageComments(tdlibClient, -1001, 1, "example", -1, 500, false, 0)
+	require.NoError(t, err)
+	assert.Len(t, comments, 500, "-1 fetches every comment")
+
+	tdlibClient = newThreadClient(500)
+	comments, err = GetMessageComments(tdlibClient, -1001, 1, "example", 0, 500, false, 0)
+	require.NoError(t, err)
+	assert.Empty(t, comments)
+	assert.Empty(t, tdlibClient.limits, "A cap of zero should not call TDLib")
+}
+
+// replyThreadClient serves a discussion thread and returns older comments
+// that fell outside the fetched batches from GetMessage
+type replyThreadClient struct {
+	*threadClient
+	byID map[int64]*client.Message
+}
+
+func (c *replyThreadClient) GetMessage(req *client.GetMessageRequest) (*client.Message, error) {
+	if m, ok := c.byID[req.MessageId]; ok && req.ChatId == m.ChatId {
+		return m, nil
+	}
+	return nil, fmt.Errorf("message %d not found", req.MessageId)
+}
+
+// newReplyThreadClient builds a thread under root message 100 of discussion
+// chat -2002 where 101 is a top-level comment, 102 replies to 101 and 103
+// replies to 102
+func newReplyThreadClient() *replyThreadClient {
+	c := &replyThreadClient{threadClient: &threadClient{}, byID: make(map[int64]*client.Message)}
+	for _, ids := range [][2]int64{{103, 102}, {102, 101}, {101, 100}} {
+		m := &client.Message{
+			Id:              ids[0],
+			ChatId:          -2002,
+			MessageThreadId: 100,
+			ReplyTo:         &client.MessageReplyToMessage{ChatId: -2002, MessageId: ids[1]},
+			Content:         &client.MessageText{Text: &client.FormattedText{Text: fmt.Sprintf("comment %d", ids[0])}},
+		}
+		c.comments = append(c.comments, m)
+		c.byID[m.Id] = m
+	}
+	return c
+}
+
+func TestGetMessageComments_ReplyDepth(t *testing.T) {
+	parents := func(comments []model.Comment) map[int64]int64 {
+		got := make(map[int64]int64)
+		for _, c := range comments {
+			got[c.MessageID] = c.ParentID
+		}
+		return got
+	}
+	// newestOnly serves only the newest comment from the thread, so its
+	// parents have to be resolved one by one
+	newestOnly := func() *replyThreadClient {
+		c := newReplyThreadClient()
+		c.comments = c.comments[:1]
+		return c
+	}
+
+	comments, err := GetMessageComments(newReplyThreadClient(), -1001, 100, "example", -1, 3, false, 0)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{101: 0, 102: 101, 103: 102}, parents(comments), "Depth 0 keeps the whole thread")
+
+	comments, err = GetMessageComments(newReplyThreadClient(), -1001, 100, "example", -1, 3, false, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{101: 0, 102: 101, 103: 102}, parents(comments), "Comments nested deeper than the depth are kept")
+
+	comments, err = GetMessageComments(newestOnly(), -1001, 100, "example", -1, 1, false, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{103: 102, 102: 101}, parents(comments), "Depth 1 resolves only the direct parent")
+
+	comments, err = GetMessageComments(newestOnly(), -1001, 100, "example", -1, 1, false, 3)
+	require.NoError(t, err)
+	require.Len(t, comments, 3)
+	assert.Equal(t, int64(103), comments[0].MessageID, "Fetched comments come before resolved parents")
+	assert.Equal(t, map[int64]int64{101: 0, 102: 101, 103: 102}, parents(comments))
+	assert.Equal(t, "comment 101", comments[2].Text)
+
+	comments, err = GetMessageComments(newestOnly(), -1001, 100, "example", 2, 1, false, 3)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{103: 102, 102: 101}, parents(comments), "Resolved parents count against the comment cap")
+}
+
+// floodingReplyThreadClient fails the first lookup of comment 102 with a
+// flood wait
+type floodingReplyThreadClient struct {
+	*replyThreadClient
+	flooded bool
+}
+
+func (c *floodingReplyThreadClient) GetMessage(req *client.GetMessageRequest) (*client.Message, error) {
+	if req.MessageId == 102 && !c.flooded {
+		c.flooded = true
+		return nil, client.ResponseError{Err: &client.Error{Code: 420, Message: "FLOOD_WAIT_3"}}
+	}
+	return c.replyThreadClient.GetMessage(req)
+}
+
+func TestGetMessageComments_ReplyDepthRetriesFloodWaits(t *testing.T) {
+	waits := recordFloodWaits(t)
+	tdlibClient := &floodingReplyThreadClient{replyThreadClient: newReplyThreadClient()}
+	tdlibClient.comments = tdlibClient.comments[:1]
+
+	comments, err := GetMessageComments(tdlibClient, -1001, 100, "example", -1, 1, false, 1)
+	require.NoError(t, err)
+	require.Len(t, comments, 2, "A flood wait should be retried rather than end the chain")
+	assert.Equal(t, int64(102), comments[1].MessageID)
+	assert.Len(t, *waits, 1)
+}
+
+func TestParseMessage_SkipComments(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	message := &client.Message{
+		Id:              1,
+		ChatId:          chat.Id,
+		Date:            int32(time.Now().Unix()),
+		Content:         &client.MessageText{Text: &client.FormattedText{Text: "hi"}},
+		InteractionInfo: &client.MessageInteractionInfo{ReplyInfo: &client.MessageReplyInfo{ReplyCount: 3}},
+	}
+
+	tdlibClient := newThreadClient(3)
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, nil, common.CrawlerConfig{MaxComments: -1, SkipComments: true})
+	require.NoError(t, err)
+	assert.Empty(t, post.Comments)
+	assert.Empty(t, tdlibClient.limits)
+
+	post, err = ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, nil, common.CrawlerConfig{MaxComments: -1})
+	require.NoError(t, err)
+	assert.Len(t, post.Comments, 3)
+}
+
+func TestParseMessage_SearchText(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+	message := &client.Message{
+		Id:              1,
+		ChatId:          chat.Id,
+		Date:            int32(time.Now().Unix()),
+		Content:         &client.MessagePhoto{Caption: &client.FormattedText{Text: "Café  CRÈME Brûlée"}, Photo: &client.Photo{}},
+		InteractionInfo: &client.MessageInteractionInfo{ReplyInfo: &client.MessageReplyInfo{ReplyCount: 2}},
+	}
+
+	tdlibClient := newThreadClient(2)
+	tdlibClient.comments[0].Content = &client.MessageText{Text: &client.FormattedText{Text: "Délicieux!"}}
+	post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, nil, common.CrawlerConfig{MaxComments: -1})
+	require.NoError(t, err)
+	require.Len(t, post.Comments, 2)
+
+	assert.Equal(t, "Café  CRÈME Brûlée\nDélicieux!\ncomment 1", post.AllText)
+	assert.Equal(t, "cafe creme brulee delicieux! comment 1", post.SearchableText)
+}
@@ -0,0 +1,62 @@
+package telegramhelper
+
+import (
+	"sync"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/crawler"
+	"github.com/rs/zerolog/log"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// topicKey identifies a forum topic within its chat.
+type topicKey struct {
+	chatID   int64
+	threadID int64
+}
+
+// topicNames caches the names of resolved forum topics, so the messages of a
+// topic don't each look its name up again.
+var topicNames sync.Map
+
+// GetTopicID returns the message thread ID of the forum topic the message
+// belongs to, or 0 if it wasn't posted in a forum topic. Comment threads of
+// channel posts also have a thread ID, so it only counts for topic messages
+// or messages of a forum supergroup. supergroup may be nil.
+func GetTopicID(message *client.Message, supergroup *client.Supergroup) int64 {
+	if message == nil || message.MessageThreadId == 0 {
+		return 0
+	}
+	if message.IsTopicMessage || (supergroup != nil && supergroup.IsForum) {
+		return message.MessageThreadId
+	}
+	return 0
+}
+
+// GetTopicName returns the name of the forum topic with the given message
+// thread ID in the chat. It returns "" if the topic can't be looked up.
+func GetTopicName(tdlibClient crawler.TDLibClient, chatID, threadID int64) string {
+	if tdlibClient == nil || threadID == 0 {
+		return ""
+	}
+	key := topicKey{chatID: chatID, threadID: threadID}
+	if name, ok := topicNames.Load(key); ok {
+		return name.(string)
+	}
+
+	var topic *client.ForumTopic
+	err := withFloodWait("get_forum_topic", func() error {
+		var err error
+		topic, err = tdlibClient.GetForumTopic(&client.GetForumTopicRequest{
+			ChatId:          chatID,
+			MessageThreadId: threadID,
+		})
+		return err
+	})
+	if err != nil || topic == nil || topic.Info == nil {
+		log.Debug().Err(err).Int64("chat_id", chatID).Int64("thread_id", threadID).Msg("Failed to look up forum topic")
+		return ""
+	}
+
+	topicNames.Store(key, topic.Info.Name)
+	return topic.Info.Name
+}
@@ -0,0 +1,76 @@
+package telegramhelper
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// forumTopicClient knows the topics of one forum supergroup
+type forumTopicClient struct {
+	MockTDLibClient
+	topics      map[int64]string
+	topicLookup int
+}
+
+func (f *forumTopicClient) GetMessage(req *client.GetMessageRequest) (*client.Message, error) {
+	return &client.Message{Id: req.MessageId, ChatId: req.ChatId}, nil
+}
+
+func (f *forumTopicClient) GetForumTopic(req *client.GetForumTopicRequest) (*client.ForumTopic, error) {
+	f.topicLookup++
+	name, ok := f.topics[req.MessageThreadId]
+	if !ok {
+		return nil, fmt.Errorf("topic %d not found", req.MessageThreadId)
+	}
+	return &client.ForumTopic{Info: &client.ForumTopicInfo{MessageThreadId: req.MessageThreadId, Name: name}}, nil
+}
+
+func TestParseMessage_ForumTopic(t *testing.T) {
+	chat := &client.Chat{Id: -1007001, Title: "Forum"}
+	tdlibClient := &forumTopicClient{topics: map[int64]string{3 << 20: "Announcements"}}
+	parse := func(id, threadID int64, isTopicMessage bool) (int64, string) {
+		message := &client.Message{
+			Id:              id,
+			ChatId:          chat.Id,
+			Date:            int32(time.Now().Unix()),
+			Content:         &client.MessageText{Text: &client.FormattedText{Text: "hello"}},
+			MessageThreadId: threadID,
+			IsTopicMessage:  isTopicMessage,
+		}
+		mlr := &client.MessageLink{Link: fmt.Sprintf("https://t.me/forum/%d", id)}
+		post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "forum", tdlibClient, nil, common.CrawlerConfig{})
+		require.NoError(t, err)
+		return post.TopicID, post.TopicName
+	}
+
+	topicID, topicName := parse(4<<20, 3<<20, true)
+	assert.Equal(t, int64(3<<20), topicID)
+	assert.Equal(t, "Announcements", topicName)
+
+	topicID, topicName = parse(5<<20, 3<<20, true)
+	assert.Equal(t, int64(3<<20), topicID)
+	assert.Equal(t, "Announcements", topicName)
+	assert.Equal(t, 1, tdlibClient.topicLookup, "The topic name should be looked up once")
+
+	topicID, topicName = parse(6<<20, 9<<20, true)
+	assert.Equal(t, int64(9<<20), topicID, "The topic is kept when its name can't be looked up")
+	assert.Empty(t, topicName)
+
+	topicID, topicName = parse(7<<20, 2<<20, false)
+	assert.Zero(t, topicID, "A comment thread outside a forum is not a topic")
+	assert.Empty(t, topicName)
+}
+
+func TestGetTopicID_ForumSupergroup(t *testing.T) {
+	message := &client.Message{MessageThreadId: 3 << 20}
+	assert.Equal(t, int64(3<<20), GetTopicID(message, &client.Supergroup{IsForum: true}))
+	assert.Zero(t, GetTopicID(message, &client.Supergroup{}))
+	assert.Zero(t, GetTopicID(message, nil))
+	assert.Zero(t, GetTopicID(&client.Message{IsTopicMessage: true}, nil))
+}
@@ -0,0 +1,54 @@
+package telegramhelper
+
+import (
+	"context"
+	"strings"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/enrich"
+	"github.com/rs/zerolog/log"
+)
+
+// transcriber returns the transcriber configured by cfg, or nil if
+// transcription is disabled.
+func transcriber(cfg common.CrawlerConfig) enrich.Transcriber {
+	if !cfg.Transcribe {
+		return nil
+	}
+	if cfg.Transcriber != nil {
+		return cfg.Transcriber
+	}
+	return enrich.WhisperCLI{Model: cfg.WhisperModel}
+}
+
+// transcribeMedia transcribes a downloaded audio or video file. A failure is
+// logged and yields no transcript, since transcription must not keep the post
+// from being stored.
+func transcribeMedia(t enrich.Transcriber, path string) (enrich.Transcript, bool) {
+	transcript, err := t.Transcribe(context.Background(), path)
+	if err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Transcription failed")
+		return enrich.Transcript{}, false
+	}
+	transcript.Text = strings.TrimSpace(transcript.Text)
+	return transcript, transcript.Text != ""
+}
+
+// joinTranscripts returns the text of every transcript, one file per line.
+func joinTranscripts(transcripts []enrich.Transcript) string {
+	texts := make([]string, 0, len(transcripts))
+	for _, t := range transcripts {
+		texts = append(texts, t.Text)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// transcriptLanguage returns the first detected spoken language.
+func transcriptLanguage(transcripts []enrich.Transcript) string {
+	for _, t := range transcripts {
+		if t.Language != "" {
+			return t.Language
+		}
+	}
+	return ""
+}
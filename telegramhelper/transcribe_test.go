@@ -0,0 +1,100 @@
+package telegramhelper
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/researchaccelerator-hub/telegram-scraper/common"
+	"github.com/researchaccelerator-hub/telegram-scraper/enrich"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zelenin/go-tdlib/client"
+)
+
+// mockTranscriber returns a fixed transcript and counts the files it was given
+type mockTranscriber struct {
+	transcript enrich.Transcript
+	calls      int
+}
+
+func (m *mockTranscriber) Transcribe(ctx context.Context, mediaPath string) (enrich.Transcript, error) {
+	if _, err := os.Stat(mediaPath); err != nil {
+		return enrich.Transcript{}, err
+	}
+	m.calls++
+	return m.transcript, nil
+}
+
+func TestParseMessage_Transcription(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	mlr := &client.MessageLink{Link: "https://t.me/example/1"}
+
+	tests := []struct {
+		name      string
+		content   client.MessageContent
+		remoteIDs []string
+		stored    int // Media files stored for the post
+	}{
+		{
+			name: "voice note",
+			content: &client.MessageVoiceNote{VoiceNote: &client.VoiceNote{
+				Voice: &client.File{Id: 3, Remote: &client.RemoteFile{Id: "remote-voice"}},
+			}},
+			remoteIDs: []string{"remote-voice"},
+			stored:    1,
+		},
+		{
+			name: "video",
+			// With an empty thumbnail, so only the video itself is downloaded
+			content: &client.MessageVideo{
+				Caption: &client.FormattedText{},
+				Video: &client.Video{
+					Thumbnail: &client.Thumbnail{File: &client.File{Remote: &client.RemoteFile{}}},
+					Video:     &client.File{Id: 3, Remote: &client.RemoteFile{Id: "remote-video"}},
+				},
+			},
+			// The video is only downloaded to be transcribed, not stored
+			remoteIDs: []string{"remote-video"},
+			stored:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tdlibClient := &remoteFileRecorder{dir: t.TempDir()}
+			speech := &mockTranscriber{transcript: enrich.Transcript{Text: " bonjour à tous ", Language: "fr"}}
+
+			message := &client.Message{Id: 1, ChatId: chat.Id, Date: int32(time.Now().Unix()), Content: tt.content}
+			cfg := common.CrawlerConfig{Transcribe: true, Transcriber: speech}
+			post, err := ParseMessage("crawl", message, mlr, chat, nil, nil, 0, 0, "example", tdlibClient, newTestStateManager(t), cfg)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.remoteIDs, tdlibClient.remoteIDs)
+			assert.Len(t, post.MediaStorageKeys, tt.stored)
+			assert.Equal(t, 1, speech.calls)
+			assert.Equal(t, "bonjour à tous", post.TranscriptText)
+			assert.Equal(t, "fr", post.TranscriptLanguage)
+			assert.Contains(t, post.SearchableText, "bonjour a tous")
+		})
+	}
+}
+
+func TestParseMessage_VideoNotDownloadedWithoutTranscription(t *testing.T) {
+	chat := &client.Chat{Id: -1001, Title: "Example"}
+	tdlibClient := &remoteFileRecorder{dir: t.TempDir()}
+
+	message := &client.Message{Id: 1, ChatId: chat.Id, Date: int32(time.Now().Unix()), Content: &client.MessageVideo{
+		Caption: &client.FormattedText{},
+		Video: &client.Video{
+			Thumbnail: &client.Thumbnail{File: &client.File{Id: 2, Remote: &client.RemoteFile{Id: "remote-thumb"}}},
+			Video:     &client.File{Id: 3, Remote: &client.RemoteFile{Id: "remote-video"}},
+		},
+	}}
+	post, err := ParseMessage("crawl", message, &client.MessageLink{Link: "https://t.me/example/1"}, chat, nil, nil, 0, 0, "example", tdlibClient, newTestStateManager(t), common.CrawlerConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"remote-thumb"}, tdlibClient.remoteIDs)
+	assert.Equal(t, "remote-video", post.MediaURL)
+	assert.Empty(t, post.TranscriptText)
+}
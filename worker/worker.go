@@ -415,6 +415,7 @@ func (w *Worker) workItemConfigToCrawlerConfig(config distributed.WorkItemConfig
 		Timeout:           config.Timeout,
 		Platform:          w.config.Platform,
 		MinPostDate:       config.MinPostDate,
+		MaxPostDate:       config.MaxPostDate,
 		PostRecency:       config.PostRecency,
 		DateBetweenMin:    config.DateBetweenMin,
 		DateBetweenMax:    config.DateBetweenMax,